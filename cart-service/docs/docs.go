@@ -108,7 +108,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add a product item to the user's shopping cart",
+                "description": "Add a product item to the user's shopping cart. Products sold in variants need the variant_id of the chosen SKU, each variant is a cart line of its own.",
                 "consumes": [
                     "application/json"
                 ],
@@ -138,7 +138,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request body / variant_id required",
                         "schema": {
                             "$ref": "#/definitions/cart-service_internal_domain.ErrorResponse"
                         }
@@ -149,6 +149,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/cart-service_internal_domain.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Product or variant not found",
+                        "schema": {
+                            "$ref": "#/definitions/cart-service_internal_domain.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Failed to add item",
                         "schema": {
@@ -165,7 +171,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the quantity of a specific product in the cart",
+                "description": "Update the quantity of a specific product, or one variant of it, in the cart",
                 "consumes": [
                     "application/json"
                 ],
@@ -184,6 +190,12 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID, for products sold in variants",
+                        "name": "variant_id",
+                        "in": "query"
+                    },
                     {
                         "description": "Updated quantity",
                         "name": "item",
@@ -227,7 +239,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove a specific product from the user's cart",
+                "description": "Remove a specific product, or one variant of it, from the user's cart",
                 "consumes": [
                     "application/json"
                 ],
@@ -245,6 +257,12 @@ const docTemplate = `{
                         "name": "product_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID, for products sold in variants",
+                        "name": "variant_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -290,6 +308,10 @@ const docTemplate = `{
                 "quantity": {
                     "type": "integer",
                     "minimum": 1
+                },
+                "variant_id": {
+                    "description": "VariantID is required for products sold in variants",
+                    "type": "integer"
                 }
             }
         },
@@ -319,6 +341,12 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "options": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "price": {
                     "type": "integer"
                 },
@@ -327,6 +355,13 @@ const docTemplate = `{
                 },
                 "quantity": {
                     "type": "integer"
+                },
+                "sku": {
+                    "type": "string"
+                },
+                "variant_id": {
+                    "description": "Variant details are only set for products sold in variants",
+                    "type": "integer"
                 }
             }
         },
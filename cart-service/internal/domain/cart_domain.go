@@ -1,5 +1,13 @@
 package domain
 
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrVariantRequired is returned when a product sold in variants is added without choosing one
+var ErrVariantRequired = errors.New("variant_id is required for products sold in variants")
+
 type Cart struct {
 	UserID   string     `json:"user_id"`
 	Items    []CartItem `json:"items"`
@@ -12,6 +20,24 @@ type CartItem struct {
 	Name	  string  `json:"name"`
 	Quantity  uint    `json:"quantity"`
 	Price     uint    `json:"price"`
+	// Variant details are only set for products sold in variants
+	VariantID uint              `json:"variant_id,omitempty"`
+	SKU       string            `json:"sku,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+}
+
+// Key is the field of the item in the cart hash, every variant of a product is a line of its own
+func (i *CartItem) Key() string {
+	return ItemKey(i.ProductID, i.VariantID)
+}
+
+// ItemKey is "<product_id>" for products without variants and "<product_id>:<variant_id>" for a variant
+func ItemKey(productID, variantID uint) string {
+	key := strconv.FormatUint(uint64(productID), 10)
+	if variantID != 0 {
+		key += ":" + strconv.FormatUint(uint64(variantID), 10)
+	}
+	return key
 }
 
 type SuccessResponse struct {
@@ -2,6 +2,8 @@ package domain
 
 type AddCartItemRequest struct {
 	ProductID uint  `json:"product_id" binding:"required"`
+	// VariantID is required for products sold in variants
+	VariantID uint     `json:"variant_id"`
 	Quantity  uint     `json:"quantity" binding:"required,min=1"`
 }
 
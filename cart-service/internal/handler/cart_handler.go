@@ -3,6 +3,7 @@ package handler
 import (
 	"cart-service/internal/domain"
 	"cart-service/internal/service"
+	"errors"
 	"strconv"
 	"strings"
 
@@ -48,15 +49,16 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 
 // AddToCart godoc
 // @Summary Add item to cart
-// @Description Add a product item to the user's shopping cart
+// @Description Add a product item to the user's shopping cart. Products sold in variants need the variant_id of the chosen SKU, each variant is a cart line of its own.
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param item body domain.AddCartItemRequest true "Cart item to add"
 // @Success 200 {object} domain.SuccessResponse "Item added successfully"
-// @Failure 400 {object} domain.ErrorResponse "Invalid request body"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / variant_id required"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 404 {object} domain.ErrorResponse "Product or variant not found"
 // @Failure 500 {object} domain.ErrorResponse "Failed to add item"
 // @Router /cart/item [post]
 func (h *CartHandler) AddToCart(c *gin.Context) {
@@ -76,6 +78,14 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 
 	err := h.cartService.AddToCart(ctx, userID, &addItemRequest)
 	if err != nil {
+		if errors.Is(err, domain.ErrVariantRequired) {
+			c.JSON(400, domain.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if strings.Contains(err.Error(), "variant not found") {
+			c.JSON(404, domain.ErrorResponse{Error: "Variant not found"})
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
         	c.JSON(404, domain.ErrorResponse{Error: "Product not found"})
         	return
@@ -89,12 +99,13 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 
 // RemoveFromCart godoc
 // @Summary Remove item from cart
-// @Description Remove a specific product from the user's cart
+// @Description Remove a specific product, or one variant of it, from the user's cart
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param product_id path string true "Product ID"
+// @Param variant_id query int false "Variant ID, for products sold in variants"
 // @Success 200 {object} domain.SuccessResponse "Item removed successfully"
 // @Failure 400 {object} domain.ErrorResponse "Invalid product ID format"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
@@ -111,16 +122,14 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 		return
 	}
 
-	// Get product ID from URL parameter
-	productIDStr := c.Param("product_id")
-	productID, err := strconv.ParseUint(productIDStr, 10, 64)
-	if err != nil {
-		c.JSON(400, domain.ErrorResponse{Error: "Invalid product ID format"})
+	// Get product and variant ID from the URL
+	key, ok := cartItemKey(c)
+	if !ok {
 		return
 	}
 
 	// Call service to remove cart item
-	err = h.cartService.RemoveCartItems(ctx, userID, []uint{uint(productID)})
+	err := h.cartService.RemoveCartItems(ctx, userID, []string{key})
 	if err != nil {
 		c.JSON(500, domain.ErrorResponse{Error: "Failed to remove cart item"})
 		return
@@ -163,12 +172,13 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 
 // UpdateCartItem godoc
 // @Summary Update cart item quantity
-// @Description Update the quantity of a specific product in the cart
+// @Description Update the quantity of a specific product, or one variant of it, in the cart
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param product_id path string true "Product ID"
+// @Param variant_id query int false "Variant ID, for products sold in variants"
 // @Param item body domain.UpdateCartItemRequest true "Updated quantity"
 // @Success 200 {object} domain.SuccessResponse "Item updated successfully"
 // @Failure 400 {object} domain.ErrorResponse "Invalid request"
@@ -186,11 +196,9 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 
-	// Get product ID from URL parameter
-	productIDStr := c.Param("product_id")
-	_, err := strconv.ParseUint(productIDStr, 10, 64)
-	if err != nil {
-		c.JSON(400, domain.ErrorResponse{Error: "Invalid product ID format"})
+	// Get product and variant ID from the URL
+	key, ok := cartItemKey(c)
+	if !ok {
 		return
 	}
 
@@ -202,11 +210,27 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 	}
 
 	// Call service to update cart item
-	err = h.cartService.UpdateCartItem(ctx, userID, productIDStr, updateRequest.Quantity)
+	err := h.cartService.UpdateCartItem(ctx, userID, key, updateRequest.Quantity)
 	if err != nil {
 		c.JSON(500, domain.ErrorResponse{Error: "Failed to update cart item"})
 		return
 	}
 
 	c.JSON(200, domain.SuccessResponse{Message: "Cart item updated successfully"})
-}
\ No newline at end of file
+}
+
+// cartItemKey reads the cart line addressed by the product_id path parameter and the optional variant_id
+// query parameter, answering 400 when either is malformed
+func cartItemKey(c *gin.Context) (string, bool) {
+	productID, err := strconv.ParseUint(c.Param("product_id"), 10, 64)
+	if err != nil {
+		c.JSON(400, domain.ErrorResponse{Error: "Invalid product ID format"})
+		return "", false
+	}
+	variantID, err := strconv.ParseUint(c.DefaultQuery("variant_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(400, domain.ErrorResponse{Error: "Invalid variant ID format"})
+		return "", false
+	}
+	return domain.ItemKey(uint(productID), uint(variantID)), true
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"cart-service/internal/domain"
 	"cart-service/internal/service"
 	"context"
 	"libs/pb"
@@ -29,6 +30,8 @@ func (s *CartGRPCServer) GetUserCart(ctx context.Context, req *pb.GetCartRequest
 	for _, item := range cart.Items {
 		items = append(items, &pb.CartItem{
 			ProductId: uint32(item.ProductID),
+			VariantId: uint32(item.VariantID),
+			Sku:       item.SKU,
 			Quantity:  uint32(item.Quantity),
 			Price:     uint64(item.Price),
 		})
@@ -65,6 +68,8 @@ func (s *CartGRPCServer) GetCartItems(ctx context.Context, req *pb.GetCartItemRe
 	for _, item := range cart {
 		items = append(items, &pb.CartItem{
 			ProductId: uint32(item.ProductID),
+			VariantId: uint32(item.VariantID),
+			Sku:       item.SKU,
 			Quantity:  uint32(item.Quantity),
 			Price:     uint64(item.Price),
 		})
@@ -89,7 +94,7 @@ func (s *CartGRPCServer) RemoveCartItems(ctx context.Context, req *pb.GetCartIte
 		productIds[i] = uint(id)
 	}
 
-	err = s.service.RemoveCartItems(ctx, uint(userId), productIds)
+	err = s.service.RemoveCartProducts(ctx, uint(userId), productIds)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +102,43 @@ func (s *CartGRPCServer) RemoveCartItems(ctx context.Context, req *pb.GetCartIte
 	return &pb.EmptyResponse{}, nil
 }
 
+func (s *CartGRPCServer) AddCartItems(ctx context.Context, req *pb.AddCartItemsRequest) (*pb.AddCartItemsResponse, error) {
+	userId, err := strconv.ParseUint(req.UserId, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.AddCartItemRequest, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, domain.AddCartItemRequest{ProductID: uint(item.ProductId), VariantID: uint(item.VariantId), Quantity: uint(item.Quantity)})
+	}
+
+	added, unavailable, err := s.service.AddCartItems(ctx, uint(userId), items)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.AddCartItemsResponse{}
+	for _, item := range added {
+		resp.Added = append(resp.Added, &pb.CartItem{
+			ProductId: uint32(item.ProductID),
+			VariantId: uint32(item.VariantID),
+			Sku:       item.SKU,
+			Name:      item.Name,
+			Quantity:  uint32(item.Quantity),
+			Price:     uint64(item.Price),
+		})
+	}
+	for _, item := range unavailable {
+		resp.Unavailable = append(resp.Unavailable, &pb.CartItem{
+			ProductId: uint32(item.ProductID),
+			VariantId: uint32(item.VariantID),
+			Quantity:  uint32(item.Quantity),
+		})
+	}
+	return resp, nil
+}
+
 func (s *CartGRPCServer) ClearUserCart(ctx context.Context, req *pb.GetCartRequest) (*pb.EmptyResponse, error) {
 	userId, err := strconv.ParseUint(req.UserId, 10, 64)
 	if err != nil {
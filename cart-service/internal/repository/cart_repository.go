@@ -4,7 +4,6 @@ import (
 	"cart-service/internal/domain"
 	"context"
 	"encoding/json"
-	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,8 +14,8 @@ type CartRepository interface {
 	GetCartItems(ctx context.Context, userID string, productIDs []uint) ([]*domain.CartItem, error)
 	SaveCart(ctx context.Context, userID string, item *domain.CartItem) error
 	ClearCart(ctx context.Context, userID string) error
-	DeleteCartItems(ctx context.Context, userID string, productIDs []uint) error
-	UpdateCartItem(ctx context.Context, userID string, productID string, qty uint) error
+	DeleteCartItems(ctx context.Context, userID string, keys []string) error
+	UpdateCartItem(ctx context.Context, userID string, key string, qty uint) error
 }
 
 type RedisCartRepository struct {
@@ -48,28 +47,23 @@ func (r *RedisCartRepository) GetCart(ctx context.Context, userID string) ([]*do
 	return items, nil
 }
 
+// GetCartItems returns the cart lines of the given products, every variant of a product included
 func (r *RedisCartRepository) GetCartItems(ctx context.Context, userID string, productIDs []uint) ([]*domain.CartItem, error) {
-	key := "cart:" + userID
-	strProductIDs := make([]string, len(productIDs))
-	for i, id := range productIDs {
-		strProductIDs[i] = strconv.FormatUint(uint64(id), 10)
-	}
-	result, err := r.redisClient.HMGet(ctx, key, strProductIDs...).Result()
-
+	// Variant lines are keyed by product and variant, so the whole hash is read and filtered
+	cartItems, err := r.GetCart(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	wanted := make(map[uint]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
 	var items []*domain.CartItem
-	for _, v := range result {
-		if v == nil {
-			continue
+	for _, item := range cartItems {
+		if wanted[item.ProductID] {
+			items = append(items, item)
 		}
-		var item domain.CartItem
-		if err := json.Unmarshal([]byte(v.(string)), &item); err != nil {
-			return nil, err
-		}
-		items = append(items, &item)
 	}
 
 	return items, nil
@@ -81,7 +75,7 @@ func (r *RedisCartRepository) SaveCart(ctx context.Context, userID string, item
 
     // Use a pipeline to set the field and update the expiration in one go
     pipe := r.redisClient.Pipeline()
-    pipe.HSet(ctx, key, item.ProductID, data)
+    pipe.HSet(ctx, key, item.Key(), data)
     pipe.Expire(ctx, key, 7 * 24 * time.Hour) // 7-day TTL
     
     _, err := pipe.Exec(ctx)
@@ -93,24 +87,25 @@ func (r *RedisCartRepository) ClearCart(ctx context.Context, userID string) erro
 	return r.redisClient.Del(ctx, key).Err()
 }
 
-func (r *RedisCartRepository) DeleteCartItems(ctx context.Context, userID string, productIDs []uint) error {
-	key := "cart:" + userID
-	strProductIDs := make([]string, len(productIDs))
-	for i, id := range productIDs {
-		strProductIDs[i] = strconv.FormatUint(uint64(id), 10)
+// DeleteCartItems removes cart lines by their domain.CartItem key
+func (r *RedisCartRepository) DeleteCartItems(ctx context.Context, userID string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
 	}
-	return r.redisClient.HDel(ctx, key, strProductIDs...).Err()
+	key := "cart:" + userID
+	return r.redisClient.HDel(ctx, key, keys...).Err()
 }
 
-func (r *RedisCartRepository) UpdateCartItem(ctx context.Context, userID string, productID string, qty uint) error {
+// UpdateCartItem sets the quantity of the cart line with the given domain.CartItem key
+func (r *RedisCartRepository) UpdateCartItem(ctx context.Context, userID string, itemKey string, qty uint) error {
     key := "cart:" + userID
 
     if qty == 0 {
-        return r.redisClient.HDel(ctx, key, productID).Err()
+        return r.redisClient.HDel(ctx, key, itemKey).Err()
     }
 
     // 1. Get existing item
-    result, err := r.redisClient.HGet(ctx, key, productID).Result()
+    result, err := r.redisClient.HGet(ctx, key, itemKey).Result()
     if err != nil {
         return err
     }
@@ -126,7 +121,7 @@ func (r *RedisCartRepository) UpdateCartItem(ctx context.Context, userID string,
 
     // 3. Use Pipeline to save and extend life of the cart
     pipe := r.redisClient.Pipeline()
-    pipe.HSet(ctx, key, productID, data)
+    pipe.HSet(ctx, key, itemKey, data)
     pipe.Expire(ctx, key, 7 * 24 * time.Hour) // Extend cart for another 7 days
     
     _, err = pipe.Exec(ctx)
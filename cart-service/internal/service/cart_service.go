@@ -55,13 +55,29 @@ func (s *CartService) GetCartItems(ctx context.Context, userID uint, productIDs
 
 func (s *CartService) AddToCart(ctx context.Context, userID uint, item *domain.AddCartItemRequest) error {
 	l := logger.ForContext(ctx)
-	resp, err := s.productClient.GetProduct(ctx, &pb.GetProductRequest{Id: uint32(item.ProductID)})
+	productsResp, err := s.productClient.GetProducts(ctx, &pb.GetProductsRequest{Ids: []uint32{uint32(item.ProductID)}})
 	if err != nil {
 		l.Error("failed to fetch product details", zap.Error(err))
 		return fmt.Errorf("failed to fetch product details: %w", err)
 	}
 
+	// Deleted products are returned by product service but can no longer be bought
+	var resp *pb.ProductResponse
+	for _, product := range productsResp.Products {
+		if product.Id == uint32(item.ProductID) && !product.Deleted {
+			resp = product
+		}
+	}
+	if resp == nil {
+		return fmt.Errorf("product not found: %d", item.ProductID)
+	}
+	variant, err := productVariant(resp, item.VariantID)
+	if err != nil {
+		return err
+	}
+
 	userIDStr := strconv.FormatUint(uint64(userID), 10)
+	key := domain.ItemKey(item.ProductID, item.VariantID)
 
 	// Check if item already exists
 	existingItems, err := s.repo.GetCart(ctx, userIDStr)
@@ -70,36 +86,136 @@ func (s *CartService) AddToCart(ctx context.Context, userID uint, item *domain.A
 		return fmt.Errorf("failed to get existing cart items: %w", err)
 	}
 	for _, existing := range existingItems {
-		if existing.ProductID == item.ProductID {
+		if existing.Key() == key {
 			// Update quantity instead of replacing
 			newQty := existing.Quantity + item.Quantity
-			err := s.repo.UpdateCartItem(ctx, userIDStr, strconv.FormatUint(uint64(item.ProductID), 10), newQty)
+			err := s.repo.UpdateCartItem(ctx, userIDStr, key, newQty)
 			if err != nil {
 				l.Error("failed to update cart item quantity", zap.Error(err))
 				return fmt.Errorf("failed to update cart item quantity: %w", err)
 			}
-			l.Info("Cart item quantity updated", zap.Uint("userID", userID), zap.Uint("productID", item.ProductID), zap.Uint("quantity", newQty))
+			l.Info("Cart item quantity updated", zap.Uint("userID", userID), zap.String("item", key), zap.Uint("quantity", newQty))
 			return nil
 		}
 	}
 
 	// If not exists, add new item
-	cartItem := &domain.CartItem{
-		ProductID: item.ProductID,
-		Quantity:  item.Quantity,
-		Name:      resp.Name,
-		Price:     uint(resp.Price),
-	}
+	cartItem := newCartItem(resp, variant, item.Quantity)
 
 	err = s.repo.SaveCart(ctx, userIDStr, cartItem)
 	if err != nil {
 		l.Error("failed to add item to cart", zap.Error(err))
 		return fmt.Errorf("failed to add item to cart: %w", err)
 	}
-	l.Info("Cart item added successfully", zap.Uint("userID", userID), zap.Uint("productID", item.ProductID), zap.Uint("quantity", item.Quantity))
+	l.Info("Cart item added successfully", zap.Uint("userID", userID), zap.String("item", key), zap.Uint("quantity", item.Quantity))
 	return nil
 }
 
+// productVariant returns the variant of product a cart line asks for. Products sold in variants need
+// one, for products without variants it is nil.
+func productVariant(product *pb.ProductResponse, variantID uint) (*pb.ProductVariant, error) {
+	if variantID == 0 {
+		if len(product.Variants) > 0 {
+			return nil, fmt.Errorf("%w: product %d", domain.ErrVariantRequired, product.Id)
+		}
+		return nil, nil
+	}
+	for _, variant := range product.Variants {
+		if variant.Id == uint32(variantID) && !variant.Deleted {
+			return variant, nil
+		}
+	}
+	return nil, fmt.Errorf("variant not found: %d", variantID)
+}
+
+// newCartItem builds a cart line at the current name and price of a product, or of its variant
+func newCartItem(product *pb.ProductResponse, variant *pb.ProductVariant, quantity uint) *domain.CartItem {
+	item := &domain.CartItem{
+		ProductID: uint(product.Id),
+		Quantity:  quantity,
+		Name:      product.Name,
+		Price:     uint(product.Price),
+	}
+	if variant != nil {
+		item.VariantID = uint(variant.Id)
+		item.SKU = variant.Sku
+		item.Options = variant.Options
+		item.Price = uint(variant.Price)
+	}
+	return item
+}
+
+// AddCartItems adds several products to the cart in one go. Items whose product or variant is deleted,
+// missing or short on stock for the quantity the cart line would end up with are skipped and returned as
+// unavailable. Items already in the cart get their quantity increased and their price refreshed.
+func (s *CartService) AddCartItems(ctx context.Context, userID uint, items []domain.AddCartItemRequest) ([]*domain.CartItem, []domain.AddCartItemRequest, error) {
+	l := logger.ForContext(ctx)
+	ids := make([]uint32, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, uint32(item.ProductID))
+	}
+
+	productsResp, err := s.productClient.GetProducts(ctx, &pb.GetProductsRequest{Ids: ids})
+	if err != nil {
+		l.Error("failed to fetch product details", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to fetch product details: %w", err)
+	}
+	products := make(map[uint]*pb.ProductResponse, len(productsResp.Products))
+	for _, product := range productsResp.Products {
+		products[uint(product.Id)] = product
+	}
+
+	userIDStr := strconv.FormatUint(uint64(userID), 10)
+	existingItems, err := s.repo.GetCart(ctx, userIDStr)
+	if err != nil {
+		l.Error("failed to get existing cart items", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to get existing cart items: %w", err)
+	}
+	existing := make(map[string]*domain.CartItem, len(existingItems))
+	for _, item := range existingItems {
+		existing[item.Key()] = item
+	}
+
+	var added []*domain.CartItem
+	var unavailable []domain.AddCartItemRequest
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok || !product.Available {
+			unavailable = append(unavailable, item)
+			continue
+		}
+		variant, err := productVariant(product, item.VariantID)
+		if err != nil {
+			unavailable = append(unavailable, item)
+			continue
+		}
+		stock := product.Stock
+		if variant != nil {
+			stock = variant.Stock
+		}
+
+		cartItem := newCartItem(product, variant, item.Quantity)
+		if current, ok := existing[cartItem.Key()]; ok {
+			cartItem.Quantity += current.Quantity
+		}
+		// The whole line has to be in stock, not just the quantity being added
+		if stock < int64(cartItem.Quantity) {
+			unavailable = append(unavailable, item)
+			continue
+		}
+
+		if err := s.repo.SaveCart(ctx, userIDStr, cartItem); err != nil {
+			l.Error("failed to add item to cart", zap.Error(err))
+			return nil, nil, fmt.Errorf("failed to add item to cart: %w", err)
+		}
+		existing[cartItem.Key()] = cartItem
+		added = append(added, newCartItem(product, variant, item.Quantity))
+	}
+
+	l.Info("Cart items added", zap.Uint("userID", userID), zap.Int("addedCount", len(added)), zap.Int("unavailableCount", len(unavailable)))
+	return added, unavailable, nil
+}
+
 func (s *CartService) ClearCart(ctx context.Context, userID uint) error {
 	l := logger.ForContext(ctx)
 	err := s.repo.ClearCart(ctx, strconv.FormatUint(uint64(userID), 10))
@@ -111,24 +227,39 @@ func (s *CartService) ClearCart(ctx context.Context, userID uint) error {
 	return nil
 }
 
-func (s *CartService) RemoveCartItems(ctx context.Context, userID uint, productIds []uint) error {
+// RemoveCartItems removes cart lines by their domain.CartItem key
+func (s *CartService) RemoveCartItems(ctx context.Context, userID uint, keys []string) error {
 	l := logger.ForContext(ctx)
-	err := s.repo.DeleteCartItems(ctx, strconv.FormatUint(uint64(userID), 10), productIds)
+	err := s.repo.DeleteCartItems(ctx, strconv.FormatUint(uint64(userID), 10), keys)
 	if err != nil {
 		l.Error("failed to remove cart items", zap.Error(err))
 		return fmt.Errorf("failed to remove cart items: %w", err)
 	}
-	l.Info("Cart items removed successfully", zap.Uint("userID", userID), zap.Int("itemCount", len(productIds)))
+	l.Info("Cart items removed successfully", zap.Uint("userID", userID), zap.Int("itemCount", len(keys)))
 	return nil
 }
 
-func (s *CartService) UpdateCartItem(ctx context.Context, userID uint, productId string, qty uint) error {
+// RemoveCartProducts removes every cart line of the given products, whatever their variant
+func (s *CartService) RemoveCartProducts(ctx context.Context, userID uint, productIDs []uint) error {
+	items, err := s.GetCartItems(ctx, userID, productIDs)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key())
+	}
+	return s.RemoveCartItems(ctx, userID, keys)
+}
+
+// UpdateCartItem sets the quantity of the cart line with the given domain.CartItem key
+func (s *CartService) UpdateCartItem(ctx context.Context, userID uint, key string, qty uint) error {
 	l := logger.ForContext(ctx)
-	err := s.repo.UpdateCartItem(ctx, strconv.FormatUint(uint64(userID), 10), productId, qty)
+	err := s.repo.UpdateCartItem(ctx, strconv.FormatUint(uint64(userID), 10), key, qty)
 	if err != nil {
 		l.Error("failed to update cart item", zap.Error(err))
 		return fmt.Errorf("failed to update cart item: %w", err)
 	}
-	l.Info("Cart item updated successfully", zap.Uint("userID", userID), zap.String("productID", productId), zap.Uint("quantity", qty))
+	l.Info("Cart item updated successfully", zap.Uint("userID", userID), zap.String("item", key), zap.Uint("quantity", qty))
 	return nil
 }
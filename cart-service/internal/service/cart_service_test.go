@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"cart-service/internal/domain"
@@ -18,9 +19,9 @@ type mockCartRepository struct {
 	savedItem   *domain.CartItem
 	savedUserID string
 
-	updatedUserID    string
-	updatedProductID string
-	updatedQty       uint
+	updatedUserID string
+	updatedKey    string
+	updatedQty    uint
 }
 
 func (m *mockCartRepository) GetCart(ctx context.Context, userID string) ([]*domain.CartItem, error) {
@@ -38,13 +39,13 @@ func (m *mockCartRepository) SaveCart(ctx context.Context, userID string, item *
 }
 
 func (m *mockCartRepository) ClearCart(ctx context.Context, userID string) error { return nil }
-func (m *mockCartRepository) DeleteCartItems(ctx context.Context, userID string, productIDs []uint) error {
+func (m *mockCartRepository) DeleteCartItems(ctx context.Context, userID string, keys []string) error {
 	return nil
 }
 
-func (m *mockCartRepository) UpdateCartItem(ctx context.Context, userID string, productID string, qty uint) error {
+func (m *mockCartRepository) UpdateCartItem(ctx context.Context, userID string, key string, qty uint) error {
 	m.updatedUserID = userID
-	m.updatedProductID = productID
+	m.updatedKey = key
 	m.updatedQty = qty
 	return nil
 }
@@ -61,6 +62,28 @@ func (m *mockProductClient) GetProduct(ctx context.Context, in *pb.GetProductReq
 	return m.productResp, nil
 }
 
+func (m *mockProductClient) GetProducts(ctx context.Context, in *pb.GetProductsRequest, opts ...grpc.CallOption) (*pb.GetProductsResponse, error) {
+	if m.productErr != nil {
+		return nil, m.productErr
+	}
+	if m.productResp == nil {
+		return &pb.GetProductsResponse{MissingIds: in.Ids}, nil
+	}
+	resp := &pb.GetProductsResponse{}
+	for _, id := range in.Ids {
+		resp.Products = append(resp.Products, &pb.ProductResponse{
+			Id:        id,
+			Name:      m.productResp.Name,
+			Price:     m.productResp.Price,
+			Stock:     m.productResp.Stock,
+			Deleted:   m.productResp.Deleted,
+			Available: m.productResp.Available,
+			Variants:  m.productResp.Variants,
+		})
+	}
+	return resp, nil
+}
+
 func (m *mockProductClient) UpdateStock(ctx context.Context, in *pb.UpdateStockRequest, opts ...grpc.CallOption) (*pb.UpdateStockResponse, error) {
 	return &pb.UpdateStockResponse{}, nil
 }
@@ -122,3 +145,103 @@ func TestAddToCartReturnsErrorWhenProductLookupFails(t *testing.T) {
 		t.Fatal("expected AddToCart to fail when product lookup fails")
 	}
 }
+
+func TestAddToCartRejectsDeletedProduct(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "Old Keyboard", Price: 500, Deleted: true}})
+
+	err := svc.AddToCart(context.Background(), 10, &domain.AddCartItemRequest{ProductID: 99, Quantity: 1})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected product not found error, got %v", err)
+	}
+	if repo.savedItem != nil {
+		t.Fatal("did not expect a deleted product to be added")
+	}
+}
+
+func TestAddCartItemsMergesExistingItemWithCurrentPrice(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{{ProductID: 5, Quantity: 1, Price: 80}}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "Mouse", Price: 100, Stock: 10, Available: true}})
+
+	added, unavailable, err := svc.AddCartItems(context.Background(), 21, []domain.AddCartItemRequest{{ProductID: 5, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("AddCartItems() error = %v", err)
+	}
+	if len(unavailable) != 0 || len(added) != 1 || added[0].Quantity != 2 || added[0].Price != 100 {
+		t.Fatalf("unexpected result: added=%v unavailable=%v", added, unavailable)
+	}
+	if repo.savedItem == nil || repo.savedItem.Quantity != 3 || repo.savedItem.Price != 100 {
+		t.Fatalf("expected cart item with qty 3 at current price, got %#v", repo.savedItem)
+	}
+}
+
+func TestAddCartItemsSkipsProductsWithoutEnoughStock(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "Mouse", Price: 100, Stock: 1, Available: true}})
+
+	added, unavailable, err := svc.AddCartItems(context.Background(), 21, []domain.AddCartItemRequest{{ProductID: 5, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("AddCartItems() error = %v", err)
+	}
+	if len(added) != 0 || len(unavailable) != 1 || unavailable[0].ProductID != 5 {
+		t.Fatalf("expected product 5 unavailable, got added=%v unavailable=%v", added, unavailable)
+	}
+	if repo.savedItem != nil {
+		t.Fatal("did not expect an unavailable product to be saved")
+	}
+}
+
+func TestAddCartItemsChecksStockForTheWholeCartLine(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{{ProductID: 5, Quantity: 2, Price: 100}}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "Mouse", Price: 100, Stock: 3, Available: true}})
+
+	added, unavailable, err := svc.AddCartItems(context.Background(), 21, []domain.AddCartItemRequest{{ProductID: 5, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("AddCartItems() error = %v", err)
+	}
+	if len(added) != 0 || len(unavailable) != 1 || unavailable[0].ProductID != 5 {
+		t.Fatalf("expected 4 in the cart to exceed a stock of 3, got added=%v unavailable=%v", added, unavailable)
+	}
+	if repo.savedItem != nil {
+		t.Fatal("did not expect the cart line to grow past the stock")
+	}
+}
+
+func TestAddToCartRequiresVariantForProductWithVariants(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{}}
+	variants := []*pb.ProductVariant{{Id: 3, Sku: "TS-M", Price: 120, Stock: 4, Available: true, Options: map[string]string{"size": "M"}}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "T-shirt", Price: 100, Stock: 4, Available: true, Variants: variants}})
+
+	err := svc.AddToCart(context.Background(), 10, &domain.AddCartItemRequest{ProductID: 8, Quantity: 1})
+	if !errors.Is(err, domain.ErrVariantRequired) {
+		t.Fatalf("expected ErrVariantRequired, got %v", err)
+	}
+
+	err = svc.AddToCart(context.Background(), 10, &domain.AddCartItemRequest{ProductID: 8, VariantID: 3, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddToCart() error = %v", err)
+	}
+	if repo.savedItem == nil || repo.savedItem.Key() != "8:3" || repo.savedItem.Price != 120 || repo.savedItem.SKU != "TS-M" {
+		t.Fatalf("expected variant line at the variant price, got %#v", repo.savedItem)
+	}
+}
+
+func TestAddToCartMergesOnlyTheSameVariant(t *testing.T) {
+	repo := &mockCartRepository{getCartItems: []*domain.CartItem{{ProductID: 8, VariantID: 3, Quantity: 1, Price: 120}}}
+	variants := []*pb.ProductVariant{{Id: 3, Sku: "TS-M", Price: 120, Stock: 4}, {Id: 4, Sku: "TS-L", Price: 130, Stock: 4}}
+	svc := NewCartService(repo, &mockProductClient{productResp: &pb.ProductResponse{Name: "T-shirt", Price: 120, Stock: 8, Available: true, Variants: variants}})
+
+	if err := svc.AddToCart(context.Background(), 10, &domain.AddCartItemRequest{ProductID: 8, VariantID: 4, Quantity: 2}); err != nil {
+		t.Fatalf("AddToCart() error = %v", err)
+	}
+	if repo.updatedKey != "" || repo.savedItem == nil || repo.savedItem.Key() != "8:4" {
+		t.Fatalf("expected a new line for the other variant, got saved=%#v updated=%q", repo.savedItem, repo.updatedKey)
+	}
+
+	if err := svc.AddToCart(context.Background(), 10, &domain.AddCartItemRequest{ProductID: 8, VariantID: 3, Quantity: 2}); err != nil {
+		t.Fatalf("AddToCart() error = %v", err)
+	}
+	if repo.updatedKey != "8:3" || repo.updatedQty != 3 {
+		t.Fatalf("expected variant line 8:3 updated to 3, got %q=%d", repo.updatedKey, repo.updatedQty)
+	}
+}
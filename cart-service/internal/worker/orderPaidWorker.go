@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"cart-service/internal/domain"
 	"cart-service/internal/infrastructure"
 	"cart-service/internal/service"
 	"context"
@@ -15,6 +16,7 @@ import (
 
 type orderItemMessage struct {
 	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id"`
 	Quantity  uint `json:"quantity"`
 }
 
@@ -48,6 +50,11 @@ func (d *OrderPaidWorker) Listen(ctx context.Context) {
 			return nil
 		}
 
+		// Subscription renewals are not ordered from the cart, so the cart is left as it is
+		if subscriptionID, ok := msg.Values["subscription_id"].(string); ok && subscriptionID != "" && subscriptionID != "0" {
+			return nil
+		}
+
 		itemsStr, ok := msg.Values["items"].(string)
 		if !ok {
 			logger.Log.Warn("dropping invalid order paid message: missing items",
@@ -60,12 +67,13 @@ func (d *OrderPaidWorker) Listen(ctx context.Context) {
 			return fmt.Errorf("failed to unmarshal order items: %w", err)
 		}
 
-		productIDs := make([]uint, 0, len(items))
+		// Only the variants that were bought leave the cart
+		keys := make([]string, 0, len(items))
 		for _, item := range items {
-			productIDs = append(productIDs, item.ProductID)
+			keys = append(keys, domain.ItemKey(item.ProductID, item.VariantID))
 		}
 
-		if err := d.s.RemoveCartItems(ctx, uint(userID), productIDs); err != nil {
+		if err := d.s.RemoveCartItems(ctx, uint(userID), keys); err != nil {
 			return err
 		}
 		return nil
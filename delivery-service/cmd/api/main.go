@@ -75,7 +75,7 @@ func main() {
 	}
 
 	// Auto-migrate (creates the table if it doesn't exist)
-	db.AutoMigrate(&domain.Delivery{}, &domain.DeliveryOutboxMessage{})
+	db.AutoMigrate(&domain.Delivery{}, &domain.DeliveryOutboxMessage{}, &domain.ShipmentOrigin{})
 
 	// Redis Broker Client for events
 	redisBrokerClient := infrastructure.NewRedisBroker(
@@ -105,6 +105,10 @@ func main() {
 	orderPaidWorker := worker.NewOrderPaidWorker(redisBrokerClient, svc)
 	go orderPaidWorker.Listen(ctx)
 
+	// Worker recording the warehouses reserved orders ship from
+	stockReservedWorker := worker.NewStockReservedWorker(redisBrokerClient, svc)
+	go stockReservedWorker.Listen(ctx)
+
 	// Outbox worker for publishing events
 	outboxWorker := worker.NewOutboxWorker(svc)
 	go outboxWorker.ListenForOutboxMessages(ctx)
@@ -1,10 +1,41 @@
 package domain
 
+import "time"
+
 type Delivery struct {
-	ID            uint   `gorm:"primaryKey" json:"id"`
-	OrderID       uint   `gorm:"not null;index" json:"order_id"`
-	Status        string `gorm:"type:varchar(50);not null;default:'RECEIVED'" json:"status" oneof:"RECEIVED,IN_TRANSIT,DELIVERED,FAILED"`
-	CorrelationID string `gorm:"type:varchar(100);index" json:"correlation_id,omitempty"`
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	OrderID       uint      `gorm:"not null;index" json:"order_id"`
+	Status        string    `gorm:"type:varchar(50);not null;default:'RECEIVED'" json:"status" oneof:"RECEIVED,IN_TRANSIT,DELIVERED,FAILED"`
+	CorrelationID string    `gorm:"type:varchar(100);index" json:"correlation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// ShippingAddress is copied from the paid order, deliveries of older orders have none
+	ShippingAddress ShippingAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+	// Origins are the warehouses the order ships from, as product service reserved its stock
+	Origins []ShipmentOrigin `gorm:"-" json:"origins,omitempty"`
+}
+
+// ShipmentOrigin is the part of an order line shipped from one warehouse. Product service reserves the stock
+// before the order is paid, so origins are kept by order and not by delivery.
+type ShipmentOrigin struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	OrderID       uint      `gorm:"not null;uniqueIndex:idx_shipment_origins_line" json:"-"`
+	ProductID     uint      `gorm:"not null;uniqueIndex:idx_shipment_origins_line" json:"product_id"`
+	VariantID     uint      `gorm:"not null;default:0;uniqueIndex:idx_shipment_origins_line" json:"variant_id,omitempty"`
+	WarehouseID   uint      `gorm:"not null;uniqueIndex:idx_shipment_origins_line" json:"warehouse_id"`
+	WarehouseCode string    `gorm:"type:varchar(50);not null" json:"warehouse_code"`
+	Quantity      int       `gorm:"not null" json:"quantity"`
+	CreatedAt     time.Time `json:"-"`
+}
+
+// ShippingAddress is the destination of a delivery as captured on the order
+type ShippingAddress struct {
+	Recipient  string `gorm:"type:varchar(100)" json:"recipient"`
+	Phone      string `gorm:"type:varchar(20)" json:"phone"`
+	Street     string `gorm:"type:varchar(255)" json:"street"`
+	City       string `gorm:"type:varchar(100)" json:"city"`
+	PostalCode string `gorm:"type:varchar(10)" json:"postal_code"`
+	Country    string `gorm:"type:char(2)" json:"country"`
 }
 
 type DeliveryOutboxMessage struct {
@@ -28,8 +28,10 @@ func (s *DeliveryGRPCServer) GetDeliveryByOrderId(ctx context.Context, req *pb.G
 
 	return &pb.DeliveryResponse{
 		Delivery: &pb.DeliveryInfo{
-			OrderId: uint32(delivery.OrderID),
-			Status:  delivery.Status,
+			OrderId:   uint32(delivery.OrderID),
+			Status:    delivery.Status,
+			CreatedAt: delivery.CreatedAt.Unix(),
+			UpdatedAt: delivery.UpdatedAt.Unix(),
 		},
 	}, nil
 }
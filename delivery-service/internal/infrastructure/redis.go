@@ -19,7 +19,8 @@ func NewRedisBroker(addr string, password string, db int) *redis.Client {
 
 func InitDeliveryConsumerGroup(ctx context.Context, client *redis.Client) error {
 	streams := map[string]string{
-		"stream:orders:paid": "delivery-group",
+		"stream:orders:paid":    "delivery-group",
+		"stream:stock:reserved": "delivery-group",
 	}
 
 	for stream, group := range streams {
@@ -5,6 +5,7 @@ import (
 	"delivery-service/internal/domain"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeliveryRepository interface {
@@ -17,6 +18,8 @@ type DeliveryRepository interface {
 	CreateOutboxMessage(ctx context.Context, eventType string, payload *domain.Delivery) error
 	GetPendingOutboxMessages(ctx context.Context) ([]*domain.DeliveryOutboxMessage, error)
 	MarkOutboxMessageAsPublished(ctx context.Context, id uint) error
+	CreateShipmentOrigins(ctx context.Context, origins []domain.ShipmentOrigin) error
+	GetShipmentOrigins(orderIDs []uint) ([]domain.ShipmentOrigin, error)
 }
 
 type PostgresRepository struct {
@@ -112,6 +115,20 @@ func (r *PostgresRepository) MarkOutboxMessageAsPublished(ctx context.Context, i
 	return r.db.Model(&domain.DeliveryOutboxMessage{}).Where("id = ?", id).Update("published", true).Error
 }
 
+// CreateShipmentOrigins saves the warehouses an order ships from, origins already saved by a redelivered
+// stock reservation are kept
+func (r *PostgresRepository) CreateShipmentOrigins(ctx context.Context, origins []domain.ShipmentOrigin) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&origins).Error
+}
+
+func (r *PostgresRepository) GetShipmentOrigins(orderIDs []uint) ([]domain.ShipmentOrigin, error) {
+	var origins []domain.ShipmentOrigin
+	if err := r.db.Where("order_id IN ?", orderIDs).Order("order_id, product_id, variant_id, warehouse_id").Find(&origins).Error; err != nil {
+		return nil, err
+	}
+	return origins, nil
+}
+
 func correlationIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
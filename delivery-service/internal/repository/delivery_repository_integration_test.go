@@ -24,7 +24,7 @@ func openDeliveryTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Skipf("skipping integration test, cannot connect to delivery-db: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.Delivery{}, &domain.DeliveryOutboxMessage{}); err != nil {
+	if err := db.AutoMigrate(&domain.Delivery{}, &domain.DeliveryOutboxMessage{}, &domain.ShipmentOrigin{}); err != nil {
 		t.Fatalf("AutoMigrate() error = %v", err)
 	}
 	return db
@@ -41,7 +41,8 @@ func TestDeliveryRepository_CreateUpdateAndOutbox_Integration(t *testing.T) {
 	db := openDeliveryTestDB(t)
 	repo := NewPostgresRepository(db)
 
-	delivery := &domain.Delivery{OrderID: uint(time.Now().UnixNano() % 100000), Status: "RECEIVED"}
+	address := domain.ShippingAddress{Recipient: "Jane Doe", Phone: "+62 812-3456-7890", Street: "Jl. Sudirman 1", City: "Jakarta", PostalCode: "10220", Country: "ID"}
+	delivery := &domain.Delivery{OrderID: uint(time.Now().UnixNano() % 100000), Status: "RECEIVED", ShippingAddress: address}
 	ctx := context.Background()
 	if err := repo.CreateDelivery(ctx, delivery); err != nil {
 		t.Fatalf("CreateDelivery() error = %v", err)
@@ -74,4 +75,34 @@ func TestDeliveryRepository_CreateUpdateAndOutbox_Integration(t *testing.T) {
 	if updated.Status != "DELIVERED" {
 		t.Fatalf("expected status DELIVERED, got %s", updated.Status)
 	}
+	if updated.ShippingAddress != address {
+		t.Fatalf("expected shipping address to be stored, got %#v", updated.ShippingAddress)
+	}
+}
+
+func TestDeliveryRepository_ShipmentOriginsSavedOnce_Integration(t *testing.T) {
+	db := openDeliveryTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	orderID := uint(time.Now().UnixNano() % 1_000_000_000)
+	origins := []domain.ShipmentOrigin{
+		{OrderID: orderID, ProductID: 1, WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 2},
+		{OrderID: orderID, ProductID: 1, WarehouseID: 2, WarehouseCode: "SBY", Quantity: 1},
+	}
+	ctx := context.Background()
+	// The second call is a redelivered stock reservation
+	for attempt := 0; attempt < 2; attempt++ {
+		retried := append([]domain.ShipmentOrigin(nil), origins...)
+		if err := repo.CreateShipmentOrigins(ctx, retried); err != nil {
+			t.Fatalf("CreateShipmentOrigins() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetShipmentOrigins([]uint{orderID})
+	if err != nil {
+		t.Fatalf("GetShipmentOrigins() error = %v", err)
+	}
+	if len(got) != 2 || got[0].WarehouseCode != "MAIN" || got[1].WarehouseCode != "SBY" {
+		t.Fatalf("expected the order shipped from MAIN and SBY once, got %+v", got)
+	}
 }
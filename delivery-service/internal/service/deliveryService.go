@@ -36,6 +36,9 @@ func (s *DeliveryService) GetDeliveryByID(ctx context.Context, id uint) (*domain
 	if err != nil {
 		return nil, fmt.Errorf("failed to get delivery by id: %w", err)
 	}
+	if err := s.attachOrigins(delivery); err != nil {
+		return nil, err
+	}
 	l.Info("Delivery retrieved successfully", zap.Uint("deliveryID", id))
 	return delivery, nil
 }
@@ -46,6 +49,9 @@ func (s *DeliveryService) GetDeliveryByOrderID(ctx context.Context, orderID uint
 	if err != nil {
 		return nil, fmt.Errorf("failed to get delivery by order id: %w", err)
 	}
+	if err := s.attachOrigins(delivery); err != nil {
+		return nil, err
+	}
 	l.Info("Delivery retrieved by order id", zap.Uint("orderID", orderID))
 	return delivery, nil
 }
@@ -56,10 +62,50 @@ func (s *DeliveryService) ListDeliveries(ctx context.Context, status string) ([]
 	if err != nil {
 		return nil, fmt.Errorf("failed to list deliveries: %w", err)
 	}
+	if err := s.attachOrigins(deliveries...); err != nil {
+		return nil, err
+	}
 	l.Info("Deliveries listed successfully", zap.String("delivery_status", status), zap.Int("count", len(deliveries)))
 	return deliveries, nil
 }
 
+// RecordShipmentOrigins saves the warehouses product service reserved the stock of an order in, which its
+// delivery ships from
+func (s *DeliveryService) RecordShipmentOrigins(ctx context.Context, orderID uint, origins []domain.ShipmentOrigin) error {
+	l := logger.ForContext(ctx)
+	for i := range origins {
+		origins[i].OrderID = orderID
+	}
+	if err := s.repo.CreateShipmentOrigins(ctx, origins); err != nil {
+		return fmt.Errorf("failed to record shipment origins: %w", err)
+	}
+	l.Info("Shipment origins recorded successfully", zap.Uint("orderID", orderID), zap.Int("count", len(origins)))
+	return nil
+}
+
+// attachOrigins loads the warehouses the orders of deliveries ship from
+func (s *DeliveryService) attachOrigins(deliveries ...*domain.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	orderIDs := make([]uint, len(deliveries))
+	for i, delivery := range deliveries {
+		orderIDs[i] = delivery.OrderID
+	}
+	origins, err := s.repo.GetShipmentOrigins(orderIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get shipment origins: %w", err)
+	}
+	byOrder := make(map[uint][]domain.ShipmentOrigin)
+	for _, origin := range origins {
+		byOrder[origin.OrderID] = append(byOrder[origin.OrderID], origin)
+	}
+	for _, delivery := range deliveries {
+		delivery.Origins = byOrder[delivery.OrderID]
+	}
+	return nil
+}
+
 func (s *DeliveryService) UpdateDeliveryStatus(ctx context.Context, id uint, status string) error {
 	l := logger.ForContext(ctx)
 	err := s.repo.WithTransaction(ctx, func(txRepo repository.DeliveryRepository) error {
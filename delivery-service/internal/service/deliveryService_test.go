@@ -15,12 +15,17 @@ type mockDeliveryRepo struct {
 	createdOutboxEventType string
 	createdOutboxPayload   *domain.Delivery
 	markedOutboxID         uint
+	deliveries             []*domain.Delivery
+	origins                []domain.ShipmentOrigin
+	originOrderIDs         []uint
 }
 
 func (m *mockDeliveryRepo) CreateDelivery(ctx context.Context, delivery *domain.Delivery) error { return nil }
 func (m *mockDeliveryRepo) GetDeliveryByID(id uint) (*domain.Delivery, error) { return m.getDeliveryByIDResp, nil }
 func (m *mockDeliveryRepo) GetDeliveryByOrderID(orderID uint) (*domain.Delivery, error) { return nil, nil }
-func (m *mockDeliveryRepo) GetAllDeliveries(status string) ([]*domain.Delivery, error) { return nil, nil }
+func (m *mockDeliveryRepo) GetAllDeliveries(status string) ([]*domain.Delivery, error) {
+	return m.deliveries, nil
+}
 func (m *mockDeliveryRepo) UpdateDeliveryStatus(id uint, status string) error {
 	m.updatedID = id
 	m.updatedStatus = status
@@ -42,6 +47,15 @@ func (m *mockDeliveryRepo) MarkOutboxMessageAsPublished(ctx context.Context, id
 	return nil
 }
 
+func (m *mockDeliveryRepo) CreateShipmentOrigins(ctx context.Context, origins []domain.ShipmentOrigin) error {
+	m.origins = append(m.origins, origins...)
+	return nil
+}
+func (m *mockDeliveryRepo) GetShipmentOrigins(orderIDs []uint) ([]domain.ShipmentOrigin, error) {
+	m.originOrderIDs = orderIDs
+	return m.origins, nil
+}
+
 type mockDeliveryEventRepo struct {
 	lastEventType string
 	lastDelivery  *domain.Delivery
@@ -98,3 +112,30 @@ func TestPublishOutboxMessagePublishesAndMarksAsPublished(t *testing.T) {
 		t.Fatalf("expected outbox ID 8 marked published, got %d", repo.markedOutboxID)
 	}
 }
+
+func TestListDeliveriesAttachesTheWarehousesOrdersShipFrom(t *testing.T) {
+	repo := &mockDeliveryRepo{deliveries: []*domain.Delivery{{ID: 1, OrderID: 30}, {ID: 2, OrderID: 31}}}
+	svc := NewDeliveryService(repo, &mockDeliveryEventRepo{})
+
+	err := svc.RecordShipmentOrigins(context.Background(), 30, []domain.ShipmentOrigin{
+		{ProductID: 5, WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 2},
+		{ProductID: 5, WarehouseID: 2, WarehouseCode: "SBY", Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("RecordShipmentOrigins() error = %v", err)
+	}
+
+	deliveries, err := svc.ListDeliveries(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(repo.originOrderIDs) != 2 {
+		t.Fatalf("expected origins loaded for both orders in one call, got %v", repo.originOrderIDs)
+	}
+	if origins := deliveries[0].Origins; len(origins) != 2 || origins[0].OrderID != 30 || origins[1].WarehouseCode != "SBY" {
+		t.Fatalf("expected order 30 shipped from MAIN and SBY, got %+v", origins)
+	}
+	if len(deliveries[1].Origins) != 0 {
+		t.Fatalf("expected no origins for order 31, got %+v", deliveries[1].Origins)
+	}
+}
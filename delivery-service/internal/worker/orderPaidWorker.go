@@ -5,6 +5,8 @@ import (
 	"delivery-service/internal/domain"
 	"delivery-service/internal/infrastructure"
 	"delivery-service/internal/service"
+	"encoding/json"
+	"fmt"
 	"libs/logger"
 	"strconv"
 
@@ -46,6 +48,18 @@ func (d *OrderPaidWorker) Listen(ctx context.Context) {
 			OrderID: uint(orderID),
 			Status:  "PENDING",
 		}
+
+		// Orders placed before shipping addresses were captured are published without one. An address that
+		// cannot be read fails the message, so it ends up in the DLQ instead of leaving a paid order unshipped.
+		if addressJSON, ok := msg.Values["shipping_address"].(string); ok {
+			if err := json.Unmarshal([]byte(addressJSON), &delivery.ShippingAddress); err != nil {
+				logger.Log.Error("invalid shipping_address in order paid message",
+					zap.String("orderID", orderIDStr),
+					zap.Error(err),
+				)
+				return fmt.Errorf("invalid shipping_address for order %d: %w", orderID, err)
+			}
+		}
 		if err := d.s.CreateDelivery(ctx, &delivery); err != nil {
 			return err
 		}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"delivery-service/internal/domain"
+	"delivery-service/internal/infrastructure"
+	"delivery-service/internal/service"
+	"encoding/json"
+	"libs/logger"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// StockReservedWorker records the warehouses product service reserved the stock of an order in
+type StockReservedWorker struct {
+	s *service.DeliveryService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewStockReservedWorker(brokerRedis *redis.Client, service *service.DeliveryService) *StockReservedWorker {
+	return &StockReservedWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:stock:reserved", "stream:stock:reserved:dlq", "delivery-group", "stock-reserved-worker"),
+	}
+}
+
+func (d *StockReservedWorker) Listen(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderIDStr, ok := msg.Values["order_id"].(string)
+		if !ok {
+			logger.Log.Warn("dropping invalid stock reserved message: missing order_id",
+				zap.Any("raw_values", msg.Values),
+			)
+			return nil
+		}
+		orderID, err := strconv.ParseUint(orderIDStr, 10, 64)
+		if err != nil {
+			logger.Log.Warn("dropping invalid stock reserved message: invalid order_id",
+				zap.String("orderID", orderIDStr),
+				zap.Any("raw_values", msg.Values),
+			)
+			return nil
+		}
+
+		// Reservations made before warehouses existed, or of orders reserving nothing, carry no allocations
+		allocationsJSON, ok := msg.Values["allocations"].(string)
+		if !ok {
+			return nil
+		}
+		var origins []domain.ShipmentOrigin
+		if err := json.Unmarshal([]byte(allocationsJSON), &origins); err != nil {
+			logger.Log.Warn("dropping invalid stock reserved message: invalid allocations",
+				zap.String("orderID", orderIDStr),
+				zap.Error(err),
+			)
+			return nil
+		}
+		if len(origins) == 0 {
+			return nil
+		}
+		return d.s.RecordShipmentOrigins(ctx, uint(orderID), origins)
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +14,9 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"order-service/internal/config"
 	"order-service/internal/domain"
@@ -25,6 +29,7 @@ import (
 
 	"libs/consulclient"
 	"libs/logger"
+	"libs/pb"
 	sharedMiddleware "libs/middleware/gin"
 
 	_ "order-service/docs"
@@ -63,23 +68,30 @@ func main() {
 	}
 
 	// Auto-migrate (creates the table if it doesn't exist)
-	db.AutoMigrate(&domain.Order{}, &domain.OrderItem{})
+	db.AutoMigrate(&domain.Order{}, &domain.OrderItem{}, &domain.OrderStatusHistory{}, &domain.OrderOutboxMessage{}, &domain.OrderIdempotencyKey{}, &domain.OrderInvoice{}, &domain.OrderTaxLine{}, &domain.OrderRefund{}, &domain.Subscription{}, &domain.SubscriptionItem{})
 
-	// Set up Consul
-	consulClient, err := consulclient.NewConsulClient(cfg.ConsulAddr)
+	taxRules, err := domain.ParseTaxRules(cfg.TaxRates)
 	if err != nil {
-		logger.Log.Error("Failed to create Consul client", zap.Error(err))
+		logger.Log.Error("Invalid TAX_RATES", zap.Error(err))
+		os.Exit(1)
+	}
+	discounts, err := domain.ParseDiscounts(cfg.DiscountCodes)
+	if err != nil {
+		logger.Log.Error("Invalid DISCOUNT_CODES", zap.Error(err))
 		os.Exit(1)
 	}
 
-	hostname, _ := os.Hostname()
-	serviceID := fmt.Sprintf("order-service-%s", hostname)
-	err = consulClient.RegisterService(serviceID, "order-service", "order-service", cfg.GRPCPort)
+	if cfg.QuoteSecret == "" {
+		logger.Log.Error("QUOTE_SECRET or JWT_SECRET must be set to sign checkout quotes")
+		os.Exit(1)
+	}
+
+	// Set up Consul
+	consulClient, err := consulclient.NewConsulClient(cfg.ConsulAddr)
 	if err != nil {
-		logger.Log.Error("Failed to register service with Consul", zap.Error(err))
+		logger.Log.Error("Failed to create Consul client", zap.Error(err))
 		os.Exit(1)
 	}
-	defer consulClient.DeregisterService(serviceID)
 
 	// Set up gRPC connection to Cart Service
 	CartClient := infrastructure.NewCartGRPCClient(cfg.ConsulAddr)
@@ -90,13 +102,28 @@ func main() {
 	// Set up GRPC connection to Payment Service
 	PaymentClient := infrastructure.NewPaymentGRPCClient(cfg.ConsulAddr)
 
+	// Set up GRPC connection to Delivery Service
+	DeliveryClient := infrastructure.NewDeliveryGRPCClient(cfg.ConsulAddr)
+
 	// Redis Broker Client
 	redisBrokerClient := infrastructure.NewRedisBroker(cfg.GetRedisAddr(), cfg.RedisBroker.Password, cfg.RedisBroker.DB)
 
 	// Initialize repositories, services, and handlers
 	repo := repository.NewPostgresRepository(db)
 	brokerRepo := repository.NewRedisRepository(redisBrokerClient)
-	svc := service.NewOrderService(repo, brokerRepo, CartClient, ProductClient, PaymentClient)
+	svc := service.NewOrderService(repo, brokerRepo, CartClient, ProductClient, PaymentClient, DeliveryClient, service.Settings{
+		PaymentExpiry: time.Duration(cfg.PaymentExpiryMinutes) * time.Minute,
+		Pricing: domain.PricingRules{
+			Tax:                 taxRules,
+			ShippingFee:         cfg.ShippingFee,
+			FreeShippingMinimum: cfg.FreeShippingMinimum,
+			Discounts:           discounts,
+		},
+		QuoteSecret: []byte(cfg.QuoteSecret),
+		QuoteTTL:    time.Duration(cfg.QuoteTTLMinutes) * time.Minute,
+
+		SubscriptionMaxAttempts: cfg.SubscriptionMaxAttempts,
+	})
 	hdl := handler.NewOrderHandler(svc)
 
 	// Create cancellable context for graceful shutdown
@@ -134,6 +161,22 @@ func main() {
 	DeliveryFailedWorker := worker.NewDeliveryFailedWorker(redisBrokerClient, svc)
 	go DeliveryFailedWorker.ListenForDeliveryFailed(ctx)
 
+	// Outbox worker for publishing order events
+	OutboxWorker := worker.NewOutboxWorker(svc)
+	go OutboxWorker.ListenForOutboxMessages(ctx)
+
+	// Sweeper for cancelling orders that were not paid before their deadline
+	ExpirySweeperWorker := worker.NewExpirySweeperWorker(svc)
+	go ExpirySweeperWorker.StartExpirySweeper(ctx)
+
+	// Retry for refunds of cancelled order lines that payment service has not confirmed
+	RefundRetryWorker := worker.NewRefundRetryWorker(svc)
+	go RefundRetryWorker.StartRefundRetry(ctx)
+
+	// Scheduler for placing the orders of subscriptions that are due
+	SubscriptionSchedulerWorker := worker.NewSubscriptionSchedulerWorker(svc)
+	go SubscriptionSchedulerWorker.StartSubscriptionScheduler(ctx)
+
 	// register routes
 	r := gin.New()
 	r.Use(sharedMiddleware.GinLogger())
@@ -144,18 +187,97 @@ func main() {
 			c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		})
 
+		// admin only routes
+		adminOrder := api.Group("/order/admin")
+		adminOrder.Use(middleware.AdminMiddleware())
+		{
+			adminOrder.GET("", hdl.ListAllOrders)
+			adminOrder.PATCH("/:id/status", hdl.OverrideOrderStatus)
+			adminOrder.POST("/:id/items/:itemId/cancel", hdl.CancelOrderItem)
+		}
+
+		// guest orders are looked up by order number and email, without a token
+		api.POST("/order/lookup", hdl.LookupGuestOrder)
+
 		order := api.Group("/order")
 		order.Use(middleware.AuthMiddleware())
 		{
+			// guest checkout tokens may place orders
 			order.POST("", hdl.PostOrder)
-			order.GET("", hdl.GetOrders)
-			order.GET("/:id", hdl.GetOrderByID)
+
+			member := order.Group("")
+			member.Use(middleware.MembersOnly())
+			{
+				member.GET("", hdl.GetOrders)
+				member.GET("/:id", hdl.GetOrderByID)
+				member.POST("/:id/cancel", hdl.CancelOrder)
+				member.GET("/:id/history", hdl.GetOrderHistory)
+				member.GET("/:id/tracking", hdl.GetOrderTracking)
+				member.GET("/:id/invoice", hdl.GetOrderInvoice)
+				member.POST("/:id/reorder", hdl.ReorderOrder)
+			}
+		}
+
+		checkout := api.Group("/checkout")
+		checkout.Use(middleware.AuthMiddleware())
+		{
+			checkout.POST("/quote", hdl.QuoteCheckout)
+		}
+
+		subscription := api.Group("/subscription")
+		subscription.Use(middleware.AuthMiddleware(), middleware.MembersOnly())
+		{
+			subscription.POST("", hdl.CreateSubscription)
+			subscription.GET("", hdl.GetSubscriptions)
+			subscription.GET("/:id", hdl.GetSubscription)
+			subscription.POST("/:id/pause", hdl.PauseSubscription)
+			subscription.POST("/:id/resume", hdl.ResumeSubscription)
+			subscription.POST("/:id/skip", hdl.SkipSubscriptionRenewal)
+			subscription.POST("/:id/cancel", hdl.CancelSubscription)
 		}
 	}
 
 	// Swagger Documentation Route
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Start gRPC Server in a goroutine
+	grpcReady := make(chan struct{})
+	var grpcServer *grpc.Server
+	go func() {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logger.Log.Error("Failed to listen on gRPC port", zap.String("port", cfg.GRPCPort), zap.Error(err))
+			os.Exit(1)
+		}
+
+		grpcServer = grpc.NewServer(grpc.UnaryInterceptor(middleware.InternalAuthInterceptor))
+		grpcHandler := handler.NewOrderGRPCServer(svc)
+		pb.RegisterOrderServiceServer(grpcServer, grpcHandler)
+
+		healthServer := health.NewServer()
+		grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+		healthServer.SetServingStatus("order-service", grpc_health_v1.HealthCheckResponse_SERVING)
+
+		logger.Log.Info("gRPC server starting", zap.String("port", cfg.GRPCPort))
+
+		close(grpcReady)
+
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.Log.Error("gRPC server stopped", zap.Error(err))
+		}
+	}()
+
+	<-grpcReady
+
+	hostname, _ := os.Hostname()
+	serviceID := fmt.Sprintf("order-service-%s", hostname)
+	err = consulClient.RegisterService(serviceID, "order-service", "order-service", cfg.GRPCPort)
+	if err != nil {
+		logger.Log.Error("Failed to register service with Consul", zap.Error(err))
+		os.Exit(1)
+	}
+	defer consulClient.DeregisterService(serviceID)
+
 	// Start Server
 	// log.Println("Order Service starting on port 8081...")
 	// if err := r.Run(":8081"); err != nil {
@@ -193,4 +315,11 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Log.Error("HTTP server forced to shutdown", zap.Error(err))
 	}
+
+	// Shutdown gRPC server
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	logger.Log.Info("Servers gracefully stopped")
 }
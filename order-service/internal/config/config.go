@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -21,6 +22,20 @@ type Config struct {
 		Password string
 		DB       int
 	}
+	// PaymentExpiryMinutes is how long a customer has to pay before the order is cancelled
+	PaymentExpiryMinutes int
+	// TaxRates is the tax rule set, e.g. "default:11,books:0" with rates in percent
+	TaxRates string
+	// ShippingFee is charged on every order unless its subtotal reaches FreeShippingMinimum (0 = never free)
+	ShippingFee         uint
+	FreeShippingMinimum uint
+	// DiscountCodes lists the codes customers can order with, e.g. "WELCOME10:10%,FLAT5K:5000"
+	DiscountCodes string
+	// QuoteSecret signs checkout quote tokens, it falls back to the JWT secret
+	QuoteSecret     string
+	QuoteTTLMinutes int
+	// SubscriptionMaxAttempts is how many failed renewals in a row suspend a subscription
+	SubscriptionMaxAttempts int
 }
 
 func LoadConfig() *Config {
@@ -45,6 +60,14 @@ func LoadConfig() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       1,
 		},
+		PaymentExpiryMinutes:    getEnvInt("PAYMENT_EXPIRY_MINUTES", 30),
+		TaxRates:                getEnv("TAX_RATES", "default:11"),
+		ShippingFee:             uint(getEnvInt("SHIPPING_FEE", 0)),
+		FreeShippingMinimum:     uint(getEnvInt("FREE_SHIPPING_MINIMUM", 0)),
+		DiscountCodes:           getEnv("DISCOUNT_CODES", ""),
+		QuoteSecret:             getEnv("QUOTE_SECRET", os.Getenv("JWT_SECRET")),
+		QuoteTTLMinutes:         getEnvInt("QUOTE_TTL_MINUTES", 15),
+		SubscriptionMaxAttempts: getEnvInt("SUBSCRIPTION_MAX_ATTEMPTS", 3),
 	}
 }
 
@@ -84,3 +107,12 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
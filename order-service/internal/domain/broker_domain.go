@@ -1,25 +1,65 @@
 package domain
 
+import "time"
+
 type OrderEvent struct {
 	OrderID       string             `json:"order_id"`
 	UserID        string             `json:"user_id"`
 	TotalAmount   uint               `json:"total_amount"`
 	Items         []OrderItemMessage `json:"items"`
 	CorrelationID string             `json:"correlation_id,omitempty"`
+	// StockReserved tells consumers whether product-service already holds stock for the order.
+	StockReserved bool `json:"stock_reserved,omitempty"`
+	// ShippingAddress is only set on paid events, where delivery service needs it
+	ShippingAddress *ShippingAddress `json:"shipping_address,omitempty"`
+	// ShippingPostalCode is set on created events, product service reserves stock near it
+	ShippingPostalCode string `json:"shipping_postal_code,omitempty"`
+	// SubscriptionID marks orders placed by a subscription, which were not checked out from the cart
+	SubscriptionID uint `json:"subscription_id,omitempty"`
 }
 
 type OrderItemMessage struct {
 	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id,omitempty"`
 	Quantity  uint `json:"quantity"`
 }
 
+// ItemKey identifies a product without variants, or one variant of a product, as product service
+// reports it for lines that could not be reserved
+type ItemKey struct {
+	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id,omitempty"`
+}
+
 func ConvertToOrderItemMessages(items []OrderItem) []OrderItemMessage {
 	msgs := make([]OrderItemMessage, len(items))
 	for i, item := range items {
 		msgs[i] = OrderItemMessage{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
 			Quantity:  item.Quantity,
 		}
 	}
 	return msgs
 }
+
+const (
+	OrderEventCreated   = "created"
+	OrderEventPaid      = "paid"
+	OrderEventCancelled = "cancelled"
+	OrderEventExpired   = "expired"
+	// OrderEventItemsCancelled is queued when single lines are cancelled from an order that goes on without them
+	OrderEventItemsCancelled = "items_cancelled"
+)
+
+// OrderOutboxMessage is an order event written in the same transaction as the order change
+// and published to its stream later by the outbox worker.
+type OrderOutboxMessage struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	EventType     string    `gorm:"type:varchar(50);not null" json:"event_type" oneof:"created,paid,cancelled"`
+	OrderID       uint      `gorm:"not null;index" json:"order_id"`
+	Payload       string    `gorm:"type:text;not null" json:"payload"` // JSON encoded OrderEvent
+	CorrelationID string    `gorm:"type:varchar(100);index" json:"correlation_id,omitempty"`
+	Published     bool      `gorm:"not null;default:false;index" json:"published"`
+	CreatedAt     time.Time `json:"created_at"`
+}
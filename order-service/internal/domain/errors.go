@@ -0,0 +1,23 @@
+package domain
+
+import "errors"
+
+var (
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrOrderNotCancellable     = errors.New("order can no longer be cancelled")
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrIdempotencyKeyMismatch  = errors.New("idempotency key was already used with a different request")
+	ErrProductsNotFound        = errors.New("products not found")
+	ErrInvalidOrderFilter      = errors.New("invalid order filter")
+	ErrInvoiceNotIssued        = errors.New("invoice has not been issued for this order")
+	ErrCartEmpty               = errors.New("cart is empty or no valid items found")
+	ErrInvalidQuote            = errors.New("invalid quote token")
+	ErrQuoteExpired            = errors.New("quote has expired")
+	ErrQuoteMismatch           = errors.New("cart no longer matches the quote")
+	ErrInvalidShippingAddress  = errors.New("invalid shipping address")
+	ErrInvalidDiscountCode     = errors.New("invalid discount code")
+	ErrSubscriptionNotFound    = errors.New("subscription not found")
+	ErrSubscriptionStatus      = errors.New("action not allowed in the current subscription status")
+	ErrOrderItemNotFound       = errors.New("order item not found")
+	ErrOrderItemNotCancellable = errors.New("order item cannot be cancelled")
+)
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderInvoice is the invoice issued when an order becomes PAID. The number and issue date are
+// stored so the invoice renders the same way every time.
+type OrderInvoice struct {
+	ID       uint      `gorm:"primaryKey" json:"id"`
+	OrderID  uint      `gorm:"uniqueIndex;not null" json:"order_id"`
+	Sequence uint      `gorm:"uniqueIndex;not null" json:"sequence"`
+	Number   string    `gorm:"type:varchar(32);uniqueIndex;not null" json:"number"`
+	IssuedAt time.Time `gorm:"not null" json:"issued_at"`
+}
+
+// InvoiceNumber formats a sequence number as an invoice number, e.g. INV-2026-000042
+func InvoiceNumber(sequence uint, issuedAt time.Time) string {
+	return fmt.Sprintf("INV-%d-%06d", issuedAt.Year(), sequence)
+}
+
+// Invoice is everything needed to render an invoice document
+type Invoice struct {
+	Number      string         `json:"number"`
+	IssuedAt    time.Time      `json:"issued_at"`
+	OrderID     uint           `json:"order_id"`
+	OrderDate   time.Time      `json:"order_date"`
+	BuyerID     uint           `json:"buyer_id"`
+	BuyerName   string         `json:"buyer_name"`
+	BuyerEmail  string         `json:"buyer_email"`
+	Lines       []InvoiceLine  `json:"lines"`
+	Subtotal    uint           `json:"subtotal"`
+	TaxLines    []OrderTaxLine `json:"tax_lines"`
+	ShippingFee uint           `json:"shipping_fee"`
+	Discount    uint           `json:"discount"`
+	TotalAmount uint           `json:"total_amount"`
+}
+
+type InvoiceLine struct {
+	Name      string `json:"name"`
+	Quantity  uint   `json:"quantity"`
+	UnitPrice uint   `json:"unit_price"`
+	Amount    uint   `json:"amount"`
+}
@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
 
 type Order struct {
     ID          uint        `gorm:"primaryKey" json:"id"`
@@ -11,13 +16,227 @@ type Order struct {
     CreatedAt   time.Time   `json:"created_at"`
     PaymentURL  string      `gorm:"column:payment_url" json:"payment_url,omitempty"`
     PaymentExpires time.Time `gorm:"column:payment_expires" json:"payment_expires,omitempty"`
+    BuyerName      string    `gorm:"column:buyer_name" json:"buyer_name,omitempty"`  // Snapshot of the buyer at time of order
+    BuyerEmail     string    `gorm:"column:buyer_email" json:"buyer_email,omitempty"`
+    // TotalAmount is the grand total: Subtotal - DiscountAmount + TaxAmount + ShippingFee
+    Subtotal       uint           `json:"subtotal"`
+    TaxAmount      uint           `json:"tax_amount"`
+    ShippingFee    uint           `json:"shipping_fee"`
+    DiscountAmount uint           `json:"discount_amount"`
+    // DiscountCode is the code the discount was given for. A percentage discount keeps its rate, so it
+    // shrinks with the order when lines are dropped.
+    DiscountCode        string `gorm:"type:varchar(50)" json:"discount_code,omitempty"`
+    DiscountBasisPoints uint   `gorm:"not null;default:0" json:"discount_basis_points,omitempty"`
+    TaxLines       []OrderTaxLine `gorm:"foreignKey:OrderID" json:"tax_lines"`
+    // ShippingAddress is a snapshot taken when the order is placed and is never updated afterwards
+    ShippingAddress ShippingAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+    // Guest orders were placed with a guest checkout token and can be looked up by ID and BuyerEmail
+    Guest bool `gorm:"not null;default:false" json:"guest"`
+    // SubscriptionID is set on orders placed by a subscription renewal
+    SubscriptionID uint `gorm:"index" json:"subscription_id,omitempty"`
+    // AllowBackorder keeps lines that are out of stock as BACKORDERED instead of cancelling them
+    AllowBackorder bool `gorm:"not null;default:false" json:"allow_backorder"`
+    // RefundedAmount is what is paid back for lines cancelled after payment, see OrderRefund
+    RefundedAmount uint `gorm:"not null;default:0" json:"refunded_amount"`
 }
 
 type OrderItem struct {
     ID        uint    `gorm:"primaryKey" json:"id"`
     OrderID   uint    `gorm:"index" json:"order_id"`
     ProductID uint  `json:"product_id"`
+    // VariantID and SKU are only set for products sold in variants
+    VariantID uint   `gorm:"not null;default:0" json:"variant_id,omitempty"`
+    SKU       string `gorm:"type:varchar(64)" json:"sku,omitempty"`
     Name      string  `json:"name"`     // Snapshot of name at time of order
     Quantity  uint     `json:"quantity"`
     Price     uint `json:"price"`    // Snapshot of price at time of order
-}
\ No newline at end of file
+    Status    string `gorm:"type:varchar(20);not null;default:PENDING" json:"status" oneof:"PENDING RESERVED BACKORDERED CANCELLED"`
+    // TaxCategory is the tax line the item was charged under when the order was placed
+    TaxCategory string `gorm:"type:varchar(100)" json:"tax_category,omitempty"`
+}
+
+const (
+	// OrderItemStatusPending lines wait for product-service to reserve their stock
+	OrderItemStatusPending     = "PENDING"
+	OrderItemStatusReserved    = "RESERVED"
+	OrderItemStatusBackordered = "BACKORDERED"
+	OrderItemStatusCancelled   = "CANCELLED"
+)
+
+// Key identifies the product, or the variant of it, the line is for
+func (i OrderItem) Key() ItemKey {
+	return ItemKey{ProductID: i.ProductID, VariantID: i.VariantID}
+}
+
+// IsActive reports whether the line is still part of the order, that is charged, holds stock and is shipped.
+// Backordered and cancelled lines are not.
+func (i OrderItem) IsActive() bool {
+	return i.Status != OrderItemStatusBackordered && i.Status != OrderItemStatusCancelled
+}
+
+// ActiveItems returns the lines of the order that are still part of it
+func (o *Order) ActiveItems() []OrderItem {
+	items := make([]OrderItem, 0, len(o.Items))
+	for _, item := range o.Items {
+		if item.IsActive() {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ApplyReservation records the outcome of the stock reservation on the pending lines of the order.
+// Lines of unavailable products and variants are backordered if the customer allowed it and cancelled otherwise,
+// the other lines are reserved. It returns how many lines were left out.
+func (o *Order) ApplyReservation(unavailable []ItemKey) int {
+	missing := make(map[ItemKey]bool, len(unavailable))
+	for _, key := range unavailable {
+		missing[key] = true
+	}
+
+	leftOut := 0
+	for i := range o.Items {
+		item := &o.Items[i]
+		if item.Status != OrderItemStatusPending && item.Status != "" {
+			continue
+		}
+		switch {
+		case !missing[item.Key()]:
+			item.Status = OrderItemStatusReserved
+		case o.AllowBackorder:
+			item.Status = OrderItemStatusBackordered
+			leftOut++
+		default:
+			item.Status = OrderItemStatusCancelled
+			leftOut++
+		}
+	}
+	return leftOut
+}
+
+// ShippingAddress is where an order is delivered to
+type ShippingAddress struct {
+	Recipient  string `gorm:"type:varchar(100)" json:"recipient" binding:"required,max=100"`
+	Phone      string `gorm:"type:varchar(20)" json:"phone" binding:"required,max=20"`
+	Street     string `gorm:"type:varchar(255)" json:"street" binding:"required,max=255"`
+	City       string `gorm:"type:varchar(100)" json:"city" binding:"required,max=100"`
+	PostalCode string `gorm:"type:varchar(10)" json:"postal_code" binding:"required,max=10"`
+	Country    string `gorm:"type:char(2)" json:"country" binding:"required,len=2"` // ISO 3166-1 alpha-2, e.g. ID
+}
+
+// Normalize trims every field and upper cases the country code
+func (a *ShippingAddress) Normalize() {
+	a.Recipient = strings.TrimSpace(a.Recipient)
+	a.Phone = strings.TrimSpace(a.Phone)
+	a.Street = strings.TrimSpace(a.Street)
+	a.City = strings.TrimSpace(a.City)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate checks a normalized address
+func (a *ShippingAddress) Validate() error {
+	required := []struct{ field, value string }{
+		{"recipient", a.Recipient}, {"phone", a.Phone}, {"street", a.Street},
+		{"city", a.City}, {"postal_code", a.PostalCode}, {"country", a.Country},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidShippingAddress, r.field)
+		}
+	}
+
+	// Phone numbers may be written with a leading + and spaces or dashes between digit groups
+	digits := 0
+	for i, c := range a.Phone {
+		switch {
+		case unicode.IsDigit(c):
+			digits++
+		case c == '+' && i == 0, c == ' ', c == '-':
+		default:
+			return fmt.Errorf("%w: phone may only contain digits, spaces, dashes and a leading +", ErrInvalidShippingAddress)
+		}
+	}
+	if digits < 6 || digits > 15 {
+		return fmt.Errorf("%w: phone must have 6 to 15 digits", ErrInvalidShippingAddress)
+	}
+
+	for _, c := range a.PostalCode {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' && c != '-' {
+			return fmt.Errorf("%w: invalid postal_code", ErrInvalidShippingAddress)
+		}
+	}
+	if len(a.Country) != 2 || a.Country[0] < 'A' || a.Country[0] > 'Z' || a.Country[1] < 'A' || a.Country[1] > 'Z' {
+		return fmt.Errorf("%w: country must be a two letter ISO 3166 code", ErrInvalidShippingAddress)
+	}
+	return nil
+}
+
+const (
+	OrderStatusReceived        = "RECEIVED"
+	OrderStatusAwaitingPayment = "AWAITING_PAYMENT"
+	OrderStatusPaid            = "PAID"
+	OrderStatusShipped         = "SHIPPED"
+	OrderStatusDelivered       = "DELIVERED"
+	OrderStatusFailed          = "FAILED"
+	OrderStatusCancelled       = "CANCELLED"
+)
+
+// orderTransitions lists the statuses an order may move to from each status.
+// A shipped order may still fail in delivery, but DELIVERED, FAILED and CANCELLED are terminal,
+// so a late delivery failure never overwrites a delivered order.
+var orderTransitions = map[string][]string{
+	OrderStatusReceived:        {OrderStatusAwaitingPayment, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusAwaitingPayment: {OrderStatusPaid, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusPaid:            {OrderStatusShipped, OrderStatusDelivered, OrderStatusFailed},
+	OrderStatusShipped:         {OrderStatusDelivered, OrderStatusFailed},
+}
+
+// CanTransition reports whether an order in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidOrderStatus reports whether status is one of the known order statuses
+func IsValidOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusReceived, OrderStatusAwaitingPayment, OrderStatusPaid, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusFailed, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type OrderStatusHistory struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	OrderID       uint      `gorm:"index;not null" json:"order_id"`
+	FromStatus    string    `gorm:"type:varchar(50);not null" json:"from_status"`
+	ToStatus      string    `gorm:"type:varchar(50);not null" json:"to_status"`
+	Source        string    `gorm:"type:varchar(100);not null" json:"source"` // stream name or API action that caused the change
+	ChangedBy     uint      `gorm:"index" json:"changed_by,omitempty"`        // admin user ID for manual overrides
+	CorrelationID string    `gorm:"type:varchar(100);index" json:"correlation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
+
+// OrderIdempotencyKey remembers which order a client supplied Idempotency-Key produced,
+// so retried create requests return the same order instead of placing a new one.
+type OrderIdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_order_idempotency_user_key" json:"user_id"`
+	Key         string `gorm:"type:varchar(255);not null;uniqueIndex:idx_order_idempotency_user_key" json:"key"`
+	Fingerprint string `gorm:"type:varchar(64);not null" json:"fingerprint"` // sha256 of the request body
+	OrderID     uint   `gorm:"not null" json:"order_id"`
+	// ResponseStatus and ResponseBody are the answer the request got, replayed for a retry
+	ResponseStatus int       `gorm:"not null;default:0" json:"response_status"`
+	ResponseBody   string    `gorm:"type:text" json:"response_body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
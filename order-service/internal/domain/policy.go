@@ -0,0 +1,28 @@
+package domain
+
+const (
+	RoleAdmin = "admin"
+	// RoleService is used for internal services calling over gRPC with the internal secret
+	RoleService = "service"
+	// RoleGuest is a guest checkout token issued by user service for an email without an account
+	RoleGuest = "guest"
+)
+
+// InternalCaller is the caller for requests from other services, which may read any order
+var InternalCaller = Caller{Role: RoleService}
+
+// Caller is the authenticated user behind a request
+type Caller struct {
+	UserID uint
+	Role   string
+}
+
+func (c Caller) IsAdmin() bool {
+	return c.Role == RoleAdmin
+}
+
+// CanAccessOrder is the ownership rule for every order resource: the owner, admins and internal services only.
+// Guest tokens can be requested by anyone who knows the email, so they never count as the owner.
+func CanAccessOrder(caller Caller, order *Order) bool {
+	return caller.IsAdmin() || caller.Role == RoleService || (caller.Role != RoleGuest && order.UserID == caller.UserID)
+}
@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTaxCategory labels the tax line for products none of whose categories has its own rate
+const DefaultTaxCategory = "default"
+
+// OrderTaxLine is the tax charged on the part of an order that falls under one tax category
+type OrderTaxLine struct {
+	ID              uint   `gorm:"primaryKey" json:"id"`
+	OrderID         uint   `gorm:"index;not null" json:"order_id"`
+	Category        string `gorm:"type:varchar(100);not null" json:"category"`
+	RateBasisPoints uint   `gorm:"not null" json:"rate_basis_points"` // 1100 = 11%
+	TaxableAmount   uint   `gorm:"not null" json:"taxable_amount"`
+	Amount          uint   `gorm:"not null" json:"amount"`
+}
+
+// TaxRules holds the tax rate per product category in basis points, with a fallback for everything else
+type TaxRules struct {
+	DefaultRate   uint
+	CategoryRates map[string]uint // keyed by lower case category name
+}
+
+// ParseTaxRules parses a rule set like "default:11,books:0,food:5.5" where rates are percentages
+func ParseTaxRules(spec string) (TaxRules, error) {
+	rules := TaxRules{CategoryRates: map[string]uint{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		category, rate, ok := strings.Cut(entry, ":")
+		if !ok {
+			return TaxRules{}, fmt.Errorf("invalid tax rule %q, expected category:percent", entry)
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return TaxRules{}, fmt.Errorf("invalid tax rate in rule %q", entry)
+		}
+		basisPoints := uint(math.Round(percent * 100))
+
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == DefaultTaxCategory {
+			rules.DefaultRate = basisPoints
+		} else {
+			rules.CategoryRates[category] = basisPoints
+		}
+	}
+	return rules, nil
+}
+
+// RateFor picks the tax category and rate for a product. A product in several taxed categories
+// pays the highest of their rates, a product in none pays the default rate.
+func (r TaxRules) RateFor(categories []string) (string, uint) {
+	category, rate, matched := DefaultTaxCategory, r.DefaultRate, false
+	for _, name := range categories {
+		key := strings.ToLower(name)
+		categoryRate, ok := r.CategoryRates[key]
+		if !ok {
+			continue
+		}
+		if !matched || categoryRate > rate || (categoryRate == rate && key < category) {
+			category, rate, matched = key, categoryRate, true
+		}
+	}
+	return category, rate
+}
+
+// Discount takes either a percentage (in basis points) or a fixed amount off the subtotal
+type Discount struct {
+	BasisPoints uint
+	Amount      uint
+}
+
+// ParseDiscounts parses discount codes like "WELCOME10:10%,FLAT5K:5000" into discounts keyed by upper case
+// code. A rate ending in % is a percentage of the subtotal, anything else a fixed amount.
+func ParseDiscounts(spec string) (map[string]Discount, error) {
+	discounts := map[string]Discount{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(entry, ":")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		value = strings.TrimSpace(value)
+		if !ok || code == "" {
+			return nil, fmt.Errorf("invalid discount %q, expected code:amount or code:percent%%", entry)
+		}
+		if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+			rate, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+			if err != nil || rate <= 0 || rate > 100 {
+				return nil, fmt.Errorf("invalid discount percentage in %q", entry)
+			}
+			discounts[code] = Discount{BasisPoints: uint(math.Round(rate * 100))}
+			continue
+		}
+		amount, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || amount == 0 {
+			return nil, fmt.Errorf("invalid discount amount in %q", entry)
+		}
+		discounts[code] = Discount{Amount: uint(amount)}
+	}
+	return discounts, nil
+}
+
+// PricingRules is everything needed to turn order items into the amounts charged
+type PricingRules struct {
+	Tax         TaxRules
+	ShippingFee uint
+	// FreeShippingMinimum waives the shipping fee for subtotals at or above it, 0 disables it
+	FreeShippingMinimum uint
+	// Discounts are the discount codes customers can order with, keyed by upper case code
+	Discounts map[string]Discount
+}
+
+// NormalizeDiscountCode is the form discount codes are configured and compared in
+func NormalizeDiscountCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ApplyDiscount gives order the discount of code, an empty code gives none. It fails with
+// ErrInvalidDiscountCode for codes that are not configured.
+func (p PricingRules) ApplyDiscount(order *Order, code string) error {
+	code = NormalizeDiscountCode(code)
+	order.DiscountCode, order.DiscountBasisPoints, order.DiscountAmount = "", 0, 0
+	if code == "" {
+		return nil
+	}
+	discount, ok := p.Discounts[code]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidDiscountCode, code)
+	}
+	order.DiscountCode, order.DiscountBasisPoints, order.DiscountAmount = code, discount.BasisPoints, discount.Amount
+	return nil
+}
+
+// ApplyPricing fills in the subtotal, tax lines, shipping fee and grand total of order from its active items,
+// less the discount given with ApplyDiscount. categories maps product IDs to their category names. The tax
+// category of each item is kept on it, so the order can later be repriced at the rates it was charged with.
+func (p PricingRules) ApplyPricing(order *Order, categories map[uint][]string) {
+	rates := map[string]uint{}
+	for i := range order.Items {
+		category, rate := p.Tax.RateFor(categories[order.Items[i].ProductID])
+		order.Items[i].TaxCategory = category
+		rates[category] = rate
+	}
+	order.applyTaxRates(rates)
+
+	order.ShippingFee = p.ShippingFee
+	if p.FreeShippingMinimum > 0 && order.Subtotal >= p.FreeShippingMinimum {
+		order.ShippingFee = 0
+	}
+
+	order.TotalAmount = order.Subtotal - order.DiscountAmount + order.TaxAmount + order.ShippingFee
+}
+
+// Reprice recomputes the amounts of an order after some of its lines dropped out, at the tax rates of its
+// tax lines rather than today's rules and categories. Items keep the price they were ordered at, a percentage
+// discount keeps its rate, and the shipping fee stays what the customer was shown even if the order no longer
+// reaches the free shipping minimum.
+func (o *Order) Reprice() {
+	rates := make(map[string]uint, len(o.TaxLines))
+	for _, line := range o.TaxLines {
+		rates[line.Category] = line.RateBasisPoints
+	}
+
+	// Items priced before their tax category was kept fall under the only tax line of the order, or the default one
+	fallback := DefaultTaxCategory
+	if len(o.TaxLines) == 1 {
+		fallback = o.TaxLines[0].Category
+	}
+	for i := range o.Items {
+		if _, ok := rates[o.Items[i].TaxCategory]; !ok {
+			o.Items[i].TaxCategory = fallback
+		}
+	}
+
+	o.applyTaxRates(rates)
+	o.TotalAmount = o.Subtotal - o.DiscountAmount + o.TaxAmount + o.ShippingFee
+}
+
+// applyTaxRates fills in the subtotal and tax lines of the order from its active items, taxing each item at
+// the rate of its tax category
+func (o *Order) applyTaxRates(rates map[string]uint) {
+	taxable := map[string]uint{}
+	o.Subtotal = 0
+	for _, item := range o.ActiveItems() {
+		amount := item.Price * item.Quantity
+		o.Subtotal += amount
+		taxable[item.TaxCategory] += amount
+	}
+
+	if o.DiscountBasisPoints > 0 {
+		o.DiscountAmount = divideRoundHalfUp(o.Subtotal*o.DiscountBasisPoints, 10000)
+	}
+	if o.DiscountAmount > o.Subtotal {
+		o.DiscountAmount = o.Subtotal
+	}
+
+	// Discounts are not applied to a single category, so they reduce each group's taxable amount pro rata
+	o.TaxLines = nil
+	o.TaxAmount = 0
+	for category, amount := range taxable {
+		if o.DiscountAmount > 0 && o.Subtotal > 0 {
+			amount -= divideRoundHalfUp(amount*o.DiscountAmount, o.Subtotal)
+		}
+		rate := rates[category]
+		tax := divideRoundHalfUp(amount*rate, 10000)
+		o.TaxLines = append(o.TaxLines, OrderTaxLine{
+			Category:        category,
+			RateBasisPoints: rate,
+			TaxableAmount:   amount,
+			Amount:          tax,
+		})
+		o.TaxAmount += tax
+	}
+	sort.Slice(o.TaxLines, func(i, j int) bool {
+		return o.TaxLines[i].Category < o.TaxLines[j].Category
+	})
+}
+
+func divideRoundHalfUp(numerator, denominator uint) uint {
+	return (numerator + denominator/2) / denominator
+}
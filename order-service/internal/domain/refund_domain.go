@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	OrderRefundPending = "PENDING"
+	OrderRefundDone    = "DONE"
+)
+
+// OrderRefund is money owed back for a line cancelled after payment. It is saved with the cancellation and
+// sent to payment service afterwards, outside the order lock, until payment service confirms it. RefundKey
+// is fixed per line, so sending it again never refunds twice.
+type OrderRefund struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrderID     uint      `gorm:"index;not null" json:"order_id"`
+	OrderItemID uint      `gorm:"not null" json:"order_item_id"`
+	RefundKey   string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"refund_key"`
+	Amount      uint      `gorm:"not null" json:"amount"`
+	Reason      string    `gorm:"type:varchar(255);not null" json:"reason"`
+	Status      string    `gorm:"type:varchar(20);not null;default:'PENDING';index" json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OrderItemRefundKey is the refund key of a cancelled line of an order
+func OrderItemRefundKey(orderID, itemID uint) string {
+	return fmt.Sprintf("order-%d-item-%d", orderID, itemID)
+}
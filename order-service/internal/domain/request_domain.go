@@ -1,5 +1,52 @@
 package domain
 
+import "time"
+
 type CreateOrderRequest struct {
 	ProductIDs []uint `json:"product_ids" binding:"omitempty,min=1"`
-}
\ No newline at end of file
+	// QuoteToken from POST /checkout/quote locks the quoted prices while it is valid
+	QuoteToken      string           `json:"quote_token,omitempty"`
+	ShippingAddress *ShippingAddress `json:"shipping_address" binding:"required"`
+	// DiscountCode is one of the configured discount codes, it is taken off the subtotal before tax
+	DiscountCode string `json:"discount_code,omitempty"`
+	// AllowBackorder keeps products that turn out to be out of stock on the order as backordered, uncharged
+	// lines. Without it they are cancelled and the rest of the order goes ahead.
+	AllowBackorder bool `json:"allow_backorder,omitempty"`
+	// IdempotencyKey comes from the Idempotency-Key header and is not part of the request fingerprint
+	IdempotencyKey string `json:"-"`
+	// Buyer details come from the JWT and are snapshotted on the order for invoicing
+	BuyerName  string `json:"-"`
+	BuyerEmail string `json:"-"`
+	Guest      bool   `json:"-"`
+	// Lines replace the cart for orders placed by a subscription renewal
+	Lines          []OrderLine `json:"-"`
+	SubscriptionID uint        `json:"-"`
+}
+
+type CheckoutQuoteRequest struct {
+	ProductIDs   []uint `json:"product_ids" binding:"omitempty,min=1"`
+	DiscountCode string `json:"discount_code,omitempty"`
+}
+
+// GuestOrderLookupRequest finds a guest order by order number and the email it was placed with
+type GuestOrderLookupRequest struct {
+	OrderID uint   `json:"order_id" binding:"required"`
+	Email   string `json:"email" binding:"required,email"`
+}
+
+// OrderListFilter holds the admin order list filters, zero values mean no filter
+type OrderListFilter struct {
+	Status    string
+	UserID    uint
+	From      *time.Time // created_at >= From
+	To        *time.Time // created_at < To
+	MinAmount uint
+	SortBy    string
+	Order     string
+	Page      int
+	Limit     int
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
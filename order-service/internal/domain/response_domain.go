@@ -1,9 +1,101 @@
 package domain
 
+import "time"
+
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
+// OrderReceipt answers a create order request. It is saved with the idempotency key, so a retried request
+// gets the same status and body as the first one.
+type OrderReceipt struct {
+	Status  int    `json:"-"`
+	Message string `json:"message"`
+	OrderID uint   `json:"order_id"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
-}
\ No newline at end of file
+}
+type PaginatedOrders struct {
+	Orders     []Order `json:"orders"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page"`
+	Limit      int     `json:"limit"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// TrackingEvent is one entry of the order tracking timeline
+type TrackingEvent struct {
+	Source    string    `json:"source"` // order, payment or delivery
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type OrderTracking struct {
+	OrderID        uint            `json:"order_id"`
+	OrderStatus    string          `json:"order_status"`
+	PaymentStatus  string          `json:"payment_status,omitempty"`
+	DeliveryStatus string          `json:"delivery_status,omitempty"`
+	Timeline       []TrackingEvent `json:"timeline"`
+	// Degraded is set when payment or delivery status could not be fetched, UnavailableSources names them
+	Degraded           bool     `json:"degraded"`
+	UnavailableSources []string `json:"unavailable_sources,omitempty"`
+}
+
+// ReorderLine is one line of a past order put back in the cart
+type ReorderLine struct {
+	ProductID     uint   `json:"product_id"`
+	VariantID     uint   `json:"variant_id,omitempty"`
+	SKU           string `json:"sku,omitempty"`
+	Name          string `json:"name"`
+	Quantity      uint   `json:"quantity"`
+	Price         uint   `json:"price,omitempty"` // current price, empty for unavailable lines
+	PreviousPrice uint   `json:"previous_price"`  // price paid in the original order
+}
+
+// ReorderResult reports what happened to each line of the reordered order.
+// Repriced lines were added too, at their current price.
+type ReorderResult struct {
+	OrderID     uint          `json:"order_id"`
+	Added       []ReorderLine `json:"added"`
+	Repriced    []ReorderLine `json:"repriced"`
+	Unavailable []ReorderLine `json:"unavailable"`
+}
+
+// QuoteLine is one priced line of a checkout quote
+type QuoteLine struct {
+	ProductID uint   `json:"product_id"`
+	VariantID uint   `json:"variant_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Name      string `json:"name"`
+	Quantity  uint   `json:"quantity"`
+	UnitPrice uint   `json:"unit_price"`
+	Amount    uint   `json:"amount"`
+}
+
+// StockWarning flags a quoted line that product service does not currently have enough stock for
+type StockWarning struct {
+	ProductID uint   `json:"product_id"`
+	VariantID uint   `json:"variant_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Name      string `json:"name"`
+	Requested uint   `json:"requested"`
+	Available uint   `json:"available"`
+}
+
+// CheckoutQuote is what the cart would cost if it were ordered now. Passing QuoteToken when creating
+// the order keeps these prices until ExpiresAt.
+type CheckoutQuote struct {
+	Lines          []QuoteLine    `json:"lines"`
+	StockWarnings  []StockWarning `json:"stock_warnings"`
+	Subtotal       uint           `json:"subtotal"`
+	TaxLines       []OrderTaxLine `json:"tax_lines"`
+	TaxAmount      uint           `json:"tax_amount"`
+	ShippingFee    uint           `json:"shipping_fee"`
+	DiscountCode   string         `json:"discount_code,omitempty"`
+	DiscountAmount uint           `json:"discount_amount"`
+	TotalAmount    uint           `json:"total_amount"`
+	QuoteToken     string         `json:"quote_token"`
+	ExpiresAt      time.Time      `json:"expires_at"`
+}
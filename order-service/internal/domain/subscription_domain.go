@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+const (
+	SubscriptionStatusActive = "ACTIVE"
+	SubscriptionStatusPaused = "PAUSED"
+	// SubscriptionStatusSuspended is set after too many failed renewals, the user can resume it
+	SubscriptionStatusSuspended = "SUSPENDED"
+	SubscriptionStatusCancelled = "CANCELLED"
+)
+
+// Subscription places an order for the same products every IntervalDays days.
+// Renewals go through the regular order flow, so the customer pays for each order.
+type Subscription struct {
+	ID           uint               `gorm:"primaryKey" json:"id"`
+	UserID       uint               `gorm:"index;not null" json:"user_id"`
+	Status       string             `gorm:"type:varchar(20);not null;default:ACTIVE;index" json:"status" oneof:"ACTIVE PAUSED SUSPENDED CANCELLED"`
+	IntervalDays uint               `gorm:"not null" json:"interval_days"`
+	NextRunAt    time.Time          `gorm:"not null;index" json:"next_run_at"`
+	Items        []SubscriptionItem `gorm:"foreignKey:SubscriptionID" json:"items"`
+	// Buyer details and address are used for every order the subscription places
+	BuyerName       string          `gorm:"column:buyer_name" json:"buyer_name,omitempty"`
+	BuyerEmail      string          `gorm:"column:buyer_email" json:"buyer_email,omitempty"`
+	ShippingAddress ShippingAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+	// FailedAttempts counts consecutive renewals that could not place an order
+	FailedAttempts uint      `gorm:"not null;default:0" json:"failed_attempts"`
+	LastError      string    `gorm:"type:text" json:"last_error,omitempty"`
+	LastOrderID    uint      `json:"last_order_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type SubscriptionItem struct {
+	ID             uint `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint `gorm:"index;not null" json:"subscription_id"`
+	ProductID      uint `gorm:"not null" json:"product_id"`
+	VariantID      uint `gorm:"not null;default:0" json:"variant_id,omitempty"`
+	Quantity       uint `gorm:"not null" json:"quantity"`
+}
+
+// Interval is the time between two renewals
+func (s *Subscription) Interval() time.Duration {
+	return time.Duration(s.IntervalDays) * 24 * time.Hour
+}
+
+// OrderLine is a product and quantity to order without going through the cart
+type OrderLine struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	// VariantID is required for products sold in variants
+	VariantID uint `json:"variant_id"`
+	Quantity  uint `json:"quantity" binding:"required,min=1"`
+}
+
+// Key identifies the product, or the variant of it, the line is for
+func (l OrderLine) Key() ItemKey {
+	return ItemKey{ProductID: l.ProductID, VariantID: l.VariantID}
+}
+
+type CreateSubscriptionRequest struct {
+	Items           []OrderLine      `json:"items" binding:"required,min=1,dive"`
+	IntervalDays    uint             `json:"interval_days" binding:"required,min=1,max=365"`
+	ShippingAddress *ShippingAddress `json:"shipping_address" binding:"required"`
+	// Buyer details come from the JWT
+	BuyerName  string `json:"-"`
+	BuyerEmail string `json:"-"`
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"libs/pb"
+	"order-service/internal/domain"
+	"order-service/internal/service"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type OrderGRPCServer struct {
+	pb.UnimplementedOrderServiceServer
+	service *service.OrderService
+}
+
+func NewOrderGRPCServer(service *service.OrderService) *OrderGRPCServer {
+	return &OrderGRPCServer{service: service}
+}
+
+func (s *OrderGRPCServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderResponse, error) {
+	order, err := s.getOrder(ctx, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.OrderResponse{Order: toOrderInfo(order)}, nil
+}
+
+func (s *OrderGRPCServer) ListOrdersByUser(ctx context.Context, req *pb.ListOrdersByUserRequest) (*pb.ListOrdersByUserResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user_id is required")
+	}
+	if req.Status != "" && !domain.IsValidOrderStatus(req.Status) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid order status: %s", req.Status)
+	}
+
+	orders, err := s.service.GetOrders(ctx, uint(req.UserId), req.Status)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list orders")
+	}
+
+	resp := &pb.ListOrdersByUserResponse{Orders: make([]*pb.OrderInfo, 0, len(orders))}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, toOrderInfo(&orders[i]))
+	}
+	return resp, nil
+}
+
+func (s *OrderGRPCServer) GetOrderItems(ctx context.Context, req *pb.GetOrderItemsRequest) (*pb.GetOrderItemsResponse, error) {
+	order, err := s.getOrder(ctx, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetOrderItemsResponse{OrderId: uint32(order.ID), Items: toOrderItemInfos(order.Items)}, nil
+}
+
+func (s *OrderGRPCServer) getOrder(ctx context.Context, orderID uint32) (*domain.Order, error) {
+	order, err := s.service.GetOrderByID(ctx, strconv.FormatUint(uint64(orderID), 10), domain.InternalCaller)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get order")
+	}
+	return order, nil
+}
+
+func toOrderInfo(order *domain.Order) *pb.OrderInfo {
+	return &pb.OrderInfo{
+		Id:             uint32(order.ID),
+		UserId:         uint32(order.UserID),
+		Status:         order.Status,
+		TotalAmount:    uint64(order.TotalAmount),
+		Items:          toOrderItemInfos(order.Items),
+		BuyerName:      order.BuyerName,
+		BuyerEmail:     order.BuyerEmail,
+		CreatedAt:      order.CreatedAt.Unix(),
+		Subtotal:       uint64(order.Subtotal),
+		TaxAmount:      uint64(order.TaxAmount),
+		ShippingFee:    uint64(order.ShippingFee),
+		DiscountAmount: uint64(order.DiscountAmount),
+	}
+}
+
+func toOrderItemInfos(items []domain.OrderItem) []*pb.OrderItemInfo {
+	infos := make([]*pb.OrderItemInfo, 0, len(items))
+	for _, item := range items {
+		infos = append(infos, &pb.OrderItemInfo{
+			ProductId: uint32(item.ProductID),
+			VariantId: uint32(item.VariantID),
+			Sku:       item.SKU,
+			Name:      item.Name,
+			Quantity:  uint32(item.Quantity),
+			Price:     uint64(item.Price),
+			Status:    item.Status,
+		})
+	}
+	return infos
+}
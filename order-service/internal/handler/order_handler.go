@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"order-service/internal/domain"
 	"order-service/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type OrderHandler struct {
@@ -16,38 +18,143 @@ func NewOrderHandler(orderService *service.OrderService) *OrderHandler {
 	return &OrderHandler{orderService: orderService}
 }
 
+// callerFromContext returns the user set on the context by the auth middlewares
+func callerFromContext(c *gin.Context) domain.Caller {
+	return domain.Caller{UserID: c.GetUint("userID"), Role: c.GetString("role")}
+}
+
 // PostOrder godoc
 // @Summary Create a new order
-// @Description Create a new order from cart items. Can order all cart items or specific products by providing product IDs.
+// @Description Create a new order from cart items. Can order all cart items or specific products by providing product IDs. A shipping address is required and is stored on the order as it was at checkout. Guest checkout tokens may place orders too, they are looked up afterwards with POST /order/lookup.
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param order body domain.CreateOrderRequest false "Order details with optional product IDs. Leave empty to checkout entire cart."
-// @Success 201 {object} map[string]interface{} "Order created with ID"
-// @Failure 400 {object} map[string]string "Invalid request body"
+// @Param order body domain.CreateOrderRequest true "Shipping address with optional product IDs, quote token and discount code. Leave product IDs empty to checkout entire cart."
+// @Param Idempotency-Key header string false "Client generated key. Retrying with the same key returns the status and body of the original response instead of creating a new order."
+// @Success 201 {object} domain.OrderReceipt "Order created with ID"
+// @Failure 400 {object} map[string]string "Invalid request body or shipping address, empty cart, invalid quote token, unknown discount code or products no longer available"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Quote expired or cart changed since it was quoted"
+// @Failure 422 {object} map[string]string "Idempotency key reused with a different request"
 // @Failure 500 {object} map[string]string "Failed to create order"
 // @Router /order [post]
 func (h *OrderHandler) PostOrder(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req domain.CreateOrderRequest
-	
-	// Bind JSON but don't fail if body is empty
-	_ = c.ShouldBindJSON(&req)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	req.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	if len(req.IdempotencyKey) > 255 {
+		c.JSON(400, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+		return
+	}
 
 	userID := c.GetUint("userID")
+	req.BuyerName = c.GetString("username")
+	req.BuyerEmail = c.GetString("email")
+	req.Guest = c.GetString("role") == domain.RoleGuest
 
 	// Call the service layer to create the order
-	orderID, err := h.orderService.CreateOrder(&req, ctx, userID)
+	receipt, err := h.orderService.CreateOrder(&req, ctx, userID)
 
 	if err != nil {
 		c.Error(err)
+
+		if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+			c.JSON(422, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			return
+		}
+		if errors.Is(err, domain.ErrProductsNotFound) || errors.Is(err, domain.ErrCartEmpty) || errors.Is(err, domain.ErrInvalidQuote) ||
+			errors.Is(err, domain.ErrInvalidShippingAddress) || errors.Is(err, domain.ErrInvalidDiscountCode) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrQuoteExpired) || errors.Is(err, domain.ErrQuoteMismatch) {
+			c.JSON(409, gin.H{"error": err.Error() + ", request a new quote"})
+			return
+		}
+
 		c.JSON(500, gin.H{"error": "Failed to create order"})
 		return
 	}
 
-	c.JSON(201, gin.H{"message": "Order received", "order_id": orderID})
+	c.JSON(receipt.Status, receipt)
+}
+
+// LookupGuestOrder godoc
+// @Summary Look up a guest order
+// @Description Find an order placed with guest checkout by its order number and the email used at checkout. No token is required.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param lookup body domain.GuestOrderLookupRequest true "Order number and email"
+// @Success 200 {object} domain.Order
+// @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 500 {object} map[string]string "Failed to look up order"
+// @Router /order/lookup [post]
+func (h *OrderHandler) LookupGuestOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req domain.GuestOrderLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	order, err := h.orderService.LookupGuestOrder(ctx, req.OrderID, req.Email)
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to look up order"})
+		return
+	}
+
+	c.JSON(200, order)
+}
+
+// QuoteCheckout godoc
+// @Summary Quote checkout totals
+// @Description Price the cart (or the given products in it) the same way placing an order would, without creating an order. Returns the priced lines, stock warnings, totals and a short-lived quote token that POST /order accepts to keep these prices.
+// @Tags Checkout
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param quote body domain.CheckoutQuoteRequest false "Optional product IDs and discount code. Leave product IDs empty to quote the entire cart."
+// @Success 200 {object} domain.CheckoutQuote
+// @Failure 400 {object} map[string]string "Empty cart, products no longer available or unknown discount code"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to quote checkout"
+// @Router /checkout/quote [post]
+func (h *OrderHandler) QuoteCheckout(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req domain.CheckoutQuoteRequest
+
+	// Bind JSON but don't fail if body is empty
+	_ = c.ShouldBindJSON(&req)
+
+	quote, err := h.orderService.QuoteCheckout(ctx, &req, c.GetUint("userID"))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrProductsNotFound) || errors.Is(err, domain.ErrCartEmpty) || errors.Is(err, domain.ErrInvalidDiscountCode) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to quote checkout"})
+		return
+	}
+
+	c.JSON(200, quote)
 }
 
 // GetOrders godoc
@@ -79,7 +186,7 @@ func (h *OrderHandler) GetOrders(c *gin.Context) {
 
 // GetOrderByID godoc
 // @Summary Get order by ID
-// @Description Retrieve a specific order by its ID for the authenticated user
+// @Description Retrieve a specific order by its ID. Customers only see their own orders, admins see any order.
 // @Tags Orders
 // @Accept json
 // @Produce json
@@ -94,12 +201,11 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 	ctx := c.Request.Context()
 	orderID := c.Param("id")
 
-	order, err := h.orderService.GetOrderByID(ctx, orderID)
+	order, err := h.orderService.GetOrderByID(ctx, orderID, callerFromContext(c))
 	if err != nil {
 		c.Error(err)
 
-		// Check if it's a record not found error
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, domain.ErrOrderNotFound) {
 			c.JSON(404, gin.H{"error": "Order not found"})
 			return
 		}
@@ -109,4 +215,380 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 	}
 
 	c.JSON(200, order)
-}
\ No newline at end of file
+}
+
+// CancelOrder godoc
+// @Summary Cancel an order
+// @Description Cancel an order of the authenticated user that is still RECEIVED or AWAITING_PAYMENT. Reserved stock is released and the open payment is voided.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} domain.SuccessResponse "Order cancelled"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Order can no longer be cancelled"
+// @Failure 500 {object} map[string]string "Failed to cancel order"
+// @Router /order/{id}/cancel [post]
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	err := h.orderService.CancelOrder(ctx, orderID, callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrOrderNotCancellable) {
+			c.JSON(409, gin.H{"error": "Order can no longer be cancelled"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to cancel order"})
+		return
+	}
+
+	c.JSON(200, domain.SuccessResponse{Message: "Order cancelled"})
+}
+
+// GetOrderHistory godoc
+// @Summary Get order status history
+// @Description Retrieve every status change of an order, oldest first. Customers only see their own orders, admins see any order.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {array} domain.OrderStatusHistory
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 500 {object} map[string]string "Failed to retrieve order history"
+// @Router /order/{id}/history [get]
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	history, err := h.orderService.GetOrderStatusHistory(ctx, orderID, callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to retrieve order history"})
+		return
+	}
+
+	c.JSON(200, history)
+}
+
+// ListAllOrders godoc
+// @Summary List orders of all users
+// @Description List orders across all customers with filters, pagination and sorting (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Filter by order status"
+// @Param user_id query int false "Filter by user ID"
+// @Param from query string false "Created on or after this date (YYYY-MM-DD)"
+// @Param to query string false "Created on or before this date (YYYY-MM-DD)"
+// @Param min_amount query int false "Minimum total amount"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param sort_by query string false "Sort field (created_at, total_amount, status, user_id)" default(created_at)
+// @Param order query string false "Sort order (asc/desc)" default(desc)
+// @Success 200 {object} domain.PaginatedOrders
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Admins only"
+// @Failure 500 {object} map[string]string "Failed to retrieve orders"
+// @Router /order/admin [get]
+func (h *OrderHandler) ListAllOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+	filter := domain.OrderListFilter{
+		Status: c.Query("status"),
+		SortBy: c.DefaultQuery("sort_by", "created_at"),
+		Order:  c.DefaultQuery("order", "desc"),
+	}
+
+	// Parse pagination parameters
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	if minAmount := c.Query("min_amount"); minAmount != "" {
+		amount, err := strconv.ParseUint(minAmount, 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid min_amount"})
+			return
+		}
+		filter.MinAmount = uint(amount)
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = &date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		// include the whole "to" day
+		end := date.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+
+	result, err := h.orderService.ListAllOrders(ctx, &filter)
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrInvalidOrderFilter) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to retrieve orders"})
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// OverrideOrderStatus godoc
+// @Summary Override order status
+// @Description Manually move an order to another status (Admin only). The change must be allowed by the order state machine and is recorded in the order history with the admin's user ID.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param status body domain.UpdateOrderStatusRequest true "New status"
+// @Success 200 {object} domain.SuccessResponse "Order status updated"
+// @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Admins only"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Status change not allowed"
+// @Failure 500 {object} map[string]string "Failed to update order status"
+// @Router /order/admin/{id}/status [patch]
+func (h *OrderHandler) OverrideOrderStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+	adminID := c.GetUint("userID")
+
+	var req domain.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err := h.orderService.OverrideOrderStatus(ctx, orderID, req.Status, adminID)
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidStatusTransition) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to update order status"})
+		return
+	}
+
+	c.JSON(200, domain.SuccessResponse{Message: "Order status updated"})
+}
+
+// CancelOrderItem godoc
+// @Summary Cancel an order line
+// @Description Cancel a single line of an order (Admin only). A backordered line is simply cancelled. A line of a PAID order is refunded: the order is repriced without it, the difference is refunded through the payment gateway and the line's stock is released. The last active line can't be cancelled, cancel the order instead.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param itemId path int true "Order item ID"
+// @Success 200 {object} domain.Order
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Admins only"
+// @Failure 404 {object} map[string]string "Order or item not found"
+// @Failure 409 {object} map[string]string "Item can't be cancelled"
+// @Failure 500 {object} map[string]string "Failed to cancel order item"
+// @Router /order/admin/{id}/items/{itemId}/cancel [post]
+func (h *OrderHandler) CancelOrderItem(c *gin.Context) {
+	ctx := c.Request.Context()
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 64)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "Order item not found"})
+		return
+	}
+
+	order, err := h.orderService.CancelOrderItem(ctx, c.Param("id"), uint(itemID), c.GetUint("userID"))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrOrderItemNotFound) {
+			c.JSON(404, gin.H{"error": "Order item not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrOrderItemNotCancellable) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to cancel order item"})
+		return
+	}
+
+	c.JSON(200, order)
+}
+
+// GetOrderTracking godoc
+// @Summary Track an order
+// @Description Combine order, payment and delivery status into one chronological timeline. If payment or delivery service is unavailable the response is partial and marked as degraded.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} domain.OrderTracking
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 500 {object} map[string]string "Failed to retrieve order tracking"
+// @Router /order/{id}/tracking [get]
+func (h *OrderHandler) GetOrderTracking(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	tracking, err := h.orderService.GetOrderTracking(ctx, orderID, callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to retrieve order tracking"})
+		return
+	}
+
+	c.JSON(200, tracking)
+}
+
+// ReorderOrder godoc
+// @Summary Reorder a past order
+// @Description Add the items of a previous order back to the cart. Prices and availability are re-checked, so the response lists the lines that were added, the added lines whose price changed and the lines that are no longer available.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} domain.ReorderResult
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 500 {object} map[string]string "Failed to reorder"
+// @Router /order/{id}/reorder [post]
+func (h *OrderHandler) ReorderOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	result, err := h.orderService.ReorderOrder(ctx, orderID, callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to reorder"})
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// GetOrderInvoice godoc
+// @Summary Get order invoice
+// @Description Render the invoice of a paid order as PDF (default) or HTML. The invoice number is assigned when the order becomes PAID, so the document is the same every time it is rendered.
+// @Tags Orders
+// @Produce application/pdf
+// @Produce text/html
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param format query string false "Document format" Enums(pdf, html) default(pdf)
+// @Success 200 {file} file "Invoice document"
+// @Failure 400 {object} map[string]string "Unsupported format"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Order has not been paid yet"
+// @Failure 500 {object} map[string]string "Failed to render invoice"
+// @Router /order/{id}/invoice [get]
+func (h *OrderHandler) GetOrderInvoice(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		c.JSON(400, gin.H{"error": "format must be pdf or html"})
+		return
+	}
+
+	invoice, err := h.orderService.GetOrderInvoice(ctx, orderID, callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": "Order not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrInvoiceNotIssued) {
+			c.JSON(409, gin.H{"error": "Invoice is available once the order is paid"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to render invoice"})
+		return
+	}
+
+	if format == "html" {
+		body, err := service.RenderInvoiceHTML(invoice)
+		if err != nil {
+			c.Error(err)
+			c.JSON(500, gin.H{"error": "Failed to render invoice"})
+			return
+		}
+		c.Data(200, "text/html; charset=utf-8", body)
+		return
+	}
+
+	c.Header("Content-Disposition", "inline; filename=\""+invoice.Number+".pdf\"")
+	c.Data(200, "application/pdf", service.RenderInvoicePDF(invoice))
+}
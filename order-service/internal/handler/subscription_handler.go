@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSubscription godoc
+// @Summary Create a subscription
+// @Description Subscribe to a set of products that are ordered again every interval_days days. Each renewal is a regular order, priced at that time and paid through its own payment link. The first order is placed within a minute.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subscription body domain.CreateSubscriptionRequest true "Products, quantities, interval in days and shipping address"
+// @Success 201 {object} domain.Subscription
+// @Failure 400 {object} map[string]string "Invalid request body or shipping address, or products not available"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Guest accounts cannot subscribe"
+// @Failure 500 {object} map[string]string "Failed to create subscription"
+// @Router /subscription [post]
+func (h *OrderHandler) CreateSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req domain.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	req.BuyerName = c.GetString("username")
+	req.BuyerEmail = c.GetString("email")
+
+	subscription, err := h.orderService.CreateSubscription(ctx, &req, c.GetUint("userID"))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrInvalidShippingAddress) || errors.Is(err, domain.ErrProductsNotFound) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to create subscription"})
+		return
+	}
+
+	c.JSON(201, subscription)
+}
+
+// GetSubscriptions godoc
+// @Summary Get subscriptions
+// @Description Retrieve every subscription of the authenticated user, including cancelled ones
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve subscriptions"
+// @Router /subscription [get]
+func (h *OrderHandler) GetSubscriptions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	subscriptions, err := h.orderService.GetSubscriptions(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "Failed to retrieve subscriptions"})
+		return
+	}
+
+	c.JSON(200, subscriptions)
+}
+
+// GetSubscription godoc
+// @Summary Get subscription by ID
+// @Description Retrieve a subscription with its items and next renewal time. Customers only see their own subscriptions, admins see any subscription.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 500 {object} map[string]string "Failed to retrieve subscription"
+// @Router /subscription/{id} [get]
+func (h *OrderHandler) GetSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	subscription, err := h.orderService.GetSubscription(ctx, c.Param("id"), callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			c.JSON(404, gin.H{"error": "Subscription not found"})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to retrieve subscription"})
+		return
+	}
+
+	c.JSON(200, subscription)
+}
+
+// PauseSubscription godoc
+// @Summary Pause a subscription
+// @Description Stop placing renewal orders for an ACTIVE subscription until it is resumed
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is not active"
+// @Failure 500 {object} map[string]string "Failed to update subscription"
+// @Router /subscription/{id}/pause [post]
+func (h *OrderHandler) PauseSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.orderService.PauseSubscription)
+}
+
+// ResumeSubscription godoc
+// @Summary Resume a subscription
+// @Description Restart a PAUSED or SUSPENDED subscription. A renewal that fell due while it was stopped is placed within a minute.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is not paused or suspended"
+// @Failure 500 {object} map[string]string "Failed to update subscription"
+// @Router /subscription/{id}/resume [post]
+func (h *OrderHandler) ResumeSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.orderService.ResumeSubscription)
+}
+
+// SkipSubscriptionRenewal godoc
+// @Summary Skip the next renewal
+// @Description Move the next renewal of a subscription one interval later. Can be repeated to skip several renewals.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is cancelled"
+// @Failure 500 {object} map[string]string "Failed to update subscription"
+// @Router /subscription/{id}/skip [post]
+func (h *OrderHandler) SkipSubscriptionRenewal(c *gin.Context) {
+	h.changeSubscription(c, h.orderService.SkipNextRenewal)
+}
+
+// CancelSubscription godoc
+// @Summary Cancel a subscription
+// @Description End a subscription for good. Orders it already placed are not affected.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is already cancelled"
+// @Failure 500 {object} map[string]string "Failed to update subscription"
+// @Router /subscription/{id}/cancel [post]
+func (h *OrderHandler) CancelSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.orderService.CancelSubscription)
+}
+
+// changeSubscription runs one of the subscription status changes of the service and writes its response
+func (h *OrderHandler) changeSubscription(c *gin.Context, change func(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error)) {
+	ctx := c.Request.Context()
+
+	subscription, err := change(ctx, c.Param("id"), callerFromContext(c))
+	if err != nil {
+		c.Error(err)
+
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			c.JSON(404, gin.H{"error": "Subscription not found"})
+			return
+		}
+		if errors.Is(err, domain.ErrSubscriptionStatus) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": "Failed to update subscription"})
+		return
+	}
+
+	c.JSON(200, subscription)
+}
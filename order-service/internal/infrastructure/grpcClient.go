@@ -24,3 +24,9 @@ func NewPaymentGRPCClient(address string) pb.PaymentServiceClient {
 	conn := infrastructure.NewGRPCClient(target)
 	return pb.NewPaymentServiceClient(conn)
 }
+
+func NewDeliveryGRPCClient(address string) pb.DeliveryServiceClient {
+	target := fmt.Sprintf("consul://%s/delivery-service?wait=14s", address)
+	conn := infrastructure.NewGRPCClient(target)
+	return pb.NewDeliveryServiceClient(conn)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"order-service/internal/domain"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			c.Abort()
+			return
+		}
+
+		// 1. Extract the token
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		claims := &domain.JWTClaims{}
+
+		// 2. Parse and Validate the token
+		jwtSecret := os.Getenv("JWT_SECRET")
+		if jwtSecret == "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Server configuration error"})
+			c.Abort()
+			return
+		}
+
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(jwtSecret), nil
+		})
+
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		// 3. Check the Role
+		if claims.Role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Admins only"})
+			c.Abort()
+			return
+		}
+
+		// 4. Store user info in context (recorded as changed_by on status overrides)
+		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+
+		c.Next()
+	}
+}
@@ -36,6 +36,9 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// 3. Store user info in context
 		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Set("email", claims.Email)
+		c.Set("username", claims.Username)
 
 		c.Next()
     }
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"order-service/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MembersOnly rejects guest checkout tokens. It runs after AuthMiddleware on routes that need a
+// registered account, guests look up their orders by order number and email instead.
+func MembersOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") == domain.RoleGuest {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: guest checkout tokens can only place orders"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
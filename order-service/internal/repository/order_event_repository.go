@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"order-service/internal/domain"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +13,9 @@ import (
 type OrderEventRepository interface {
 	PublishOrderCreatedEvent(ctx context.Context, event *domain.OrderEvent) error
 	PublishOrderPaidEvent(ctx context.Context, event *domain.OrderEvent) error
+	PublishOrderCancelledEvent(ctx context.Context, event *domain.OrderEvent) error
+	PublishOrderExpiredEvent(ctx context.Context, event *domain.OrderEvent) error
+	PublishOrderItemsCancelledEvent(ctx context.Context, event *domain.OrderEvent) error
 }
 
 type RedisRepository struct {
@@ -42,6 +46,9 @@ func (r *RedisRepository) PublishOrderCreatedEvent(ctx context.Context, event *d
 		"created_at":     time.Now().Format(time.RFC3339),
 		"correlation_id": correlationID,
 	}
+	if event.ShippingPostalCode != "" {
+		msg["shipping_postal_code"] = event.ShippingPostalCode
+	}
 
 	// Add to Stream
 	return r.redisClient.XAdd(ctx, &redis.XAddArgs{
@@ -72,6 +79,16 @@ func (r *RedisRepository) PublishOrderPaidEvent(ctx context.Context, event *doma
 		"created_at":     time.Now().Format(time.RFC3339),
 		"correlation_id": correlationID,
 	}
+	if event.ShippingAddress != nil {
+		addressJSON, err := json.Marshal(event.ShippingAddress)
+		if err != nil {
+			return err
+		}
+		msg["shipping_address"] = string(addressJSON)
+	}
+	if event.SubscriptionID != 0 {
+		msg["subscription_id"] = strconv.FormatUint(uint64(event.SubscriptionID), 10)
+	}
 
 	// Add to Stream
 	return r.redisClient.XAdd(ctx, &redis.XAddArgs{
@@ -82,6 +99,99 @@ func (r *RedisRepository) PublishOrderPaidEvent(ctx context.Context, event *doma
 	}).Err()
 }
 
+func (r *RedisRepository) PublishOrderCancelledEvent(ctx context.Context, event *domain.OrderEvent) error {
+	// Serialize the items payload for stream transport.
+	itemsJSON, err := json.Marshal(event.Items)
+	if err != nil {
+		return err
+	}
+
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = correlationIDFromContext(ctx)
+	}
+
+	msg := map[string]interface{}{
+		"order_id":       event.OrderID,
+		"user_id":        event.UserID,
+		"total_amount":   event.TotalAmount,
+		"items":          string(itemsJSON),
+		"stock_reserved": strconv.FormatBool(event.StockReserved),
+		"created_at":     time.Now().Format(time.RFC3339),
+		"correlation_id": correlationID,
+	}
+
+	// Add to Stream
+	return r.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: "stream:orders:cancelled",
+		MaxLen: 1000,
+		Approx: true,
+		Values: msg,
+	}).Err()
+}
+
+func (r *RedisRepository) PublishOrderExpiredEvent(ctx context.Context, event *domain.OrderEvent) error {
+	// Serialize the items payload for stream transport.
+	itemsJSON, err := json.Marshal(event.Items)
+	if err != nil {
+		return err
+	}
+
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = correlationIDFromContext(ctx)
+	}
+
+	msg := map[string]interface{}{
+		"order_id":       event.OrderID,
+		"user_id":        event.UserID,
+		"total_amount":   event.TotalAmount,
+		"items":          string(itemsJSON),
+		"created_at":     time.Now().Format(time.RFC3339),
+		"correlation_id": correlationID,
+	}
+
+	// Add to Stream
+	return r.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: "stream:orders:expired",
+		MaxLen: 1000,
+		Approx: true,
+		Values: msg,
+	}).Err()
+}
+
+// PublishOrderItemsCancelledEvent announces single lines cancelled on an order that goes on with the rest,
+// so product service releases their stock
+func (r *RedisRepository) PublishOrderItemsCancelledEvent(ctx context.Context, event *domain.OrderEvent) error {
+	// Serialize the items payload for stream transport.
+	itemsJSON, err := json.Marshal(event.Items)
+	if err != nil {
+		return err
+	}
+
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = correlationIDFromContext(ctx)
+	}
+
+	msg := map[string]interface{}{
+		"order_id":       event.OrderID,
+		"user_id":        event.UserID,
+		"total_amount":   event.TotalAmount,
+		"items":          string(itemsJSON),
+		"created_at":     time.Now().Format(time.RFC3339),
+		"correlation_id": correlationID,
+	}
+
+	// Add to Stream
+	return r.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: "stream:orders:items_cancelled",
+		MaxLen: 1000,
+		Approx: true,
+		Values: msg,
+	}).Err()
+}
+
 func correlationIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
@@ -2,17 +2,43 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"order-service/internal/domain"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository interface {
 	AddOrder(ctx context.Context, order *domain.Order) error
 	GetOrders(ctx context.Context, userID uint, status string) ([]domain.Order, error)
+	ListOrders(ctx context.Context, filter *domain.OrderListFilter) ([]domain.Order, int64, error)
 	GetOrderByID(ctx context.Context, orderID string) (*domain.Order, error)
-	UpdateOrderStatus(ctx context.Context, orderID string, status string) error
-	UpdatePaymentUrl(ctx context.Context, orderID string, paymentUrl string) error
+	GetOrderByIDForUpdate(ctx context.Context, orderID string) (*domain.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, history *domain.OrderStatusHistory) error
+	GetOrderStatusHistory(ctx context.Context, orderID string) ([]domain.OrderStatusHistory, error)
+	UpdatePaymentInfo(ctx context.Context, orderID string, paymentUrl string, expiresAt time.Time) error
+	UpdateOrderLines(ctx context.Context, order *domain.Order) error
+	GetOverdueAwaitingPaymentOrders(ctx context.Context, now time.Time, limit int) ([]domain.Order, error)
+	WithTransaction(ctx context.Context, fn func(repo OrderRepository) error) error
+	CreateOutboxMessage(ctx context.Context, eventType string, event *domain.OrderEvent) error
+	GetPendingOutboxMessages(ctx context.Context) ([]*domain.OrderOutboxMessage, error)
+	MarkOutboxMessageAsPublished(ctx context.Context, id uint) error
+	HasStockReleaseQueued(ctx context.Context, orderID uint) (bool, error)
+	CreateRefund(ctx context.Context, refund *domain.OrderRefund) error
+	GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]domain.OrderRefund, error)
+	MarkRefundDone(ctx context.Context, id uint) error
+	GetIdempotencyKey(ctx context.Context, userID uint, key string) (*domain.OrderIdempotencyKey, error)
+	CreateIdempotencyKey(ctx context.Context, idempotencyKey *domain.OrderIdempotencyKey) error
+	CreateInvoice(ctx context.Context, orderID uint, issuedAt time.Time) (*domain.OrderInvoice, error)
+	GetInvoiceByOrderID(ctx context.Context, orderID uint) (*domain.OrderInvoice, error)
+	CreateSubscription(ctx context.Context, subscription *domain.Subscription) error
+	GetSubscriptionByID(ctx context.Context, id uint) (*domain.Subscription, error)
+	GetSubscriptions(ctx context.Context, userID uint) ([]domain.Subscription, error)
+	UpdateSubscriptionSchedule(ctx context.Context, subscription *domain.Subscription) error
+	GetDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]domain.Subscription, error)
 }
 
 type PostgresRepository struct {
@@ -33,24 +59,283 @@ func (r *PostgresRepository) GetOrders(ctx context.Context, userID uint, status
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
-	if err := query.Preload("Items").Find(&orders).Error; err != nil {
+	if err := query.Preload("Items").Preload("TaxLines").Find(&orders).Error; err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
 
+// ListOrders returns one page of orders across all users. filter.SortBy must already be validated.
+func (r *PostgresRepository) ListOrders(ctx context.Context, filter *domain.OrderListFilter) ([]domain.Order, int64, error) {
+	var orders []domain.Order
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Order{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinAmount > 0 {
+		query = query.Where("total_amount >= ?", filter.MinAmount)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Preload("Items").Preload("TaxLines").
+		Order(filter.SortBy + " " + filter.Order + ", id " + filter.Order).
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&orders).Error
+
+	return orders, total, err
+}
+
 func (r *PostgresRepository) GetOrderByID(ctx context.Context, orderID string) (*domain.Order, error) {
 	var order domain.Order
-	if err := r.db.WithContext(ctx).Where("id = ?", orderID).Preload("Items").First(&order).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", orderID).Preload("Items").Preload("TaxLines").First(&order).Error; err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (r *PostgresRepository) UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
-	return r.db.WithContext(ctx).Model(&domain.Order{}).Where("id = ?", orderID).Update("status", status).Error
+// GetOrderByIDForUpdate loads an order and locks its row until the transaction ends, so changes computed
+// from it are not made concurrently from the same state
+func (r *PostgresRepository) GetOrderByIDForUpdate(ctx context.Context, orderID string) (*domain.Order, error) {
+	var order domain.Order
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", orderID).Preload("Items").Preload("TaxLines").First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// UpdateOrderStatus moves the order from history.FromStatus to history.ToStatus and records the change.
+// The update only applies while the order is still in FromStatus, so a concurrent change makes it fail
+// with domain.ErrInvalidStatusTransition instead of being overwritten.
+func (r *PostgresRepository) UpdateOrderStatus(ctx context.Context, orderID string, history *domain.OrderStatusHistory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Order{}).
+			Where("id = ? AND status = ?", orderID, history.FromStatus).
+			Update("status", history.ToStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInvalidStatusTransition
+		}
+		return tx.Create(history).Error
+	})
+}
+
+func (r *PostgresRepository) GetOrderStatusHistory(ctx context.Context, orderID string) ([]domain.OrderStatusHistory, error) {
+	var history []domain.OrderStatusHistory
+	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *PostgresRepository) UpdatePaymentInfo(ctx context.Context, orderID string, paymentUrl string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+		"payment_url":     paymentUrl,
+		"payment_expires": expiresAt,
+	}).Error
+}
+
+// UpdateOrderLines saves the item statuses of an order together with the amounts and tax lines
+// they were repriced to
+func (r *PostgresRepository) UpdateOrderLines(ctx context.Context, order *domain.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, item := range order.Items {
+			if err := tx.Model(&domain.OrderItem{}).Where("id = ?", item.ID).Update("status", item.Status).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&domain.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"subtotal":        order.Subtotal,
+			"tax_amount":      order.TaxAmount,
+			"shipping_fee":    order.ShippingFee,
+			"discount_amount": order.DiscountAmount,
+			"total_amount":    order.TotalAmount,
+			"refunded_amount": order.RefundedAmount,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("order_id = ?", order.ID).Delete(&domain.OrderTaxLine{}).Error; err != nil {
+			return err
+		}
+		for i := range order.TaxLines {
+			order.TaxLines[i].ID = 0
+			order.TaxLines[i].OrderID = order.ID
+		}
+		if len(order.TaxLines) > 0 {
+			return tx.Create(&order.TaxLines).Error
+		}
+		return nil
+	})
+}
+
+// GetOverdueAwaitingPaymentOrders returns unpaid orders whose payment deadline has passed, oldest first.
+// Orders created before deadlines were recorded have a zero deadline and are left to Midtrans.
+func (r *PostgresRepository) GetOverdueAwaitingPaymentOrders(ctx context.Context, now time.Time, limit int) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.db.WithContext(ctx).Preload("Items").Preload("TaxLines").
+		Where("status = ? AND payment_expires > ? AND payment_expires <= ?", domain.OrderStatusAwaitingPayment, time.Time{}, now).
+		Order("payment_expires, id").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Transactional wrapper
+func (r *PostgresRepository) WithTransaction(ctx context.Context, fn func(repo OrderRepository) error) error {
+	tx := r.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	repoWithTx := &PostgresRepository{db: tx}
+
+	if err := fn(repoWithTx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) CreateOutboxMessage(ctx context.Context, eventType string, event *domain.OrderEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := strconv.ParseUint(event.OrderID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	outbox := &domain.OrderOutboxMessage{
+		EventType:     eventType,
+		OrderID:       uint(orderID),
+		Payload:       string(payload),
+		CorrelationID: event.CorrelationID,
+	}
+	return r.db.WithContext(ctx).Create(outbox).Error
+}
+
+func (r *PostgresRepository) GetPendingOutboxMessages(ctx context.Context) ([]*domain.OrderOutboxMessage, error) {
+	var messages []*domain.OrderOutboxMessage
+	// Oldest first so consumers see an order's events in the order they happened
+	if err := r.db.WithContext(ctx).Where("published = ?", false).Order("id").Limit(100).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *PostgresRepository) MarkOutboxMessageAsPublished(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.OrderOutboxMessage{}).Where("id = ?", id).Update("published", true).Error
+}
+
+// HasStockReleaseQueued reports whether a cancelled event that hands back the reserved stock of the order
+// was already queued
+func (r *PostgresRepository) HasStockReleaseQueued(ctx context.Context, orderID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.OrderOutboxMessage{}).
+		Where("order_id = ? AND event_type = ? AND (payload::jsonb ->> 'stock_reserved')::boolean", orderID, domain.OrderEventCancelled).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *PostgresRepository) CreateRefund(ctx context.Context, refund *domain.OrderRefund) error {
+	return r.db.WithContext(ctx).Create(refund).Error
+}
+
+// GetPendingRefunds returns refunds created before before that payment service has not confirmed yet, oldest first
+func (r *PostgresRepository) GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]domain.OrderRefund, error) {
+	var refunds []domain.OrderRefund
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", domain.OrderRefundPending, before).
+		Order("id").
+		Limit(limit).
+		Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+func (r *PostgresRepository) MarkRefundDone(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.OrderRefund{}).Where("id = ?", id).Update("status", domain.OrderRefundDone).Error
+}
+
+func (r *PostgresRepository) GetIdempotencyKey(ctx context.Context, userID uint, key string) (*domain.OrderIdempotencyKey, error) {
+	var idempotencyKey domain.OrderIdempotencyKey
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND key = ?", userID, key).First(&idempotencyKey).Error; err != nil {
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+func (r *PostgresRepository) CreateIdempotencyKey(ctx context.Context, idempotencyKey *domain.OrderIdempotencyKey) error {
+	return r.db.WithContext(ctx).Create(idempotencyKey).Error
+}
+
+// CreateInvoice issues the next invoice number for an order. It must run inside WithTransaction:
+// the table lock keeps invoice numbers gapless while concurrent payments are recorded.
+func (r *PostgresRepository) CreateInvoice(ctx context.Context, orderID uint, issuedAt time.Time) (*domain.OrderInvoice, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec("LOCK TABLE order_invoices IN EXCLUSIVE MODE").Error; err != nil {
+		return nil, err
+	}
+
+	var last uint
+	if err := db.Model(&domain.OrderInvoice{}).Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error; err != nil {
+		return nil, err
+	}
+
+	invoice := &domain.OrderInvoice{
+		OrderID:  orderID,
+		Sequence: last + 1,
+		Number:   domain.InvoiceNumber(last+1, issuedAt),
+		IssuedAt: issuedAt,
+	}
+	if err := db.Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return invoice, nil
 }
 
-func (r *PostgresRepository) UpdatePaymentUrl(ctx context.Context, orderID string, paymentUrl string) error { 
-	return r.db.WithContext(ctx).Model(&domain.Order{}).Where("id = ?", orderID).Update("payment_url", paymentUrl).Error
-}
\ No newline at end of file
+func (r *PostgresRepository) GetInvoiceByOrderID(ctx context.Context, orderID uint) (*domain.OrderInvoice, error) {
+	var invoice domain.OrderInvoice
+	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
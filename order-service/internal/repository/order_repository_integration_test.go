@@ -5,6 +5,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -25,7 +26,7 @@ func openOrderTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Skipf("skipping integration test, cannot connect to order-db: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.Order{}, &domain.OrderItem{}); err != nil {
+	if err := db.AutoMigrate(&domain.Order{}, &domain.OrderItem{}, &domain.OrderStatusHistory{}, &domain.OrderOutboxMessage{}, &domain.OrderIdempotencyKey{}, &domain.OrderInvoice{}, &domain.OrderTaxLine{}, &domain.Subscription{}, &domain.SubscriptionItem{}); err != nil {
 		t.Fatalf("AutoMigrate() error = %v", err)
 	}
 	return db
@@ -61,3 +62,195 @@ func TestOrderRepository_AddAndGetByID_Integration(t *testing.T) {
 		t.Fatalf("expected order ID %d, got %d", order.ID, got.ID)
 	}
 }
+
+func TestOrderRepository_UpdateOrderStatusRequiresCurrentStatus_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	order := &domain.Order{UserID: 78, TotalAmount: 1000, Status: "DELIVERED"}
+	if err := repo.AddOrder(context.Background(), order); err != nil {
+		t.Fatalf("AddOrder() error = %v", err)
+	}
+	orderID := fmt.Sprintf("%d", order.ID)
+
+	err := repo.UpdateOrderStatus(context.Background(), orderID, &domain.OrderStatusHistory{
+		OrderID: order.ID, FromStatus: "PAID", ToStatus: "FAILED", Source: "test",
+	})
+	if !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+
+	history, err := repo.GetOrderStatusHistory(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("GetOrderStatusHistory() error = %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected no history for rejected update, got %d entries", len(history))
+	}
+}
+
+func TestOrderRepository_GetOverdueAwaitingPaymentOrders_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	now := time.Now()
+	overdue := &domain.Order{UserID: 79, TotalAmount: 1000, Status: "AWAITING_PAYMENT", PaymentExpires: now.Add(-time.Minute)}
+	pending := &domain.Order{UserID: 79, TotalAmount: 1000, Status: "AWAITING_PAYMENT", PaymentExpires: now.Add(time.Hour)}
+	for _, order := range []*domain.Order{overdue, pending} {
+		if err := repo.AddOrder(context.Background(), order); err != nil {
+			t.Fatalf("AddOrder() error = %v", err)
+		}
+	}
+
+	orders, err := repo.GetOverdueAwaitingPaymentOrders(context.Background(), now, 1000)
+	if err != nil {
+		t.Fatalf("GetOverdueAwaitingPaymentOrders() error = %v", err)
+	}
+	found := map[uint]bool{}
+	for _, order := range orders {
+		found[order.ID] = true
+	}
+	if !found[overdue.ID] || found[pending.ID] {
+		t.Fatalf("expected only overdue order %d, got %v", overdue.ID, found)
+	}
+}
+
+func TestOrderRepository_CreateInvoiceAssignsNextSequence_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	var invoices []*domain.OrderInvoice
+	for i := 0; i < 2; i++ {
+		order := &domain.Order{UserID: 80, TotalAmount: 1000, Status: "PAID"}
+		if err := repo.AddOrder(context.Background(), order); err != nil {
+			t.Fatalf("AddOrder() error = %v", err)
+		}
+		err := repo.WithTransaction(context.Background(), func(txRepo OrderRepository) error {
+			invoice, err := txRepo.CreateInvoice(context.Background(), order.ID, time.Now())
+			invoices = append(invoices, invoice)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("CreateInvoice() error = %v", err)
+		}
+	}
+
+	if invoices[1].Sequence != invoices[0].Sequence+1 {
+		t.Fatalf("expected consecutive invoice sequences, got %d and %d", invoices[0].Sequence, invoices[1].Sequence)
+	}
+
+	got, err := repo.GetInvoiceByOrderID(context.Background(), invoices[1].OrderID)
+	if err != nil {
+		t.Fatalf("GetInvoiceByOrderID() error = %v", err)
+	}
+	if got.Number != invoices[1].Number {
+		t.Fatalf("expected stored number %s, got %s", invoices[1].Number, got.Number)
+	}
+}
+
+func TestOrderRepository_GetDueSubscriptions_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	now := time.Now()
+	due := &domain.Subscription{UserID: 81, Status: domain.SubscriptionStatusActive, IntervalDays: 7, NextRunAt: now.Add(-time.Minute), Items: []domain.SubscriptionItem{{ProductID: 1, Quantity: 2}}}
+	later := &domain.Subscription{UserID: 81, Status: domain.SubscriptionStatusActive, IntervalDays: 7, NextRunAt: now.Add(time.Hour)}
+	paused := &domain.Subscription{UserID: 81, Status: domain.SubscriptionStatusPaused, IntervalDays: 7, NextRunAt: now.Add(-time.Minute)}
+	for _, subscription := range []*domain.Subscription{due, later, paused} {
+		if err := repo.CreateSubscription(context.Background(), subscription); err != nil {
+			t.Fatalf("CreateSubscription() error = %v", err)
+		}
+	}
+
+	subscriptions, err := repo.GetDueSubscriptions(context.Background(), now, 1000)
+	if err != nil {
+		t.Fatalf("GetDueSubscriptions() error = %v", err)
+	}
+	found := map[uint]domain.Subscription{}
+	for _, subscription := range subscriptions {
+		found[subscription.ID] = subscription
+	}
+	if _, ok := found[due.ID]; !ok || len(found[due.ID].Items) != 1 {
+		t.Fatalf("expected due subscription %d with its items, got %v", due.ID, found)
+	}
+	if _, ok := found[later.ID]; ok {
+		t.Fatal("expected subscription that is not due yet to be skipped")
+	}
+	if _, ok := found[paused.ID]; ok {
+		t.Fatal("expected paused subscription to be skipped")
+	}
+}
+
+func TestOrderRepository_UpdateOrderLines_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	order := &domain.Order{
+		UserID: 82, Status: "RECEIVED", Subtotal: 300, TaxAmount: 33, TotalAmount: 333,
+		TaxLines: []domain.OrderTaxLine{{Category: domain.DefaultTaxCategory, RateBasisPoints: 1100, TaxableAmount: 300, Amount: 33}},
+		Items: []domain.OrderItem{
+			{ProductID: 1, Name: "kept", Quantity: 1, Price: 100, Status: domain.OrderItemStatusPending},
+			{ProductID: 2, Name: "dropped", Quantity: 1, Price: 200, Status: domain.OrderItemStatusPending},
+		},
+	}
+	if err := repo.AddOrder(context.Background(), order); err != nil {
+		t.Fatalf("AddOrder() error = %v", err)
+	}
+
+	order.ApplyReservation([]domain.ItemKey{{ProductID: 2}})
+	order.Subtotal, order.TaxAmount, order.TotalAmount = 100, 11, 111
+	order.TaxLines = []domain.OrderTaxLine{{Category: domain.DefaultTaxCategory, RateBasisPoints: 1100, TaxableAmount: 100, Amount: 11}}
+	if err := repo.UpdateOrderLines(context.Background(), order); err != nil {
+		t.Fatalf("UpdateOrderLines() error = %v", err)
+	}
+
+	got, err := repo.GetOrderByID(context.Background(), fmt.Sprintf("%d", order.ID))
+	if err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+	if got.TotalAmount != 111 || len(got.TaxLines) != 1 || got.TaxLines[0].TaxableAmount != 100 {
+		t.Fatalf("expected repriced amounts and tax lines, got %#v", got)
+	}
+	statuses := map[uint]string{}
+	for _, item := range got.Items {
+		statuses[item.ProductID] = item.Status
+	}
+	if statuses[1] != domain.OrderItemStatusReserved || statuses[2] != domain.OrderItemStatusCancelled {
+		t.Fatalf("expected item statuses to be saved, got %v", statuses)
+	}
+}
+
+func TestOrderRepository_HasStockReleaseQueued_Integration(t *testing.T) {
+	db := openOrderTestDB(t)
+	repo := NewPostgresRepository(db)
+	ctx := context.Background()
+
+	order := &domain.Order{UserID: 79, TotalAmount: 1000, Status: "CANCELLED"}
+	if err := repo.AddOrder(ctx, order); err != nil {
+		t.Fatalf("AddOrder() error = %v", err)
+	}
+	orderID := fmt.Sprintf("%d", order.ID)
+
+	// Cancelled before stock was reserved, nothing to hand back yet
+	if err := repo.CreateOutboxMessage(ctx, domain.OrderEventCancelled, &domain.OrderEvent{OrderID: orderID}); err != nil {
+		t.Fatalf("CreateOutboxMessage() error = %v", err)
+	}
+	queued, err := repo.HasStockReleaseQueued(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("HasStockReleaseQueued() error = %v", err)
+	}
+	if queued {
+		t.Fatal("did not expect a cancellation without reserved stock to count as a release")
+	}
+
+	if err := repo.CreateOutboxMessage(ctx, domain.OrderEventCancelled, &domain.OrderEvent{OrderID: orderID, StockReserved: true}); err != nil {
+		t.Fatalf("CreateOutboxMessage() error = %v", err)
+	}
+	queued, err = repo.HasStockReleaseQueued(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("HasStockReleaseQueued() error = %v", err)
+	}
+	if !queued {
+		t.Fatal("expected the stock release to be found")
+	}
+}
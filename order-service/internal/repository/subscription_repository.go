@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"order-service/internal/domain"
+	"time"
+)
+
+func (r *PostgresRepository) CreateSubscription(ctx context.Context, subscription *domain.Subscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *PostgresRepository) GetSubscriptionByID(ctx context.Context, id uint) (*domain.Subscription, error) {
+	var subscription domain.Subscription
+	if err := r.db.WithContext(ctx).Preload("Items").First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *PostgresRepository) GetSubscriptions(ctx context.Context, userID uint) ([]domain.Subscription, error) {
+	var subscriptions []domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Items").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// UpdateSubscriptionSchedule saves the status and renewal bookkeeping of a subscription, its items never change
+func (r *PostgresRepository) UpdateSubscriptionSchedule(ctx context.Context, subscription *domain.Subscription) error {
+	return r.db.WithContext(ctx).Model(subscription).
+		Select("status", "next_run_at", "failed_attempts", "last_error", "last_order_id").
+		Updates(subscription).Error
+}
+
+// GetDueSubscriptions returns active subscriptions whose next renewal is at or before now, oldest first
+func (r *PostgresRepository) GetDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]domain.Subscription, error) {
+	var subscriptions []domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Items").
+		Where("status = ? AND next_run_at <= ?", domain.SubscriptionStatusActive, now).
+		Order("next_run_at, id").
+		Limit(limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
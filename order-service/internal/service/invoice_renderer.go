@@ -0,0 +1,264 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"order-service/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Invoices only depend on the stored invoice and order snapshots, so rendering the same
+// invoice twice produces byte-identical documents.
+
+const invoiceSeller = "Ecommerce Microservice"
+
+var invoiceHTMLTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount":   formatInvoiceAmount,
+	"date":     formatInvoiceDate,
+	"taxLabel": invoiceTaxLabel,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 40px; color: #222; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+tfoot td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>{{.Seller}}</p>
+<p>
+Issued: {{date .IssuedAt}}<br>
+Order: #{{.OrderID}} ({{date .OrderDate}})
+</p>
+<h2>Bill to</h2>
+<p>
+{{if .BuyerName}}{{.BuyerName}}<br>{{end}}
+{{if .BuyerEmail}}{{.BuyerEmail}}<br>{{end}}
+Customer ID: {{.BuyerID}}
+</p>
+<table>
+<thead>
+<tr><th>Item</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount</th></tr>
+</thead>
+<tbody>
+{{range .Lines}}<tr><td>{{.Name}}</td><td class="num">{{.Quantity}}</td><td class="num">{{amount .UnitPrice}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><td colspan="3">Subtotal</td><td class="num">{{amount .Subtotal}}</td></tr>
+{{if .Discount}}<tr><td colspan="3">Discount</td><td class="num">-{{amount .Discount}}</td></tr>
+{{end}}{{range .TaxLines}}<tr><td colspan="3">{{taxLabel .}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}<tr><td colspan="3">Shipping</td><td class="num">{{amount .ShippingFee}}</td></tr>
+<tr><td colspan="3">Total</td><td class="num">{{amount .TotalAmount}}</td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML renders an invoice as a standalone HTML page
+func RenderInvoiceHTML(invoice *domain.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	data := struct {
+		*domain.Invoice
+		Seller string
+	}{invoice, invoiceSeller}
+	if err := invoiceHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render invoice html: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderInvoicePDF renders an invoice as an A4 PDF using the standard Helvetica fonts
+func RenderInvoicePDF(invoice *domain.Invoice) []byte {
+	doc := &pdfDocument{}
+	page := doc.addPage()
+
+	page.text(50, 790, true, 20, "Invoice "+invoice.Number)
+	page.text(50, 768, false, 10, invoiceSeller)
+	page.text(50, 740, false, 10, "Issued: "+formatInvoiceDate(invoice.IssuedAt))
+	page.text(50, 726, false, 10, fmt.Sprintf("Order: #%d (%s)", invoice.OrderID, formatInvoiceDate(invoice.OrderDate)))
+
+	y := 696.0
+	page.text(50, y, true, 12, "Bill to")
+	y -= 16
+	for _, line := range []string{invoice.BuyerName, invoice.BuyerEmail, fmt.Sprintf("Customer ID: %d", invoice.BuyerID)} {
+		if line == "" {
+			continue
+		}
+		page.text(50, y, false, 10, line)
+		y -= 14
+	}
+
+	header := func(y float64) {
+		page.text(50, y, true, 10, "Item")
+		page.text(320, y, true, 10, "Qty")
+		page.text(370, y, true, 10, "Unit price")
+		page.text(470, y, true, 10, "Amount")
+	}
+	y -= 16
+	header(y)
+	y -= 18
+	for _, line := range invoice.Lines {
+		if y < 60 {
+			page = doc.addPage()
+			y = 790
+			header(y)
+			y -= 18
+		}
+		page.text(50, y, false, 10, truncateInvoiceText(line.Name, 48))
+		page.text(320, y, false, 10, strconv.FormatUint(uint64(line.Quantity), 10))
+		page.text(370, y, false, 10, formatInvoiceAmount(line.UnitPrice))
+		page.text(470, y, false, 10, formatInvoiceAmount(line.Amount))
+		y -= 14
+	}
+
+	summary := [][2]string{{"Subtotal", formatInvoiceAmount(invoice.Subtotal)}}
+	if invoice.Discount > 0 {
+		summary = append(summary, [2]string{"Discount", "-" + formatInvoiceAmount(invoice.Discount)})
+	}
+	for _, taxLine := range invoice.TaxLines {
+		summary = append(summary, [2]string{invoiceTaxLabel(taxLine), formatInvoiceAmount(taxLine.Amount)})
+	}
+	summary = append(summary, [2]string{"Shipping", formatInvoiceAmount(invoice.ShippingFee)})
+
+	y -= 6
+	for _, row := range summary {
+		if y < 60 {
+			page = doc.addPage()
+			y = 790
+		}
+		page.text(300, y, false, 10, row[0])
+		page.text(470, y, false, 10, row[1])
+		y -= 14
+	}
+
+	if y < 60 {
+		page = doc.addPage()
+		y = 790
+	}
+	page.text(300, y-6, true, 12, "Total")
+	page.text(470, y-6, true, 12, formatInvoiceAmount(invoice.TotalAmount))
+
+	return doc.bytes()
+}
+
+// formatInvoiceAmount formats an amount in rupiah with thousand separators, e.g. IDR 1,500,000
+func formatInvoiceAmount(amount uint) string {
+	digits := strconv.FormatUint(uint64(amount), 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return "IDR " + b.String()
+}
+
+// invoiceTaxLabel describes a tax line, e.g. "Tax books (5.5%)"
+func invoiceTaxLabel(taxLine domain.OrderTaxLine) string {
+	rate := strconv.FormatFloat(float64(taxLine.RateBasisPoints)/100, 'f', -1, 64)
+	return fmt.Sprintf("Tax %s (%s%%)", taxLine.Category, rate)
+}
+
+func formatInvoiceDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func truncateInvoiceText(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// pdfDocument is a minimal PDF writer, enough for text-only documents like invoices
+type pdfDocument struct {
+	pages []*pdfPage
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (d *pdfDocument) addPage() *pdfPage {
+	page := &pdfPage{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (p *pdfPage) text(x, y float64, bold bool, size float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, y, escapePDFText(s))
+}
+
+// escapePDFText escapes a string for a PDF literal. Helvetica uses WinAnsiEncoding, so characters
+// outside Latin-1 are replaced.
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func (d *pdfDocument) bytes() []byte {
+	// Object layout: 1 catalog, 2 page tree, 3 regular font, 4 bold font, then a page and content object per page
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for _, page := range d.pages {
+		pageObj := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.content.Len(), page.content.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -2,54 +2,238 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"libs/logger"
 	"libs/pb"
+	"net/http"
 	"order-service/internal/domain"
 	"order-service/internal/repository"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type OrderService struct {
-	repo          repository.OrderRepository
-	eventRepo     repository.OrderEventRepository
-	cartClient    pb.CartServiceClient
-	productClient pb.ProductServiceClient
-	paymentClient pb.PaymentServiceClient
+	repo           repository.OrderRepository
+	eventRepo      repository.OrderEventRepository
+	cartClient     pb.CartServiceClient
+	productClient  pb.ProductServiceClient
+	paymentClient  pb.PaymentServiceClient
+	deliveryClient pb.DeliveryServiceClient
+	paymentExpiry  time.Duration
+	pricing        domain.PricingRules
+	quoteSecret    []byte
+	quoteTTL       time.Duration
+
+	subscriptionMaxAttempts int
+}
+
+// Settings are the business rules the order service is configured with
+type Settings struct {
+	// PaymentExpiry is how long a customer has to pay before the order is cancelled
+	PaymentExpiry time.Duration
+	Pricing       domain.PricingRules
+	// QuoteSecret signs checkout quote tokens, which stay valid for QuoteTTL
+	QuoteSecret []byte
+	QuoteTTL    time.Duration
+	// SubscriptionMaxAttempts is how many renewals in a row may fail before a subscription is suspended
+	SubscriptionMaxAttempts int
 }
 
-func NewOrderService(repo repository.OrderRepository, eventRepo repository.OrderEventRepository, cartClient pb.CartServiceClient, productClient pb.ProductServiceClient, paymentClient pb.PaymentServiceClient) *OrderService {
-	return &OrderService{repo: repo, eventRepo: eventRepo, cartClient: cartClient, productClient: productClient, paymentClient: paymentClient}
+func NewOrderService(repo repository.OrderRepository, eventRepo repository.OrderEventRepository, cartClient pb.CartServiceClient, productClient pb.ProductServiceClient, paymentClient pb.PaymentServiceClient, deliveryClient pb.DeliveryServiceClient, settings Settings) *OrderService {
+	return &OrderService{repo: repo, eventRepo: eventRepo, cartClient: cartClient, productClient: productClient, paymentClient: paymentClient, deliveryClient: deliveryClient, paymentExpiry: settings.PaymentExpiry, pricing: settings.Pricing, quoteSecret: settings.QuoteSecret, quoteTTL: settings.QuoteTTL, subscriptionMaxAttempts: settings.SubscriptionMaxAttempts}
 }
 
-func (s *OrderService) CreateOrder(req *domain.CreateOrderRequest, ctx context.Context, userID uint) (uint, error) {
+// trackingTimeout bounds how long order tracking waits for payment and delivery service
+const trackingTimeout = 2 * time.Second
+
+// expiredOrdersBatchSize caps how many overdue orders a single sweep cancels
+const expiredOrdersBatchSize = 100
+
+// pendingRefundsBatchSize caps how many pending refunds a single retry sends
+const pendingRefundsBatchSize = 100
+
+func (s *OrderService) CreateOrder(req *domain.CreateOrderRequest, ctx context.Context, userID uint) (*domain.OrderReceipt, error) {
 	l := logger.ForContext(ctx)
+
+	if req.ShippingAddress == nil {
+		return nil, fmt.Errorf("%w: shipping_address is required", domain.ErrInvalidShippingAddress)
+	}
+	req.ShippingAddress.Normalize()
+	if err := req.ShippingAddress.Validate(); err != nil {
+		return nil, err
+	}
+
+	// A retried request with a known Idempotency-Key gets the answer it got the first time
+	var fingerprint string
+	if req.IdempotencyKey != "" {
+		fingerprint = requestFingerprint(req)
+		receipt, err := s.findIdempotentOrder(ctx, userID, req.IdempotencyKey, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if receipt != nil {
+			l.Info("Replaying order for idempotency key", zap.Uint("orderID", receipt.OrderID), zap.Uint("userID", userID))
+			return receipt, nil
+		}
+	}
+
+	// Subscription renewals order their own lines, everything else checks out the cart
+	var cart *checkoutCart
+	var err error
+	if len(req.Lines) > 0 {
+		cartItems := make([]*pb.CartItem, len(req.Lines))
+		for i, line := range req.Lines {
+			cartItems[i] = &pb.CartItem{ProductId: uint32(line.ProductID), VariantId: uint32(line.VariantID), Quantity: uint32(line.Quantity)}
+		}
+		cart, err = s.priceCartItems(ctx, cartItems)
+	} else {
+		cart, err = s.loadCheckoutCart(ctx, userID, req.ProductIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A quote token locks the prices the customer was shown, as long as the cart still holds the quoted lines
+	if req.QuoteToken != "" {
+		quote, err := s.verifyQuoteToken(req.QuoteToken, userID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if err := quote.checkDiscount(req.DiscountCode); err != nil {
+			return nil, err
+		}
+		if err := quote.lockPrices(cart.items); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create order
+	order := &domain.Order{
+		UserID:          userID,
+		Items:           cart.items,
+		BuyerName:       req.BuyerName,
+		BuyerEmail:      req.BuyerEmail,
+		ShippingAddress: *req.ShippingAddress,
+		Guest:           req.Guest,
+		SubscriptionID:  req.SubscriptionID,
+		AllowBackorder:  req.AllowBackorder,
+	}
+	// Guest usernames are generated, the recipient is the best name for the invoice
+	if req.Guest {
+		order.BuyerName = req.ShippingAddress.Recipient
+	}
+	if err := s.pricing.ApplyDiscount(order, req.DiscountCode); err != nil {
+		return nil, err
+	}
+	s.pricing.ApplyPricing(order, cart.categories)
+
+	// Save order and its created event in one transaction, the outbox worker publishes the event
+	var receipt *domain.OrderReceipt
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		if err := txRepo.AddOrder(ctx, order); err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+		receipt = &domain.OrderReceipt{Status: http.StatusCreated, Message: "Order received", OrderID: order.ID}
+
+		// The unique (user_id, key) index makes a concurrent request with the same key roll back here
+		if req.IdempotencyKey != "" {
+			body, err := json.Marshal(receipt)
+			if err != nil {
+				return fmt.Errorf("failed to encode order receipt: %w", err)
+			}
+			if err := txRepo.CreateIdempotencyKey(ctx, &domain.OrderIdempotencyKey{
+				UserID:         userID,
+				Key:            req.IdempotencyKey,
+				Fingerprint:    fingerprint,
+				OrderID:        order.ID,
+				ResponseStatus: receipt.Status,
+				ResponseBody:   string(body),
+			}); err != nil {
+				return fmt.Errorf("failed to save idempotency key: %w", err)
+			}
+		}
+
+		if err := txRepo.CreateOutboxMessage(ctx, domain.OrderEventCreated, &domain.OrderEvent{
+			OrderID:            strconv.FormatUint(uint64(order.ID), 10),
+			UserID:             strconv.FormatUint(uint64(userID), 10),
+			TotalAmount:        order.TotalAmount,
+			Items:              domain.ConvertToOrderItemMessages(order.Items),
+			CorrelationID:      correlationIDFromContext(ctx),
+			ShippingPostalCode: order.ShippingAddress.PostalCode,
+		}); err != nil {
+			return fmt.Errorf("failed to create order created outbox message: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if req.IdempotencyKey != "" {
+			if replay, lookupErr := s.findIdempotentOrder(ctx, userID, req.IdempotencyKey, fingerprint); lookupErr != nil || replay != nil {
+				return replay, lookupErr
+			}
+		}
+		l.Error("failed to create order", zap.Error(err))
+		return nil, err
+	}
+	l.Info("Order created successfully",
+		zap.Uint("orderID", order.ID), zap.Uint("userID", userID), zap.Uint("totalAmount", order.TotalAmount))
+
+	return receipt, nil
+}
+
+// checkoutCart is the part of a cart being checked out, with the current details of every product in it
+type checkoutCart struct {
+	items      []domain.OrderItem // priced at the current product price
+	categories map[uint][]string
+	products   map[uint32]*pb.ProductResponse
+}
+
+// loadCheckoutCart fetches the whole cart, or only the lines of productIDs when given, and looks up every line
+// in product service. Every variant of a selected product in the cart is checked out.
+func (s *OrderService) loadCheckoutCart(ctx context.Context, userID uint, productIDs []uint) (*checkoutCart, error) {
+	l := logger.ForContext(ctx)
+
 	// Fetch cart (entire or specific items)
 	var cartItems []*pb.CartItem
 	userIDStr := strconv.FormatUint(uint64(userID), 10)
 
-	if len(req.ProductIDs) > 0 {
+	if len(productIDs) > 0 {
 
 		// Fetch specific items
-		productIDs := make([]uint32, len(req.ProductIDs))
-		for i, id := range req.ProductIDs {
-			productIDs[i] = uint32(id)
+		ids := make([]uint32, len(productIDs))
+		for i, id := range productIDs {
+			ids[i] = uint32(id)
 		}
 
 		cartResp, err := s.cartClient.GetCartItems(ctx, &pb.GetCartItemRequest{
 			UserId:     userIDStr,
-			ProductIds: productIDs,
+			ProductIds: ids,
 		})
 
 		if err != nil {
 			l.Error("failed to fetch cart items", zap.Error(err))
-			return 0, fmt.Errorf("failed to fetch cart items: %w", err)
+			return nil, fmt.Errorf("failed to fetch cart items: %w", err)
 		}
 
-		if len(cartResp.Items) != len(req.ProductIDs) {
-			return 0, fmt.Errorf("invalid product id")
+		inCart := make(map[uint32]bool, len(cartResp.Items))
+		for _, item := range cartResp.Items {
+			inCart[item.ProductId] = true
+		}
+		for _, id := range ids {
+			if !inCart[id] {
+				return nil, fmt.Errorf("invalid product id")
+			}
 		}
 
 		cartItems = cartResp.Items
@@ -60,85 +244,196 @@ func (s *OrderService) CreateOrder(req *domain.CreateOrderRequest, ctx context.C
 		})
 		if err != nil {
 			l.Error("failed to fetch cart", zap.Error(err))
-			return 0, fmt.Errorf("failed to fetch cart: %w", err)
+			return nil, fmt.Errorf("failed to fetch cart: %w", err)
 		}
 		cartItems = cartResp.Items
 	}
 
 	// Validate cart not empty
 	if len(cartItems) == 0 {
-		return 0, fmt.Errorf("cart is empty or no valid items found")
+		return nil, domain.ErrCartEmpty
 	}
+	return s.priceCartItems(ctx, cartItems)
+}
 
-	// Build order items
-	orderItems := make([]domain.OrderItem, 0, len(cartItems))
-	var totalAmt uint
+// priceCartItems looks up every line in product service, failing if any product or variant is gone.
+// Lines of products sold in variants are priced at their variant.
+func (s *OrderService) priceCartItems(ctx context.Context, cartItems []*pb.CartItem) (*checkoutCart, error) {
+	l := logger.ForContext(ctx)
 
-	for _, cartItem := range cartItems {
+	// fetch latest prices for every line in one call to product service
+	ids := make([]uint32, len(cartItems))
+	for i, cartItem := range cartItems {
+		ids[i] = cartItem.ProductId
+	}
+	productsResp, err := s.productClient.GetProducts(ctx, &pb.GetProductsRequest{Ids: ids})
+	if err != nil {
+		l.Error("failed to fetch product details", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch product details: %w", err)
+	}
 
-		// fetch price from product service to ensure latest price
-		productResp, err := s.productClient.GetProduct(ctx, &pb.GetProductRequest{Id: cartItem.ProductId})
-		if err != nil {
-			l.Error("failed to fetch product details", zap.Error(err))
-			return 0, fmt.Errorf("failed to fetch product details: %w", err)
+	products := make(map[uint32]*pb.ProductResponse, len(productsResp.Products))
+	for _, product := range productsResp.Products {
+		if !product.Deleted {
+			products[product.Id] = product
+		}
+	}
+
+	// Report every line that can no longer be ordered at once, as product_id or product_id:variant_id
+	var missing []string
+	for _, cartItem := range cartItems {
+		product, ok := products[cartItem.ProductId]
+		if ok && productVariant(product, cartItem.VariantId) == nil && (cartItem.VariantId != 0 || len(product.Variants) > 0) {
+			ok = false
 		}
+		if !ok {
+			line := strconv.FormatUint(uint64(cartItem.ProductId), 10)
+			if cartItem.VariantId != 0 {
+				line += ":" + strconv.FormatUint(uint64(cartItem.VariantId), 10)
+			}
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %v", domain.ErrProductsNotFound, missing)
+	}
 
-		orderItems = append(orderItems, domain.OrderItem{
+	cart := &checkoutCart{
+		items:      make([]domain.OrderItem, 0, len(cartItems)),
+		categories: make(map[uint][]string, len(cartItems)),
+		products:   products,
+	}
+	for _, cartItem := range cartItems {
+		product := products[cartItem.ProductId]
+		item := domain.OrderItem{
 			ProductID: uint(cartItem.ProductId),
 			Quantity:  uint(cartItem.Quantity),
-			Name:      productResp.Name,
-			Price:     uint(productResp.Price),
-		})
-		totalAmt += uint(productResp.Price) * uint(cartItem.Quantity)
+			Name:      product.Name,
+			Price:     uint(product.Price),
+			Status:    domain.OrderItemStatusPending,
+		}
+		if variant := productVariant(product, cartItem.VariantId); variant != nil {
+			item.VariantID = uint(variant.Id)
+			item.SKU = variant.Sku
+			item.Price = uint(variant.Price)
+		}
+		cart.items = append(cart.items, item)
+		cart.categories[uint(cartItem.ProductId)] = product.Categories
 	}
+	return cart, nil
+}
 
-	// Create order
-	order := &domain.Order{
-		UserID:      userID,
-		Items:       orderItems,
-		TotalAmount: totalAmt,
+// productVariant returns the variant of product with variantID, nil when there is none or it is deleted
+func productVariant(product *pb.ProductResponse, variantID uint32) *pb.ProductVariant {
+	if variantID == 0 {
+		return nil
+	}
+	for _, variant := range product.Variants {
+		if variant.Id == variantID && !variant.Deleted {
+			return variant
+		}
 	}
+	return nil
+}
 
-	// Save order to database
-	if err := s.repo.AddOrder(ctx, order); err != nil {
-		l.Error("failed to create order", zap.Error(err))
-		return 0, fmt.Errorf("failed to create order: %w", err)
+// QuoteCheckout prices the cart exactly like CreateOrder would without saving anything. The returned
+// token can be passed to CreateOrder to keep the quoted prices until it expires.
+func (s *OrderService) QuoteCheckout(ctx context.Context, req *domain.CheckoutQuoteRequest, userID uint) (*domain.CheckoutQuote, error) {
+	cart, err := s.loadCheckoutCart(ctx, userID, req.ProductIDs)
+	if err != nil {
+		return nil, err
 	}
-	l.Info("Order created successfully",
-		zap.Uint("orderID", order.ID), zap.Uint("userID", userID), zap.Uint("totalAmount", totalAmt))
 
-	// Publish order created event
-	if err := s.eventRepo.PublishOrderCreatedEvent(ctx, &domain.OrderEvent{
-		OrderID:       strconv.FormatUint(uint64(order.ID), 10),
-		UserID:        userIDStr,
-		TotalAmount:   order.TotalAmount,
-		Items:         domain.ConvertToOrderItemMessages(order.Items),
-		CorrelationID: correlationIDFromContext(ctx),
-	}); err != nil {
-		l.Error("failed to publish order created event", zap.Error(err))
-		return 0, fmt.Errorf("failed to publish order created event: %w", err)
+	order := &domain.Order{UserID: userID, Items: cart.items}
+	if err := s.pricing.ApplyDiscount(order, req.DiscountCode); err != nil {
+		return nil, err
+	}
+	s.pricing.ApplyPricing(order, cart.categories)
+
+	expiresAt := time.Now().Add(s.quoteTTL).UTC().Truncate(time.Second)
+	quote := &domain.CheckoutQuote{
+		Lines:          make([]domain.QuoteLine, 0, len(cart.items)),
+		StockWarnings:  []domain.StockWarning{},
+		Subtotal:       order.Subtotal,
+		TaxLines:       order.TaxLines,
+		TaxAmount:      order.TaxAmount,
+		ShippingFee:    order.ShippingFee,
+		DiscountCode:   order.DiscountCode,
+		DiscountAmount: order.DiscountAmount,
+		TotalAmount:    order.TotalAmount,
+		QuoteToken:     s.signQuoteToken(newQuoteClaims(userID, expiresAt, cart.items, order.DiscountCode)),
+		ExpiresAt:      expiresAt,
 	}
-	l.Info("Order Event Created", zap.String("orderID", strconv.FormatUint(uint64(order.ID), 10)))
+	for _, item := range cart.items {
+		quote.Lines = append(quote.Lines, domain.QuoteLine{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			Amount:    item.Price * item.Quantity,
+		})
 
-	return order.ID, nil
+		// Stock is only reserved once the order is placed, so a short line is a warning rather than an error
+		product := cart.products[uint32(item.ProductID)]
+		inStock, stock := product.Available, product.Stock
+		if variant := productVariant(product, uint32(item.VariantID)); variant != nil {
+			inStock, stock = variant.Available, variant.Stock
+		}
+		if !inStock || stock < int64(item.Quantity) {
+			available := uint(0)
+			if stock > 0 {
+				available = uint(stock)
+			}
+			quote.StockWarnings = append(quote.StockWarnings, domain.StockWarning{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				SKU:       item.SKU,
+				Name:      item.Name,
+				Requested: item.Quantity,
+				Available: available,
+			})
+		}
+	}
+	return quote, nil
+}
+
+// findIdempotentOrder looks up the answer given earlier to a request with the same idempotency key. It returns
+// nil when the key is new.
+func (s *OrderService) findIdempotentOrder(ctx context.Context, userID uint, key string, fingerprint string) (*domain.OrderReceipt, error) {
+	idempotencyKey, err := s.repo.GetIdempotencyKey(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if idempotencyKey.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyMismatch
+	}
+	// Keys saved before responses were stored only know their order
+	if idempotencyKey.ResponseBody == "" {
+		return &domain.OrderReceipt{Status: http.StatusCreated, Message: "Order received", OrderID: idempotencyKey.OrderID}, nil
+	}
+	receipt := &domain.OrderReceipt{Status: idempotencyKey.ResponseStatus}
+	if err := json.Unmarshal([]byte(idempotencyKey.ResponseBody), receipt); err != nil {
+		return nil, fmt.Errorf("failed to decode stored response of idempotency key: %w", err)
+	}
+	return receipt, nil
+}
+
+func requestFingerprint(req *domain.CreateOrderRequest) string {
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *OrderService) GetOrders(ctx context.Context, userID uint, status string) ([]domain.Order, error) {
 	l := logger.ForContext(ctx)
-	if status != "" {
-		// validate status
-		validStatuses := map[string]bool{
-			"RECEIVED":         true,
-			"AWAITING_PAYMENT": true,
-			"PAID":             true,
-			"SHIPPED":          true,
-			"DELIVERED":        true,
-			"CANCELLED":        true,
-			"FAILED":           true,
-		}
-		if !validStatuses[status] {
-			return nil, fmt.Errorf("invalid order status: %s", status)
-		}
+	if status != "" && !domain.IsValidOrderStatus(status) {
+		return nil, fmt.Errorf("invalid order status: %s", status)
 	}
 
 	orders, err := s.repo.GetOrders(ctx, userID, status)
@@ -151,65 +446,317 @@ func (s *OrderService) GetOrders(ctx context.Context, userID uint, status string
 	return orders, nil
 }
 
-func (s *OrderService) GetOrderByID(ctx context.Context, orderID string) (*domain.Order, error) {
+func (s *OrderService) GetOrderByID(ctx context.Context, orderID string, caller domain.Caller) (*domain.Order, error) {
+	l := logger.ForContext(ctx)
+	order, err := s.getOrderFor(ctx, orderID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("Order retrieved successfully", zap.String("orderID", orderID))
+	return order, nil
+}
+
+// LookupGuestOrder finds a guest order by its ID and the email it was placed with. Any mismatch is
+// reported as not found so the lookup does not reveal which order numbers exist.
+func (s *OrderService) LookupGuestOrder(ctx context.Context, orderID uint, email string) (*domain.Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, strconv.FormatUint(uint64(orderID), 10))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if !order.Guest || order.BuyerEmail == "" || !strings.EqualFold(order.BuyerEmail, strings.TrimSpace(email)) {
+		return nil, domain.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// getOrderFor loads an order the caller is allowed to access. Orders of other users are reported
+// as not found so their IDs can't be probed. Endpoints working on a single order go through here.
+func (s *OrderService) getOrderFor(ctx context.Context, orderID string, caller domain.Caller) (*domain.Order, error) {
 	l := logger.ForContext(ctx)
 	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrOrderNotFound
+		}
 		l.Error("failed to get order by id", zap.Error(err))
 		return nil, fmt.Errorf("failed to get order by id: %w", err)
 	}
 
-	l.Info("Order retrieved successfully", zap.String("orderID", orderID))
+	if !domain.CanAccessOrder(caller, order) {
+		l.Warn("order access denied", zap.String("orderID", orderID), zap.Uint("userID", caller.UserID))
+		return nil, domain.ErrOrderNotFound
+	}
 	return order, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
+// GetOrderTracking combines the order history with the payment and delivery status into one timeline.
+// Payment or delivery service being unreachable degrades the result instead of failing it.
+func (s *OrderService) GetOrderTracking(ctx context.Context, orderID string, caller domain.Caller) (*domain.OrderTracking, error) {
 	l := logger.ForContext(ctx)
-	err := s.repo.UpdateOrderStatus(ctx, orderID, status)
+	order, err := s.getOrderFor(ctx, orderID, caller)
 	if err != nil {
-		l.Error("failed to update order status", zap.Error(err))
-		return fmt.Errorf("failed to update order status: %w", err)
+		return nil, err
+	}
+
+	history, err := s.repo.GetOrderStatusHistory(ctx, orderID)
+	if err != nil {
+		l.Error("failed to get order status history", zap.Error(err))
+		return nil, fmt.Errorf("failed to get order status history: %w", err)
+	}
+
+	tracking := &domain.OrderTracking{
+		OrderID:     order.ID,
+		OrderStatus: order.Status,
+		Timeline:    []domain.TrackingEvent{{Source: "order", Status: domain.OrderStatusReceived, Timestamp: order.CreatedAt}},
+	}
+	for _, h := range history {
+		tracking.Timeline = append(tracking.Timeline, domain.TrackingEvent{Source: "order", Status: h.ToStatus, Timestamp: h.CreatedAt})
+	}
+
+	// Ask payment and delivery service in parallel
+	callCtx, cancel := context.WithTimeout(ctx, trackingTimeout)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		payment     *pb.GetPaymentStatusResponse
+		paymentErr  error
+		delivery    *pb.DeliveryResponse
+		deliveryErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		payment, paymentErr = s.paymentClient.GetPaymentStatus(callCtx, &pb.GetPaymentStatusRequest{OrderId: uint32(order.ID)})
+	}()
+	go func() {
+		defer wg.Done()
+		delivery, deliveryErr = s.deliveryClient.GetDeliveryByOrderId(callCtx, &pb.GetDeliveryByOrderIdRequest{OrderId: uint32(order.ID)})
+	}()
+	wg.Wait()
+
+	// NotFound only means the order has not reached that step yet
+	switch {
+	case paymentErr == nil:
+		tracking.PaymentStatus = payment.Status
+		tracking.Timeline = append(tracking.Timeline, domain.TrackingEvent{Source: "payment", Status: "PENDING", Timestamp: time.Unix(payment.CreatedAt, 0)})
+		if payment.Status != "PENDING" {
+			tracking.Timeline = append(tracking.Timeline, domain.TrackingEvent{Source: "payment", Status: payment.Status, Timestamp: time.Unix(payment.UpdatedAt, 0)})
+		}
+	case grpcstatus.Code(paymentErr) != codes.NotFound:
+		l.Warn("payment status unavailable for tracking", zap.String("orderID", orderID), zap.Error(paymentErr))
+		tracking.Degraded = true
+		tracking.UnavailableSources = append(tracking.UnavailableSources, "payment")
+	}
+
+	switch {
+	case deliveryErr == nil && delivery.Delivery != nil:
+		info := delivery.Delivery
+		tracking.DeliveryStatus = info.Status
+		tracking.Timeline = append(tracking.Timeline, domain.TrackingEvent{Source: "delivery", Status: "RECEIVED", Timestamp: time.Unix(info.CreatedAt, 0)})
+		if info.Status != "RECEIVED" {
+			tracking.Timeline = append(tracking.Timeline, domain.TrackingEvent{Source: "delivery", Status: info.Status, Timestamp: time.Unix(info.UpdatedAt, 0)})
+		}
+	case deliveryErr != nil && grpcstatus.Code(deliveryErr) != codes.NotFound:
+		l.Warn("delivery status unavailable for tracking", zap.String("orderID", orderID), zap.Error(deliveryErr))
+		tracking.Degraded = true
+		tracking.UnavailableSources = append(tracking.UnavailableSources, "delivery")
+	}
+
+	sort.SliceStable(tracking.Timeline, func(i, j int) bool {
+		return tracking.Timeline[i].Timestamp.Before(tracking.Timeline[j].Timestamp)
+	})
+
+	l.Info("Order tracking retrieved", zap.String("orderID", orderID), zap.Bool("degraded", tracking.Degraded))
+	return tracking, nil
+}
+
+// sortableOrderColumns whitelists the columns admins can sort the order list by
+var sortableOrderColumns = map[string]bool{
+	"created_at":   true,
+	"total_amount": true,
+	"status":       true,
+	"user_id":      true,
+}
+
+// ListAllOrders lists orders of every user for the admin API
+func (s *OrderService) ListAllOrders(ctx context.Context, filter *domain.OrderListFilter) (*domain.PaginatedOrders, error) {
+	l := logger.ForContext(ctx)
+	// Set default values
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+	// Set max limit to prevent abuse
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	if filter.Order == "" {
+		filter.Order = "desc"
+	}
+
+	if filter.Status != "" && !domain.IsValidOrderStatus(filter.Status) {
+		return nil, fmt.Errorf("%w: unknown status %s", domain.ErrInvalidOrderFilter, filter.Status)
+	}
+	if !sortableOrderColumns[filter.SortBy] {
+		return nil, fmt.Errorf("%w: cannot sort by %s", domain.ErrInvalidOrderFilter, filter.SortBy)
 	}
+	if filter.Order != "asc" && filter.Order != "desc" {
+		return nil, fmt.Errorf("%w: order must be asc or desc", domain.ErrInvalidOrderFilter)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", domain.ErrInvalidOrderFilter)
+	}
+
+	orders, total, err := s.repo.ListOrders(ctx, filter)
+	if err != nil {
+		l.Error("failed to list orders", zap.Error(err))
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(total) / filter.Limit
+	if int(total)%filter.Limit != 0 {
+		totalPages++
+	}
+
+	l.Info("Orders listed successfully", zap.Int("count", len(orders)), zap.Int("page", filter.Page), zap.Int("limit", filter.Limit))
+
+	return &domain.PaginatedOrders{
+		Orders:     orders,
+		Total:      total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
 
-	l.Info("Order status updated", zap.String("orderID", orderID), zap.String("order_status", status))
+// OverrideOrderStatus lets an admin move an order to another status. The change still has to be allowed
+// by the state machine, and the same events as the automatic flow are queued for PAID and CANCELLED. An
+// unpaid order set to FAILED queues the cancelled event as well, so its stock and payment are released.
+func (s *OrderService) OverrideOrderStatus(ctx context.Context, orderID string, status string, adminID uint) error {
+	l := logger.ForContext(ctx)
+	if !domain.IsValidOrderStatus(status) {
+		return fmt.Errorf("%w: unknown status %s", domain.ErrInvalidStatusTransition, status)
+	}
 
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrOrderNotFound
+		}
+		l.Error("failed to get order", zap.Error(err))
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+
+	previousStatus := order.Status
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		if status == domain.OrderStatusPaid {
+			return s.markOrderPaid(ctx, txRepo, order, "admin:status_override", adminID)
+		}
+
+		if err := s.transitionOrder(ctx, txRepo, order, status, "admin:status_override", adminID); err != nil {
+			return err
+		}
+
+		// An order stopped before it was paid still holds stock and an open payment, so it gets the same
+		// compensation as a cancelled one. Once paid the stock is sold and the payment settled.
+		switch status {
+		case domain.OrderStatusCancelled, domain.OrderStatusFailed:
+			if previousStatus != domain.OrderStatusReceived && previousStatus != domain.OrderStatusAwaitingPayment {
+				return nil
+			}
+			return s.queueOrderCancelled(ctx, txRepo, order, previousStatus == domain.OrderStatusAwaitingPayment)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidStatusTransition) {
+			return fmt.Errorf("%w: %s to %s", domain.ErrInvalidStatusTransition, previousStatus, status)
+		}
+		l.Error("failed to override order status", zap.Error(err))
+		return fmt.Errorf("failed to override order status: %w", err)
+	}
+
+	l.Info("Order status overridden by admin", zap.String("orderID", orderID), zap.String("from", previousStatus),
+		zap.String("to", status), zap.Uint("adminID", adminID))
 	return nil
 }
 
-func (s *OrderService) UpdateOrderToPaid(ctx context.Context, orderID string) error {
+// UpdateOrderStatus applies a status change coming from another service's event.
+// Changes the state machine does not allow (late or redelivered events) are logged and dropped.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, status string, source string) error {
 	l := logger.ForContext(ctx)
-	// Update order status to PAID
-	err := s.repo.UpdateOrderStatus(ctx, orderID, "PAID")
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		l.Error("failed to get order", zap.Error(err))
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		if err := s.transitionOrder(ctx, txRepo, order, status, source, 0); err != nil {
+			return err
+		}
+		// Payment or stock failed before the order was paid, so a renewal order did not renew anything
+		if status == domain.OrderStatusCancelled {
+			return s.recordRenewalOutcome(ctx, txRepo, order, fmt.Sprintf("renewal order %d cancelled by %s", order.ID, source))
+		}
+		return nil
+	})
+	if errors.Is(err, domain.ErrInvalidStatusTransition) {
+		l.Warn("ignoring order status transition", zap.String("orderID", orderID),
+			zap.String("from", order.Status), zap.String("to", status), zap.String("source", source))
+		return nil
+	}
 	if err != nil {
 		l.Error("failed to update order status", zap.Error(err))
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
-	l.Info("Order marked as PAID", zap.String("orderID", orderID))
 
-	// Publish order paid event
+	l.Info("Order status updated", zap.String("orderID", orderID), zap.String("order_status", status), zap.String("source", source))
+
+	return nil
+}
+
+func (s *OrderService) UpdateOrderToPaid(ctx context.Context, orderID string, source string) error {
+	l := logger.ForContext(ctx)
 	order, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		l.Error("failed to get order for event publishing", zap.Error(err))
-		return fmt.Errorf("failed to get order for event publishing: %w", err)
+		l.Error("failed to get order", zap.Error(err))
+		return fmt.Errorf("failed to get order: %w", err)
 	}
 
-	userIDStr := strconv.FormatUint(uint64(order.UserID), 10)
-	if err := s.eventRepo.PublishOrderPaidEvent(ctx, &domain.OrderEvent{
-		OrderID:       orderID,
-		UserID:        userIDStr,
-		TotalAmount:   order.TotalAmount,
-		Items:         domain.ConvertToOrderItemMessages(order.Items),
-		CorrelationID: correlationIDFromContext(ctx),
-	}); err != nil {
-		l.Error("failed to publish order paid event", zap.Error(err))
-		return fmt.Errorf("failed to publish order paid event: %w", err)
+	// Update order status to PAID, issue the invoice and queue the paid event in the same transaction
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		return s.markOrderPaid(ctx, txRepo, order, source, 0)
+	})
+	if errors.Is(err, domain.ErrInvalidStatusTransition) {
+		// Already paid or no longer payable, so the paid event must not go out again
+		l.Warn("ignoring payment for order", zap.String("orderID", orderID), zap.String("order_status", order.Status))
+		return nil
+	}
+	if err != nil {
+		l.Error("failed to update order status", zap.Error(err))
+		return fmt.Errorf("failed to update order status: %w", err)
 	}
-	l.Info("Order Paid Event Sent", zap.String("orderID", orderID))
+	l.Info("Order marked as PAID", zap.String("orderID", orderID))
 
 	return nil
 }
 
-func (s *OrderService) ProcessAwaitingPaymentOrders(ctx context.Context, orderID string) error {
+// ProcessAwaitingPaymentOrders asks payment service for the payment link of an order once its stock is
+// reserved. Lines of the unavailable products were not reserved, they are cancelled or backordered and
+// the order is repriced without them before the customer is asked to pay.
+func (s *OrderService) ProcessAwaitingPaymentOrders(ctx context.Context, orderID string, unavailable []domain.ItemKey, source string) error {
 	l := logger.ForContext(ctx)
 	// Get order details to fetch the total amount
 	order, err := s.repo.GetOrderByID(ctx, orderID)
@@ -217,6 +764,41 @@ func (s *OrderService) ProcessAwaitingPaymentOrders(ctx context.Context, orderID
 		l.Error("failed to get order", zap.Error(err))
 		return fmt.Errorf("failed to get order: %w", err)
 	}
+	leftOut := order.ApplyReservation(unavailable)
+
+	// The order was cancelled, or failed before it got a payment link, while stock was still being reserved,
+	// so hand the stock back. A redelivered event finds the compensation already queued and sends nothing more.
+	if order.Status == domain.OrderStatusCancelled || (order.Status == domain.OrderStatusFailed && order.PaymentURL == "") {
+		err := s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+			queued, err := txRepo.HasStockReleaseQueued(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			if queued {
+				l.Info("Compensation for cancelled order already queued", zap.String("orderID", orderID))
+				return nil
+			}
+			return s.queueOrderCancelled(ctx, txRepo, order, true)
+		})
+		if err != nil {
+			l.Error("failed to create order cancelled outbox message", zap.Error(err))
+			return fmt.Errorf("failed to create order cancelled outbox message: %w", err)
+		}
+		l.Info("Stock reserved for cancelled order, compensation sent", zap.String("orderID", orderID))
+		return nil
+	}
+
+	// Redelivered stock reserved event, the order already moved on
+	if order.Status != domain.OrderStatusReceived {
+		l.Warn("ignoring stock reserved event", zap.String("orderID", orderID), zap.String("order_status", order.Status))
+		return nil
+	}
+
+	if leftOut > 0 {
+		order.Reprice()
+		l.Info("Order partially reserved", zap.String("orderID", orderID), zap.Int("leftOutCount", leftOut),
+			zap.Uint("totalAmount", order.TotalAmount))
+	}
 
 	// Call payment service to get payment URL
 	orderIDUint, err := strconv.ParseUint(orderID, 10, 32)
@@ -226,8 +808,9 @@ func (s *OrderService) ProcessAwaitingPaymentOrders(ctx context.Context, orderID
 	}
 
 	paymentResp, err := s.paymentClient.GetPaymentURL(ctx, &pb.GetPaymentRequest{
-		OrderId: uint32(orderIDUint),
-		Amount:  uint64(order.TotalAmount),
+		OrderId:       uint32(orderIDUint),
+		Amount:        uint64(order.TotalAmount),
+		ExpiryMinutes: uint32(s.paymentExpiry / time.Minute),
 	})
 	if err != nil {
 		l.Error("failed to get payment URL", zap.Error(err))
@@ -235,24 +818,509 @@ func (s *OrderService) ProcessAwaitingPaymentOrders(ctx context.Context, orderID
 	}
 
 	l.Info("Payment URL generated for order", zap.String("orderID", orderID), zap.String("paymentUrl", paymentResp.PaymentUrl))
+	paymentExpires := time.Now().Add(s.paymentExpiry)
 
-	// Update order status to awaiting payment
-	err = s.repo.UpdateOrderStatus(ctx, orderID, "AWAITING_PAYMENT")
+	// Save the lines, move the order to awaiting payment and store its payment info together. If the customer
+	// cancelled in the meantime the transition fails, nothing is saved and the retry takes the compensation
+	// path above with the lines still pending.
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		if err := txRepo.UpdateOrderLines(ctx, order); err != nil {
+			return fmt.Errorf("failed to update order lines: %w", err)
+		}
+		if err := s.transitionOrder(ctx, txRepo, order, domain.OrderStatusAwaitingPayment, source, 0); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+		if err := txRepo.UpdatePaymentInfo(ctx, orderID, paymentResp.PaymentUrl, paymentExpires); err != nil {
+			return fmt.Errorf("failed to update order payment info: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		l.Error("failed to update order status", zap.Error(err))
-		return fmt.Errorf("failed to update order status: %w", err)
+		l.Error("failed to move order to awaiting payment", zap.Error(err))
+		return err
 	}
 
-	err = s.repo.UpdatePaymentUrl(ctx, orderID, paymentResp.PaymentUrl)
+	l.Info("Order payment info updated", zap.String("orderID", orderID), zap.Time("paymentExpires", paymentExpires))
+	return nil
+}
+
+// ExpireOverdueOrders cancels orders whose payment deadline has passed and queues an expired event so
+// product-service releases the reserved stock and payment-service voids the Snap transaction.
+// It returns the number of orders that were expired.
+func (s *OrderService) ExpireOverdueOrders(ctx context.Context) (int, error) {
+	l := logger.ForContext(ctx)
+	orders, err := s.repo.GetOverdueAwaitingPaymentOrders(ctx, time.Now(), expiredOrdersBatchSize)
 	if err != nil {
-		l.Error("failed to update order payment info", zap.Error(err))
-		return fmt.Errorf("failed to update order payment info: %w", err)
+		return 0, fmt.Errorf("failed to get overdue orders: %w", err)
+	}
+
+	expired := 0
+	for i := range orders {
+		order := &orders[i]
+		err := s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+			if err := s.transitionOrder(ctx, txRepo, order, domain.OrderStatusCancelled, "sweeper:payment_expired", 0); err != nil {
+				return err
+			}
+			if err := s.recordRenewalOutcome(ctx, txRepo, order, fmt.Sprintf("renewal order %d expired unpaid", order.ID)); err != nil {
+				return err
+			}
+			return txRepo.CreateOutboxMessage(ctx, domain.OrderEventExpired, &domain.OrderEvent{
+				OrderID:       strconv.FormatUint(uint64(order.ID), 10),
+				UserID:        strconv.FormatUint(uint64(order.UserID), 10),
+				TotalAmount:   order.TotalAmount,
+				Items:         domain.ConvertToOrderItemMessages(order.ActiveItems()),
+				CorrelationID: correlationIDFromContext(ctx),
+				StockReserved: true,
+			})
+		})
+		if err != nil {
+			// Paid or cancelled between the query and the update, nothing to expire
+			if errors.Is(err, domain.ErrInvalidStatusTransition) {
+				l.Warn("skipping overdue order that already moved on", zap.Uint("orderID", order.ID))
+				continue
+			}
+			return expired, fmt.Errorf("failed to expire order %d: %w", order.ID, err)
+		}
+		expired++
+		l.Info("Order expired", zap.Uint("orderID", order.ID), zap.Time("paymentExpires", order.PaymentExpires))
 	}
 
-	l.Info("Order payment info updated", zap.String("orderID", orderID))
+	return expired, nil
+}
+
+// CancelOrder cancels an order while it is still RECEIVED or AWAITING_PAYMENT
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string, caller domain.Caller) error {
+	l := logger.ForContext(ctx)
+	order, err := s.getOrderFor(ctx, orderID, caller)
+	if err != nil {
+		return err
+	}
+
+	// Stock is only held once product-service confirmed the reservation (AWAITING_PAYMENT)
+	var stockReserved bool
+	switch order.Status {
+	case domain.OrderStatusReceived:
+		stockReserved = false
+	case domain.OrderStatusAwaitingPayment:
+		stockReserved = true
+	default:
+		return domain.ErrOrderNotCancellable
+	}
+
+	// Queue the cancelled event together with the status change so compensation is never lost
+	err = s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		var changedBy uint
+		if caller.IsAdmin() && caller.UserID != order.UserID {
+			changedBy = caller.UserID
+		}
+		if err := s.transitionOrder(ctx, txRepo, order, domain.OrderStatusCancelled, "api:cancel_order", changedBy); err != nil {
+			return err
+		}
+		return s.queueOrderCancelled(ctx, txRepo, order, stockReserved)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidStatusTransition) {
+			return domain.ErrOrderNotCancellable
+		}
+		l.Error("failed to cancel order", zap.Error(err))
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	l.Info("Order cancelled", zap.String("orderID", orderID), zap.Uint("userID", caller.UserID))
+
 	return nil
 }
 
+// CancelOrderItem lets an admin cancel a single line of an order. A backordered line holds no stock and was
+// never charged, so it is just cancelled. A line of a PAID order is refunded: the order is repriced without it
+// at the tax rates it was charged with and the difference is refunded through payment service, then its stock
+// is released. The refund is saved with the cancellation and sent once the order is unlocked, refunds payment
+// service does not confirm are sent again by RetryPendingRefunds. The last active line can't be cancelled this
+// way, the whole order has to be cancelled instead.
+func (s *OrderService) CancelOrderItem(ctx context.Context, orderID string, itemID uint, adminID uint) (*domain.Order, error) {
+	l := logger.ForContext(ctx)
+	var order *domain.Order
+	var refund *domain.OrderRefund
+	var refunded bool
+	// The order row stays locked until the line is saved, so concurrent cancellations refund one after the other
+	err := s.repo.WithTransaction(ctx, func(txRepo repository.OrderRepository) error {
+		var err error
+		order, err = txRepo.GetOrderByIDForUpdate(ctx, orderID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		index := -1
+		for i := range order.Items {
+			if order.Items[i].ID == itemID {
+				index = i
+			}
+		}
+		if index < 0 {
+			return domain.ErrOrderItemNotFound
+		}
+		item := &order.Items[index]
+
+		switch {
+		case item.Status == domain.OrderItemStatusBackordered:
+			item.Status = domain.OrderItemStatusCancelled
+			if err := txRepo.UpdateOrderLines(ctx, order); err != nil {
+				return fmt.Errorf("failed to update order lines: %w", err)
+			}
+			return nil
+		case !item.IsActive():
+			return fmt.Errorf("%w: item is %s", domain.ErrOrderItemNotCancellable, item.Status)
+		case order.Status != domain.OrderStatusPaid:
+			return fmt.Errorf("%w: order is %s", domain.ErrOrderItemNotCancellable, order.Status)
+		case len(order.ActiveItems()) == 1:
+			return fmt.Errorf("%w: last active item, cancel the order instead", domain.ErrOrderItemNotCancellable)
+		}
+
+		previousTotal := order.TotalAmount
+		item.Status = domain.OrderItemStatusCancelled
+		order.Reprice()
+
+		// Payment service is only called after the commit, a slow one must not hold the order row locked
+		if order.TotalAmount < previousTotal {
+			refund = &domain.OrderRefund{
+				OrderID:     order.ID,
+				OrderItemID: item.ID,
+				RefundKey:   domain.OrderItemRefundKey(order.ID, item.ID),
+				Amount:      previousTotal - order.TotalAmount,
+				Reason:      "order line cancelled",
+				Status:      domain.OrderRefundPending,
+			}
+			if err := txRepo.CreateRefund(ctx, refund); err != nil {
+				return fmt.Errorf("failed to save refund: %w", err)
+			}
+			order.RefundedAmount += refund.Amount
+		}
+		refunded = true
+
+		if err := txRepo.UpdateOrderLines(ctx, order); err != nil {
+			return fmt.Errorf("failed to update order lines: %w", err)
+		}
+		return txRepo.CreateOutboxMessage(ctx, domain.OrderEventItemsCancelled, &domain.OrderEvent{
+			OrderID:       strconv.FormatUint(uint64(order.ID), 10),
+			UserID:        strconv.FormatUint(uint64(order.UserID), 10),
+			TotalAmount:   order.TotalAmount,
+			Items:         domain.ConvertToOrderItemMessages([]domain.OrderItem{*item}),
+			CorrelationID: correlationIDFromContext(ctx),
+			StockReserved: true,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) || errors.Is(err, domain.ErrOrderItemNotFound) || errors.Is(err, domain.ErrOrderItemNotCancellable) {
+			return nil, err
+		}
+		l.Error("failed to cancel order item", zap.Error(err))
+		return nil, fmt.Errorf("failed to cancel order item: %w", err)
+	}
+
+	if !refunded {
+		l.Info("Backordered order item cancelled by admin", zap.String("orderID", orderID), zap.Uint("itemID", itemID),
+			zap.Uint("adminID", adminID))
+		return order, nil
+	}
+	l.Info("Order item cancelled by admin", zap.String("orderID", orderID), zap.Uint("itemID", itemID),
+		zap.Uint("refundedAmount", order.RefundedAmount), zap.Uint("adminID", adminID))
+
+	if refund != nil {
+		if err := s.sendRefund(ctx, refund); err != nil {
+			// The line stays cancelled, the pending refund is sent again by RetryPendingRefunds
+			l.Warn("refund of cancelled order item left pending", zap.Uint("refundID", refund.ID), zap.Error(err))
+		}
+	}
+	return order, nil
+}
+
+// RetryPendingRefunds sends the refunds payment service has not confirmed yet again. Refunds younger than a
+// minute are left to the cancellation that saved them. It returns the number of refunds sent.
+func (s *OrderService) RetryPendingRefunds(ctx context.Context) (int, error) {
+	l := logger.ForContext(ctx)
+	refunds, err := s.repo.GetPendingRefunds(ctx, time.Now().Add(-time.Minute), pendingRefundsBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending refunds: %w", err)
+	}
+
+	sent := 0
+	for i := range refunds {
+		if err := s.sendRefund(ctx, &refunds[i]); err != nil {
+			l.Warn("pending refund not sent", zap.Uint("refundID", refunds[i].ID), zap.Uint("orderID", refunds[i].OrderID), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendRefund asks payment service to pay a pending refund back and marks it done. The refund key makes
+// payment service refund it once, however often it is sent.
+func (s *OrderService) sendRefund(ctx context.Context, refund *domain.OrderRefund) error {
+	_, err := s.paymentClient.RefundPayment(ctx, &pb.RefundPaymentRequest{
+		OrderId:   uint32(refund.OrderID),
+		Amount:    uint64(refund.Amount),
+		RefundKey: refund.RefundKey,
+		Reason:    refund.Reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund order %d: %w", refund.OrderID, err)
+	}
+	if err := s.repo.MarkRefundDone(ctx, refund.ID); err != nil {
+		return fmt.Errorf("failed to mark refund as done: %w", err)
+	}
+	refund.Status = domain.OrderRefundDone
+	return nil
+}
+
+// GetOrderStatusHistory returns the recorded status changes of an order
+func (s *OrderService) GetOrderStatusHistory(ctx context.Context, orderID string, caller domain.Caller) ([]domain.OrderStatusHistory, error) {
+	l := logger.ForContext(ctx)
+	if _, err := s.getOrderFor(ctx, orderID, caller); err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.GetOrderStatusHistory(ctx, orderID)
+	if err != nil {
+		l.Error("failed to get order status history", zap.Error(err))
+		return nil, fmt.Errorf("failed to get order status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// ReorderOrder puts the lines of a past order back in the caller's cart. Cart service re-checks
+// every product with product service, so lines are added at today's price and unavailable
+// products are skipped.
+func (s *OrderService) ReorderOrder(ctx context.Context, orderID string, caller domain.Caller) (*domain.ReorderResult, error) {
+	l := logger.ForContext(ctx)
+	order, err := s.getOrderFor(ctx, orderID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*pb.CartItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, &pb.CartItem{ProductId: uint32(item.ProductID), VariantId: uint32(item.VariantID), Quantity: uint32(item.Quantity)})
+	}
+
+	cartResp, err := s.cartClient.AddCartItems(ctx, &pb.AddCartItemsRequest{
+		UserId: strconv.FormatUint(uint64(caller.UserID), 10),
+		Items:  items,
+	})
+	if err != nil {
+		l.Error("failed to add items to cart", zap.Error(err))
+		return nil, fmt.Errorf("failed to add items to cart: %w", err)
+	}
+
+	added := make(map[domain.ItemKey]*pb.CartItem, len(cartResp.Added))
+	for _, item := range cartResp.Added {
+		added[domain.ItemKey{ProductID: uint(item.ProductId), VariantID: uint(item.VariantId)}] = item
+	}
+
+	result := &domain.ReorderResult{
+		OrderID:     order.ID,
+		Added:       []domain.ReorderLine{},
+		Repriced:    []domain.ReorderLine{},
+		Unavailable: []domain.ReorderLine{},
+	}
+	for _, item := range order.Items {
+		line := domain.ReorderLine{
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			SKU:           item.SKU,
+			Name:          item.Name,
+			Quantity:      item.Quantity,
+			PreviousPrice: item.Price,
+		}
+
+		current, ok := added[item.Key()]
+		if !ok {
+			result.Unavailable = append(result.Unavailable, line)
+			continue
+		}
+
+		line.Name = current.Name
+		line.Price = uint(current.Price)
+		result.Added = append(result.Added, line)
+		if line.Price != line.PreviousPrice {
+			result.Repriced = append(result.Repriced, line)
+		}
+	}
+
+	l.Info("Order reordered", zap.String("orderID", orderID), zap.Uint("userID", caller.UserID),
+		zap.Int("addedCount", len(result.Added)), zap.Int("repricedCount", len(result.Repriced)),
+		zap.Int("unavailableCount", len(result.Unavailable)))
+	return result, nil
+}
+
+// GetOrderInvoice returns the invoice of an order the caller may access.
+// Orders that never reached PAID have no invoice and return ErrInvoiceNotIssued.
+func (s *OrderService) GetOrderInvoice(ctx context.Context, orderID string, caller domain.Caller) (*domain.Invoice, error) {
+	l := logger.ForContext(ctx)
+	order, err := s.getOrderFor(ctx, orderID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	issued, err := s.repo.GetInvoiceByOrderID(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrInvoiceNotIssued
+		}
+		l.Error("failed to get invoice", zap.Error(err))
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	invoice := &domain.Invoice{
+		Number:      issued.Number,
+		IssuedAt:    issued.IssuedAt,
+		OrderID:     order.ID,
+		OrderDate:   order.CreatedAt,
+		BuyerID:     order.UserID,
+		BuyerName:   order.BuyerName,
+		BuyerEmail:  order.BuyerEmail,
+		TaxLines:    order.TaxLines,
+		ShippingFee: order.ShippingFee,
+		Discount:    order.DiscountAmount,
+		TotalAmount: order.TotalAmount,
+	}
+	for _, item := range order.ActiveItems() {
+		invoice.Lines = append(invoice.Lines, domain.InvoiceLine{
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			Amount:    item.Price * item.Quantity,
+		})
+		invoice.Subtotal += item.Price * item.Quantity
+	}
+	return invoice, nil
+}
+
+// transitionOrder moves order to status if the state machine allows it and records the change in the history.
+// changedBy is the admin making a manual change, 0 for system changes.
+func (s *OrderService) transitionOrder(ctx context.Context, repo repository.OrderRepository, order *domain.Order, status string, source string, changedBy uint) error {
+	if !domain.CanTransition(order.Status, status) {
+		return fmt.Errorf("%w: %s to %s", domain.ErrInvalidStatusTransition, order.Status, status)
+	}
+
+	orderID := strconv.FormatUint(uint64(order.ID), 10)
+	if err := repo.UpdateOrderStatus(ctx, orderID, &domain.OrderStatusHistory{
+		OrderID:       order.ID,
+		FromStatus:    order.Status,
+		ToStatus:      status,
+		Source:        source,
+		ChangedBy:     changedBy,
+		CorrelationID: correlationIDFromContext(ctx),
+	}); err != nil {
+		return err
+	}
+
+	order.Status = status
+	return nil
+}
+
+// markOrderPaid moves an order to PAID, issues its invoice and queues the paid event.
+// It must run inside WithTransaction so a rejected transition leaves no invoice behind.
+func (s *OrderService) markOrderPaid(ctx context.Context, repo repository.OrderRepository, order *domain.Order, source string, changedBy uint) error {
+	if err := s.transitionOrder(ctx, repo, order, domain.OrderStatusPaid, source, changedBy); err != nil {
+		return err
+	}
+
+	if _, err := repo.CreateInvoice(ctx, order.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to issue invoice: %w", err)
+	}
+	if err := s.recordRenewalOutcome(ctx, repo, order, ""); err != nil {
+		return err
+	}
+
+	paidEvent := &domain.OrderEvent{
+		OrderID:        strconv.FormatUint(uint64(order.ID), 10),
+		UserID:         strconv.FormatUint(uint64(order.UserID), 10),
+		TotalAmount:    order.TotalAmount,
+		Items:          domain.ConvertToOrderItemMessages(order.ActiveItems()),
+		CorrelationID:  correlationIDFromContext(ctx),
+		SubscriptionID: order.SubscriptionID,
+	}
+	// Orders placed before shipping addresses were captured have none
+	if order.ShippingAddress != (domain.ShippingAddress{}) {
+		address := order.ShippingAddress
+		paidEvent.ShippingAddress = &address
+	}
+	if err := repo.CreateOutboxMessage(ctx, domain.OrderEventPaid, paidEvent); err != nil {
+		return fmt.Errorf("failed to create order paid outbox message: %w", err)
+	}
+	return nil
+}
+
+// queueOrderCancelled queues the cancelled event of an order. Backordered and cancelled lines hold no stock,
+// so only the active lines are in it.
+func (s *OrderService) queueOrderCancelled(ctx context.Context, repo repository.OrderRepository, order *domain.Order, stockReserved bool) error {
+	return repo.CreateOutboxMessage(ctx, domain.OrderEventCancelled, &domain.OrderEvent{
+		OrderID:       strconv.FormatUint(uint64(order.ID), 10),
+		UserID:        strconv.FormatUint(uint64(order.UserID), 10),
+		TotalAmount:   order.TotalAmount,
+		Items:         domain.ConvertToOrderItemMessages(order.ActiveItems()),
+		CorrelationID: correlationIDFromContext(ctx),
+		StockReserved: stockReserved,
+	})
+}
+
+// PublishOutboxMessage publishes a queued order event to its stream and marks it as published
+func (s *OrderService) PublishOutboxMessage(ctx context.Context, outbox *domain.OrderOutboxMessage) error {
+	msgCtx := ctx
+	if outbox.CorrelationID != "" {
+		msgCtx = context.WithValue(msgCtx, "correlation_id", outbox.CorrelationID)
+	}
+
+	l := logger.ForContext(msgCtx)
+	var event domain.OrderEvent
+	if err := json.Unmarshal([]byte(outbox.Payload), &event); err != nil {
+		return fmt.Errorf("failed to decode outbox payload: %w", err)
+	}
+
+	var err error
+	switch outbox.EventType {
+	case domain.OrderEventCreated:
+		err = s.eventRepo.PublishOrderCreatedEvent(msgCtx, &event)
+	case domain.OrderEventPaid:
+		err = s.eventRepo.PublishOrderPaidEvent(msgCtx, &event)
+	case domain.OrderEventCancelled:
+		err = s.eventRepo.PublishOrderCancelledEvent(msgCtx, &event)
+	case domain.OrderEventExpired:
+		err = s.eventRepo.PublishOrderExpiredEvent(msgCtx, &event)
+	case domain.OrderEventItemsCancelled:
+		err = s.eventRepo.PublishOrderItemsCancelledEvent(msgCtx, &event)
+	default:
+		return fmt.Errorf("unknown outbox event type: %s", outbox.EventType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	if err := s.repo.MarkOutboxMessageAsPublished(msgCtx, outbox.ID); err != nil {
+		return fmt.Errorf("failed to mark outbox message as published: %w", err)
+	}
+	l.Info("Order outbox message published successfully", zap.Uint("orderID", outbox.OrderID), zap.String("eventType", outbox.EventType), zap.Uint("outboxID", outbox.ID))
+	return nil
+}
+
+func (s *OrderService) GetPendingOutboxMessages(ctx context.Context) ([]*domain.OrderOutboxMessage, error) {
+	l := logger.ForContext(ctx)
+	outbox, err := s.repo.GetPendingOutboxMessages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending outbox messages: %w", err)
+	}
+
+	count := len(outbox)
+	if count > 0 {
+		l.Info("Pending outbox messages found", zap.Int("count", count))
+	}
+	return outbox, nil
+}
+
 func correlationIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
@@ -2,12 +2,20 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"libs/pb"
 	"order-service/internal/domain"
+	"order-service/internal/repository"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type mockOrderRepo struct {
@@ -15,29 +23,168 @@ type mockOrderRepo struct {
 	ordersErr        error
 	updatedOrderID   string
 	updatedStatus    string
+	updatedHistory   *domain.OrderStatusHistory
 	getOrderByIDResp *domain.Order
 	getOrderByIDErr  error
+	outboxTypes      []string
+	outboxEvents     []*domain.OrderEvent
+	publishedOutbox  uint
+	idempotencyKey   *domain.OrderIdempotencyKey
+	savedKey         *domain.OrderIdempotencyKey
+	listFilter       *domain.OrderListFilter
+	listTotal        int64
+	history          []domain.OrderStatusHistory
+	updateStatusErr  error
+	paymentURL       string
+	paymentExpires   time.Time
+	overdueOrders    []domain.Order
+	invoice          *domain.OrderInvoice
+	addedOrder       *domain.Order
+	subscription     *domain.Subscription
+	dueSubscriptions []domain.Subscription
+	updatedSchedules []domain.Subscription
+	updatedLines     *domain.Order
+	lockedOrderID    string
+	releaseQueued    bool
+	refunds          []*domain.OrderRefund
+	inTransaction    bool
+	linesInTx        bool
 }
 
-func (m *mockOrderRepo) AddOrder(ctx context.Context, order *domain.Order) error { return nil }
+const testPaymentExpiry = 30 * time.Minute
+
+// testSettings charges no tax or shipping so order totals are the plain sum of the items
+var testSettings = Settings{PaymentExpiry: testPaymentExpiry, QuoteSecret: []byte("quote-secret"), QuoteTTL: 15 * time.Minute, SubscriptionMaxAttempts: 3}
+
+func (m *mockOrderRepo) AddOrder(ctx context.Context, order *domain.Order) error {
+	m.addedOrder = order
+	return nil
+}
 func (m *mockOrderRepo) GetOrders(ctx context.Context, userID uint, status string) ([]domain.Order, error) {
 	return m.orders, m.ordersErr
 }
+func (m *mockOrderRepo) ListOrders(ctx context.Context, filter *domain.OrderListFilter) ([]domain.Order, int64, error) {
+	m.listFilter = filter
+	return m.orders, m.listTotal, m.ordersErr
+}
 func (m *mockOrderRepo) GetOrderByID(ctx context.Context, orderID string) (*domain.Order, error) {
 	return m.getOrderByIDResp, m.getOrderByIDErr
 }
-func (m *mockOrderRepo) UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
+func (m *mockOrderRepo) GetOrderByIDForUpdate(ctx context.Context, orderID string) (*domain.Order, error) {
+	m.lockedOrderID = orderID
+	return m.getOrderByIDResp, m.getOrderByIDErr
+}
+func (m *mockOrderRepo) UpdateOrderStatus(ctx context.Context, orderID string, history *domain.OrderStatusHistory) error {
 	m.updatedOrderID = orderID
-	m.updatedStatus = status
+	m.updatedStatus = history.ToStatus
+	m.updatedHistory = history
+	return m.updateStatusErr
+}
+func (m *mockOrderRepo) GetOrderStatusHistory(ctx context.Context, orderID string) ([]domain.OrderStatusHistory, error) {
+	return m.history, nil
+}
+func (m *mockOrderRepo) WithTransaction(ctx context.Context, fn func(repo repository.OrderRepository) error) error {
+	m.inTransaction = true
+	defer func() { m.inTransaction = false }()
+	return fn(m)
+}
+func (m *mockOrderRepo) CreateOutboxMessage(ctx context.Context, eventType string, event *domain.OrderEvent) error {
+	m.outboxTypes = append(m.outboxTypes, eventType)
+	m.outboxEvents = append(m.outboxEvents, event)
+	return nil
+}
+func (m *mockOrderRepo) CreateRefund(ctx context.Context, refund *domain.OrderRefund) error {
+	refund.ID = uint(len(m.refunds) + 1)
+	m.refunds = append(m.refunds, refund)
+	return nil
+}
+func (m *mockOrderRepo) GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]domain.OrderRefund, error) {
+	var pending []domain.OrderRefund
+	for _, refund := range m.refunds {
+		if refund.Status == domain.OrderRefundPending {
+			pending = append(pending, *refund)
+		}
+	}
+	return pending, nil
+}
+func (m *mockOrderRepo) MarkRefundDone(ctx context.Context, id uint) error {
+	m.refunds[id-1].Status = domain.OrderRefundDone
+	return nil
+}
+func (m *mockOrderRepo) HasStockReleaseQueued(ctx context.Context, orderID uint) (bool, error) {
+	return m.releaseQueued, nil
+}
+func (m *mockOrderRepo) GetPendingOutboxMessages(ctx context.Context) ([]*domain.OrderOutboxMessage, error) {
+	return nil, nil
+}
+func (m *mockOrderRepo) MarkOutboxMessageAsPublished(ctx context.Context, id uint) error {
+	m.publishedOutbox = id
+	return nil
+}
+func (m *mockOrderRepo) GetIdempotencyKey(ctx context.Context, userID uint, key string) (*domain.OrderIdempotencyKey, error) {
+	if m.idempotencyKey == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.idempotencyKey, nil
+}
+func (m *mockOrderRepo) CreateIdempotencyKey(ctx context.Context, idempotencyKey *domain.OrderIdempotencyKey) error {
+	m.savedKey = idempotencyKey
+	return nil
+}
+func (m *mockOrderRepo) UpdatePaymentInfo(ctx context.Context, orderID string, paymentURL string, expiresAt time.Time) error {
+	m.paymentURL = paymentURL
+	m.paymentExpires = expiresAt
+	return nil
+}
+func (m *mockOrderRepo) UpdateOrderLines(ctx context.Context, order *domain.Order) error {
+	m.updatedLines = order
+	m.linesInTx = m.inTransaction
+	return nil
+}
+func (m *mockOrderRepo) CreateInvoice(ctx context.Context, orderID uint, issuedAt time.Time) (*domain.OrderInvoice, error) {
+	m.invoice = &domain.OrderInvoice{OrderID: orderID, Sequence: 1, Number: domain.InvoiceNumber(1, issuedAt), IssuedAt: issuedAt}
+	return m.invoice, nil
+}
+func (m *mockOrderRepo) GetInvoiceByOrderID(ctx context.Context, orderID uint) (*domain.OrderInvoice, error) {
+	if m.invoice == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.invoice, nil
+}
+func (m *mockOrderRepo) GetOverdueAwaitingPaymentOrders(ctx context.Context, now time.Time, limit int) ([]domain.Order, error) {
+	return m.overdueOrders, nil
+}
+func (m *mockOrderRepo) CreateSubscription(ctx context.Context, subscription *domain.Subscription) error {
+	subscription.ID = 1
+	m.subscription = subscription
 	return nil
 }
-func (m *mockOrderRepo) UpdatePaymentUrl(ctx context.Context, orderID string, paymentURL string) error {
+func (m *mockOrderRepo) GetSubscriptionByID(ctx context.Context, id uint) (*domain.Subscription, error) {
+	if m.subscription == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.subscription, nil
+}
+func (m *mockOrderRepo) GetSubscriptions(ctx context.Context, userID uint) ([]domain.Subscription, error) {
+	if m.subscription == nil {
+		return nil, nil
+	}
+	return []domain.Subscription{*m.subscription}, nil
+}
+func (m *mockOrderRepo) UpdateSubscriptionSchedule(ctx context.Context, subscription *domain.Subscription) error {
+	m.updatedSchedules = append(m.updatedSchedules, *subscription)
 	return nil
 }
+func (m *mockOrderRepo) GetDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]domain.Subscription, error) {
+	return m.dueSubscriptions, nil
+}
 
 type mockOrderEventRepo struct {
-	paidCalled  bool
-	paidOrderID string
+	paidCalled     bool
+	paidOrderID    string
+	cancelledEvent *domain.OrderEvent
+	expiredEvent   *domain.OrderEvent
+	itemsCancelled *domain.OrderEvent
 }
 
 func (m *mockOrderEventRepo) PublishOrderCreatedEvent(ctx context.Context, event *domain.OrderEvent) error {
@@ -48,10 +195,25 @@ func (m *mockOrderEventRepo) PublishOrderPaidEvent(ctx context.Context, event *d
 	m.paidOrderID = event.OrderID
 	return nil
 }
+func (m *mockOrderEventRepo) PublishOrderCancelledEvent(ctx context.Context, event *domain.OrderEvent) error {
+	m.cancelledEvent = event
+	return nil
+}
+func (m *mockOrderEventRepo) PublishOrderExpiredEvent(ctx context.Context, event *domain.OrderEvent) error {
+	m.expiredEvent = event
+	return nil
+}
+
+func (m *mockOrderEventRepo) PublishOrderItemsCancelledEvent(ctx context.Context, event *domain.OrderEvent) error {
+	m.itemsCancelled = event
+	return nil
+}
 
 type mockOrderCartClient struct {
 	userCartResp *pb.CartResponse
 	userCartErr  error
+	addItemsReq  *pb.AddCartItemsRequest
+	addItemsResp *pb.AddCartItemsResponse
 }
 
 func (m *mockOrderCartClient) GetUserCart(ctx context.Context, in *pb.GetCartRequest, opts ...grpc.CallOption) (*pb.CartResponse, error) {
@@ -63,27 +225,98 @@ func (m *mockOrderCartClient) ClearUserCart(ctx context.Context, in *pb.GetCartR
 func (m *mockOrderCartClient) GetCartItems(ctx context.Context, in *pb.GetCartItemRequest, opts ...grpc.CallOption) (*pb.CartResponse, error) {
 	return &pb.CartResponse{}, nil
 }
+func (m *mockOrderCartClient) AddCartItems(ctx context.Context, in *pb.AddCartItemsRequest, opts ...grpc.CallOption) (*pb.AddCartItemsResponse, error) {
+	m.addItemsReq = in
+	if m.addItemsResp == nil {
+		return &pb.AddCartItemsResponse{}, nil
+	}
+	return m.addItemsResp, nil
+}
 func (m *mockOrderCartClient) RemoveCartItems(ctx context.Context, in *pb.GetCartItemRequest, opts ...grpc.CallOption) (*pb.EmptyResponse, error) {
 	return &pb.EmptyResponse{}, nil
 }
 
-type mockOrderProductClient struct{}
+type mockOrderProductClient struct {
+	deletedIDs map[uint32]bool
+	missingIDs map[uint32]bool
+	categories map[uint32][]string
+	prices     map[uint32]uint64 // defaults to 100
+	stock      map[uint32]int64  // defaults to 100
+	variants   map[uint32][]*pb.ProductVariant
+	batchCalls int
+}
 
 func (m *mockOrderProductClient) GetProduct(ctx context.Context, in *pb.GetProductRequest, opts ...grpc.CallOption) (*pb.ProductResponse, error) {
 	return &pb.ProductResponse{}, nil
 }
+func (m *mockOrderProductClient) GetProducts(ctx context.Context, in *pb.GetProductsRequest, opts ...grpc.CallOption) (*pb.GetProductsResponse, error) {
+	m.batchCalls++
+	resp := &pb.GetProductsResponse{}
+	for _, id := range in.Ids {
+		if m.missingIDs[id] {
+			resp.MissingIds = append(resp.MissingIds, id)
+			continue
+		}
+		price, ok := m.prices[id]
+		if !ok {
+			price = 100
+		}
+		stock, ok := m.stock[id]
+		if !ok {
+			stock = 100
+		}
+		resp.Products = append(resp.Products, &pb.ProductResponse{
+			Id: id, Name: "product", Price: price, Stock: stock, Deleted: m.deletedIDs[id],
+			Available: !m.deletedIDs[id] && stock > 0, Categories: m.categories[id], Variants: m.variants[id],
+		})
+	}
+	return resp, nil
+}
 func (m *mockOrderProductClient) UpdateStock(ctx context.Context, in *pb.UpdateStockRequest, opts ...grpc.CallOption) (*pb.UpdateStockResponse, error) {
 	return &pb.UpdateStockResponse{}, nil
 }
 
-type mockOrderPaymentClient struct{}
+type mockOrderPaymentClient struct {
+	statusResp *pb.GetPaymentStatusResponse
+	statusErr  error
+	paymentReq *pb.GetPaymentRequest
+	refundReq  *pb.RefundPaymentRequest
+	refundErr  error
+}
 
 func (m *mockOrderPaymentClient) GetPaymentURL(ctx context.Context, in *pb.GetPaymentRequest, opts ...grpc.CallOption) (*pb.GetPaymentResponse, error) {
+	m.paymentReq = in
 	return &pb.GetPaymentResponse{PaymentUrl: "https://example.com/pay"}, nil
 }
+func (m *mockOrderPaymentClient) GetPaymentStatus(ctx context.Context, in *pb.GetPaymentStatusRequest, opts ...grpc.CallOption) (*pb.GetPaymentStatusResponse, error) {
+	if m.statusResp == nil && m.statusErr == nil {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	return m.statusResp, m.statusErr
+}
+
+func (m *mockOrderPaymentClient) RefundPayment(ctx context.Context, in *pb.RefundPaymentRequest, opts ...grpc.CallOption) (*pb.RefundPaymentResponse, error) {
+	m.refundReq = in
+	if m.refundErr != nil {
+		return nil, m.refundErr
+	}
+	return &pb.RefundPaymentResponse{OrderId: in.OrderId, Status: "SUCCESS", RefundedAmount: in.Amount}, nil
+}
+
+type mockOrderDeliveryClient struct {
+	deliveryResp *pb.DeliveryResponse
+	deliveryErr  error
+}
+
+func (m *mockOrderDeliveryClient) GetDeliveryByOrderId(ctx context.Context, in *pb.GetDeliveryByOrderIdRequest, opts ...grpc.CallOption) (*pb.DeliveryResponse, error) {
+	if m.deliveryResp == nil && m.deliveryErr == nil {
+		return nil, status.Error(codes.NotFound, "delivery not found")
+	}
+	return m.deliveryResp, m.deliveryErr
+}
 
 func TestGetOrdersRejectsInvalidStatus(t *testing.T) {
-	svc := NewOrderService(&mockOrderRepo{}, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{})
+	svc := NewOrderService(&mockOrderRepo{}, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
 
 	_, err := svc.GetOrders(context.Background(), 1, "NOT_A_STATUS")
 	if err == nil {
@@ -91,6 +324,10 @@ func TestGetOrdersRejectsInvalidStatus(t *testing.T) {
 	}
 }
 
+func testShippingAddress() *domain.ShippingAddress {
+	return &domain.ShippingAddress{Recipient: "Jane Doe", Phone: "+62 812-3456-7890", Street: "Jl. Sudirman 1", City: "Jakarta", PostalCode: "10220", Country: "ID"}
+}
+
 func TestCreateOrderReturnsErrorWhenCartIsEmpty(t *testing.T) {
 	svc := NewOrderService(
 		&mockOrderRepo{},
@@ -98,27 +335,1370 @@ func TestCreateOrderReturnsErrorWhenCartIsEmpty(t *testing.T) {
 		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{}}},
 		&mockOrderProductClient{},
 		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
 	)
 
-	_, err := svc.CreateOrder(&domain.CreateOrderRequest{}, context.Background(), 10)
-	if err == nil {
-		t.Fatal("expected CreateOrder to fail for empty cart")
+	_, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10)
+	if !errors.Is(err, domain.ErrCartEmpty) {
+		t.Fatalf("expected ErrCartEmpty, got %v", err)
 	}
 }
 
-func TestUpdateOrderToPaidUpdatesRepoAndPublishesEvent(t *testing.T) {
-	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 22, UserID: 7, TotalAmount: 900, Items: []domain.OrderItem{{ProductID: 1, Quantity: 2}}}}
+func TestUpdateOrderToPaidUpdatesRepoAndQueuesEvent(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 22, UserID: 7, TotalAmount: 900, Status: "AWAITING_PAYMENT", Items: []domain.OrderItem{{ProductID: 1, Quantity: 2}}, ShippingAddress: *testShippingAddress()}}
 	eventRepo := &mockOrderEventRepo{}
-	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{})
+	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
 
-	err := svc.UpdateOrderToPaid(context.Background(), "22")
+	err := svc.UpdateOrderToPaid(context.Background(), "22", "stream:payment:success")
 	if err != nil {
 		t.Fatalf("UpdateOrderToPaid() error = %v", err)
 	}
 	if repo.updatedOrderID != "22" || repo.updatedStatus != "PAID" {
 		t.Fatalf("expected order 22 status PAID, got order=%s status=%s", repo.updatedOrderID, repo.updatedStatus)
 	}
-	if !eventRepo.paidCalled || eventRepo.paidOrderID != "22" {
-		t.Fatalf("expected paid event for order 22, got called=%v order=%s", eventRepo.paidCalled, eventRepo.paidOrderID)
+	if len(repo.outboxTypes) != 1 || repo.outboxTypes[0] != "paid" || repo.outboxEvents[0].OrderID != "22" {
+		t.Fatalf("expected paid outbox message for order 22, got types=%v", repo.outboxTypes)
+	}
+	if address := repo.outboxEvents[0].ShippingAddress; address == nil || *address != *testShippingAddress() {
+		t.Fatalf("expected the shipping address in the paid event, got %#v", address)
+	}
+	if eventRepo.paidCalled {
+		t.Fatal("expected paid event to be left to the outbox worker")
+	}
+	if repo.invoice == nil || repo.invoice.OrderID != 22 {
+		t.Fatalf("expected invoice issued for order 22, got %#v", repo.invoice)
+	}
+}
+
+func TestCancelOrderReleasesReservedStock(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 31, UserID: 4, Status: "AWAITING_PAYMENT", Items: []domain.OrderItem{{ProductID: 3, Quantity: 1}}}}
+	eventRepo := &mockOrderEventRepo{}
+	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.CancelOrder(context.Background(), "31", domain.Caller{UserID: 4}); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if repo.updatedStatus != "CANCELLED" {
+		t.Fatalf("expected status CANCELLED, got %s", repo.updatedStatus)
+	}
+	if len(repo.outboxTypes) != 1 || repo.outboxTypes[0] != "cancelled" {
+		t.Fatalf("expected cancelled outbox message, got types=%v", repo.outboxTypes)
+	}
+	if event := repo.outboxEvents[0]; event.OrderID != "31" || !event.StockReserved {
+		t.Fatalf("unexpected cancelled event: %#v", event)
+	}
+}
+
+func TestCancelOrderRejectsPaidOrder(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 32, UserID: 4, Status: "PAID"}}
+	eventRepo := &mockOrderEventRepo{}
+	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	err := svc.CancelOrder(context.Background(), "32", domain.Caller{UserID: 4})
+	if !errors.Is(err, domain.ErrOrderNotCancellable) {
+		t.Fatalf("expected ErrOrderNotCancellable, got %v", err)
+	}
+	if repo.updatedStatus != "" || len(repo.outboxTypes) != 0 {
+		t.Fatal("did not expect a paid order to be cancelled")
+	}
+}
+
+func TestCancelOrderHidesOtherUsersOrders(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 33, UserID: 4, Status: "RECEIVED"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	err := svc.CancelOrder(context.Background(), "33", domain.Caller{UserID: 5})
+	if !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestUpdateOrderToPaidIgnoresRedeliveredPayment(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 23, UserID: 7, Status: "PAID"}}
+	eventRepo := &mockOrderEventRepo{}
+	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.UpdateOrderToPaid(context.Background(), "23", "stream:payment:success"); err != nil {
+		t.Fatalf("UpdateOrderToPaid() error = %v", err)
+	}
+	if repo.updatedStatus != "" || len(repo.outboxTypes) != 0 || repo.invoice != nil {
+		t.Fatal("did not expect a second paid transition, event or invoice")
+	}
+}
+
+func TestUpdateOrderStatusKeepsDeliveredOrder(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 24, UserID: 7, Status: "DELIVERED"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.UpdateOrderStatus(context.Background(), "24", "FAILED", "stream:delivery:failed"); err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+	if repo.updatedStatus != "" {
+		t.Fatalf("expected DELIVERED order to stay untouched, got update to %s", repo.updatedStatus)
+	}
+}
+
+func TestUpdateOrderStatusIgnoresFailureAfterDelivery(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 24, UserID: 7, Status: "SHIPPED"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.UpdateOrderStatus(context.Background(), "24", "DELIVERED", "stream:delivery:delivered"); err != nil {
+		t.Fatalf("UpdateOrderStatus() delivered error = %v", err)
+	}
+	if err := svc.UpdateOrderStatus(context.Background(), "24", "FAILED", "stream:delivery:failed"); err != nil {
+		t.Fatalf("UpdateOrderStatus() failed error = %v", err)
+	}
+	if repo.updatedStatus != "DELIVERED" || repo.getOrderByIDResp.Status != "DELIVERED" {
+		t.Fatalf("expected the order to stay DELIVERED, got update to %s", repo.updatedStatus)
+	}
+}
+
+func TestUpdateOrderStatusRecordsHistory(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 25, UserID: 7, Status: "PAID"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+	ctx := context.WithValue(context.Background(), "correlation_id", "corr-25")
+
+	if err := svc.UpdateOrderStatus(ctx, "25", "DELIVERED", "stream:delivery:delivered"); err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+	h := repo.updatedHistory
+	if h == nil || h.OrderID != 25 || h.FromStatus != "PAID" || h.ToStatus != "DELIVERED" ||
+		h.Source != "stream:delivery:delivered" || h.CorrelationID != "corr-25" {
+		t.Fatalf("unexpected history entry: %#v", h)
+	}
+}
+
+func TestPublishOutboxMessagePublishesAndMarksMessage(t *testing.T) {
+	repo := &mockOrderRepo{}
+	eventRepo := &mockOrderEventRepo{}
+	svc := NewOrderService(repo, eventRepo, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	outbox := &domain.OrderOutboxMessage{ID: 5, EventType: "paid", OrderID: 26, Payload: `{"order_id":"26","user_id":"7","total_amount":900,"items":[]}`}
+	if err := svc.PublishOutboxMessage(context.Background(), outbox); err != nil {
+		t.Fatalf("PublishOutboxMessage() error = %v", err)
+	}
+	if !eventRepo.paidCalled || eventRepo.paidOrderID != "26" {
+		t.Fatalf("expected paid event for order 26, got called=%v order=%s", eventRepo.paidCalled, eventRepo.paidOrderID)
+	}
+	if repo.publishedOutbox != 5 {
+		t.Fatalf("expected outbox message 5 marked as published, got %d", repo.publishedOutbox)
+	}
+}
+
+func TestCreateOrderReplaysIdempotencyKey(t *testing.T) {
+	req := &domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), ProductIDs: []uint{3}, IdempotencyKey: "retry-1"}
+	repo := &mockOrderRepo{idempotencyKey: &domain.OrderIdempotencyKey{UserID: 10, Key: "retry-1", Fingerprint: requestFingerprint(req), OrderID: 41}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	receipt, err := svc.CreateOrder(req, context.Background(), 10)
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if receipt.OrderID != 41 || receipt.Status != 201 {
+		t.Fatalf("expected original order 41, got %#v", receipt)
+	}
+	if len(repo.outboxTypes) != 0 {
+		t.Fatal("did not expect a replay to create another order")
+	}
+}
+
+func TestCreateOrderRejectsIdempotencyKeyWithDifferentBody(t *testing.T) {
+	original := &domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), ProductIDs: []uint{3}}
+	repo := &mockOrderRepo{idempotencyKey: &domain.OrderIdempotencyKey{UserID: 10, Key: "retry-2", Fingerprint: requestFingerprint(original), OrderID: 42}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	_, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), ProductIDs: []uint{4}, IdempotencyKey: "retry-2"}, context.Background(), 10)
+	if !errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		t.Fatalf("expected ErrIdempotencyKeyMismatch, got %v", err)
+	}
+}
+
+func TestCreateOrderStoresIdempotencyKey(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 3, Quantity: 1}}}},
+		&mockOrderProductClient{},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	req := &domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), IdempotencyKey: "first-try"}
+	receipt, err := svc.CreateOrder(req, context.Background(), 10)
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if repo.savedKey == nil || repo.savedKey.Key != "first-try" || repo.savedKey.UserID != 10 || repo.savedKey.Fingerprint != requestFingerprint(req) {
+		t.Fatalf("unexpected idempotency key: %#v", repo.savedKey)
+	}
+	wantBody := fmt.Sprintf(`{"message":"Order received","order_id":%d}`, receipt.OrderID)
+	if repo.savedKey.ResponseStatus != 201 || repo.savedKey.ResponseBody != wantBody {
+		t.Fatalf("expected the response to be stored with the key, got %d %s", repo.savedKey.ResponseStatus, repo.savedKey.ResponseBody)
+	}
+}
+
+func TestCreateOrderReplaysStoredResponse(t *testing.T) {
+	req := &domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), ProductIDs: []uint{3}, IdempotencyKey: "retry-3"}
+	repo := &mockOrderRepo{idempotencyKey: &domain.OrderIdempotencyKey{UserID: 10, Key: "retry-3", Fingerprint: requestFingerprint(req), OrderID: 43,
+		ResponseStatus: 201, ResponseBody: `{"message":"Order received","order_id":43}`}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	receipt, err := svc.CreateOrder(req, context.Background(), 10)
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if receipt.Status != 201 || receipt.Message != "Order received" || receipt.OrderID != 43 {
+		t.Fatalf("expected the stored response to be replayed, got %#v", receipt)
+	}
+}
+
+func TestCreateOrderPricesCartInOneBatchCall(t *testing.T) {
+	repo := &mockOrderRepo{}
+	productClient := &mockOrderProductClient{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}, {ProductId: 2, Quantity: 1}}}},
+		productClient,
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if productClient.batchCalls != 1 {
+		t.Fatalf("expected one batch product lookup, got %d", productClient.batchCalls)
+	}
+	if event := repo.outboxEvents[0]; event.TotalAmount != 300 || event.ShippingPostalCode != "10220" {
+		t.Fatalf("expected total 300 shipped to 10220, got %d to %q", event.TotalAmount, event.ShippingPostalCode)
+	}
+}
+
+func TestCreateOrderAppliesDiscountCodeBeforeTax(t *testing.T) {
+	taxRules, err := domain.ParseTaxRules("default:10")
+	if err != nil {
+		t.Fatalf("ParseTaxRules() error = %v", err)
+	}
+	discounts, err := domain.ParseDiscounts("welcome10:10%, FLAT5K:5000")
+	if err != nil {
+		t.Fatalf("ParseDiscounts() error = %v", err)
+	}
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}}}},
+		&mockOrderProductClient{},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		Settings{PaymentExpiry: testPaymentExpiry, Pricing: domain.PricingRules{Tax: taxRules, Discounts: discounts}},
+	)
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), DiscountCode: "Welcome10"}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	order := repo.addedOrder
+	if order.DiscountCode != "WELCOME10" || order.DiscountAmount != 20 || order.TaxLines[0].TaxableAmount != 180 {
+		t.Fatalf("expected 10%% off the subtotal before tax, got code %q discount %d tax lines %#v", order.DiscountCode, order.DiscountAmount, order.TaxLines)
+	}
+	if order.Subtotal != 200 || order.TaxAmount != 18 || order.TotalAmount != 198 {
+		t.Fatalf("unexpected breakdown: subtotal %d tax %d total %d", order.Subtotal, order.TaxAmount, order.TotalAmount)
+	}
+
+	repo.addedOrder = nil
+	_, err = svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), DiscountCode: "NOPE"}, context.Background(), 10)
+	if !errors.Is(err, domain.ErrInvalidDiscountCode) || repo.addedOrder != nil {
+		t.Fatalf("expected an unknown code to be rejected, got %v", err)
+	}
+}
+
+func TestCreateOrderAppliesTaxPerCategoryAndShipping(t *testing.T) {
+	taxRules, err := domain.ParseTaxRules("default:11, Books:0, food:5.5")
+	if err != nil {
+		t.Fatalf("ParseTaxRules() error = %v", err)
+	}
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 3}, {ProductId: 2, Quantity: 1}, {ProductId: 3, Quantity: 2}}}},
+		&mockOrderProductClient{categories: map[uint32][]string{1: {"food", "books"}, 2: {"Books"}}},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		Settings{PaymentExpiry: testPaymentExpiry, Pricing: domain.PricingRules{Tax: taxRules, ShippingFee: 50, FreeShippingMinimum: 1000}},
+	)
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	order := repo.addedOrder
+	// product 1 pays the higher food rate, product 3 has no taxed category and pays the default
+	expected := []domain.OrderTaxLine{
+		{Category: "books", RateBasisPoints: 0, TaxableAmount: 100, Amount: 0},
+		{Category: "default", RateBasisPoints: 1100, TaxableAmount: 200, Amount: 22},
+		{Category: "food", RateBasisPoints: 550, TaxableAmount: 300, Amount: 17},
+	}
+	if len(order.TaxLines) != len(expected) {
+		t.Fatalf("expected %d tax lines, got %#v", len(expected), order.TaxLines)
+	}
+	for i, line := range expected {
+		if order.TaxLines[i] != line {
+			t.Fatalf("tax line %d: expected %#v, got %#v", i, line, order.TaxLines[i])
+		}
+	}
+	if order.Subtotal != 600 || order.TaxAmount != 39 || order.ShippingFee != 50 || order.TotalAmount != 689 {
+		t.Fatalf("unexpected breakdown: subtotal %d tax %d shipping %d total %d", order.Subtotal, order.TaxAmount, order.ShippingFee, order.TotalAmount)
+	}
+	if event := repo.outboxEvents[0]; event.TotalAmount != 689 {
+		t.Fatalf("expected the created event to carry the grand total, got %d", event.TotalAmount)
+	}
+}
+
+func TestCreateOrderWaivesShippingAboveFreeShippingMinimum(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 5}}}},
+		&mockOrderProductClient{},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		Settings{PaymentExpiry: testPaymentExpiry, Pricing: domain.PricingRules{ShippingFee: 50, FreeShippingMinimum: 500}},
+	)
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if order := repo.addedOrder; order.ShippingFee != 0 || order.TotalAmount != 500 {
+		t.Fatalf("expected free shipping and total 500, got shipping %d total %d", order.ShippingFee, order.TotalAmount)
+	}
+}
+
+func TestQuoteCheckoutPricesCartWithoutSaving(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}, {ProductId: 2, Quantity: 3}}}},
+		&mockOrderProductClient{stock: map[uint32]int64{2: 1}},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		Settings{QuoteSecret: []byte("quote-secret"), QuoteTTL: 15 * time.Minute, Pricing: domain.PricingRules{ShippingFee: 20}},
+	)
+
+	quote, err := svc.QuoteCheckout(context.Background(), &domain.CheckoutQuoteRequest{}, 10)
+	if err != nil {
+		t.Fatalf("QuoteCheckout() error = %v", err)
+	}
+	if len(quote.Lines) != 2 || quote.Lines[1].Amount != 300 || quote.Subtotal != 500 || quote.ShippingFee != 20 || quote.TotalAmount != 520 {
+		t.Fatalf("unexpected quote: %#v", quote)
+	}
+	if len(quote.StockWarnings) != 1 || quote.StockWarnings[0] != (domain.StockWarning{ProductID: 2, Name: "product", Requested: 3, Available: 1}) {
+		t.Fatalf("expected a stock warning for product 2, got %#v", quote.StockWarnings)
+	}
+	if quote.QuoteToken == "" || time.Until(quote.ExpiresAt) > 15*time.Minute {
+		t.Fatalf("expected a quote token valid for 15 minutes, got %q until %s", quote.QuoteToken, quote.ExpiresAt)
+	}
+	if repo.addedOrder != nil || len(repo.outboxEvents) != 0 {
+		t.Fatal("expected quoting not to create an order")
+	}
+}
+
+func TestCreateOrderKeepsQuotedPrices(t *testing.T) {
+	repo := &mockOrderRepo{}
+	productClient := &mockOrderProductClient{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}}}},
+		productClient,
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	quote, err := svc.QuoteCheckout(context.Background(), &domain.CheckoutQuoteRequest{}, 10)
+	if err != nil {
+		t.Fatalf("QuoteCheckout() error = %v", err)
+	}
+	productClient.prices = map[uint32]uint64{1: 150}
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), QuoteToken: quote.QuoteToken}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if order := repo.addedOrder; order.Items[0].Price != 100 || order.TotalAmount != 200 {
+		t.Fatalf("expected the quoted price to be kept, got price %d total %d", order.Items[0].Price, order.TotalAmount)
+	}
+}
+
+func TestCreateOrderRejectsUnusableQuotes(t *testing.T) {
+	cartClient := &mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}}}}
+	svc := NewOrderService(&mockOrderRepo{}, &mockOrderEventRepo{}, cartClient, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	items := []domain.OrderItem{{ProductID: 1, Quantity: 2, Price: 100}}
+	valid := svc.signQuoteToken(newQuoteClaims(10, time.Now().Add(time.Minute), items, ""))
+	tests := []struct {
+		name   string
+		token  string
+		userID uint
+		want   error
+	}{
+		{"expired", svc.signQuoteToken(newQuoteClaims(10, time.Now().Add(-time.Second), items, "")), 10, domain.ErrQuoteExpired},
+		{"other user", valid, 11, domain.ErrInvalidQuote},
+		{"tampered", strings.Replace(valid, ".", "x.", 1), 10, domain.ErrInvalidQuote},
+		{"cart changed", svc.signQuoteToken(newQuoteClaims(10, time.Now().Add(time.Minute), []domain.OrderItem{{ProductID: 1, Quantity: 1, Price: 100}}, "")), 10, domain.ErrQuoteMismatch},
+	}
+	for _, tt := range tests {
+		if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), QuoteToken: tt.token}, context.Background(), tt.userID); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestCreateOrderRejectsQuoteForAnotherDiscountCode(t *testing.T) {
+	discounts, err := domain.ParseDiscounts("WELCOME10:10%,FLAT5K:5000")
+	if err != nil {
+		t.Fatalf("ParseDiscounts() error = %v", err)
+	}
+	repo := &mockOrderRepo{}
+	settings := testSettings
+	settings.Pricing = domain.PricingRules{Discounts: discounts}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 2}}}},
+		&mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, settings)
+
+	quote, err := svc.QuoteCheckout(context.Background(), &domain.CheckoutQuoteRequest{DiscountCode: "welcome10"}, 10)
+	if err != nil {
+		t.Fatalf("QuoteCheckout() error = %v", err)
+	}
+	for _, code := range []string{"FLAT5K", ""} {
+		_, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), QuoteToken: quote.QuoteToken, DiscountCode: code}, context.Background(), 10)
+		if !errors.Is(err, domain.ErrQuoteMismatch) || repo.addedOrder != nil {
+			t.Fatalf("code %q: expected ErrQuoteMismatch, got %v", code, err)
+		}
+	}
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), QuoteToken: quote.QuoteToken, DiscountCode: " Welcome10"}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() with the quoted code error = %v", err)
+	}
+	if repo.addedOrder == nil || repo.addedOrder.DiscountCode != "WELCOME10" || repo.addedOrder.TotalAmount != quote.TotalAmount {
+		t.Fatalf("expected the order placed at the quoted total %d, got %#v", quote.TotalAmount, repo.addedOrder)
+	}
+}
+
+func TestCreateOrderSnapshotsShippingAddress(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 1}}}},
+		&mockOrderProductClient{},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	address := testShippingAddress()
+	address.City = "  Jakarta "
+	address.Country = "id"
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: address}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if got := repo.addedOrder.ShippingAddress; got != *testShippingAddress() {
+		t.Fatalf("expected normalized address snapshot, got %#v", got)
+	}
+
+	for _, invalid := range []func(a *domain.ShippingAddress){
+		func(a *domain.ShippingAddress) { a.Recipient = "   " },
+		func(a *domain.ShippingAddress) { a.Phone = "call me" },
+		func(a *domain.ShippingAddress) { a.Phone = "123" },
+		func(a *domain.ShippingAddress) { a.Country = "IDN" },
+	} {
+		address := testShippingAddress()
+		invalid(address)
+		if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: address}, context.Background(), 10); !errors.Is(err, domain.ErrInvalidShippingAddress) {
+			t.Fatalf("expected ErrInvalidShippingAddress for %#v, got %v", address, err)
+		}
+	}
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{}, context.Background(), 10); !errors.Is(err, domain.ErrInvalidShippingAddress) {
+		t.Fatalf("expected a missing address to be rejected, got %v", err)
+	}
+}
+
+func TestCreateOrderMarksGuestOrders(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 1}}}},
+		&mockOrderProductClient{},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	req := &domain.CreateOrderRequest{ShippingAddress: testShippingAddress(), BuyerName: "guest-1a2b", BuyerEmail: "jane@example.com", Guest: true}
+	if _, err := svc.CreateOrder(req, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if order := repo.addedOrder; !order.Guest || order.BuyerName != "Jane Doe" || order.BuyerEmail != "jane@example.com" {
+		t.Fatalf("expected a guest order billed to the recipient, got %#v", order)
+	}
+}
+
+func TestLookupGuestOrderRequiresMatchingEmail(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 95, UserID: 10, Status: "PAID", Guest: true, BuyerEmail: "jane@example.com"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.LookupGuestOrder(context.Background(), 95, " Jane@Example.com")
+	if err != nil || order.ID != 95 {
+		t.Fatalf("expected guest order 95, got %#v, %v", order, err)
+	}
+	if _, err := svc.LookupGuestOrder(context.Background(), 95, "someone@example.com"); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound for another email, got %v", err)
+	}
+
+	repo.getOrderByIDResp.Guest = false
+	if _, err := svc.LookupGuestOrder(context.Background(), 95, "jane@example.com"); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected member orders to be hidden from guest lookup, got %v", err)
+	}
+}
+
+func TestGetOrderByIDRejectsGuestTokens(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 96, UserID: 10, Status: "PAID", Guest: true}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if _, err := svc.GetOrderByID(context.Background(), "96", domain.Caller{UserID: 10, Role: domain.RoleGuest}); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound for a guest token, got %v", err)
+	}
+}
+
+func TestCreateOrderReportsAllMissingProducts(t *testing.T) {
+	svc := NewOrderService(
+		&mockOrderRepo{},
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 1}, {ProductId: 3, Quantity: 1}}}},
+		&mockOrderProductClient{missingIDs: map[uint32]bool{1: true}, deletedIDs: map[uint32]bool{3: true}},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	_, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10)
+	if !errors.Is(err, domain.ErrProductsNotFound) {
+		t.Fatalf("expected ErrProductsNotFound, got %v", err)
+	}
+	if err.Error() != "products not found: [1 3]" {
+		t.Fatalf("expected both missing products in the error, got %q", err.Error())
+	}
+}
+
+func TestCreateOrderPricesVariantLines(t *testing.T) {
+	repo := &mockOrderRepo{}
+	productClient := &mockOrderProductClient{variants: map[uint32][]*pb.ProductVariant{
+		1: {{Id: 5, Sku: "TEE-S", Price: 150, Stock: 10, Available: true}, {Id: 6, Sku: "TEE-M", Price: 180, Stock: 10, Available: true}},
+	}}
+	svc := NewOrderService(
+		repo,
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{
+			{ProductId: 1, VariantId: 5, Quantity: 1},
+			{ProductId: 1, VariantId: 6, Quantity: 2},
+		}}},
+		productClient,
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	if _, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	items := repo.addedOrder.Items
+	if len(items) != 2 || items[0].VariantID != 5 || items[0].SKU != "TEE-S" || items[0].Price != 150 ||
+		items[1].VariantID != 6 || items[1].Price != 180 {
+		t.Fatalf("unexpected order items: %#v", items)
+	}
+	if event := repo.outboxEvents[0]; event.TotalAmount != 510 || event.Items[1].VariantID != 6 {
+		t.Fatalf("unexpected order created event: %#v", event)
+	}
+}
+
+func TestCreateOrderRejectsMissingVariants(t *testing.T) {
+	svc := NewOrderService(
+		&mockOrderRepo{},
+		&mockOrderEventRepo{},
+		&mockOrderCartClient{userCartResp: &pb.CartResponse{Items: []*pb.CartItem{
+			{ProductId: 1, VariantId: 5, Quantity: 1},
+			{ProductId: 1, VariantId: 7, Quantity: 1},
+			{ProductId: 1, Quantity: 1},
+		}}},
+		&mockOrderProductClient{variants: map[uint32][]*pb.ProductVariant{
+			1: {{Id: 5, Sku: "TEE-S", Price: 150, Stock: 10, Available: true}, {Id: 7, Sku: "TEE-L", Price: 150, Deleted: true}},
+		}},
+		&mockOrderPaymentClient{},
+		&mockOrderDeliveryClient{},
+		testSettings,
+	)
+
+	_, err := svc.CreateOrder(&domain.CreateOrderRequest{ShippingAddress: testShippingAddress()}, context.Background(), 10)
+	if !errors.Is(err, domain.ErrProductsNotFound) {
+		t.Fatalf("expected ErrProductsNotFound, got %v", err)
+	}
+	if err.Error() != "products not found: [1:7 1]" {
+		t.Fatalf("expected the deleted variant and the line without variant in the error, got %q", err.Error())
+	}
+}
+
+func TestListAllOrdersAppliesDefaults(t *testing.T) {
+	repo := &mockOrderRepo{listTotal: 21}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	result, err := svc.ListAllOrders(context.Background(), &domain.OrderListFilter{})
+	if err != nil {
+		t.Fatalf("ListAllOrders() error = %v", err)
+	}
+	f := repo.listFilter
+	if f.Page != 1 || f.Limit != 10 || f.SortBy != "created_at" || f.Order != "desc" {
+		t.Fatalf("unexpected defaults: %#v", f)
+	}
+	if result.TotalPages != 3 {
+		t.Fatalf("expected 3 pages, got %d", result.TotalPages)
+	}
+}
+
+func TestListAllOrdersRejectsUnknownSortColumn(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	_, err := svc.ListAllOrders(context.Background(), &domain.OrderListFilter{SortBy: "id; DROP TABLE orders"})
+	if !errors.Is(err, domain.ErrInvalidOrderFilter) {
+		t.Fatalf("expected ErrInvalidOrderFilter, got %v", err)
+	}
+	if repo.listFilter != nil {
+		t.Fatal("did not expect the repository to be queried")
+	}
+}
+
+func TestOverrideOrderStatusRecordsAdmin(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 51, UserID: 7, Status: "PAID"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.OverrideOrderStatus(context.Background(), "51", "SHIPPED", 1); err != nil {
+		t.Fatalf("OverrideOrderStatus() error = %v", err)
+	}
+	h := repo.updatedHistory
+	if h == nil || h.ToStatus != "SHIPPED" || h.ChangedBy != 1 || h.Source != "admin:status_override" {
+		t.Fatalf("unexpected history entry: %#v", h)
+	}
+}
+
+func TestOverrideOrderStatusFollowsTransitionRules(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 52, UserID: 7, Status: "DELIVERED"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	err := svc.OverrideOrderStatus(context.Background(), "52", "RECEIVED", 1)
+	if !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	if repo.updatedStatus != "" {
+		t.Fatal("did not expect the order to be updated")
+	}
+}
+
+func TestOverrideOrderStatusFailedReleasesUnpaidOrder(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 53, UserID: 7, Status: "AWAITING_PAYMENT", TotalAmount: 300,
+		Items: []domain.OrderItem{{ProductID: 1, Quantity: 3, Price: 100, Status: domain.OrderItemStatusReserved}}}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.OverrideOrderStatus(context.Background(), "53", "FAILED", 1); err != nil {
+		t.Fatalf("OverrideOrderStatus() error = %v", err)
+	}
+	if len(repo.outboxEvents) != 1 || repo.outboxTypes[0] != domain.OrderEventCancelled || !repo.outboxEvents[0].StockReserved {
+		t.Fatalf("expected a cancelled event releasing the reserved stock, got %v", repo.outboxTypes)
+	}
+}
+
+func TestOverrideOrderStatusFailedLeavesPaidOrder(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 54, UserID: 7, Status: "PAID"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.OverrideOrderStatus(context.Background(), "54", "FAILED", 1); err != nil {
+		t.Fatalf("OverrideOrderStatus() error = %v", err)
+	}
+	if len(repo.outboxTypes) != 0 {
+		t.Fatalf("did not expect a paid order to release stock, got %v", repo.outboxTypes)
+	}
+}
+
+func TestGetOrderByIDHidesOtherUsersOrders(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 61, UserID: 4, PaymentURL: "https://example.com/pay"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.GetOrderByID(context.Background(), "61", domain.Caller{UserID: 5, Role: "user"})
+	if !errors.Is(err, domain.ErrOrderNotFound) || order != nil {
+		t.Fatalf("expected ErrOrderNotFound, got order=%v err=%v", order, err)
+	}
+}
+
+func TestGetOrderByIDAllowsAdmin(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 62, UserID: 4}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.GetOrderByID(context.Background(), "62", domain.Caller{UserID: 1, Role: "admin"})
+	if err != nil || order == nil || order.ID != 62 {
+		t.Fatalf("expected admin to read order 62, got order=%v err=%v", order, err)
+	}
+}
+
+func TestGetOrderByIDAllowsInternalServices(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 63, UserID: 4}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.GetOrderByID(context.Background(), "63", domain.InternalCaller)
+	if err != nil || order == nil || order.ID != 63 {
+		t.Fatalf("expected internal caller to read order 63, got order=%v err=%v", order, err)
+	}
+}
+
+func TestGetOrderTrackingBuildsChronologicalTimeline(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	repo := &mockOrderRepo{
+		getOrderByIDResp: &domain.Order{ID: 71, UserID: 4, Status: "PAID", CreatedAt: created},
+		history: []domain.OrderStatusHistory{
+			{ToStatus: "AWAITING_PAYMENT", CreatedAt: created.Add(time.Minute)},
+			{ToStatus: "PAID", CreatedAt: created.Add(5 * time.Minute)},
+		},
+	}
+	paymentClient := &mockOrderPaymentClient{statusResp: &pb.GetPaymentStatusResponse{
+		Status:    "SUCCESS",
+		CreatedAt: created.Add(2 * time.Minute).Unix(),
+		UpdatedAt: created.Add(4 * time.Minute).Unix(),
+	}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	tracking, err := svc.GetOrderTracking(context.Background(), "71", domain.Caller{UserID: 4})
+	if err != nil {
+		t.Fatalf("GetOrderTracking() error = %v", err)
+	}
+	if tracking.Degraded || tracking.PaymentStatus != "SUCCESS" || tracking.DeliveryStatus != "" {
+		t.Fatalf("unexpected tracking summary: %#v", tracking)
+	}
+
+	var got []string
+	for _, event := range tracking.Timeline {
+		got = append(got, event.Source+":"+event.Status)
+	}
+	want := "order:RECEIVED order:AWAITING_PAYMENT payment:PENDING payment:SUCCESS order:PAID"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("expected timeline %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestGetOrderTrackingDegradesWhenDeliveryIsDown(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 72, UserID: 4, Status: "PAID"}}
+	deliveryClient := &mockOrderDeliveryClient{deliveryErr: status.Error(codes.Unavailable, "connection refused")}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, deliveryClient, testSettings)
+
+	tracking, err := svc.GetOrderTracking(context.Background(), "72", domain.Caller{UserID: 4})
+	if err != nil {
+		t.Fatalf("GetOrderTracking() error = %v", err)
+	}
+	if !tracking.Degraded || len(tracking.UnavailableSources) != 1 || tracking.UnavailableSources[0] != "delivery" {
+		t.Fatalf("expected degraded tracking without delivery, got %#v", tracking)
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersSetsPaymentDeadline(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 80, UserID: 4, Status: "RECEIVED", TotalAmount: 500}}
+	paymentClient := &mockOrderPaymentClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	before := time.Now()
+	if err := svc.ProcessAwaitingPaymentOrders(context.Background(), "80", nil, "stream:stock:reserved"); err != nil {
+		t.Fatalf("ProcessAwaitingPaymentOrders() error = %v", err)
+	}
+	if paymentClient.paymentReq == nil || paymentClient.paymentReq.ExpiryMinutes != 30 {
+		t.Fatalf("expected Snap expiry of 30 minutes, got %#v", paymentClient.paymentReq)
+	}
+	if repo.updatedStatus != "AWAITING_PAYMENT" || repo.paymentURL != "https://example.com/pay" {
+		t.Fatalf("expected order awaiting payment with url, got status=%s url=%s", repo.updatedStatus, repo.paymentURL)
+	}
+	if repo.paymentExpires.Before(before.Add(testPaymentExpiry)) || repo.paymentExpires.After(time.Now().Add(testPaymentExpiry)) {
+		t.Fatalf("expected payment deadline %s from now, got %s", testPaymentExpiry, repo.paymentExpires)
+	}
+}
+
+// partialOrder is a RECEIVED order of three products at 100, 200 and 300 that are still being reserved
+func partialOrder(allowBackorder bool) *domain.Order {
+	return &domain.Order{
+		ID: 85, UserID: 4, Status: "RECEIVED", AllowBackorder: allowBackorder,
+		Subtotal: 600, TotalAmount: 600,
+		Items: []domain.OrderItem{
+			{ID: 1, ProductID: 1, Quantity: 1, Price: 100, Status: domain.OrderItemStatusPending},
+			{ID: 2, ProductID: 2, Quantity: 1, Price: 200, Status: domain.OrderItemStatusPending},
+			{ID: 3, ProductID: 3, Quantity: 1, Price: 300, Status: domain.OrderItemStatusPending},
+		},
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersCancelsUnavailableLines(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: partialOrder(false)}
+	paymentClient := &mockOrderPaymentClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.ProcessAwaitingPaymentOrders(context.Background(), "85", []domain.ItemKey{{ProductID: 2}}, "stream:stock:reserved"); err != nil {
+		t.Fatalf("ProcessAwaitingPaymentOrders() error = %v", err)
+	}
+	if paymentClient.paymentReq == nil || paymentClient.paymentReq.Amount != 400 {
+		t.Fatalf("expected payment for the reserved lines only, got %#v", paymentClient.paymentReq)
+	}
+	order := repo.updatedLines
+	if order == nil || order.TotalAmount != 400 {
+		t.Fatalf("expected repriced order lines to be saved, got %#v", order)
+	}
+	want := []string{domain.OrderItemStatusReserved, domain.OrderItemStatusCancelled, domain.OrderItemStatusReserved}
+	for i, item := range order.Items {
+		if item.Status != want[i] {
+			t.Fatalf("item %d: expected status %s, got %s", item.ID, want[i], item.Status)
+		}
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersSavesLinesWithTheTransition(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: partialOrder(false), updateStatusErr: domain.ErrInvalidStatusTransition}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	// The customer cancelled while the payment link was requested, the lines must roll back with the transition
+	err := svc.ProcessAwaitingPaymentOrders(context.Background(), "85", []domain.ItemKey{{ProductID: 2}}, "stream:stock:reserved")
+	if !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+	if repo.updatedLines == nil || !repo.linesInTx {
+		t.Fatal("expected the order lines saved in the transaction of the status change")
+	}
+	if repo.paymentURL != "" {
+		t.Fatalf("expected no payment info stored, got %q", repo.paymentURL)
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersBackordersUnavailableLines(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: partialOrder(true)}
+	paymentClient := &mockOrderPaymentClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.ProcessAwaitingPaymentOrders(context.Background(), "85", []domain.ItemKey{{ProductID: 3}}, "stream:stock:reserved"); err != nil {
+		t.Fatalf("ProcessAwaitingPaymentOrders() error = %v", err)
+	}
+	if paymentClient.paymentReq == nil || paymentClient.paymentReq.Amount != 300 {
+		t.Fatalf("expected payment without the backordered line, got %#v", paymentClient.paymentReq)
+	}
+	if status := repo.updatedLines.Items[2].Status; status != domain.OrderItemStatusBackordered {
+		t.Fatalf("expected unavailable line to be backordered, got %s", status)
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersReleasesOnlyReservedLinesOfCancelledOrder(t *testing.T) {
+	order := partialOrder(false)
+	order.Status = "CANCELLED"
+	repo := &mockOrderRepo{getOrderByIDResp: order}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.ProcessAwaitingPaymentOrders(context.Background(), "85", []domain.ItemKey{{ProductID: 1}}, "stream:stock:reserved"); err != nil {
+		t.Fatalf("ProcessAwaitingPaymentOrders() error = %v", err)
+	}
+	if len(repo.outboxEvents) != 1 || repo.outboxTypes[0] != domain.OrderEventCancelled {
+		t.Fatalf("expected one cancelled event, got %v", repo.outboxTypes)
+	}
+	if items := repo.outboxEvents[0].Items; len(items) != 2 || items[0].ProductID != 2 || items[1].ProductID != 3 {
+		t.Fatalf("expected only the reserved lines to be released, got %#v", items)
+	}
+}
+
+func TestProcessAwaitingPaymentOrdersQueuesCompensationOnce(t *testing.T) {
+	order := partialOrder(false)
+	order.Status = "CANCELLED"
+	repo := &mockOrderRepo{getOrderByIDResp: order, releaseQueued: true}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.ProcessAwaitingPaymentOrders(context.Background(), "85", nil, "stream:stock:reserved"); err != nil {
+		t.Fatalf("ProcessAwaitingPaymentOrders() error = %v", err)
+	}
+	if len(repo.outboxTypes) != 0 {
+		t.Fatalf("expected a redelivered event to queue nothing, got %v", repo.outboxTypes)
+	}
+}
+
+// paidPartialOrder is a PAID order of products 1 and 2 at 100 and 200 with a backordered line for product 3
+func paidPartialOrder() *domain.Order {
+	order := partialOrder(true)
+	order.Status = "PAID"
+	order.ApplyReservation([]domain.ItemKey{{ProductID: 3}})
+	order.Subtotal, order.TotalAmount = 300, 300
+	return order
+}
+
+func TestCancelOrderItemRefundsPaidLine(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: paidPartialOrder()}
+	paymentClient := &mockOrderPaymentClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.CancelOrderItem(context.Background(), "85", 2, 1)
+	if err != nil {
+		t.Fatalf("CancelOrderItem() error = %v", err)
+	}
+	if paymentClient.refundReq == nil || paymentClient.refundReq.Amount != 200 || paymentClient.refundReq.RefundKey != "order-85-item-2" {
+		t.Fatalf("expected refund of 200 for the cancelled line, got %#v", paymentClient.refundReq)
+	}
+	if order.TotalAmount != 100 || order.RefundedAmount != 200 || order.Items[1].Status != domain.OrderItemStatusCancelled {
+		t.Fatalf("expected order repriced with the line refunded, got total %d refunded %d", order.TotalAmount, order.RefundedAmount)
+	}
+	if repo.updatedLines != order {
+		t.Fatal("expected order lines to be saved")
+	}
+	if len(repo.outboxTypes) != 1 || repo.outboxTypes[0] != domain.OrderEventItemsCancelled {
+		t.Fatalf("expected items cancelled event, got %v", repo.outboxTypes)
+	}
+	if items := repo.outboxEvents[0].Items; len(items) != 1 || items[0].ProductID != 2 {
+		t.Fatalf("expected stock of the cancelled line to be released, got %#v", items)
+	}
+}
+
+func TestCancelOrderItemKeepsTheRefundPendingWhenPaymentServiceFails(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: paidPartialOrder()}
+	paymentClient := &mockOrderPaymentClient{refundErr: status.Error(codes.Unavailable, "payment service down")}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.CancelOrderItem(context.Background(), "85", 2, 1)
+	if err != nil {
+		t.Fatalf("CancelOrderItem() error = %v", err)
+	}
+	if order.Items[1].Status != domain.OrderItemStatusCancelled || repo.updatedLines != order {
+		t.Fatal("expected the line cancelled even though the refund was not sent")
+	}
+	if len(repo.refunds) != 1 || repo.refunds[0].Status != domain.OrderRefundPending || repo.refunds[0].Amount != 200 {
+		t.Fatalf("expected a pending refund of 200, got %#v", repo.refunds)
+	}
+
+	paymentClient.refundErr = nil
+	sent, err := svc.RetryPendingRefunds(context.Background())
+	if err != nil || sent != 1 {
+		t.Fatalf("RetryPendingRefunds() = %d, %v", sent, err)
+	}
+	if paymentClient.refundReq.RefundKey != "order-85-item-2" || repo.refunds[0].Status != domain.OrderRefundDone {
+		t.Fatalf("expected the refund sent with its line key and done, got %#v", repo.refunds[0])
+	}
+}
+
+func TestCancelOrderItemRefundsAtTheRatesTheOrderWasCharged(t *testing.T) {
+	order := paidPartialOrder()
+	order.Items[0].TaxCategory = domain.DefaultTaxCategory
+	order.Items[1].TaxCategory = "books"
+	order.Subtotal, order.TaxAmount, order.TotalAmount = 300, 11, 311
+	order.TaxLines = []domain.OrderTaxLine{
+		{Category: "books", RateBasisPoints: 0, TaxableAmount: 200, Amount: 0},
+		{Category: domain.DefaultTaxCategory, RateBasisPoints: 1100, TaxableAmount: 100, Amount: 11},
+	}
+	repo := &mockOrderRepo{getOrderByIDResp: order}
+	paymentClient := &mockOrderPaymentClient{}
+	// The tax rules changed since the order was paid, the refund must not follow them
+	settings := testSettings
+	settings.Pricing = domain.PricingRules{Tax: domain.TaxRules{DefaultRate: 2000}, ShippingFee: 500}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, settings)
+
+	order, err := svc.CancelOrderItem(context.Background(), "85", 1, 1)
+	if err != nil {
+		t.Fatalf("CancelOrderItem() error = %v", err)
+	}
+	if repo.lockedOrderID != "85" {
+		t.Fatalf("expected order 85 locked while the line is cancelled, got %q", repo.lockedOrderID)
+	}
+	if paymentClient.refundReq == nil || paymentClient.refundReq.Amount != 111 {
+		t.Fatalf("expected the line refunded with the 11%% it was taxed at, got %#v", paymentClient.refundReq)
+	}
+	if order.TotalAmount != 200 || order.TaxAmount != 0 || order.ShippingFee != 0 {
+		t.Fatalf("expected the books line left at 200 without tax or shipping, got total %d tax %d shipping %d", order.TotalAmount, order.TaxAmount, order.ShippingFee)
+	}
+}
+
+func TestCancelOrderItemCancelsBackorderedLineWithoutRefund(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: paidPartialOrder()}
+	paymentClient := &mockOrderPaymentClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+	order, err := svc.CancelOrderItem(context.Background(), "85", 3, 1)
+	if err != nil {
+		t.Fatalf("CancelOrderItem() error = %v", err)
+	}
+	if paymentClient.refundReq != nil || len(repo.outboxTypes) != 0 {
+		t.Fatalf("expected no refund or event for a backordered line, got %#v %v", paymentClient.refundReq, repo.outboxTypes)
+	}
+	if order.Items[2].Status != domain.OrderItemStatusCancelled || order.TotalAmount != 300 {
+		t.Fatalf("expected backordered line cancelled with the total unchanged, got %s %d", order.Items[2].Status, order.TotalAmount)
+	}
+}
+
+func TestCancelOrderItemRejectsUnpaidOrderAndLastLine(t *testing.T) {
+	unpaid := paidPartialOrder()
+	unpaid.Status = "AWAITING_PAYMENT"
+	lastLine := paidPartialOrder()
+	lastLine.Items[0].Status = domain.OrderItemStatusCancelled
+
+	for name, order := range map[string]*domain.Order{"unpaid": unpaid, "last line": lastLine} {
+		repo := &mockOrderRepo{getOrderByIDResp: order}
+		paymentClient := &mockOrderPaymentClient{}
+		svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, paymentClient, &mockOrderDeliveryClient{}, testSettings)
+
+		if _, err := svc.CancelOrderItem(context.Background(), "85", 2, 1); !errors.Is(err, domain.ErrOrderItemNotCancellable) {
+			t.Fatalf("%s: expected ErrOrderItemNotCancellable, got %v", name, err)
+		}
+		if paymentClient.refundReq != nil {
+			t.Fatalf("%s: expected no refund", name)
+		}
+	}
+	svc := NewOrderService(&mockOrderRepo{getOrderByIDResp: paidPartialOrder()}, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+	if _, err := svc.CancelOrderItem(context.Background(), "85", 9, 1); !errors.Is(err, domain.ErrOrderItemNotFound) {
+		t.Fatalf("expected ErrOrderItemNotFound, got %v", err)
+	}
+}
+
+func TestExpireOverdueOrdersCancelsAndQueuesExpiredEvent(t *testing.T) {
+	repo := &mockOrderRepo{overdueOrders: []domain.Order{{
+		ID:          81,
+		UserID:      4,
+		Status:      "AWAITING_PAYMENT",
+		TotalAmount: 300,
+		Items:       []domain.OrderItem{{ProductID: 3, Quantity: 2}},
+	}}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	expired, err := svc.ExpireOverdueOrders(context.Background())
+	if err != nil {
+		t.Fatalf("ExpireOverdueOrders() error = %v", err)
+	}
+	if expired != 1 {
+		t.Fatalf("expected 1 expired order, got %d", expired)
+	}
+	if h := repo.updatedHistory; h == nil || h.ToStatus != "CANCELLED" || h.Source != "sweeper:payment_expired" {
+		t.Fatalf("unexpected history entry: %#v", h)
+	}
+	if len(repo.outboxTypes) != 1 || repo.outboxTypes[0] != "expired" {
+		t.Fatalf("expected expired outbox event, got %v", repo.outboxTypes)
+	}
+	if event := repo.outboxEvents[0]; event.OrderID != "81" || !event.StockReserved || len(event.Items) != 1 {
+		t.Fatalf("unexpected expired event: %#v", event)
+	}
+}
+
+func TestExpireOverdueOrdersSkipsOrdersPaidInTheMeantime(t *testing.T) {
+	repo := &mockOrderRepo{
+		overdueOrders:   []domain.Order{{ID: 82, UserID: 4, Status: "AWAITING_PAYMENT"}},
+		updateStatusErr: domain.ErrInvalidStatusTransition,
+	}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	expired, err := svc.ExpireOverdueOrders(context.Background())
+	if err != nil {
+		t.Fatalf("ExpireOverdueOrders() error = %v", err)
+	}
+	if expired != 0 || len(repo.outboxTypes) != 0 {
+		t.Fatalf("expected nothing expired, got %d expired and outbox %v", expired, repo.outboxTypes)
+	}
+}
+
+func TestGetOrderInvoiceRequiresPaidOrder(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 90, UserID: 4, Status: "AWAITING_PAYMENT"}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	_, err := svc.GetOrderInvoice(context.Background(), "90", domain.Caller{UserID: 4})
+	if !errors.Is(err, domain.ErrInvoiceNotIssued) {
+		t.Fatalf("expected ErrInvoiceNotIssued, got %v", err)
+	}
+}
+
+func TestGetOrderInvoiceRendersStoredInvoice(t *testing.T) {
+	issuedAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	repo := &mockOrderRepo{
+		getOrderByIDResp: &domain.Order{
+			ID: 91, UserID: 4, Status: "PAID", BuyerName: "jane", BuyerEmail: "jane@example.com",
+			Subtotal: 1250000, TaxAmount: 137500, ShippingFee: 20000, TotalAmount: 1407500,
+			TaxLines:  []domain.OrderTaxLine{{Category: "default", RateBasisPoints: 1100, TaxableAmount: 1250000, Amount: 137500}},
+			CreatedAt: issuedAt.Add(-time.Hour),
+			Items: []domain.OrderItem{
+				{Name: "Keyboard (TKL)", Quantity: 2, Price: 500000},
+				{Name: "Mouse <wireless>", Quantity: 1, Price: 250000},
+			},
+		},
+		invoice: &domain.OrderInvoice{OrderID: 91, Sequence: 42, Number: domain.InvoiceNumber(42, issuedAt), IssuedAt: issuedAt},
+	}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	invoice, err := svc.GetOrderInvoice(context.Background(), "91", domain.Caller{UserID: 4})
+	if err != nil {
+		t.Fatalf("GetOrderInvoice() error = %v", err)
+	}
+	if invoice.Number != "INV-2026-000042" || len(invoice.Lines) != 2 || invoice.Lines[0].Amount != 1000000 || invoice.Subtotal != 1250000 {
+		t.Fatalf("unexpected invoice: %#v", invoice)
+	}
+
+	pdf := RenderInvoicePDF(invoice)
+	if !strings.HasPrefix(string(pdf), "%PDF-1.4") || !strings.Contains(string(pdf), "(Invoice INV-2026-000042)") ||
+		!strings.Contains(string(pdf), `(Keyboard \(TKL\))`) || !strings.Contains(string(pdf), "(IDR 1,250,000)") ||
+		!strings.Contains(string(pdf), `(Tax default \(11%\))`) || !strings.Contains(string(pdf), "(IDR 1,407,500)") {
+		t.Fatalf("unexpected pdf content:\n%s", pdf)
+	}
+	if string(RenderInvoicePDF(invoice)) != string(pdf) {
+		t.Fatal("expected re-rendering to produce the same pdf")
+	}
+
+	html, err := RenderInvoiceHTML(invoice)
+	if err != nil {
+		t.Fatalf("RenderInvoiceHTML() error = %v", err)
+	}
+	if !strings.Contains(string(html), "Mouse &lt;wireless&gt;") || !strings.Contains(string(html), "jane@example.com") ||
+		!strings.Contains(string(html), "Tax default (11%)") || !strings.Contains(string(html), "IDR 20,000") {
+		t.Fatalf("unexpected html content:\n%s", html)
+	}
+}
+
+func TestReorderOrderReportsAddedRepricedAndUnavailableLines(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 95, UserID: 4, Status: "DELIVERED", Items: []domain.OrderItem{
+		{ProductID: 1, Name: "Keyboard", Quantity: 1, Price: 500},
+		{ProductID: 2, Name: "Mouse", Quantity: 2, Price: 100},
+		{ProductID: 3, Name: "Monitor", Quantity: 1, Price: 2000},
+	}}}
+	cartClient := &mockOrderCartClient{addItemsResp: &pb.AddCartItemsResponse{
+		Added: []*pb.CartItem{
+			{ProductId: 1, Name: "Keyboard", Quantity: 1, Price: 500},
+			{ProductId: 2, Name: "Mouse", Quantity: 2, Price: 120},
+		},
+		Unavailable: []*pb.CartItem{{ProductId: 3, Name: "Monitor", Quantity: 1}},
+	}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, cartClient, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	result, err := svc.ReorderOrder(context.Background(), "95", domain.Caller{UserID: 4})
+	if err != nil {
+		t.Fatalf("ReorderOrder() error = %v", err)
+	}
+	if cartClient.addItemsReq == nil || cartClient.addItemsReq.UserId != "4" || len(cartClient.addItemsReq.Items) != 3 {
+		t.Fatalf("unexpected AddCartItems request: %#v", cartClient.addItemsReq)
+	}
+	if len(result.Added) != 2 || len(result.Repriced) != 1 || len(result.Unavailable) != 1 {
+		t.Fatalf("unexpected reorder result: %#v", result)
+	}
+	if r := result.Repriced[0]; r.ProductID != 2 || r.PreviousPrice != 100 || r.Price != 120 {
+		t.Fatalf("unexpected repriced line: %#v", r)
+	}
+	if u := result.Unavailable[0]; u.ProductID != 3 || u.Name != "Monitor" {
+		t.Fatalf("unexpected unavailable line: %#v", u)
+	}
+}
+
+func TestReorderOrderHidesOtherUsersOrders(t *testing.T) {
+	repo := &mockOrderRepo{getOrderByIDResp: &domain.Order{ID: 96, UserID: 4, Status: "DELIVERED"}}
+	cartClient := &mockOrderCartClient{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, cartClient, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	_, err := svc.ReorderOrder(context.Background(), "96", domain.Caller{UserID: 5})
+	if !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+	if cartClient.addItemsReq != nil {
+		t.Fatal("did not expect items added to another user's cart")
+	}
+}
+
+func TestCreateSubscriptionMergesDuplicateProducts(t *testing.T) {
+	repo := &mockOrderRepo{}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	subscription, err := svc.CreateSubscription(context.Background(), &domain.CreateSubscriptionRequest{
+		Items:           []domain.OrderLine{{ProductID: 4, Quantity: 1}, {ProductID: 5, Quantity: 2}, {ProductID: 4, Quantity: 2}},
+		IntervalDays:    30,
+		ShippingAddress: testShippingAddress(),
+	}, 7)
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	if subscription.Status != domain.SubscriptionStatusActive || subscription.UserID != 7 {
+		t.Fatalf("expected active subscription of user 7, got %#v", subscription)
+	}
+	if len(subscription.Items) != 2 || subscription.Items[0].ProductID != 4 || subscription.Items[0].Quantity != 3 {
+		t.Fatalf("expected product 4 merged to quantity 3, got %#v", subscription.Items)
+	}
+}
+
+func TestCreateSubscriptionRejectsUnavailableProducts(t *testing.T) {
+	repo := &mockOrderRepo{}
+	products := &mockOrderProductClient{deletedIDs: map[uint32]bool{5: true}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, products, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	_, err := svc.CreateSubscription(context.Background(), &domain.CreateSubscriptionRequest{
+		Items:           []domain.OrderLine{{ProductID: 4, Quantity: 1}, {ProductID: 5, Quantity: 1}},
+		IntervalDays:    7,
+		ShippingAddress: testShippingAddress(),
+	}, 7)
+	if !errors.Is(err, domain.ErrProductsNotFound) {
+		t.Fatalf("expected ErrProductsNotFound, got %v", err)
+	}
+	if repo.subscription != nil {
+		t.Fatal("expected no subscription to be stored")
+	}
+}
+
+func dueTestSubscription(runAt time.Time) domain.Subscription {
+	return domain.Subscription{
+		ID: 9, UserID: 7, Status: domain.SubscriptionStatusActive, IntervalDays: 14, NextRunAt: runAt,
+		Items:           []domain.SubscriptionItem{{ProductID: 4, Quantity: 2}},
+		BuyerName:       "jane",
+		BuyerEmail:      "jane@example.com",
+		ShippingAddress: *testShippingAddress(),
+	}
+}
+
+func TestRenewDueSubscriptionsPlacesOrderWithoutTheCart(t *testing.T) {
+	runAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	repo := &mockOrderRepo{dueSubscriptions: []domain.Subscription{dueTestSubscription(runAt)}}
+	// The cart is empty, a renewal that checked it out would fail
+	cart := &mockOrderCartClient{userCartResp: &pb.CartResponse{}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, cart, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	renewed, err := svc.RenewDueSubscriptions(context.Background())
+	if err != nil {
+		t.Fatalf("RenewDueSubscriptions() error = %v", err)
+	}
+	if renewed != 1 {
+		t.Fatalf("expected 1 renewal, got %d", renewed)
+	}
+
+	order := repo.addedOrder
+	if order == nil || order.SubscriptionID != 9 || order.UserID != 7 || len(order.Items) != 1 || order.Items[0].Quantity != 2 {
+		t.Fatalf("expected order for subscription 9 with 2 of product 4, got %#v", order)
+	}
+	if order.TotalAmount != 200 || order.ShippingAddress != *testShippingAddress() {
+		t.Fatalf("expected priced order shipped to the subscription address, got %#v", order)
+	}
+	if repo.savedKey == nil || repo.savedKey.Key != fmt.Sprintf("subscription:9:%d", runAt.Unix()) {
+		t.Fatalf("expected idempotency key for this run, got %#v", repo.savedKey)
+	}
+	if len(repo.outboxEvents) != 1 {
+		t.Fatalf("expected order created event, got %v", repo.outboxTypes)
+	}
+
+	if len(repo.updatedSchedules) != 1 {
+		t.Fatalf("expected subscription schedule update, got %d", len(repo.updatedSchedules))
+	}
+	updated := repo.updatedSchedules[0]
+	if !updated.NextRunAt.Equal(runAt.Add(14*24*time.Hour)) || updated.FailedAttempts != 0 || updated.LastOrderID != order.ID {
+		t.Fatalf("expected next run one interval after the last, got %#v", updated)
+	}
+}
+
+func TestRenewDueSubscriptionsRetriesThenSuspends(t *testing.T) {
+	subscription := dueTestSubscription(time.Now().Add(-time.Minute))
+	repo := &mockOrderRepo{dueSubscriptions: []domain.Subscription{subscription}}
+	products := &mockOrderProductClient{deletedIDs: map[uint32]bool{4: true}}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, products, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	before := time.Now()
+	if renewed, err := svc.RenewDueSubscriptions(context.Background()); err != nil || renewed != 0 {
+		t.Fatalf("expected no renewal, got renewed=%d err=%v", renewed, err)
+	}
+	retry := repo.updatedSchedules[0]
+	if retry.Status != domain.SubscriptionStatusActive || retry.FailedAttempts != 1 || retry.LastError == "" {
+		t.Fatalf("expected first failure to be recorded, got %#v", retry)
+	}
+	if retry.NextRunAt.Before(before.Add(subscriptionRetryDelay)) {
+		t.Fatalf("expected retry after %s, got %s", subscriptionRetryDelay, retry.NextRunAt)
+	}
+
+	subscription.FailedAttempts = 2
+	repo.dueSubscriptions = []domain.Subscription{subscription}
+	if _, err := svc.RenewDueSubscriptions(context.Background()); err != nil {
+		t.Fatalf("RenewDueSubscriptions() error = %v", err)
+	}
+	if suspended := repo.updatedSchedules[1]; suspended.Status != domain.SubscriptionStatusSuspended || suspended.FailedAttempts != 3 {
+		t.Fatalf("expected subscription suspended after 3 failures, got %#v", suspended)
+	}
+	if repo.addedOrder != nil {
+		t.Fatal("expected no order to be placed")
+	}
+}
+
+func TestExpiredRenewalOrderCountsAsFailedRenewal(t *testing.T) {
+	subscription := dueTestSubscription(time.Now().Add(14 * 24 * time.Hour))
+	subscription.LastOrderID = 86
+	repo := &mockOrderRepo{
+		subscription:  &subscription,
+		overdueOrders: []domain.Order{{ID: 86, UserID: 7, Status: "AWAITING_PAYMENT", SubscriptionID: 9}},
+	}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	before := time.Now()
+	if _, err := svc.ExpireOverdueOrders(context.Background()); err != nil {
+		t.Fatalf("ExpireOverdueOrders() error = %v", err)
+	}
+	if len(repo.updatedSchedules) != 1 {
+		t.Fatalf("expected the renewal to be settled, got %d updates", len(repo.updatedSchedules))
+	}
+	retry := repo.updatedSchedules[0]
+	if retry.FailedAttempts != 1 || retry.LastError == "" || retry.NextRunAt.After(before.Add(2*subscriptionRetryDelay)) {
+		t.Fatalf("expected the expired order to count as a failed renewal retried soon, got %#v", retry)
+	}
+}
+
+func TestPaidRenewalOrderClearsFailedAttempts(t *testing.T) {
+	subscription := dueTestSubscription(time.Now().Add(14 * 24 * time.Hour))
+	subscription.LastOrderID, subscription.FailedAttempts, subscription.LastError = 87, 2, "renewal order 80 expired unpaid"
+	repo := &mockOrderRepo{
+		subscription:     &subscription,
+		getOrderByIDResp: &domain.Order{ID: 87, UserID: 7, Status: "AWAITING_PAYMENT", SubscriptionID: 9},
+	}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+
+	if err := svc.UpdateOrderToPaid(context.Background(), "87", "stream:payment:success"); err != nil {
+		t.Fatalf("UpdateOrderToPaid() error = %v", err)
+	}
+	if len(repo.updatedSchedules) != 1 || repo.updatedSchedules[0].FailedAttempts != 0 || repo.updatedSchedules[0].LastError != "" {
+		t.Fatalf("expected the paid renewal to clear failed attempts, got %#v", repo.updatedSchedules)
+	}
+}
+
+func TestSubscriptionStatusChanges(t *testing.T) {
+	runAt := time.Now().Add(48 * time.Hour)
+	subscription := dueTestSubscription(runAt)
+	repo := &mockOrderRepo{subscription: &subscription}
+	svc := NewOrderService(repo, &mockOrderEventRepo{}, &mockOrderCartClient{}, &mockOrderProductClient{}, &mockOrderPaymentClient{}, &mockOrderDeliveryClient{}, testSettings)
+	ctx, owner := context.Background(), domain.Caller{UserID: 7}
+
+	if _, err := svc.ResumeSubscription(ctx, "9", owner); !errors.Is(err, domain.ErrSubscriptionStatus) {
+		t.Fatalf("expected active subscription not to resume, got %v", err)
+	}
+	if _, err := svc.PauseSubscription(ctx, "9", domain.Caller{UserID: 8}); !errors.Is(err, domain.ErrSubscriptionNotFound) {
+		t.Fatalf("expected other users subscription to be hidden, got %v", err)
+	}
+
+	paused, err := svc.PauseSubscription(ctx, "9", owner)
+	if err != nil || paused.Status != domain.SubscriptionStatusPaused {
+		t.Fatalf("expected PAUSED, got %#v err=%v", paused, err)
+	}
+	skipped, err := svc.SkipNextRenewal(ctx, "9", owner)
+	if err != nil || !skipped.NextRunAt.Equal(runAt.Add(14*24*time.Hour)) {
+		t.Fatalf("expected next run moved one interval, got %#v err=%v", skipped, err)
+	}
+	resumed, err := svc.ResumeSubscription(ctx, "9", owner)
+	if err != nil || resumed.Status != domain.SubscriptionStatusActive {
+		t.Fatalf("expected ACTIVE, got %#v err=%v", resumed, err)
+	}
+	cancelled, err := svc.CancelSubscription(ctx, "9", owner)
+	if err != nil || cancelled.Status != domain.SubscriptionStatusCancelled {
+		t.Fatalf("expected CANCELLED, got %#v err=%v", cancelled, err)
+	}
+	if _, err := svc.SkipNextRenewal(ctx, "9", owner); !errors.Is(err, domain.ErrSubscriptionStatus) {
+		t.Fatalf("expected cancelled subscription to stay cancelled, got %v", err)
 	}
 }
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/domain"
+	"strings"
+	"time"
+)
+
+// A quote token is base64url(json claims) + "." + base64url(HMAC-SHA256 of the encoded claims).
+// It carries the quoted lines and discount code itself, so quotes need no storage.
+
+type quoteClaims struct {
+	UserID    uint         `json:"uid"`
+	ExpiresAt int64        `json:"exp"` // unix seconds
+	Lines     []quotedLine `json:"lines"`
+	// DiscountCode is the normalized code the quote was priced with, empty for none
+	DiscountCode string `json:"discount,omitempty"`
+}
+
+type quotedLine struct {
+	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id,omitempty"`
+	Quantity  uint `json:"quantity"`
+	Price     uint `json:"price"`
+}
+
+func newQuoteClaims(userID uint, expiresAt time.Time, items []domain.OrderItem, discountCode string) *quoteClaims {
+	claims := &quoteClaims{UserID: userID, ExpiresAt: expiresAt.Unix(), DiscountCode: domain.NormalizeDiscountCode(discountCode)}
+	for _, item := range items {
+		claims.Lines = append(claims.Lines, quotedLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity, Price: item.Price})
+	}
+	return claims
+}
+
+func (s *OrderService) signQuoteToken(claims *quoteClaims) string {
+	body, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.quoteSignature(payload))
+}
+
+func (s *OrderService) quoteSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, s.quoteSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// verifyQuoteToken checks the token signature and that it was issued to userID and has not expired at now
+func (s *OrderService) verifyQuoteToken(token string, userID uint, now time.Time) (*quoteClaims, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, domain.ErrInvalidQuote
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.quoteSignature(payload)) {
+		return nil, domain.ErrInvalidQuote
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, domain.ErrInvalidQuote
+	}
+	var claims quoteClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, domain.ErrInvalidQuote
+	}
+
+	if claims.UserID != userID {
+		return nil, domain.ErrInvalidQuote
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, domain.ErrQuoteExpired
+	}
+	return &claims, nil
+}
+
+// lockPrices replaces the current prices of items with the quoted ones. The items must be exactly the
+// quoted products, variants and quantities, otherwise the customer would be charged for something they were not shown.
+func (c *quoteClaims) lockPrices(items []domain.OrderItem) error {
+	if len(items) != len(c.Lines) {
+		return fmt.Errorf("%w: quoted %d lines, cart has %d", domain.ErrQuoteMismatch, len(c.Lines), len(items))
+	}
+
+	quoted := make(map[domain.ItemKey]quotedLine, len(c.Lines))
+	for _, line := range c.Lines {
+		quoted[domain.ItemKey{ProductID: line.ProductID, VariantID: line.VariantID}] = line
+	}
+	for i, item := range items {
+		line, ok := quoted[item.Key()]
+		if !ok || line.Quantity != item.Quantity {
+			return fmt.Errorf("%w: product %d variant %d", domain.ErrQuoteMismatch, item.ProductID, item.VariantID)
+		}
+		items[i].Price = line.Price
+	}
+	return nil
+}
+
+// checkDiscount makes sure the order uses the discount code the quote was priced with, so a quote
+// can't lock the totals of one code while the order is placed with another or none.
+func (c *quoteClaims) checkDiscount(code string) error {
+	if domain.NormalizeDiscountCode(code) != c.DiscountCode {
+		return fmt.Errorf("%w: quoted with discount code %q", domain.ErrQuoteMismatch, c.DiscountCode)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"libs/logger"
+	"libs/pb"
+	"order-service/internal/domain"
+	"order-service/internal/repository"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// subscriptionRetryDelay is how long a failed renewal waits before it is tried again
+const subscriptionRetryDelay = time.Hour
+
+// dueSubscriptionsBatchSize caps how many subscriptions a single scheduler run renews
+const dueSubscriptionsBatchSize = 100
+
+// CreateSubscription starts a subscription for userID. The first order is placed by the next scheduler run.
+func (s *OrderService) CreateSubscription(ctx context.Context, req *domain.CreateSubscriptionRequest, userID uint) (*domain.Subscription, error) {
+	l := logger.ForContext(ctx)
+
+	if req.ShippingAddress == nil {
+		return nil, fmt.Errorf("%w: shipping_address is required", domain.ErrInvalidShippingAddress)
+	}
+	req.ShippingAddress.Normalize()
+	if err := req.ShippingAddress.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The same product or variant listed twice is one line with the summed quantity
+	subscription := &domain.Subscription{
+		UserID:          userID,
+		Status:          domain.SubscriptionStatusActive,
+		IntervalDays:    req.IntervalDays,
+		NextRunAt:       time.Now(),
+		BuyerName:       req.BuyerName,
+		BuyerEmail:      req.BuyerEmail,
+		ShippingAddress: *req.ShippingAddress,
+	}
+	lines := make(map[domain.ItemKey]int, len(req.Items))
+	for _, item := range req.Items {
+		if i, ok := lines[item.Key()]; ok {
+			subscription.Items[i].Quantity += item.Quantity
+			continue
+		}
+		lines[item.Key()] = len(subscription.Items)
+		subscription.Items = append(subscription.Items, domain.SubscriptionItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	// Reject products that could not be ordered right away
+	cartItems := make([]*pb.CartItem, len(subscription.Items))
+	for i, item := range subscription.Items {
+		cartItems[i] = &pb.CartItem{ProductId: uint32(item.ProductID), VariantId: uint32(item.VariantID), Quantity: uint32(item.Quantity)}
+	}
+	if _, err := s.priceCartItems(ctx, cartItems); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		l.Error("failed to create subscription", zap.Error(err))
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	l.Info("Subscription created", zap.Uint("subscriptionID", subscription.ID), zap.Uint("userID", userID))
+	return subscription, nil
+}
+
+func (s *OrderService) GetSubscriptions(ctx context.Context, userID uint) ([]domain.Subscription, error) {
+	subscriptions, err := s.repo.GetSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *OrderService) GetSubscription(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	return s.getSubscriptionFor(ctx, subscriptionID, caller)
+}
+
+// PauseSubscription stops renewals until the subscription is resumed
+func (s *OrderService) PauseSubscription(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	return s.changeSubscription(ctx, subscriptionID, caller, []string{domain.SubscriptionStatusActive}, func(sub *domain.Subscription) {
+		sub.Status = domain.SubscriptionStatusPaused
+	})
+}
+
+// ResumeSubscription restarts a paused or suspended subscription. A renewal that fell due meanwhile is placed
+// by the next scheduler run.
+func (s *OrderService) ResumeSubscription(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	allowed := []string{domain.SubscriptionStatusPaused, domain.SubscriptionStatusSuspended}
+	return s.changeSubscription(ctx, subscriptionID, caller, allowed, func(sub *domain.Subscription) {
+		sub.Status = domain.SubscriptionStatusActive
+		sub.FailedAttempts = 0
+		sub.LastError = ""
+		if now := time.Now(); sub.NextRunAt.Before(now) {
+			sub.NextRunAt = now
+		}
+	})
+}
+
+// SkipNextRenewal moves the next renewal one interval later
+func (s *OrderService) SkipNextRenewal(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	allowed := []string{domain.SubscriptionStatusActive, domain.SubscriptionStatusPaused, domain.SubscriptionStatusSuspended}
+	return s.changeSubscription(ctx, subscriptionID, caller, allowed, func(sub *domain.Subscription) {
+		sub.NextRunAt = sub.NextRunAt.Add(sub.Interval())
+	})
+}
+
+// CancelSubscription ends a subscription for good. Orders it already placed are not affected.
+func (s *OrderService) CancelSubscription(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	allowed := []string{domain.SubscriptionStatusActive, domain.SubscriptionStatusPaused, domain.SubscriptionStatusSuspended}
+	return s.changeSubscription(ctx, subscriptionID, caller, allowed, func(sub *domain.Subscription) {
+		sub.Status = domain.SubscriptionStatusCancelled
+	})
+}
+
+// changeSubscription applies change to a subscription of the caller if it is in one of the allowed statuses
+func (s *OrderService) changeSubscription(ctx context.Context, subscriptionID string, caller domain.Caller, allowed []string, change func(sub *domain.Subscription)) (*domain.Subscription, error) {
+	subscription, err := s.getSubscriptionFor(ctx, subscriptionID, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	permitted := false
+	for _, status := range allowed {
+		permitted = permitted || subscription.Status == status
+	}
+	if !permitted {
+		return nil, fmt.Errorf("%w: subscription is %s", domain.ErrSubscriptionStatus, subscription.Status)
+	}
+
+	change(subscription)
+	if err := s.repo.UpdateSubscriptionSchedule(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// getSubscriptionFor loads a subscription of the caller. Subscriptions of other users are reported as not found.
+func (s *OrderService) getSubscriptionFor(ctx context.Context, subscriptionID string, caller domain.Caller) (*domain.Subscription, error) {
+	id, err := strconv.ParseUint(subscriptionID, 10, 64)
+	if err != nil {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	subscription, err := s.repo.GetSubscriptionByID(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !caller.IsAdmin() && subscription.UserID != caller.UserID {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// RenewDueSubscriptions places the orders of every active subscription that is due and returns how many were placed
+func (s *OrderService) RenewDueSubscriptions(ctx context.Context) (int, error) {
+	now := time.Now()
+	subscriptions, err := s.repo.GetDueSubscriptions(ctx, now, dueSubscriptionsBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due subscriptions: %w", err)
+	}
+
+	renewed := 0
+	for i := range subscriptions {
+		ok, err := s.renewSubscription(ctx, &subscriptions[i], now)
+		if err != nil {
+			return renewed, err
+		}
+		if ok {
+			renewed++
+		}
+	}
+	return renewed, nil
+}
+
+// renewSubscription places the order of one renewal through CreateOrder. A renewal only counts as done once
+// its order is paid (see recordRenewalOutcome). A failed renewal is retried after subscriptionRetryDelay until
+// it has failed subscriptionMaxAttempts times in a row, then the subscription is suspended.
+func (s *OrderService) renewSubscription(ctx context.Context, subscription *domain.Subscription, now time.Time) (bool, error) {
+	l := logger.ForContext(ctx).With(zap.Uint("subscriptionID", subscription.ID))
+
+	lines := make([]domain.OrderLine, len(subscription.Items))
+	for i, item := range subscription.Items {
+		lines[i] = domain.OrderLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+	}
+	address := subscription.ShippingAddress
+	// The key is unique per scheduled run, so a run repeated after a crash or by another instance reuses the order
+	runAt := subscription.NextRunAt
+	receipt, orderErr := s.CreateOrder(&domain.CreateOrderRequest{
+		IdempotencyKey:  fmt.Sprintf("subscription:%d:%d", subscription.ID, runAt.Unix()),
+		ShippingAddress: &address,
+		BuyerName:       subscription.BuyerName,
+		BuyerEmail:      subscription.BuyerEmail,
+		Lines:           lines,
+		SubscriptionID:  subscription.ID,
+	}, ctx, subscription.UserID)
+
+	if orderErr != nil {
+		s.failRenewal(ctx, subscription, orderErr.Error(), now)
+	} else {
+		subscription.LastOrderID = receipt.OrderID
+		// Keep the original cadence unless renewals fell more than an interval behind
+		subscription.NextRunAt = runAt.Add(subscription.Interval())
+		if !subscription.NextRunAt.After(now) {
+			subscription.NextRunAt = now.Add(subscription.Interval())
+		}
+		l.Info("Subscription renewal order placed", zap.Uint("orderID", receipt.OrderID), zap.Time("nextRunAt", subscription.NextRunAt))
+	}
+
+	if err := s.repo.UpdateSubscriptionSchedule(ctx, subscription); err != nil {
+		return false, fmt.Errorf("failed to update subscription %d: %w", subscription.ID, err)
+	}
+	return orderErr == nil, nil
+}
+
+// recordRenewalOutcome settles the renewal that placed order. A paid order clears the failed attempts, an
+// order that ended unpaid (failure says why) counts as a failed renewal. Orders that are not part of a
+// subscription, or were followed by a newer renewal, change nothing.
+func (s *OrderService) recordRenewalOutcome(ctx context.Context, repo repository.OrderRepository, order *domain.Order, failure string) error {
+	if order.SubscriptionID == 0 {
+		return nil
+	}
+	subscription, err := repo.GetSubscriptionByID(ctx, order.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get subscription %d: %w", order.SubscriptionID, err)
+	}
+	if subscription.LastOrderID > order.ID {
+		return nil
+	}
+
+	if failure == "" {
+		subscription.FailedAttempts = 0
+		subscription.LastError = ""
+		logger.ForContext(ctx).Info("Subscription renewed", zap.Uint("subscriptionID", subscription.ID), zap.Uint("orderID", order.ID))
+	} else {
+		s.failRenewal(ctx, subscription, failure, time.Now())
+	}
+	if err := repo.UpdateSubscriptionSchedule(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to update subscription %d: %w", subscription.ID, err)
+	}
+	return nil
+}
+
+// failRenewal counts a failed renewal. An active subscription retries it after subscriptionRetryDelay, or is
+// suspended once it failed subscriptionMaxAttempts times in a row.
+func (s *OrderService) failRenewal(ctx context.Context, subscription *domain.Subscription, reason string, now time.Time) {
+	l := logger.ForContext(ctx).With(zap.Uint("subscriptionID", subscription.ID))
+	subscription.FailedAttempts++
+	subscription.LastError = reason
+	if subscription.Status != domain.SubscriptionStatusActive {
+		return
+	}
+	if subscription.FailedAttempts >= uint(max(s.subscriptionMaxAttempts, 1)) {
+		subscription.Status = domain.SubscriptionStatusSuspended
+		l.Warn("subscription suspended after failed renewals", zap.Uint("attempts", subscription.FailedAttempts), zap.String("reason", reason))
+		return
+	}
+	subscription.NextRunAt = now.Add(subscriptionRetryDelay)
+	l.Warn("subscription renewal failed, will retry", zap.Uint("attempts", subscription.FailedAttempts), zap.String("reason", reason))
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"libs/logger"
+	"order-service/internal/domain"
 	"order-service/internal/infrastructure"
 	"order-service/internal/service"
 
@@ -31,7 +32,7 @@ func (d *DeliveryFailedWorker) ListenForDeliveryFailed(ctx context.Context) {
 			return nil
 		}
 
-		return d.s.UpdateOrderStatus(ctx, orderIDStr, "FAILED")
+		return d.s.UpdateOrderStatus(ctx, orderIDStr, domain.OrderStatusFailed, "stream:delivery:failed")
 	})
 }
 
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"libs/logger"
+	"order-service/internal/domain"
 	"order-service/internal/infrastructure"
 	"order-service/internal/service"
 
@@ -30,6 +31,6 @@ func (d *DeliverySuccessWorker) ListenForDeliverySuccess(ctx context.Context) {
                 zap.Any("raw_values", msg.Values))
 			return nil
 		}
-		return d.s.UpdateOrderStatus(ctx, orderIDStr, "SHIPPED")
+		return d.s.UpdateOrderStatus(ctx, orderIDStr, domain.OrderStatusDelivered, "stream:delivery:delivered")
 	})
 }
\ No newline at end of file
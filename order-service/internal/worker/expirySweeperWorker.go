@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"order-service/internal/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ExpirySweeperWorker struct {
+	service *service.OrderService
+}
+
+func NewExpirySweeperWorker(service *service.OrderService) *ExpirySweeperWorker {
+	return &ExpirySweeperWorker{service: service}
+}
+
+// StartExpirySweeper cancels orders whose payment deadline has passed every minute
+func (w *ExpirySweeperWorker) StartExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	logger.Log.Info("Starting order expiry sweeper", zap.Duration("interval", 1*time.Minute))
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Stopping order expiry sweeper")
+			return
+		case <-ticker.C:
+			expired, err := w.service.ExpireOverdueOrders(ctx)
+			if err != nil {
+				logger.Log.Error("order expiry sweep failed", zap.Int("expiredCount", expired), zap.Error(err))
+				continue
+			}
+			if expired > 0 {
+				logger.Log.Info("Order expiry sweep completed", zap.Int("expiredCount", expired))
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"order-service/internal/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type OutboxWorker struct {
+	service *service.OrderService
+}
+
+func NewOutboxWorker(service *service.OrderService) *OutboxWorker {
+	return &OutboxWorker{service: service}
+}
+
+func (w *OutboxWorker) ListenForOutboxMessages(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	logger.Log.Info("Starting outbox worker")
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Stopping outbox worker")
+			return
+		case <-ticker.C:
+			// Fetch pending outbox messages and publish them
+			outbox, err := w.service.GetPendingOutboxMessages(ctx)
+			if err != nil {
+				logger.Log.Error("failed to fetch pending outbox messages", zap.Error(err))
+				continue
+			}
+
+			for _, message := range outbox {
+				if ctx.Err() != nil {
+					logger.Log.Info("Stopping outbox worker")
+					return
+				}
+
+				if err := w.service.PublishOutboxMessage(ctx, message); err != nil {
+					logger.Log.Error("failed to publish outbox message",
+						zap.Uint("outboxID", message.ID),
+						zap.String("eventType", message.EventType),
+						zap.String("correlation_id", message.CorrelationID),
+						zap.Error(err),
+					)
+					// Stop here so later events are not published ahead of the failed one
+					break
+				}
+			}
+		}
+	}
+}
@@ -30,6 +30,6 @@ func (d *PaidSuccessWorker) ListenForPaidSuccess(ctx context.Context) {
 				zap.Any("raw_values", msg.Values))
 			return nil
 		}
-		return d.s.UpdateOrderToPaid(ctx, orderIDStr)
+		return d.s.UpdateOrderToPaid(ctx, orderIDStr, "stream:payment:success")
 	})
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"order-service/internal/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RefundRetryWorker struct {
+	service *service.OrderService
+}
+
+func NewRefundRetryWorker(service *service.OrderService) *RefundRetryWorker {
+	return &RefundRetryWorker{service: service}
+}
+
+// StartRefundRetry sends the refunds of cancelled order lines that payment service has not confirmed every minute
+func (w *RefundRetryWorker) StartRefundRetry(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	logger.Log.Info("Starting pending refund retry", zap.Duration("interval", 1*time.Minute))
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Stopping pending refund retry")
+			return
+		case <-ticker.C:
+			sent, err := w.service.RetryPendingRefunds(ctx)
+			if err != nil {
+				logger.Log.Error("pending refund retry failed", zap.Error(err))
+				continue
+			}
+			if sent > 0 {
+				logger.Log.Info("Pending refunds sent", zap.Int("sentCount", sent))
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"libs/logger"
+	"order-service/internal/domain"
 	"order-service/internal/infrastructure"
 	"order-service/internal/service"
 
@@ -30,6 +31,6 @@ func (d *StockInsufficientWorker) ListenForStockInsufficient(ctx context.Context
 				zap.Any("raw_values", msg.Values))
 			return nil
 		}
-		return d.s.UpdateOrderStatus(ctx, orderIDStr, "CANCELLED")
+		return d.s.UpdateOrderStatus(ctx, orderIDStr, domain.OrderStatusCancelled, "stream:stock:insufficient")
 	})
 }
@@ -2,7 +2,9 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"libs/logger"
+	"order-service/internal/domain"
 	"order-service/internal/infrastructure"
 	"order-service/internal/service"
 
@@ -30,6 +32,15 @@ func (d *StockreservedWorker) ListenForStockReserved(ctx context.Context) {
 				zap.Any("raw_values", msg.Values))
 			return nil
 		}
-		return d.s.ProcessAwaitingPaymentOrders(ctx, orderIDStr)
+		// Products and variants that could not be reserved, only present on partial reservations
+		var unavailable []domain.ItemKey
+		if raw, ok := msg.Values["unavailable_items"].(string); ok && raw != "" {
+			if err := json.Unmarshal([]byte(raw), &unavailable); err != nil {
+				logger.Log.Warn("dropping invalid stock reserved message: malformed unavailable_items",
+					zap.String("orderID", orderIDStr), zap.Error(err))
+				return nil
+			}
+		}
+		return d.s.ProcessAwaitingPaymentOrders(ctx, orderIDStr, unavailable, "stream:stock:reserved")
 	})
 }
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"order-service/internal/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type SubscriptionSchedulerWorker struct {
+	service *service.OrderService
+}
+
+func NewSubscriptionSchedulerWorker(service *service.OrderService) *SubscriptionSchedulerWorker {
+	return &SubscriptionSchedulerWorker{service: service}
+}
+
+// StartSubscriptionScheduler places the orders of due subscriptions every minute
+func (w *SubscriptionSchedulerWorker) StartSubscriptionScheduler(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	logger.Log.Info("Starting subscription scheduler", zap.Duration("interval", 1*time.Minute))
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Stopping subscription scheduler")
+			return
+		case <-ticker.C:
+			renewed, err := w.service.RenewDueSubscriptions(ctx)
+			if err != nil {
+				logger.Log.Error("subscription renewal run failed", zap.Int("renewedCount", renewed), zap.Error(err))
+				continue
+			}
+			if renewed > 0 {
+				logger.Log.Info("Subscription renewal run completed", zap.Int("renewedCount", renewed))
+			}
+		}
+	}
+}
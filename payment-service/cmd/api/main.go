@@ -63,7 +63,7 @@ func main() {
 	}
 
 	// Auto-migrate (creates the table if it doesn't exist)
-	db.AutoMigrate(&domain.Payment{})
+	db.AutoMigrate(&domain.Payment{}, &domain.PaymentRefund{})
 
 	// Midtrans Client
 	midtransClient := infrastructure.NewMidtransClient(cfg.MidtransServerKey, cfg.MidtransClientKey)
@@ -160,6 +160,21 @@ func main() {
 	cleanupWorker := worker.NewCleanupWorker(svc)
 	go cleanupWorker.StartCleanupJob(ctx)
 
+	// Initialize Redis Consumer Group
+	err = infrastructure.InitPaymentConsumerGroup(ctx, redisClient)
+	if err != nil {
+		logger.Log.Error("Failed to initialize consumer group", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Worker for voiding payments of cancelled orders
+	orderCancelledWorker := worker.NewOrderCancelledWorker(redisClient, svc)
+	go orderCancelledWorker.Listen(ctx)
+
+	// Worker for expiring payments of orders that passed their payment deadline
+	orderExpiredWorker := worker.NewOrderExpiredWorker(redisClient, svc)
+	go orderExpiredWorker.Listen(ctx)
+
 	// Setup signal handling for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
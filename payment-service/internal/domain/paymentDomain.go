@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,8 +13,35 @@ type Payment struct {
 	Amount        uint        `gorm:"not null" json:"amount"`
 	PaymentUrl    string         `gorm:"type:varchar(500)" json:"payment_url"`
 	SnapToken    string         `gorm:"type:varchar(255)" json:"snap_token"`
-	Status        string         `gorm:"type:varchar(50);not null;default:'PENDING'" json:"status" oneof:"PENDING,COMPLETED,FAILED"`
+	Status        string         `gorm:"type:varchar(50);not null;default:'PENDING'" json:"status" oneof:"PENDING,CHALLENGE,SUCCESS,FAILED,CANCELLED,EXPIRED,REFUNDED"`
+	// RefundedAmount is the sum of all refunds, a fully refunded payment moves to REFUNDED
+	RefundedAmount uint `gorm:"not null;default:0" json:"refunded_amount"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-}
\ No newline at end of file
+}
+
+const (
+	RefundStatusPending = "PENDING"
+	RefundStatusDone    = "DONE"
+)
+
+// PaymentRefund is one refund of a settled payment. RefundKey is chosen by the caller, so a retried
+// refund is recognised and never sent to Midtrans twice. A refund is saved as PENDING before it is
+// sent to Midtrans and moves to DONE once Midtrans accepted it.
+type PaymentRefund struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PaymentID uint      `gorm:"not null;index" json:"payment_id"`
+	OrderID   uint      `gorm:"not null;index" json:"order_id"`
+	RefundKey string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"refund_key"`
+	Amount    uint      `gorm:"not null" json:"amount"`
+	Reason    string    `gorm:"type:varchar(255)" json:"reason"`
+	Status    string    `gorm:"type:varchar(20);not null;default:'DONE'" json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	ErrPaymentNotRefundable = errors.New("payment is not settled")
+	ErrRefundExceedsPayment = errors.New("refund exceeds the amount left on the payment")
+)
@@ -2,8 +2,14 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"libs/pb"
+	"payment-service/internal/domain"
 	"payment-service/internal/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type PaymentGRPCServer struct {
@@ -16,9 +22,50 @@ func NewPaymentGRPCServer(service *service.PaymentService) *PaymentGRPCServer {
 }
 
 func (s *PaymentGRPCServer) GetPaymentURL(ctx context.Context, req *pb.GetPaymentRequest) (*pb.GetPaymentResponse, error) {
-	url, err := s.service.CreatePendingPayment(ctx, uint(req.OrderId), uint(req.Amount))
+	url, err := s.service.CreatePendingPayment(ctx, uint(req.OrderId), uint(req.Amount), uint(req.ExpiryMinutes))
 
 	return &pb.GetPaymentResponse{
 		PaymentUrl: url,
 	}, err
 }
+
+func (s *PaymentGRPCServer) GetPaymentStatus(ctx context.Context, req *pb.GetPaymentStatusRequest) (*pb.GetPaymentStatusResponse, error) {
+	payment, err := s.service.GetPaymentByOrderID(ctx, uint(req.OrderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "payment not found")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get payment")
+	}
+
+	return &pb.GetPaymentStatusResponse{
+		OrderId:   uint32(payment.OrderID),
+		Status:    payment.Status,
+		Amount:    uint64(payment.Amount),
+		CreatedAt: payment.CreatedAt.Unix(),
+		UpdatedAt: payment.UpdatedAt.Unix(),
+	}, nil
+}
+
+func (s *PaymentGRPCServer) RefundPayment(ctx context.Context, req *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+	if req.Amount == 0 || req.RefundKey == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "amount and refund_key are required")
+	}
+
+	payment, err := s.service.RefundPayment(ctx, uint(req.OrderId), uint(req.Amount), req.RefundKey, req.Reason)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "payment not found")
+		}
+		if errors.Is(err, domain.ErrPaymentNotRefundable) || errors.Is(err, domain.ErrRefundExceedsPayment) {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s", err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "could not refund payment")
+	}
+
+	return &pb.RefundPaymentResponse{
+		OrderId:        uint32(payment.OrderID),
+		Status:         payment.Status,
+		RefundedAmount: uint64(payment.RefundedAmount),
+	}, nil
+}
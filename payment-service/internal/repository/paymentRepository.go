@@ -11,6 +11,9 @@ type PaymentRepository interface {
 	GetPaymentByOrderID(orderID uint) (*domain.Payment, error)
 	UpdatePaymentStatus(orderID uint, status string) error
 	FindExpiredPendingPayments(expiryMinutes int) ([]*domain.Payment, error)
+	GetRefundByKey(refundKey string) (*domain.PaymentRefund, error)
+	AddPendingRefund(refund *domain.PaymentRefund) error
+	CompleteRefund(refund *domain.PaymentRefund) (*domain.Payment, error)
 }
 
 type PostgresRepository struct {
@@ -52,4 +55,55 @@ func (r *PostgresRepository) FindExpiredPendingPayments(expiryMinutes int) ([]*d
 		return nil, result.Error
 	}
 	return payments, nil
-}
\ No newline at end of file
+}
+
+func (r *PostgresRepository) GetRefundByKey(refundKey string) (*domain.PaymentRefund, error) {
+	var refund domain.PaymentRefund
+	if err := r.db.Where("refund_key = ?", refundKey).First(&refund).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// AddPendingRefund saves a refund as PENDING and adds it to the refunded amount of its payment, so the
+// amount stays reserved while Midtrans is asked. It fails with ErrRefundExceedsPayment if the payment has
+// less left than the refund.
+func (r *PostgresRepository) AddPendingRefund(refund *domain.PaymentRefund) error {
+	refund.Status = domain.RefundStatusPending
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Payment{}).
+			Where("id = ? AND refunded_amount + ? <= amount", refund.PaymentID, refund.Amount).
+			Update("refunded_amount", gorm.Expr("refunded_amount + ?", refund.Amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrRefundExceedsPayment
+		}
+		return tx.Create(refund).Error
+	})
+}
+
+// CompleteRefund marks a pending refund DONE once Midtrans accepted it. Its payment moves to REFUNDED
+// once nothing is left to refund.
+func (r *PostgresRepository) CompleteRefund(refund *domain.PaymentRefund) (*domain.Payment, error) {
+	var payment domain.Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.PaymentRefund{}).
+			Where("id = ?", refund.ID).
+			Update("status", domain.RefundStatusDone).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Payment{}).
+			Where("id = ? AND refunded_amount >= amount", refund.PaymentID).
+			Update("status", "REFUNDED").Error; err != nil {
+			return err
+		}
+		return tx.First(&payment, refund.PaymentID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = domain.RefundStatusDone
+	return &payment, nil
+}
@@ -4,6 +4,8 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -23,7 +25,7 @@ func openPaymentTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Skipf("skipping integration test, cannot connect to payment-db: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.Payment{}); err != nil {
+	if err := db.AutoMigrate(&domain.Payment{}, &domain.PaymentRefund{}); err != nil {
 		t.Fatalf("AutoMigrate() error = %v", err)
 	}
 	return db
@@ -57,3 +59,55 @@ func TestPaymentRepository_AddAndUpdateStatus_Integration(t *testing.T) {
 		t.Fatalf("expected payment status SUCCESS, got %s", got.Status)
 	}
 }
+
+func TestPaymentRepository_RefundsTrackRefundedAmount_Integration(t *testing.T) {
+	db := openPaymentTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	orderID := uint(time.Now().UnixNano()%100000) + 100000
+	if err := repo.AddPayment(orderID, 1000, "https://example.com/pay", "SUCCESS"); err != nil {
+		t.Fatalf("AddPayment() error = %v", err)
+	}
+	payment, err := repo.GetPaymentByOrderID(orderID)
+	if err != nil {
+		t.Fatalf("GetPaymentByOrderID() error = %v", err)
+	}
+
+	key := func(line int) string { return fmt.Sprintf("order-%d-item-%d-%d", orderID, line, time.Now().UnixNano()) }
+	first := &domain.PaymentRefund{PaymentID: payment.ID, OrderID: orderID, RefundKey: key(1), Amount: 400}
+	if err := repo.AddPendingRefund(first); err != nil {
+		t.Fatalf("AddPendingRefund() error = %v", err)
+	}
+	saved, err := repo.GetRefundByKey(first.RefundKey)
+	if err != nil {
+		t.Fatalf("GetRefundByKey() error = %v", err)
+	}
+	if saved.Status != domain.RefundStatusPending {
+		t.Fatalf("expected the refund to be PENDING before Midtrans answers, got %s", saved.Status)
+	}
+
+	// The pending refund already holds its amount
+	if err := repo.AddPendingRefund(&domain.PaymentRefund{PaymentID: payment.ID, OrderID: orderID, RefundKey: key(2), Amount: 700}); !errors.Is(err, domain.ErrRefundExceedsPayment) {
+		t.Fatalf("expected ErrRefundExceedsPayment, got %v", err)
+	}
+
+	got, err := repo.CompleteRefund(first)
+	if err != nil {
+		t.Fatalf("CompleteRefund() error = %v", err)
+	}
+	if got.RefundedAmount != 400 || got.Status != "SUCCESS" {
+		t.Fatalf("expected 400 refunded and payment still SUCCESS, got %d %s", got.RefundedAmount, got.Status)
+	}
+
+	last := &domain.PaymentRefund{PaymentID: payment.ID, OrderID: orderID, RefundKey: key(3), Amount: 600}
+	if err := repo.AddPendingRefund(last); err != nil {
+		t.Fatalf("AddPendingRefund() error = %v", err)
+	}
+	got, err = repo.CompleteRefund(last)
+	if err != nil {
+		t.Fatalf("CompleteRefund() error = %v", err)
+	}
+	if got.RefundedAmount != 1000 || got.Status != "REFUNDED" {
+		t.Fatalf("expected payment fully REFUNDED, got %d %s", got.RefundedAmount, got.Status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"libs/logger"
 	"payment-service/internal/config"
@@ -13,8 +14,10 @@ import (
 	"strconv"
 
 	"github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/midtrans/midtrans-go/snap"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type PaymentService struct {
@@ -27,7 +30,7 @@ func NewPaymentService(repo repository.PaymentRepository, eventRepo repository.E
 	return &PaymentService{repo: repo, eventRepo: eventRepo, midtransClient: midtransClient}
 }
 
-func (s *PaymentService) CreatePendingPayment(ctx context.Context, orderID uint, amount uint) (string, error) {
+func (s *PaymentService) CreatePendingPayment(ctx context.Context, orderID uint, amount uint, expiryMinutes uint) (string, error) {
 	l := logger.ForContext(ctx)
 	// Initiate Snap request
 	req := &snap.Request{
@@ -39,6 +42,13 @@ func (s *PaymentService) CreatePendingPayment(ctx context.Context, orderID uint,
 			Secure: true,
 		},
 	}
+	// Close the Snap page together with the order's payment deadline
+	if expiryMinutes > 0 {
+		req.Expiry = &snap.ExpiryDetails{
+			Unit:     "minute",
+			Duration: int64(expiryMinutes),
+		}
+	}
 
 	// Request create Snap transaction to Midtrans
 	snapResp, midtransErr := s.midtransClient.SnapClient.CreateTransaction(req)
@@ -175,6 +185,17 @@ func (s *PaymentService) handleFailedPayment(ctx context.Context, orderID string
 		return fmt.Errorf("invalid order id for failed payment handling: %w", err)
 	}
 
+	// Cancelling or expiring a payment here makes Midtrans send a cancel or expire notification after it,
+	// the order was already told and its stock released, so a closed payment is not failed again
+	payment, err := s.repo.GetPaymentByOrderID(uint(orderIDUint))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get payment for order %s: %w", orderID, err)
+	}
+	if payment != nil && isClosedPaymentStatus(payment.Status) {
+		l.Info("Payment already closed, skipping failure", zap.String("orderID", orderID), zap.String("payment_status", payment.Status))
+		return nil
+	}
+
 	// Send event to order service to update order status to PAYMENT_FAILED
 	err = s.eventRepo.PublishPaymentEvent(ctx, &domain.PaymentEvent{
 		OrderID:       uint(orderIDUint),
@@ -194,6 +215,134 @@ func (s *PaymentService) handleFailedPayment(ctx context.Context, orderID string
 	return nil
 }
 
+// isClosedPaymentStatus reports whether a payment with status ended without being paid
+func isClosedPaymentStatus(status string) bool {
+	switch status {
+	case "CANCELLED", "EXPIRED", "FAILED":
+		return true
+	}
+	return false
+}
+
+func (s *PaymentService) GetPaymentByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error) {
+	l := logger.ForContext(ctx)
+	payment, err := s.repo.GetPaymentByOrderID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment for order %d: %w", orderID, err)
+	}
+	l.Info("Payment retrieved successfully", zap.Uint("orderID", orderID), zap.String("payment_status", payment.Status))
+	return payment, nil
+}
+
+// CancelPayment voids the open Midtrans transaction of an order the customer cancelled
+func (s *PaymentService) CancelPayment(ctx context.Context, orderID uint) error {
+	return s.closePayment(ctx, orderID, "CANCELLED", func(orderIDStr string) *midtrans.Error {
+		_, err := s.midtransClient.CoreClient.CancelTransaction(orderIDStr)
+		return err
+	})
+}
+
+// ExpirePayment expires the open Midtrans transaction of an order that was not paid before its deadline
+func (s *PaymentService) ExpirePayment(ctx context.Context, orderID uint) error {
+	return s.closePayment(ctx, orderID, "EXPIRED", func(orderIDStr string) *midtrans.Error {
+		_, err := s.midtransClient.CoreClient.ExpireTransaction(orderIDStr)
+		return err
+	})
+}
+
+// closePayment voids an open payment at Midtrans and records the final status
+func (s *PaymentService) closePayment(ctx context.Context, orderID uint, status string, void func(orderID string) *midtrans.Error) error {
+	l := logger.ForContext(ctx)
+	payment, err := s.repo.GetPaymentByOrderID(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Order was closed before a payment was ever requested
+			l.Info("No payment to close for order", zap.Uint("orderID", orderID), zap.String("payment_status", status))
+			return nil
+		}
+		return fmt.Errorf("failed to get payment for order %d: %w", orderID, err)
+	}
+
+	if payment.Status != "PENDING" && payment.Status != "CHALLENGE" {
+		l.Warn("Payment is no longer open, skipping", zap.Uint("orderID", orderID), zap.String("payment_status", payment.Status))
+		return nil
+	}
+
+	orderIDStr := fmt.Sprintf("%d", orderID)
+	midtransErr := void(orderIDStr)
+	// 404 means the customer never opened the Snap page, so there is nothing to void at Midtrans
+	if midtransErr != nil && midtransErr.GetStatusCode() != 404 {
+		return fmt.Errorf("failed to void midtrans transaction %s: %w", orderIDStr, midtransErr)
+	}
+
+	err = s.repo.UpdatePaymentStatus(orderID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update payment status to %s: %w", status, err)
+	}
+
+	l.Info("Payment closed", zap.Uint("orderID", orderID), zap.String("payment_status", status))
+	return nil
+}
+
+// RefundPayment refunds amount of the settled payment of an order through Midtrans. Refunds are told apart
+// by refundKey, so repeating a refund returns the payment as it is without refunding it again.
+func (s *PaymentService) RefundPayment(ctx context.Context, orderID uint, amount uint, refundKey string, reason string) (*domain.Payment, error) {
+	l := logger.ForContext(ctx)
+	payment, err := s.repo.GetPaymentByOrderID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment for order %d: %w", orderID, err)
+	}
+
+	refund, err := s.repo.GetRefundByKey(refundKey)
+	switch {
+	case err == nil && refund.Status == domain.RefundStatusDone:
+		l.Info("Refund already made, skipping", zap.Uint("orderID", orderID), zap.String("refundKey", refundKey))
+		return payment, nil
+	case err == nil:
+		// An earlier attempt saved the refund but did not hear back from Midtrans. Midtrans knows the
+		// refund key, so asking again does not refund twice.
+		l.Info("Retrying pending refund", zap.Uint("orderID", orderID), zap.String("refundKey", refundKey))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if payment.Status != "SUCCESS" {
+			return nil, fmt.Errorf("%w: payment is %s", domain.ErrPaymentNotRefundable, payment.Status)
+		}
+		if payment.RefundedAmount+amount > payment.Amount {
+			return nil, fmt.Errorf("%w: %d of %d already refunded", domain.ErrRefundExceedsPayment, payment.RefundedAmount, payment.Amount)
+		}
+		refund = &domain.PaymentRefund{
+			PaymentID: payment.ID,
+			OrderID:   orderID,
+			RefundKey: refundKey,
+			Amount:    amount,
+			Reason:    reason,
+		}
+		if err := s.repo.AddPendingRefund(refund); err != nil {
+			return nil, fmt.Errorf("failed to save refund for order %d: %w", orderID, err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to get refund %s: %w", refundKey, err)
+	}
+
+	orderIDStr := fmt.Sprintf("%d", orderID)
+	_, midtransErr := s.midtransClient.CoreClient.RefundTransaction(orderIDStr, &coreapi.RefundReq{
+		RefundKey: refund.RefundKey,
+		Amount:    int64(refund.Amount),
+		Reason:    refund.Reason,
+	})
+	if midtransErr != nil {
+		return nil, fmt.Errorf("failed to refund midtrans transaction %s: %w", orderIDStr, midtransErr)
+	}
+
+	payment, err = s.repo.CompleteRefund(refund)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete refund for order %d: %w", orderID, err)
+	}
+
+	l.Info("Payment refunded", zap.Uint("orderID", orderID), zap.Uint("amount", refund.Amount),
+		zap.Uint("refundedAmount", payment.RefundedAmount), zap.String("payment_status", payment.Status))
+	return payment, nil
+}
+
 // CleanupExpiredPayments finds and processes payments that are stuck in PENDING status
 // This handles cases where webhooks were missed due to downtime or network issues
 func (s *PaymentService) CleanupExpiredPayments(ctx context.Context) error {
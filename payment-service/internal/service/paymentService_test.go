@@ -2,21 +2,29 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"payment-service/internal/domain"
+
+	"gorm.io/gorm"
 )
 
 type mockPaymentRepository struct {
 	updatedOrderID uint
 	updatedStatus  string
+	payment        *domain.Payment
+	paymentErr     error
+	refund         *domain.PaymentRefund
+	addedRefund    *domain.PaymentRefund
+	addRefundErr   error
 }
 
 func (m *mockPaymentRepository) AddPayment(orderID uint, amount uint, paymentURL string, status string) error {
 	return nil
 }
 func (m *mockPaymentRepository) GetPaymentByOrderID(orderID uint) (*domain.Payment, error) {
-	return nil, nil
+	return m.payment, m.paymentErr
 }
 func (m *mockPaymentRepository) UpdatePaymentStatus(orderID uint, status string) error {
 	m.updatedOrderID = orderID
@@ -26,6 +34,19 @@ func (m *mockPaymentRepository) UpdatePaymentStatus(orderID uint, status string)
 func (m *mockPaymentRepository) FindExpiredPendingPayments(expiryMinutes int) ([]*domain.Payment, error) {
 	return nil, nil
 }
+func (m *mockPaymentRepository) GetRefundByKey(refundKey string) (*domain.PaymentRefund, error) {
+	if m.refund == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.refund, nil
+}
+func (m *mockPaymentRepository) AddPendingRefund(refund *domain.PaymentRefund) error {
+	m.addedRefund = refund
+	return m.addRefundErr
+}
+func (m *mockPaymentRepository) CompleteRefund(refund *domain.PaymentRefund) (*domain.Payment, error) {
+	return m.payment, nil
+}
 
 type mockPaymentEventRepository struct {
 	publishedEvent *domain.PaymentEvent
@@ -69,3 +90,110 @@ func TestHandleFailedPaymentPublishesFailedAndUpdatesStatus(t *testing.T) {
 		t.Fatalf("unexpected published event: %#v", eventRepo.publishedEvent)
 	}
 }
+
+func TestHandleFailedPaymentSkipsClosedPayment(t *testing.T) {
+	for _, status := range []string{"CANCELLED", "EXPIRED", "FAILED"} {
+		repo := &mockPaymentRepository{payment: &domain.Payment{ID: 4, OrderID: 9, Status: status}}
+		eventRepo := &mockPaymentEventRepository{}
+		svc := NewPaymentService(repo, eventRepo, nil)
+
+		if err := svc.handleFailedPayment(context.Background(), "9"); err != nil {
+			t.Fatalf("handleFailedPayment() error = %v", err)
+		}
+		if eventRepo.publishedEvent != nil || repo.updatedStatus != "" {
+			t.Fatalf("expected a %s payment left alone, got event=%#v status=%s", status, eventRepo.publishedEvent, repo.updatedStatus)
+		}
+	}
+}
+
+func TestCancelPaymentIgnoresOrdersWithoutPayment(t *testing.T) {
+	repo := &mockPaymentRepository{paymentErr: gorm.ErrRecordNotFound}
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	if err := svc.CancelPayment(context.Background(), 12); err != nil {
+		t.Fatalf("CancelPayment() error = %v", err)
+	}
+	if repo.updatedStatus != "" {
+		t.Fatalf("did not expect a status update, got %s", repo.updatedStatus)
+	}
+}
+
+func TestCancelPaymentSkipsSettledPayment(t *testing.T) {
+	repo := &mockPaymentRepository{payment: &domain.Payment{OrderID: 13, Status: "SUCCESS"}}
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	if err := svc.CancelPayment(context.Background(), 13); err != nil {
+		t.Fatalf("CancelPayment() error = %v", err)
+	}
+	if repo.updatedStatus != "" {
+		t.Fatalf("did not expect a settled payment to be cancelled, got %s", repo.updatedStatus)
+	}
+}
+
+func TestExpirePaymentSkipsSettledPayment(t *testing.T) {
+	repo := &mockPaymentRepository{payment: &domain.Payment{OrderID: 14, Status: "SUCCESS"}}
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	if err := svc.ExpirePayment(context.Background(), 14); err != nil {
+		t.Fatalf("ExpirePayment() error = %v", err)
+	}
+	if repo.updatedStatus != "" {
+		t.Fatalf("did not expect a settled payment to be expired, got %s", repo.updatedStatus)
+	}
+}
+
+func TestRefundPaymentRequiresSettledPayment(t *testing.T) {
+	repo := &mockPaymentRepository{payment: &domain.Payment{ID: 3, OrderID: 15, Amount: 1000, Status: "PENDING"}}
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	_, err := svc.RefundPayment(context.Background(), 15, 200, "order-15-item-1", "out of stock")
+	if !errors.Is(err, domain.ErrPaymentNotRefundable) {
+		t.Fatalf("expected ErrPaymentNotRefundable, got %v", err)
+	}
+	if repo.addedRefund != nil {
+		t.Fatal("did not expect a refund to be recorded")
+	}
+}
+
+func TestRefundPaymentRejectsMoreThanIsLeft(t *testing.T) {
+	repo := &mockPaymentRepository{payment: &domain.Payment{ID: 3, OrderID: 16, Amount: 1000, RefundedAmount: 900, Status: "SUCCESS"}}
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	_, err := svc.RefundPayment(context.Background(), 16, 200, "order-16-item-1", "out of stock")
+	if !errors.Is(err, domain.ErrRefundExceedsPayment) {
+		t.Fatalf("expected ErrRefundExceedsPayment, got %v", err)
+	}
+}
+
+func TestRefundPaymentSkipsRefundKeyAlreadyUsed(t *testing.T) {
+	repo := &mockPaymentRepository{
+		payment: &domain.Payment{ID: 3, OrderID: 17, Amount: 1000, RefundedAmount: 200, Status: "SUCCESS"},
+		refund:  &domain.PaymentRefund{PaymentID: 3, OrderID: 17, RefundKey: "order-17-item-1", Amount: 200, Status: domain.RefundStatusDone},
+	}
+	// A nil Midtrans client makes the test fail if the refund is sent again
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	payment, err := svc.RefundPayment(context.Background(), 17, 200, "order-17-item-1", "out of stock")
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	if payment.RefundedAmount != 200 || repo.addedRefund != nil {
+		t.Fatalf("expected the earlier refund to be kept, got refunded=%d added=%#v", payment.RefundedAmount, repo.addedRefund)
+	}
+}
+
+func TestRefundPaymentIsNotSentWhenThePendingRefundCannotBeSaved(t *testing.T) {
+	repo := &mockPaymentRepository{
+		payment:      &domain.Payment{ID: 3, OrderID: 18, Amount: 1000, Status: "SUCCESS"},
+		addRefundErr: errors.New("connection refused"),
+	}
+	// A nil Midtrans client makes the test fail if the refund is sent before it is saved
+	svc := NewPaymentService(repo, &mockPaymentEventRepository{}, nil)
+
+	if _, err := svc.RefundPayment(context.Background(), 18, 200, "order-18-item-1", "out of stock"); err == nil {
+		t.Fatal("expected an error when the refund cannot be saved")
+	}
+	if repo.addedRefund == nil || repo.addedRefund.RefundKey != "order-18-item-1" || repo.addedRefund.Amount != 200 {
+		t.Fatalf("expected the refund to be saved under its key first, got %#v", repo.addedRefund)
+	}
+}
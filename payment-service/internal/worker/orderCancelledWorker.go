@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"payment-service/internal/infrastructure"
+	"payment-service/internal/service"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type OrderCancelledWorker struct {
+	s *service.PaymentService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewOrderCancelledWorker(brokerRedis *redis.Client, service *service.PaymentService) *OrderCancelledWorker {
+	return &OrderCancelledWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:orders:cancelled", "stream:orders:cancelled:dlq", "payment-group", "order-cancelled-worker"),
+	}
+}
+
+func (d *OrderCancelledWorker) Listen(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderIDStr, ok := msg.Values["order_id"].(string)
+		if !ok {
+			logger.Log.Warn("dropping invalid order cancelled message: missing order_id",
+				zap.Any("raw_values", msg.Values),
+			)
+			return nil
+		}
+		orderID, err := strconv.ParseUint(orderIDStr, 10, 64)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order cancelled message: invalid order_id",
+				zap.String("orderID", orderIDStr),
+				zap.Any("raw_values", msg.Values),
+			)
+			return nil
+		}
+
+		return d.s.CancelPayment(ctx, uint(orderID))
+	})
+}
@@ -78,6 +78,11 @@ func main() {
 	// Auto-migrate (creates the table if it doesn't exist)
 	db.AutoMigrate(&domain.Product{})
 	db.AutoMigrate(&domain.Category{})
+	db.AutoMigrate(&domain.ProductOption{}, &domain.ProductVariant{})
+	db.AutoMigrate(&domain.ProductImage{})
+	db.AutoMigrate(&domain.StockReservation{})
+	db.AutoMigrate(&domain.StockMovement{})
+	db.AutoMigrate(&domain.Warehouse{}, &domain.WarehouseStock{})
 
 	// Seed initial data
 	database.SeedData(db)
@@ -87,10 +92,31 @@ func main() {
 
 	// Repository Service, and handlers
 	repo := repository.NewPostgresRepository(db)
+	if err := repo.SetupSearch(); err != nil {
+		logger.Log.Error("Failed to set up product search", zap.Error(err))
+		os.Exit(1)
+	}
+	if err := repo.SetupWarehouses(); err != nil {
+		logger.Log.Error("Failed to set up warehouses", zap.Error(err))
+		os.Exit(1)
+	}
+	if err := repo.SetupLedger(); err != nil {
+		logger.Log.Error("Failed to set up stock ledger", zap.Error(err))
+		os.Exit(1)
+	}
+	if !domain.IsWarehouseStrategy(cfg.WarehouseStrategy) {
+		logger.Log.Error("Invalid warehouse strategy, expected priority, nearest or split", zap.String("strategy", cfg.WarehouseStrategy))
+		os.Exit(1)
+	}
 	eventRepo := repository.NewRedisRepository(redisBrokerClient)
-	svc := service.NewProductService(repo, eventRepo)
+	imageStorage := repository.NewLocalImageStorage(cfg.ImageDir, cfg.ImageBaseURL)
+	svc := service.NewProductService(repo, eventRepo, imageStorage, service.Settings{
+		ReservationTTL:    time.Duration(cfg.StockReservationTTLMinutes) * time.Minute,
+		WarehouseStrategy: cfg.WarehouseStrategy,
+	})
 	ProductHandler := handler.NewProductHandler(svc)
 	CategoryHandler := handler.NewCategoryHandler(svc)
+	WarehouseHandler := handler.NewWarehouseHandler(svc)
 
 	// Create cancellable context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -110,6 +136,26 @@ func main() {
 	stockInsufficientWorker := worker.NewPaymentFailedWorker(redisBrokerClient, svc)
 	go stockInsufficientWorker.ListenForPaymentFailures(ctx)
 
+	// Worker for releasing stock of cancelled orders
+	orderCancelledWorker := worker.NewOrderCancelledWorker(redisBrokerClient, svc)
+	go orderCancelledWorker.ListenForOrderCancellations(ctx)
+
+	// Worker for releasing stock of orders that expired unpaid
+	orderExpiredWorker := worker.NewOrderExpiredWorker(redisBrokerClient, svc)
+	go orderExpiredWorker.ListenForOrderExpirations(ctx)
+
+	// Worker for releasing stock of single lines cancelled from an order
+	orderItemsCancelledWorker := worker.NewOrderItemsCancelledWorker(redisBrokerClient, svc)
+	go orderItemsCancelledWorker.ListenForOrderItemCancellations(ctx)
+
+	// Worker for taking the reserved stock of paid orders off the shelf
+	orderPaidWorker := worker.NewOrderPaidWorker(redisBrokerClient, svc)
+	go orderPaidWorker.ListenForOrderPayments(ctx)
+
+	// Worker for releasing stock reservations of orders that were not paid in time
+	reservationSweeperWorker := worker.NewReservationSweeperWorker(svc)
+	go reservationSweeperWorker.StartReservationSweeper(ctx)
+
 	r := gin.New()
 	r.Use(sharedMiddleware.GinLogger())
 
@@ -127,7 +173,20 @@ func main() {
 			adminRoutes.POST("/products", ProductHandler.Create)
 			adminRoutes.PUT("/products/:id", ProductHandler.Update)
 			adminRoutes.DELETE("/products/:id", ProductHandler.Delete)
+			adminRoutes.POST("/products/:id/variants", ProductHandler.AddVariant)
+			adminRoutes.PUT("/products/:id/variants/:variantId", ProductHandler.UpdateVariant)
+			adminRoutes.DELETE("/products/:id/variants/:variantId", ProductHandler.DeleteVariant)
+			adminRoutes.POST("/products/:id/images", ProductHandler.UploadImage)
+			adminRoutes.PUT("/products/:id/images", ProductHandler.ReorderImages)
+			adminRoutes.DELETE("/products/:id/images/:imageId", ProductHandler.DeleteImage)
+			adminRoutes.GET("/products/:id/reservations", ProductHandler.GetReservations)
+			adminRoutes.GET("/products/:id/stock-movements", ProductHandler.GetStockHistory)
+			adminRoutes.GET("/products/:id/warehouse-stock", WarehouseHandler.GetProductStock)
 			adminRoutes.POST("/categories", CategoryHandler.Create)
+			adminRoutes.POST("/warehouses", WarehouseHandler.Create)
+			adminRoutes.GET("/warehouses", WarehouseHandler.List)
+			adminRoutes.PUT("/warehouses/:id", WarehouseHandler.Update)
+			adminRoutes.PUT("/warehouses/:id/stock", WarehouseHandler.SetStock)
 		}
 
 		// public routes
@@ -135,6 +194,9 @@ func main() {
 		api.GET("/products/:id", ProductHandler.GetByID)
 	}
 
+	// Uploaded product images
+	r.Static(config.ImageRoute, cfg.ImageDir)
+
 	// Swagger Documentation Route
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
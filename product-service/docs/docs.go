@@ -94,7 +94,7 @@ const docTemplate = `{
         },
         "/products": {
             "get": {
-                "description": "Get products with pagination and filters",
+                "description": "Get products with pagination and filters. The search term is matched against product names, descriptions and category names, words may be prefixes and slightly misspelt names still match. Search results carry highlighted snippets. With facets=true the response also counts the matching products per category, price range and availability, each facet ignoring its own filter.",
                 "consumes": [
                     "application/json"
                 ],
@@ -122,32 +122,55 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Search by product name",
+                        "description": "Full-text search over name, description and category names",
                         "name": "search",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Filter by category ID",
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Filter by category IDs, repeated or comma separated",
                         "name": "category_id",
                         "in": "query"
                     },
                     {
-                        "type": "number",
+                        "type": "string",
+                        "default": "any",
+                        "description": "Match any or all of the categories (any/all)",
+                        "name": "category_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
                         "description": "Minimum price",
                         "name": "min_price",
                         "in": "query"
                     },
                     {
-                        "type": "number",
+                        "type": "integer",
                         "description": "Maximum price",
                         "name": "max_price",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "description": "Only products in stock (true) or out of stock (false)",
+                        "name": "in_stock",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Include facet counts",
+                        "name": "facets",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "default": "created_at",
-                        "description": "Sort field",
+                        "description": "Sort field: relevance, created_at, updated_at, name, price or stock. Defaults to relevance when searching, created_at otherwise",
                         "name": "sort_by",
                         "in": "query"
                     },
@@ -166,6 +189,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/product-service_internal_domain.PaginatedProducts"
                         }
                     },
+                    "400": {
+                        "description": "invalid filter or sort field",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "could not retrieve products",
                         "schema": {
@@ -180,7 +209,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new product (Admin only)",
+                "description": "Create a new product (Admin only). A product sold in variants lists its options, such as size and color, and a variant per SKU with its own price, stock and barcode. Its price and stock are then taken from the variants.",
                 "consumes": [
                     "application/json"
                 ],
@@ -210,7 +239,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request body / missing required fields / invalid category ID",
+                        "description": "Invalid request body / missing required fields / invalid category ID / invalid product variant",
                         "schema": {
                             "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
                         }
@@ -297,7 +326,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update product details (Admin only)",
+                "description": "Update product details (Admin only). Price and stock of a product with variants are updated per variant. A new stock is made up by the default warehouse, the stock of other warehouses is set per warehouse.",
                 "consumes": [
                     "application/json"
                 ],
@@ -334,7 +363,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request body / price or stock of a product with variants / invalid warehouse stock",
                         "schema": {
                             "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
                         }
@@ -424,182 +453,1639 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "product-service_internal_domain.Category": {
-            "type": "object",
-            "required": [
-                "name"
-            ],
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "products": {
-                    "description": "Inverse association",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/product-service_internal_domain.Product"
+        },
+        "/products/{id}/images": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of the images of a product (Admin only). Every image of the product must be listed once, the first one becomes the main image.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Reorder product images",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Image IDs in display order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ReorderImagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Images in their new order",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ImagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body / image order must list every image of the product once",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not reorder images",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
                     }
-                },
-                "updated_at": {
-                    "type": "string"
                 }
-            }
-        },
-        "product-service_internal_domain.CategoryResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a JPEG, PNG or GIF of up to 5 MB as multipart form field \"image\" (Admin only). The image is added after the existing images of the product, with a thumbnail of at most 320x320 pixels.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Upload a product image",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Image file",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Image uploaded successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ImageSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID / missing image / invalid product image",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "image is too large",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not upload image",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "product-service_internal_domain.CategorySuccessResponse": {
-            "type": "object",
-            "properties": {
-                "category": {
-                    "$ref": "#/definitions/product-service_internal_domain.Category"
-                },
-                "message": {
-                    "type": "string"
+        "/products/{id}/images/{imageId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove an image from a product and delete its files (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete a product image",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Image ID",
+                        "name": "imageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "image not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not delete image",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "product-service_internal_domain.CreateProductRequest": {
-            "type": "object",
-            "required": [
+        "/products/{id}/reservations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Show the stock held for orders of a product along with its stock on hand, reserved and available (Admin only). Active reservations hold stock until their order is paid, cancelled or the reservation expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "List product stock reservations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "ACTIVE",
+                            "COMMITTED",
+                            "RELEASED",
+                            "EXPIRED",
+                            "RETURNED"
+                        ],
+                        "type": "string",
+                        "description": "Reservation status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservations of the product, newest first",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ProductReservations"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID / invalid reservation status",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not get reservations",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/stock-movements": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reconstruct the stock history of a product from the stock ledger (Admin only). Every change to the stock on hand or reserved is a movement, listed newest first with the stock on hand and reserved after it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get product stock history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only the movements of this variant",
+                        "name": "variant_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "OPENING",
+                            "ADJUST",
+                            "RESERVE",
+                            "RELEASE",
+                            "EXPIRE",
+                            "COMMIT",
+                            "RETURN"
+                        ],
+                        "type": "string",
+                        "description": "Movement reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock movements of the product, newest first",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.StockHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID / invalid variant ID / invalid stock movement reason",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not get stock history",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a variant to a product created with options (Admin only). The variant sets a value for every option, values the product did not have yet are added to its options.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Add a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant data",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.VariantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Variant added successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.VariantSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body / invalid product variant",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "SKU already exists",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not add variant",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/variants/{variantId}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the barcode, price or stock of a variant (Admin only). A new stock is made up by the default warehouse, the stock of other warehouses is set per warehouse.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Update a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant update data",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.UpdateVariantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Variant updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.VariantSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body / invalid warehouse stock",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "variant not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not update variant",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stop selling a variant (Admin only). The last variant of a product cannot be deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete a product variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Variant deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID / last variant of the product",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "variant not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not delete variant",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/warehouse-stock": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the stock on hand and reserved of a product, or of each of its variants, in every warehouse keeping it (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Get product stock per warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock of the product per warehouse",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ProductWarehouseStocks"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "product not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not get warehouse stock",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the warehouses by priority, the order they are reserved from (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "List warehouses",
+                "responses": {
+                    "200": {
+                        "description": "Warehouses by priority",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehousesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not list warehouses",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a warehouse stock is kept and shipped from (Admin only). Warehouses with a lower priority are reserved from first, the first one is the default warehouse.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Create a warehouse",
+                "parameters": [
+                    {
+                        "description": "Warehouse data",
+                        "name": "warehouse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehouseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Warehouse created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehouseSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "warehouse already exists",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not create warehouse",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the name, postal code or priority of a warehouse (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Update a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Warehouse update data",
+                        "name": "warehouse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.UpdateWarehouseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Warehouse updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehouseSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid warehouse ID / invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "warehouse not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not update warehouse",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/warehouses/{id}/stock": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the stock on hand of a product without variants, or of one variant, in a warehouse (Admin only). The stock of the product is that of all warehouses. Stock held by active reservations in the warehouse cannot be taken away.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Set the stock kept in a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Stock data",
+                        "name": "stock",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehouseStockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Warehouse stock updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.WarehouseStockSuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid warehouse ID / invalid request body / invalid product variant / invalid warehouse stock",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Access denied: Admins only",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "warehouse not found / product not found / variant not found",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "could not update warehouse stock",
+                        "schema": {
+                            "$ref": "#/definitions/product-service_internal_domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "product-service_internal_domain.AvailabilityFacet": {
+            "type": "object",
+            "properties": {
+                "in_stock": {
+                    "type": "integer"
+                },
+                "out_of_stock": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.Category": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "products": {
+                    "description": "Inverse association",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.Product"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.CategoryFacet": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.CategoryResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.CategorySuccessResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "$ref": "#/definitions/product-service_internal_domain.Category"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.CreateProductRequest": {
+            "type": "object",
+            "required": [
                 "category_ids",
+                "name"
+            ],
+            "properties": {
+                "category_ids": {
+                    "description": "User only sends [1, 2, 3]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductOptionRequest"
+                    }
+                },
+                "price": {
+                    "description": "Price and Stock are taken from the variants when there are any",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.VariantRequest"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.ImageSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "$ref": "#/definitions/product-service_internal_domain.ProductImage"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.ImagesResponse": {
+            "type": "object",
+            "properties": {
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductImage"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.PaginatedProducts": {
+            "type": "object",
+            "properties": {
+                "facets": {
+                    "description": "Facets is only set when requested with facets=true",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/product-service_internal_domain.ProductFacets"
+                        }
+                    ]
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.PriceRangeFacet": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "integer"
+                },
+                "min": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.Product": {
+            "type": "object",
+            "required": [
                 "name",
                 "price",
                 "stock"
             ],
             "properties": {
-                "category_ids": {
-                    "description": "User only sends [1, 2, 3]",
+                "categories": {
+                    "description": "Many-to-Many association",
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/product-service_internal_domain.Category"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductImage"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options and Variants are only set on products sold in several variants",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductOption"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductVariant"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ProductDataResponse": {
+            "type": "object",
+            "properties": {
+                "product": {
+                    "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                }
+            }
+        },
+        "product-service_internal_domain.ProductFacets": {
+            "type": "object",
+            "properties": {
+                "availability": {
+                    "$ref": "#/definitions/product-service_internal_domain.AvailabilityFacet"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.CategoryFacet"
+                    }
+                },
+                "price_ranges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.PriceRangeFacet"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ProductHighlight": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.ProductImage": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "thumbnail_url": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.ProductOption": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ProductOptionRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "values"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "values": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ProductReservations": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "on_hand": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "reservations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.StockReservation"
+                    }
+                },
+                "reserved": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.ProductResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.CategoryResponse"
                     }
                 },
                 "description": {
                     "type": "string"
                 },
-                "name": {
+                "highlight": {
+                    "description": "Highlight is only set on search results",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/product-service_internal_domain.ProductHighlight"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "images": {
+                    "description": "Images are in display order, the first one is the main image",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductImage"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options and Variants are left out for products without variants",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductOption"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.ProductVariant"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.ProductSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "product": {
+                    "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                }
+            }
+        },
+        "product-service_internal_domain.ProductVariant": {
+            "type": "object",
+            "properties": {
+                "barcode": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "options": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "price": {
                     "type": "integer"
                 },
+                "sku": {
+                    "type": "string"
+                },
                 "stock": {
-                    "type": "integer",
-                    "minimum": 0
+                    "type": "integer"
                 }
             }
         },
-        "product-service_internal_domain.ErrorResponse": {
+        "product-service_internal_domain.ProductWarehouseStocks": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string"
+                "product_id": {
+                    "type": "integer"
+                },
+                "stocks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.WarehouseStock"
+                    }
                 }
             }
         },
-        "product-service_internal_domain.PaginatedProducts": {
+        "product-service_internal_domain.ReorderImagesRequest": {
+            "type": "object",
+            "required": [
+                "image_ids"
+            ],
+            "properties": {
+                "image_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "product-service_internal_domain.StockHistory": {
             "type": "object",
             "properties": {
                 "limit": {
                     "type": "integer"
                 },
-                "page": {
-                    "type": "integer"
-                },
-                "products": {
+                "movements": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                        "$ref": "#/definitions/product-service_internal_domain.StockMovement"
                     }
                 },
-                "total": {
+                "on_hand": {
                     "type": "integer"
                 },
-                "total_pages": {
+                "page": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "reserved": {
+                    "type": "integer"
+                },
+                "total": {
                     "type": "integer"
                 }
             }
         },
-        "product-service_internal_domain.Product": {
+        "product-service_internal_domain.StockMovement": {
             "type": "object",
-            "required": [
-                "name",
-                "price",
-                "stock"
-            ],
             "properties": {
-                "categories": {
-                    "description": "Many-to-Many association",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/product-service_internal_domain.Category"
-                    }
+                "actor": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "Balances after the movement, filled in only by the stock history",
+                    "type": "integer"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
-                    "type": "string"
+                "delta": {
+                    "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
+                "order_id": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "reason": {
                     "type": "string"
                 },
-                "price": {
+                "reserved_balance": {
                     "type": "integer"
                 },
-                "stock": {
-                    "type": "integer",
-                    "minimum": 0
+                "reserved_delta": {
+                    "type": "integer"
+                },
+                "source_id": {
+                    "type": "string"
+                },
+                "variant_id": {
+                    "type": "integer"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.StockReservation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "variant_id": {
+                    "type": "integer"
+                },
+                "warehouse_id": {
+                    "type": "integer"
                 }
             }
         },
-        "product-service_internal_domain.ProductDataResponse": {
+        "product-service_internal_domain.SuccessResponse": {
             "type": "object",
             "properties": {
-                "product": {
-                    "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                "message": {
+                    "type": "string"
                 }
             }
         },
-        "product-service_internal_domain.ProductResponse": {
+        "product-service_internal_domain.UpdateProductRequest": {
             "type": "object",
             "properties": {
-                "categories": {
+                "category_ids": {
+                    "description": "If provided, we replace all categories",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/product-service_internal_domain.CategoryResponse"
+                        "type": "integer"
                     }
                 },
                 "description": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
                 "name": {
                     "type": "string"
                 },
@@ -607,54 +2093,208 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "product-service_internal_domain.UpdateVariantRequest": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "price": {
                     "type": "integer"
                 },
-                "updated_at": {
-                    "type": "string"
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
                 }
             }
         },
-        "product-service_internal_domain.ProductSuccessResponse": {
+        "product-service_internal_domain.UpdateWarehouseRequest": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string"
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
                 },
-                "product": {
-                    "$ref": "#/definitions/product-service_internal_domain.ProductResponse"
+                "postal_code": {
+                    "type": "string",
+                    "maxLength": 10
+                },
+                "priority": {
+                    "type": "integer"
                 }
             }
         },
-        "product-service_internal_domain.SuccessResponse": {
+        "product-service_internal_domain.VariantRequest": {
+            "type": "object",
+            "required": [
+                "options",
+                "price",
+                "sku"
+            ],
+            "properties": {
+                "barcode": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "options": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "sku": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "product-service_internal_domain.VariantSuccessResponse": {
             "type": "object",
             "properties": {
                 "message": {
                     "type": "string"
+                },
+                "variant": {
+                    "$ref": "#/definitions/product-service_internal_domain.ProductVariant"
                 }
             }
         },
-        "product-service_internal_domain.UpdateProductRequest": {
+        "product-service_internal_domain.Warehouse": {
             "type": "object",
             "properties": {
-                "category_ids": {
-                    "description": "If provided, we replace all categories",
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
+                "code": {
+                    "type": "string"
                 },
-                "description": {
+                "created_at": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
-                "price": {
+                "postal_code": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehouseRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "name"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "postal_code": {
+                    "type": "string",
+                    "maxLength": 10
+                },
+                "priority": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehouseStock": {
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "type": "integer"
+                },
+                "reserved": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "variant_id": {
+                    "type": "integer"
+                },
+                "warehouse": {
+                    "$ref": "#/definitions/product-service_internal_domain.Warehouse"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehouseStockRequest": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "stock"
+            ],
+            "properties": {
+                "product_id": {
                     "type": "integer"
                 },
                 "stock": {
                     "type": "integer",
                     "minimum": 0
+                },
+                "variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehouseStockSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "stock": {
+                    "$ref": "#/definitions/product-service_internal_domain.WarehouseStock"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehouseSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "warehouse": {
+                    "$ref": "#/definitions/product-service_internal_domain.Warehouse"
+                }
+            }
+        },
+        "product-service_internal_domain.WarehousesResponse": {
+            "type": "object",
+            "properties": {
+                "warehouses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/product-service_internal_domain.Warehouse"
+                    }
                 }
             }
         }
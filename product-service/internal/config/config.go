@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -21,8 +22,18 @@ type Config struct {
 		Password string
 		DB       int
 	}
+	// ImageDir is where uploaded product images are stored, ImageBaseURL the public URL they are served under
+	ImageDir     string
+	ImageBaseURL string
+	// StockReservationTTLMinutes is how long the stock of an unpaid order stays reserved
+	StockReservationTTLMinutes int
+	// WarehouseStrategy picks the warehouses orders are reserved from: priority, nearest or split
+	WarehouseStrategy string
 }
 
+// ImageRoute is the path this service serves stored product images under
+const ImageRoute = "/product-images"
+
 func LoadConfig() *Config {
 	return &Config{
 		DBHost:      getEnv("DB_HOST", "localhost"),
@@ -45,6 +56,11 @@ func LoadConfig() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       1,
 		},
+		ImageDir:     getEnv("IMAGE_DIR", "uploads/images"),
+		ImageBaseURL: getEnv("IMAGE_BASE_URL", ImageRoute),
+		// Outlasts the payment deadline of order-service, so stock is not given away while an order can still be paid
+		StockReservationTTLMinutes: getEnvInt("STOCK_RESERVATION_TTL_MINUTES", 60),
+		WarehouseStrategy:          getEnv("WAREHOUSE_STRATEGY", "priority"),
 	}
 }
 
@@ -84,3 +100,12 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
@@ -3,4 +3,8 @@ package domain
 type StockEvent struct {
 	OrderID       uint   `json:"order_id"`
 	CorrelationID string `json:"correlation_id,omitempty"`
+	// UnavailableItems are the products and variants of the order that could not be reserved
+	UnavailableItems []StockKey `json:"unavailable_items,omitempty"`
+	// Allocations are the warehouses the reserved lines are held in and shipped from
+	Allocations []StockAllocation `json:"allocations,omitempty"`
 }
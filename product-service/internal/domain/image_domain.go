@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidImage      = errors.New("invalid product image")
+	ErrImageNotFound     = errors.New("product image not found")
+	ErrInvalidImageOrder = errors.New("image order must list every image of the product once")
+)
+
+const (
+	// MaxImageSize is the largest image file accepted for upload, in bytes
+	MaxImageSize = 5 << 20
+	// MaxImagePixels caps the decoded size of an upload, so a small file cannot expand into a huge bitmap
+	MaxImagePixels = 25_000_000
+	// ThumbnailSize is the largest width and height of a thumbnail
+	ThumbnailSize = 320
+)
+
+// ProductImage is an uploaded image of a product. Images are listed by position, the first one is the main image.
+type ProductImage struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID    uint   `gorm:"index;not null" json:"-"`
+	URL          string `gorm:"type:varchar(512);not null" json:"url"`
+	ThumbnailURL string `gorm:"type:varchar(512);not null" json:"thumbnail_url"`
+	ContentType  string `gorm:"type:varchar(32);not null" json:"content_type"`
+	Width        int    `gorm:"not null" json:"width"`
+	Height       int    `gorm:"not null" json:"height"`
+	Position     int    `gorm:"not null;default:0" json:"position"`
+	// Keys of the image and its thumbnail in the image storage
+	StorageKey   string    `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey string    `gorm:"type:varchar(255);not null" json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type ReorderImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
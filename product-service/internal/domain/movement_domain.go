@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidMovementReason = errors.New("invalid stock movement reason")
+
+// Reasons of a stock movement
+const (
+	// MovementOpening is the stock a product or variant had in a warehouse when its ledger was started
+	MovementOpening = "OPENING"
+	// MovementAdjust is a stock change made by an admin or another service
+	MovementAdjust = "ADJUST"
+	// MovementReserve holds stock for an order awaiting payment
+	MovementReserve = "RESERVE"
+	// MovementRelease gives reserved stock back because the order was cancelled or its payment failed
+	MovementRelease = "RELEASE"
+	// MovementExpire gives reserved stock back because the order was not paid in time
+	MovementExpire = "EXPIRE"
+	// MovementCommit takes the stock of a paid order off the shelf
+	MovementCommit = "COMMIT"
+	// MovementReturn puts sold stock back on the shelf
+	MovementReturn = "RETURN"
+)
+
+// StockMovement is an entry of the append-only stock ledger. Delta is the change to the stock on hand and
+// ReservedDelta the change to the reserved stock in a warehouse, so summing the movements of a product or
+// variant gives its stock. An order moves the stock of a line in a warehouse once per reason, and a message
+// or request of an actor once per line, warehouse and reason, which makes redelivered messages and retried
+// requests harmless.
+type StockMovement struct {
+	ID            uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID     uint    `gorm:"not null;index;uniqueIndex:idx_stock_movements_order_warehouse,priority:3;uniqueIndex:idx_stock_movements_source_warehouse,priority:4" json:"product_id"`
+	VariantID     uint    `gorm:"not null;default:0;uniqueIndex:idx_stock_movements_order_warehouse,priority:4;uniqueIndex:idx_stock_movements_source_warehouse,priority:5" json:"variant_id,omitempty"`
+	WarehouseID   uint    `gorm:"not null;default:0;uniqueIndex:idx_stock_movements_order_warehouse,priority:5;uniqueIndex:idx_stock_movements_source_warehouse,priority:6" json:"warehouse_id"`
+	Delta         int     `gorm:"not null" json:"delta"`
+	ReservedDelta int     `gorm:"not null;default:0" json:"reserved_delta"`
+	Reason        string  `gorm:"type:varchar(20);not null;uniqueIndex:idx_stock_movements_order_warehouse,priority:2;uniqueIndex:idx_stock_movements_source_warehouse,priority:3" json:"reason"`
+	OrderID       *uint   `gorm:"uniqueIndex:idx_stock_movements_order_warehouse,priority:1" json:"order_id,omitempty"`
+	Actor         string  `gorm:"type:varchar(100);not null;uniqueIndex:idx_stock_movements_source_warehouse,priority:1" json:"actor"`
+	SourceID      *string `gorm:"type:varchar(100);uniqueIndex:idx_stock_movements_source_warehouse,priority:2" json:"source_id,omitempty"`
+	// Balances after the movement, filled in only by the stock history
+	Balance         int       `gorm:"->;-:migration" json:"balance"`
+	ReservedBalance int       `gorm:"->;-:migration" json:"reserved_balance"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// IsMovementReason reports whether reason is one of the stock movement reasons
+func IsMovementReason(reason string) bool {
+	switch reason {
+	case MovementOpening, MovementAdjust, MovementReserve, MovementRelease, MovementExpire, MovementCommit, MovementReturn:
+		return true
+	}
+	return false
+}
+
+// MovementSource tells who moved stock and, for messages and retried requests, which one did
+type MovementSource struct {
+	// Actor is the stream, API action or admin behind the movement, such as stream:orders:created or admin:12
+	Actor string
+	// SourceID is the ID of the message or request, unique for the actor, empty when there is none
+	SourceID string
+}
+
+// NewMovement returns a movement of the stock of key caused by source
+func (s MovementSource) NewMovement(key WarehouseStockKey, reason string, delta, reservedDelta int) StockMovement {
+	movement := StockMovement{
+		ProductID:     key.ProductID,
+		VariantID:     key.VariantID,
+		WarehouseID:   key.WarehouseID,
+		Delta:         delta,
+		ReservedDelta: reservedDelta,
+		Reason:        reason,
+		Actor:         s.Actor,
+	}
+	if s.SourceID != "" {
+		sourceID := s.SourceID
+		movement.SourceID = &sourceID
+	}
+	return movement
+}
+
+type movementSourceKey struct{}
+
+// WithMovementSource returns a context whose stock movements are recorded as caused by source
+func WithMovementSource(ctx context.Context, source MovementSource) context.Context {
+	return context.WithValue(ctx, movementSourceKey{}, source)
+}
+
+// MovementSourceFromContext returns the source stock movements of ctx are recorded with, "system" when none was set
+func MovementSourceFromContext(ctx context.Context) MovementSource {
+	if ctx != nil {
+		if source, ok := ctx.Value(movementSourceKey{}).(MovementSource); ok && source.Actor != "" {
+			return source
+		}
+	}
+	return MovementSource{Actor: "system"}
+}
+
+// MovementListFilter selects the stock movements of a product for its stock history
+type MovementListFilter struct {
+	ProductID uint
+	VariantID *uint  // nil for the product and all its variants
+	Reason    string // empty for every reason
+	Page      int
+	Limit     int
+}
+
+// StockHistory is a page of the stock movements of a product, newest first, along with its current stock
+type StockHistory struct {
+	ProductID uint            `json:"product_id"`
+	OnHand    int             `json:"on_hand"`
+	Reserved  int             `json:"reserved"`
+	Movements []StockMovement `json:"movements"`
+	Total     int64           `json:"total"`
+	Page      int             `json:"page"`
+	Limit     int             `json:"limit"`
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,19 +13,74 @@ type Product struct {
 	Description string `gorm:"type:text" json:"description"`
 	Price       int64  `gorm:"type:bigint;not null" json:"price" binding:"required,gt=0"`
 	Stock       int    `gorm:"not null" json:"stock" binding:"required,gte=0"`
+	// Reserved is the stock held by active reservations, it is still on hand but no longer available
+	Reserved int `gorm:"not null;default:0" json:"-"`
 	// Many-to-Many association
-	Categories []Category     `gorm:"many2many:product_categories;" json:"categories"`
-	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	Categories []Category `gorm:"many2many:product_categories;" json:"categories"`
+	// Options and Variants are only set on products sold in several variants
+	Options   []ProductOption  `gorm:"foreignKey:ProductID" json:"options,omitempty"`
+	Variants  []ProductVariant `gorm:"foreignKey:ProductID" json:"variants,omitempty"`
+	Images    []ProductImage   `gorm:"foreignKey:ProductID" json:"images"`
+	CreatedAt time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt   `gorm:"index" json:"-"`
+	// SearchDocument is the full-text search vector of the name, category names and description.
+	// It is maintained by the repository, never read or written through the struct.
+	SearchDocument string `gorm:"type:tsvector;->:false;<-:false" json:"-"`
+	// Filled in only by searches
+	SearchRank           float64 `gorm:"->;-:migration" json:"-"`
+	HighlightName        string  `gorm:"->;-:migration" json:"-"`
+	HighlightDescription string  `gorm:"->;-:migration" json:"-"`
+}
+
+// AvailableStock is the stock on hand that no active reservation holds
+func (p Product) AvailableStock() int {
+	return max(p.Stock-p.Reserved, 0)
+}
+
+// Values of sort_by when listing products. Relevance only applies to searches.
+const (
+	SortByRelevance = "relevance"
+	SortByCreatedAt = "created_at"
+)
+
+var ErrInvalidSortField = errors.New("invalid sort field")
+
+// How a product listing filtered by several categories matches them
+const (
+	CategoryMatchAny = "any"
+	CategoryMatchAll = "all"
+)
+
+// PriceFacetBounds splits prices into the ranges counted by the price facet: below the first bound,
+// between each pair of bounds and from the last bound up
+var PriceFacetBounds = []int64{1000000, 2500000, 5000000, 10000000}
+
+// ProductListFilter holds the filters, sorting and page of a product listing
+type ProductListFilter struct {
+	Search        string
+	CategoryIDs   []uint
+	CategoryMatch string // CategoryMatchAny or CategoryMatchAll
+	MinPrice      *int64
+	MaxPrice      *int64
+	InStock       *bool
+	SortBy        string
+	Order         string
+	Page          int
+	Limit         int
+	// Facets asks for facet counts along with the page
+	Facets bool
 }
 
 type CreateProductRequest struct {
     Name        string `json:"name" binding:"required"`
     Description string `json:"description"`
-    Price       int64  `json:"price" binding:"required,gt=0"`
-    Stock       int    `json:"stock" binding:"required,gte=0"`
-    CategoryIDs []uint `json:"category_ids" binding:"required"` // User only sends [1, 2, 3]
+    // Price and Stock are taken from the variants when there are any
+    Price       int64                  `json:"price" binding:"required_without=Variants,gte=0"`
+    Stock       int                    `json:"stock" binding:"required_without=Variants,gte=0"`
+    CategoryIDs []uint                 `json:"category_ids" binding:"required"` // User only sends [1, 2, 3]
+    Options     []ProductOptionRequest `json:"options" binding:"omitempty,dive"`
+    Variants    []VariantRequest       `json:"variants" binding:"omitempty,dive"`
 }
 
 type UpdateProductRequest struct {
@@ -53,13 +109,32 @@ func ToProductResponse(p Product) ProductResponse {
         }
     }
 
-    return ProductResponse{
+    images := p.Images
+    if images == nil {
+        images = []ProductImage{}
+    }
+
+    // Customers see the stock they can still order
+    var variants []ProductVariant
+    for _, v := range p.Variants {
+        v.Stock = v.AvailableStock()
+        variants = append(variants, v)
+    }
+
+    resp := ProductResponse{
         ID:          p.ID,
         Name:        p.Name,
         Description: p.Description,
         Price:       p.Price,
-        Stock:       p.Stock,
+        Stock:       p.AvailableStock(),
         Categories:  cats,
+        Options:     p.Options,
+        Variants:    variants,
+        Images:      images,
         UpdatedAt:   p.UpdatedAt,
     }
+    if p.HighlightName != "" || p.HighlightDescription != "" {
+        resp.Highlight = &ProductHighlight{Name: p.HighlightName, Description: p.HighlightDescription}
+    }
+    return resp
 }
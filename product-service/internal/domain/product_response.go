@@ -27,6 +27,46 @@ type CategorySuccessResponse struct {
 	Category Category `json:"category"`
 }
 
+// VariantSuccessResponse represents a success response with variant data
+type VariantSuccessResponse struct {
+	Message string         `json:"message"`
+	Variant ProductVariant `json:"variant"`
+}
+
+// ImageSuccessResponse represents a success response with image data
+type ImageSuccessResponse struct {
+	Message string       `json:"message"`
+	Image   ProductImage `json:"image"`
+}
+
+// WarehouseSuccessResponse represents a success response with warehouse data
+type WarehouseSuccessResponse struct {
+	Message   string    `json:"message"`
+	Warehouse Warehouse `json:"warehouse"`
+}
+
+// WarehousesResponse represents the warehouses in the order they are reserved from
+type WarehousesResponse struct {
+	Warehouses []Warehouse `json:"warehouses"`
+}
+
+// WarehouseStockSuccessResponse represents a success response with the stock of a product or variant in a warehouse
+type WarehouseStockSuccessResponse struct {
+	Message string         `json:"message"`
+	Stock   WarehouseStock `json:"stock"`
+}
+
+// ProductWarehouseStocks represents the stock of a product, or of each of its variants, per warehouse
+type ProductWarehouseStocks struct {
+	ProductID uint             `json:"product_id"`
+	Stocks    []WarehouseStock `json:"stocks"`
+}
+
+// ImagesResponse represents the images of a product in display order
+type ImagesResponse struct {
+	Images []ProductImage `json:"images"`
+}
+
 // ProductDataResponse represents a single product response
 type ProductDataResponse struct {
 	Product ProductResponse `json:"product"`
@@ -44,7 +84,20 @@ type ProductResponse struct {
 	Price       int64              `json:"price"`
 	Stock       int                `json:"stock"`
 	Categories  []CategoryResponse `json:"categories"`
-	UpdatedAt   time.Time          `json:"updated_at"`
+	// Options and Variants are left out for products without variants
+	Options  []ProductOption  `json:"options,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+	// Images are in display order, the first one is the main image
+	Images    []ProductImage `json:"images"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	// Highlight is only set on search results
+	Highlight *ProductHighlight `json:"highlight,omitempty"`
+}
+
+// ProductHighlight holds snippets of a search result with the matched words wrapped in <mark> tags
+type ProductHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 type PaginatedProducts struct {
@@ -53,4 +106,32 @@ type PaginatedProducts struct {
 	Page        int                      `json:"page"`
 	Limit       int                      `json:"limit"`
 	TotalPages  int                      `json:"total_pages"`
+	// Facets is only set when requested with facets=true
+	Facets *ProductFacets `json:"facets,omitempty"`
+}
+
+// ProductFacets counts the products of a listing per category, price range and availability.
+// Each facet ignores its own filter, so it shows what selecting another value would return.
+type ProductFacets struct {
+	Categories   []CategoryFacet   `json:"categories"`
+	PriceRanges  []PriceRangeFacet `json:"price_ranges"`
+	Availability AvailabilityFacet `json:"availability"`
+}
+
+type CategoryFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// PriceRangeFacet counts products priced from Min up to, but not including, Max. The last range has no Max.
+type PriceRangeFacet struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type AvailabilityFacet struct {
+	InStock    int64 `json:"in_stock"`
+	OutOfStock int64 `json:"out_of_stock"`
 }
\ No newline at end of file
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidReservationStatus = errors.New("invalid reservation status")
+
+// Statuses of a stock reservation
+const (
+	// ReservationActive holds stock for an order awaiting payment
+	ReservationActive = "ACTIVE"
+	// ReservationCommitted turned into a sale when the order was paid, the stock left the shelf
+	ReservationCommitted = "COMMITTED"
+	// ReservationReleased gave the stock back because the order was cancelled or its payment failed
+	ReservationReleased = "RELEASED"
+	// ReservationExpired gave the stock back because the order was neither paid nor cancelled in time
+	ReservationExpired = "EXPIRED"
+	// ReservationReturned put sold stock back on the shelf because the line was cancelled after payment
+	ReservationReturned = "RETURNED"
+)
+
+// StockReservation holds stock of a product or variant in a warehouse for one order line until it expires.
+// A line split over several warehouses holds a reservation in each. Active reservations count against the
+// stock available to other orders, the stock on hand only goes down once the order is paid.
+type StockReservation struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID     uint      `gorm:"not null;uniqueIndex:idx_stock_reservations_warehouse_line" json:"order_id"`
+	ProductID   uint      `gorm:"not null;uniqueIndex:idx_stock_reservations_warehouse_line;index" json:"product_id"`
+	VariantID   uint      `gorm:"not null;default:0;uniqueIndex:idx_stock_reservations_warehouse_line" json:"variant_id,omitempty"`
+	WarehouseID uint      `gorm:"not null;default:0;uniqueIndex:idx_stock_reservations_warehouse_line" json:"warehouse_id"`
+	Quantity    int       `gorm:"not null" json:"quantity"`
+	Status      string    `gorm:"type:varchar(20);not null;index:idx_stock_reservations_expiry,priority:1" json:"status"`
+	ExpiresAt   time.Time `gorm:"not null;index:idx_stock_reservations_expiry,priority:2" json:"expires_at"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// IsReservationStatus reports whether status is one of the reservation statuses
+func IsReservationStatus(status string) bool {
+	switch status {
+	case ReservationActive, ReservationCommitted, ReservationReleased, ReservationExpired, ReservationReturned:
+		return true
+	}
+	return false
+}
+
+func (r StockReservation) Key() StockKey {
+	return StockKey{ProductID: r.ProductID, VariantID: r.VariantID}
+}
+
+func (r StockReservation) WarehouseKey() WarehouseStockKey {
+	return r.Key().In(r.WarehouseID)
+}
+
+// ReservationListFilter selects the reservations of a product for the admin listing
+type ReservationListFilter struct {
+	ProductID uint
+	Status    string // empty for every status
+	Page      int
+	Limit     int
+}
+
+// ProductReservations is a page of the reservations of a product along with its stock totals
+type ProductReservations struct {
+	ProductID    uint               `json:"product_id"`
+	OnHand       int                `json:"on_hand"`
+	Reserved     int                `json:"reserved"`
+	Available    int                `json:"available"`
+	Reservations []StockReservation `json:"reservations"`
+	Total        int64              `json:"total"`
+	Page         int                `json:"page"`
+	Limit        int                `json:"limit"`
+}
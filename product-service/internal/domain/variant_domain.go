@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVariant  = errors.New("invalid product variant")
+	ErrVariantNotFound = errors.New("product variant not found")
+)
+
+// ProductOption is an axis a product comes in, such as size or color, with the values it can take
+type ProductOption struct {
+	ID        uint     `gorm:"primaryKey;autoIncrement" json:"-"`
+	ProductID uint     `gorm:"index;not null" json:"-"`
+	Name      string   `gorm:"type:varchar(50);not null" json:"name"`
+	Values    []string `gorm:"serializer:json;not null" json:"values"`
+	Position  int      `gorm:"not null;default:0" json:"-"`
+}
+
+// ProductVariant is one combination of option values of a product, sold under its own SKU.
+// The product keeps the lowest price and the summed stock of its variants, so listings can filter
+// and sort products with variants like any other product.
+type ProductVariant struct {
+	ID        uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID uint              `gorm:"index;not null" json:"-"`
+	SKU       string            `gorm:"type:varchar(64);uniqueIndex;not null" json:"sku"`
+	Barcode   string            `gorm:"type:varchar(64);index" json:"barcode,omitempty"`
+	Options   map[string]string `gorm:"serializer:json;not null" json:"options"`
+	Price     int64             `gorm:"type:bigint;not null" json:"price"`
+	Stock     int               `gorm:"not null" json:"stock"`
+	Reserved  int               `gorm:"not null;default:0" json:"-"`
+	CreatedAt time.Time         `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt time.Time         `gorm:"autoUpdateTime" json:"-"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// AvailableStock is the stock on hand of the variant that no active reservation holds
+func (v ProductVariant) AvailableStock() int {
+	return max(v.Stock-v.Reserved, 0)
+}
+
+func (v ProductVariant) Key() StockKey {
+	return StockKey{ProductID: v.ProductID, VariantID: v.ID}
+}
+
+// StockKey identifies the stock of a product without variants, or of one variant of a product
+type StockKey struct {
+	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id,omitempty"`
+}
+
+func (k StockKey) Less(other StockKey) bool {
+	if k.ProductID != other.ProductID {
+		return k.ProductID < other.ProductID
+	}
+	return k.VariantID < other.VariantID
+}
+
+type ProductOptionRequest struct {
+	Name   string   `json:"name" binding:"required,max=50"`
+	Values []string `json:"values" binding:"required,min=1,dive,required"`
+}
+
+type VariantRequest struct {
+	SKU     string            `json:"sku" binding:"required,max=64"`
+	Barcode string            `json:"barcode" binding:"max=64"`
+	Options map[string]string `json:"options" binding:"required"`
+	Price   int64             `json:"price" binding:"required,gt=0"`
+	Stock   int               `json:"stock" binding:"gte=0"`
+}
+
+type UpdateVariantRequest struct {
+	Barcode *string `json:"barcode" binding:"omitempty,max=64"`
+	Price   *int64  `json:"price" binding:"omitempty,gt=0"`
+	Stock   *int    `json:"stock" binding:"omitempty,gte=0"`
+}
+
+// ValidateVariants checks that the options have distinct names and values and that every variant sets a
+// value of each option, with no two variants sharing the same combination or SKU
+func ValidateVariants(options []ProductOptionRequest, variants []VariantRequest) error {
+	if len(options) == 0 && len(variants) == 0 {
+		return nil
+	}
+	if len(options) == 0 || len(variants) == 0 {
+		return fmt.Errorf("%w: options and variants must be given together", ErrInvalidVariant)
+	}
+
+	allowed := make(map[string]map[string]bool, len(options))
+	for _, option := range options {
+		if allowed[option.Name] != nil {
+			return fmt.Errorf("%w: option %q is listed twice", ErrInvalidVariant, option.Name)
+		}
+		allowed[option.Name] = make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			if allowed[option.Name][value] {
+				return fmt.Errorf("%w: option %q lists %q twice", ErrInvalidVariant, option.Name, value)
+			}
+			allowed[option.Name][value] = true
+		}
+	}
+
+	skus := make(map[string]bool, len(variants))
+	combinations := make(map[string]bool, len(variants))
+	for _, variant := range variants {
+		if skus[variant.SKU] {
+			return fmt.Errorf("%w: SKU %q is listed twice", ErrInvalidVariant, variant.SKU)
+		}
+		skus[variant.SKU] = true
+
+		if err := checkVariantOptions(variant, allowed); err != nil {
+			return err
+		}
+		combination := variantCombination(options, variant.Options)
+		if combinations[combination] {
+			return fmt.Errorf("%w: SKU %q repeats the options of another variant", ErrInvalidVariant, variant.SKU)
+		}
+		combinations[combination] = true
+	}
+	return nil
+}
+
+// checkVariantOptions checks that variant sets exactly the options of allowed to one of their values
+func checkVariantOptions(variant VariantRequest, allowed map[string]map[string]bool) error {
+	if len(variant.Options) != len(allowed) {
+		return fmt.Errorf("%w: SKU %q must set a value for each of the %d options", ErrInvalidVariant, variant.SKU, len(allowed))
+	}
+	for name, value := range variant.Options {
+		values, ok := allowed[name]
+		if !ok {
+			return fmt.Errorf("%w: SKU %q sets unknown option %q", ErrInvalidVariant, variant.SKU, name)
+		}
+		if !values[value] {
+			return fmt.Errorf("%w: SKU %q sets option %q to unknown value %q", ErrInvalidVariant, variant.SKU, name, value)
+		}
+	}
+	return nil
+}
+
+// variantCombination joins the option values of a variant in option order, identifying its combination
+func variantCombination(options []ProductOptionRequest, values map[string]string) string {
+	combination := ""
+	for _, option := range options {
+		combination += option.Name + "=" + values[option.Name] + "\x00"
+	}
+	return combination
+}
+
+// NewProductOptions turns option requests into the options of a product, keeping their order
+func NewProductOptions(options []ProductOptionRequest) []ProductOption {
+	productOptions := make([]ProductOption, len(options))
+	for i, option := range options {
+		productOptions[i] = ProductOption{Name: option.Name, Values: option.Values, Position: i}
+	}
+	return productOptions
+}
+
+func NewProductVariant(req VariantRequest) ProductVariant {
+	return ProductVariant{
+		SKU:     req.SKU,
+		Barcode: req.Barcode,
+		Options: req.Options,
+		Price:   req.Price,
+		Stock:   req.Stock,
+	}
+}
+
+// AddValue adds value to the values of the option unless it is empty or already there, reporting whether it did
+func (o *ProductOption) AddValue(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, existing := range o.Values {
+		if existing == value {
+			return false
+		}
+	}
+	o.Values = append(o.Values, value)
+	return true
+}
+
+// ValidateNewVariant checks a variant added to a product that already has options and variants
+func ValidateNewVariant(options []ProductOption, existing []ProductVariant, req VariantRequest) error {
+	if len(options) == 0 {
+		return fmt.Errorf("%w: product has no options, variants can only be added to products created with options", ErrInvalidVariant)
+	}
+
+	optionRequests := make([]ProductOptionRequest, len(options))
+	for i, option := range options {
+		optionRequests[i] = ProductOptionRequest{Name: option.Name, Values: option.Values}
+	}
+	variantRequests := make([]VariantRequest, 0, len(existing)+1)
+	for _, variant := range existing {
+		variantRequests = append(variantRequests, VariantRequest{SKU: variant.SKU, Options: variant.Options})
+	}
+	return ValidateVariants(optionRequests, append(variantRequests, req))
+}
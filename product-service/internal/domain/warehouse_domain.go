@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrWarehouseNotFound     = errors.New("warehouse not found")
+	ErrInvalidWarehouseStock = errors.New("invalid warehouse stock")
+)
+
+// Strategies picking the warehouses an order is reserved from
+const (
+	// WarehouseStrategyPriority reserves each line from the first warehouse by priority that has all of it
+	WarehouseStrategyPriority = "priority"
+	// WarehouseStrategyNearest reserves each line from the warehouse nearest to the shipping postal code that has all of it
+	WarehouseStrategyNearest = "nearest"
+	// WarehouseStrategySplit reserves a line from several warehouses by priority when no single one has all of it
+	WarehouseStrategySplit = "split"
+)
+
+// DefaultWarehouseCode is the code of the warehouse created for the stock kept before warehouses existed
+const DefaultWarehouseCode = "MAIN"
+
+// IsWarehouseStrategy reports whether strategy is one of the warehouse strategies
+func IsWarehouseStrategy(strategy string) bool {
+	switch strategy {
+	case WarehouseStrategyPriority, WarehouseStrategyNearest, WarehouseStrategySplit:
+		return true
+	}
+	return false
+}
+
+// Warehouse is a place stock is kept and shipped from. Warehouses with a lower priority are reserved from
+// first, the first one is the default warehouse that stock set on a product or variant goes to.
+type Warehouse struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code       string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name       string    `gorm:"type:varchar(255);not null" json:"name"`
+	PostalCode string    `gorm:"type:varchar(10)" json:"postal_code,omitempty"`
+	Priority   int       `gorm:"not null;default:0" json:"priority"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// WarehouseStock is the stock of a product without variants, or of one variant, kept in a warehouse. The
+// stock of a product or variant is the sum of its stock in every warehouse.
+type WarehouseStock struct {
+	ProductID   uint       `gorm:"primaryKey;autoIncrement:false" json:"product_id"`
+	VariantID   uint       `gorm:"primaryKey;autoIncrement:false" json:"variant_id,omitempty"`
+	WarehouseID uint       `gorm:"primaryKey;autoIncrement:false" json:"warehouse_id"`
+	Stock       int        `gorm:"not null;default:0" json:"stock"`
+	Reserved    int        `gorm:"not null;default:0" json:"reserved"`
+	Warehouse   *Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+}
+
+// AvailableStock is the stock in the warehouse that no active reservation holds
+func (s WarehouseStock) AvailableStock() int {
+	return max(s.Stock-s.Reserved, 0)
+}
+
+func (s WarehouseStock) Key() WarehouseStockKey {
+	return StockKey{ProductID: s.ProductID, VariantID: s.VariantID}.In(s.WarehouseID)
+}
+
+// WarehouseStockKey identifies the stock of a product or variant kept in one warehouse
+type WarehouseStockKey struct {
+	StockKey
+	WarehouseID uint
+}
+
+// In returns the key of the stock of k kept in a warehouse
+func (k StockKey) In(warehouseID uint) WarehouseStockKey {
+	return WarehouseStockKey{StockKey: k, WarehouseID: warehouseID}
+}
+
+func (k WarehouseStockKey) Less(other WarehouseStockKey) bool {
+	if k.StockKey != other.StockKey {
+		return k.StockKey.Less(other.StockKey)
+	}
+	return k.WarehouseID < other.WarehouseID
+}
+
+type WarehouseRequest struct {
+	Code       string `json:"code" binding:"required,max=50"`
+	Name       string `json:"name" binding:"required,max=255"`
+	PostalCode string `json:"postal_code" binding:"max=10"`
+	Priority   int    `json:"priority"`
+}
+
+type UpdateWarehouseRequest struct {
+	Name       *string `json:"name" binding:"omitempty,min=1,max=255"`
+	PostalCode *string `json:"postal_code" binding:"omitempty,max=10"`
+	Priority   *int    `json:"priority"`
+}
+
+// WarehouseStockRequest sets the stock of a product without variants, or of one variant, in a warehouse
+type WarehouseStockRequest struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	VariantID uint `json:"variant_id"`
+	Stock     *int `json:"stock" binding:"required,gte=0"`
+}
+
+// WarehouseSelection is how the warehouses of an order are picked
+type WarehouseSelection struct {
+	Strategy string
+	// PostalCode is where the order ships to, the nearest strategy falls back to priority without it
+	PostalCode string
+}
+
+// StockAllocation is the part of an order line reserved in one warehouse, which it is shipped from
+type StockAllocation struct {
+	StockKey
+	WarehouseID   uint   `json:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code"`
+	Quantity      int    `json:"quantity"`
+}
+
+// RankWarehouses returns warehouses in the order selection tries them in: by priority, or for the nearest
+// strategy by distance to the shipping postal code first
+func RankWarehouses(warehouses []Warehouse, selection WarehouseSelection) []Warehouse {
+	ranked := make([]Warehouse, len(warehouses))
+	copy(ranked, warehouses)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Priority != ranked[j].Priority {
+			return ranked[i].Priority < ranked[j].Priority
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if selection.Strategy == WarehouseStrategyNearest && selection.PostalCode != "" {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return postalCodeCloser(selection.PostalCode, ranked[i].PostalCode, ranked[j].PostalCode)
+		})
+	}
+	return ranked
+}
+
+// postalCodeCloser reports whether postal code a is closer to to than b. Postal codes are handed out by
+// region, so a code sharing a longer prefix with to is closer, and among those the smaller numeric gap is.
+// A warehouse without a postal code is the farthest.
+func postalCodeCloser(to, a, b string) bool {
+	if (a == "") != (b == "") {
+		return b == ""
+	}
+	prefixA, prefixB := sharedPrefix(to, a), sharedPrefix(to, b)
+	if prefixA != prefixB {
+		return prefixA > prefixB
+	}
+	gapA, okA := postalCodeGap(to, a)
+	gapB, okB := postalCodeGap(to, b)
+	if okA != okB {
+		return okA
+	}
+	return okA && gapA < gapB
+}
+
+func sharedPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// postalCodeGap is the numeric distance between two postal codes, false when either is not a number
+func postalCodeGap(a, b string) (uint64, bool) {
+	x, err := strconv.ParseUint(a, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	y, err := strconv.ParseUint(b, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if x > y {
+		return x - y, true
+	}
+	return y - x, true
+}
+
+// AllocateLine picks the warehouses quantity of a line is reserved from, trying warehouses in their order.
+// available is the stock of the line each warehouse can give. Without split the line comes from the first
+// warehouse that has all of it, with split it takes what it can from each warehouse in turn. It returns nil
+// when the warehouses cannot cover the line.
+func AllocateLine(key StockKey, warehouses []Warehouse, available map[uint]int, quantity int, split bool) []StockAllocation {
+	if quantity <= 0 {
+		return nil
+	}
+	var allocations []StockAllocation
+	remaining := quantity
+	for _, warehouse := range warehouses {
+		stock := available[warehouse.ID]
+		if stock <= 0 || (!split && stock < quantity) {
+			continue
+		}
+		take := min(stock, remaining)
+		allocations = append(allocations, StockAllocation{StockKey: key, WarehouseID: warehouse.ID, WarehouseCode: warehouse.Code, Quantity: take})
+		if remaining -= take; remaining == 0 {
+			return allocations
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"libs/pb"
+	"product-service/internal/domain"
 	"product-service/internal/service"
 
 	"google.golang.org/grpc/codes"
@@ -26,18 +27,88 @@ func (s *ProductGRPCServer) GetProduct(ctx context.Context, req *pb.GetProductRe
 	}
 
 	// 2. Map domain entity to Protobuf response
-	return &pb.ProductResponse{
-		Id:    uint32(p.ID),
-		Name:  p.Name,
-		Price: uint64(p.Price),
-	}, nil
+	return toProductResponse(p), nil
+}
+
+func (s *ProductGRPCServer) GetProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
+	if len(req.Ids) == 0 {
+		return &pb.GetProductsResponse{}, nil
+	}
+
+	ids := make([]uint, len(req.Ids))
+	for i, id := range req.Ids {
+		ids[i] = uint(id)
+	}
+
+	products, err := s.service.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not get products")
+	}
+
+	resp := &pb.GetProductsResponse{Products: make([]*pb.ProductResponse, 0, len(products))}
+	found := make(map[uint32]bool, len(products))
+	for i := range products {
+		resp.Products = append(resp.Products, toProductResponse(&products[i]))
+		found[uint32(products[i].ID)] = true
+	}
+	for _, id := range req.Ids {
+		if !found[id] {
+			resp.MissingIds = append(resp.MissingIds, id)
+			found[id] = true // report duplicates once
+		}
+	}
+
+	return resp, nil
+}
+
+func toProductResponse(p *domain.Product) *pb.ProductResponse {
+	deleted := p.DeletedAt.Valid
+	categories := make([]string, 0, len(p.Categories))
+	for _, category := range p.Categories {
+		categories = append(categories, category.Name)
+	}
+	variants := make([]*pb.ProductVariant, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		variantDeleted := deleted || v.DeletedAt.Valid
+		variants = append(variants, &pb.ProductVariant{
+			Id:        uint32(v.ID),
+			Sku:       v.SKU,
+			Barcode:   v.Barcode,
+			Options:   v.Options,
+			Price:     uint64(v.Price),
+			Stock:     int64(v.AvailableStock()),
+			Deleted:   variantDeleted,
+			Available: !variantDeleted && v.AvailableStock() > 0,
+		})
+	}
+	resp := &pb.ProductResponse{
+		Id:         uint32(p.ID),
+		Name:       p.Name,
+		Price:      uint64(p.Price),
+		Stock:      int64(p.AvailableStock()),
+		Deleted:    deleted,
+		Available:  !deleted && p.AvailableStock() > 0,
+		Categories: categories,
+		Variants:   variants,
+	}
+	if len(p.Images) > 0 {
+		resp.ImageUrl = p.Images[0].URL
+		resp.ThumbnailUrl = p.Images[0].ThumbnailURL
+	}
+	return resp
 }
 
 func (s *ProductGRPCServer) UpdateStock(ctx context.Context, req *pb.UpdateStockRequest) (*pb.UpdateStockResponse, error) {
+	// Without a request ID the ledger can't tell a retried call apart, and would change the stock twice
+	if req.RequestId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "request_id is required")
+	}
+
 	// 1. Call your existing business logic
-	id := uint(req.Id)
+	key := domain.StockKey{ProductID: uint(req.Id), VariantID: uint(req.VariantId)}
+	ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "grpc:update_stock", SourceID: req.RequestId})
 
-	err := s.service.AddStock(ctx, id, int(req.Add))
+	err := s.service.AddStock(ctx, key, uint(req.WarehouseId), int(req.Add))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "could not update stock")
 	}
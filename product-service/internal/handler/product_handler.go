@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"product-service/internal/domain"
 	"product-service/internal/service"
@@ -21,14 +25,14 @@ func NewProductHandler(ps *service.ProductService) *ProductHandler {
 
 // Create godoc
 // @Summary Create a new product
-// @Description Create a new product (Admin only)
+// @Description Create a new product (Admin only). A product sold in variants lists its options, such as size and color, and a variant per SKU with its own price, stock and barcode. Its price and stock are then taken from the variants.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param product body domain.CreateProductRequest true "Product data"
 // @Success 201 {object} domain.SuccessResponse "Product created successfully"
-// @Failure 400 {object} domain.ErrorResponse "Invalid request body / missing required fields / invalid category ID"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / missing required fields / invalid category ID / invalid product variant"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
 // @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
 // @Failure 409 {object} domain.ErrorResponse "product already exists"
@@ -44,7 +48,12 @@ func (h *ProductHandler) Create(c *gin.Context) {
 	}
 
 	// Call the service layer
-	if err := h.productService.CreateProduct(c.Request.Context(), &product); err != nil {
+	if err := h.productService.CreateProduct(adminContext(c), &product); err != nil {
+		if errors.Is(err, domain.ErrInvalidVariant) {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+			return
+		}
+
 		// Check PostgreSQL unique constraint violation return 409
 		if strings.Contains(err.Error(), "duplicate key value") {
 			c.JSON(http.StatusConflict, domain.ErrorResponse{Error: "product already exists"})
@@ -72,35 +81,83 @@ func (h *ProductHandler) Create(c *gin.Context) {
 
 // Get godoc
 // @Summary Get paginated products
-// @Description Get products with pagination and filters
+// @Description Get products with pagination and filters. The search term is matched against product names, descriptions and category names, words may be prefixes and slightly misspelt names still match. Search results carry highlighted snippets. With facets=true the response also counts the matching products per category, price range and availability, each facet ignoring its own filter.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Param search query string false "Search by product name"
-// @Param category_id query int false "Filter by category ID"
-// @Param min_price query number false "Minimum price"
-// @Param max_price query number false "Maximum price"
-// @Param sort_by query string false "Sort field" default(created_at)
+// @Param search query string false "Full-text search over name, description and category names"
+// @Param category_id query []int false "Filter by category IDs, repeated or comma separated" collectionFormat(csv)
+// @Param category_match query string false "Match any or all of the categories (any/all)" default(any)
+// @Param min_price query int false "Minimum price"
+// @Param max_price query int false "Maximum price"
+// @Param in_stock query bool false "Only products in stock (true) or out of stock (false)"
+// @Param facets query bool false "Include facet counts" default(false)
+// @Param sort_by query string false "Sort field: relevance, created_at, updated_at, name, price or stock. Defaults to relevance when searching, created_at otherwise"
 // @Param order query string false "Sort order (asc/desc)" default(desc)
 // @Success 200 {object} domain.PaginatedProducts
+// @Failure 400 {object} domain.ErrorResponse "invalid filter or sort field"
 // @Failure 500 {object} domain.ErrorResponse "could not retrieve products"
 // @Router /products [get]
 func (h *ProductHandler) Get(c *gin.Context) {
-	search := c.Query("search")
-	categoryID := c.Query("category_id")
-	minPrice := c.Query("min_price")
-	maxPrice := c.Query("max_price")
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	order := c.DefaultQuery("order", "desc")
+	filter := domain.ProductListFilter{
+		Search:        c.Query("search"),
+		CategoryMatch: c.DefaultQuery("category_match", domain.CategoryMatchAny),
+		SortBy:        c.Query("sort_by"),
+		Order:         c.DefaultQuery("order", "desc"),
+		Facets:        c.Query("facets") == "true",
+	}
 
 	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	result, err := h.productService.GetProducts(c.Request.Context(), search, categoryID, minPrice, maxPrice, order, sortBy, page, limit)
+	for _, param := range c.QueryArray("category_id") {
+		for _, value := range strings.Split(param, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid category_id"})
+				return
+			}
+			filter.CategoryIDs = append(filter.CategoryIDs, uint(id))
+		}
+	}
+	if filter.CategoryMatch != domain.CategoryMatchAny && filter.CategoryMatch != domain.CategoryMatchAll {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid category_match, expected any or all"})
+		return
+	}
+	if value := c.Query("min_price"); value != "" {
+		price, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid min_price"})
+			return
+		}
+		filter.MinPrice = &price
+	}
+	if value := c.Query("max_price"); value != "" {
+		price, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid max_price"})
+			return
+		}
+		filter.MaxPrice = &price
+	}
+	if value := c.Query("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid in_stock"})
+			return
+		}
+		filter.InStock = &inStock
+	}
+
+	result, err := h.productService.GetProducts(c.Request.Context(), &filter)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidSortField) {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not retrieve products"})
 		return
 	}
@@ -180,7 +237,7 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 
 // Update godoc
 // @Summary Update product
-// @Description Update product details (Admin only)
+// @Description Update product details (Admin only). Price and stock of a product with variants are updated per variant. A new stock is made up by the default warehouse, the stock of other warehouses is set per warehouse.
 // @Tags Products
 // @Accept json
 // @Produce json
@@ -188,7 +245,7 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 // @Param id path int true "Product ID"
 // @Param product body domain.UpdateProductRequest true "Product update data"
 // @Success 200 {object} domain.ProductSuccessResponse "Product updated successfully"
-// @Failure 400 {object} domain.ErrorResponse "Invalid request body"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / price or stock of a product with variants / invalid warehouse stock"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
 // @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
 // @Failure 500 {object} domain.ErrorResponse "could not update product"
@@ -213,8 +270,12 @@ func (h *ProductHandler) Update(c *gin.Context) {
 	}
 
 	// Call the service layer to update the product
-	updatedProduct, err := h.productService.UpdateProduct(c.Request.Context(), uint(productID), &product)
+	updatedProduct, err := h.productService.UpdateProduct(adminContext(c), uint(productID), &product)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidVariant) || errors.Is(err, domain.ErrInvalidWarehouseStock) {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not update product"})
 		return
 	}
@@ -222,3 +283,387 @@ func (h *ProductHandler) Update(c *gin.Context) {
 	// Success response
 	c.JSON(http.StatusOK, domain.ProductSuccessResponse{Message: "Product updated successfully", Product: domain.ToProductResponse(*updatedProduct)})
 }
+
+// AddVariant godoc
+// @Summary Add a product variant
+// @Description Add a variant to a product created with options (Admin only). The variant sets a value for every option, values the product did not have yet are added to its options.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param variant body domain.VariantRequest true "Variant data"
+// @Success 201 {object} domain.VariantSuccessResponse "Variant added successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / invalid product variant"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 409 {object} domain.ErrorResponse "SKU already exists"
+// @Failure 500 {object} domain.ErrorResponse "could not add variant"
+// @Router /products/{id}/variants [post]
+func (h *ProductHandler) AddVariant(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	var req domain.VariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	variant, err := h.productService.AddVariant(adminContext(c), uint(productID), &req)
+	if err != nil {
+		h.variantError(c, err, "could not add variant")
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.VariantSuccessResponse{Message: "Variant added successfully", Variant: *variant})
+}
+
+// UpdateVariant godoc
+// @Summary Update a product variant
+// @Description Update the barcode, price or stock of a variant (Admin only). A new stock is made up by the default warehouse, the stock of other warehouses is set per warehouse.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param variantId path int true "Variant ID"
+// @Param variant body domain.UpdateVariantRequest true "Variant update data"
+// @Success 200 {object} domain.VariantSuccessResponse "Variant updated successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / invalid warehouse stock"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "variant not found"
+// @Failure 500 {object} domain.ErrorResponse "could not update variant"
+// @Router /products/{id}/variants/{variantId} [put]
+func (h *ProductHandler) UpdateVariant(c *gin.Context) {
+	productID, variantID, ok := variantParams(c)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	variant, err := h.productService.UpdateVariant(adminContext(c), productID, variantID, &req)
+	if err != nil {
+		h.variantError(c, err, "could not update variant")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.VariantSuccessResponse{Message: "Variant updated successfully", Variant: *variant})
+}
+
+// DeleteVariant godoc
+// @Summary Delete a product variant
+// @Description Stop selling a variant (Admin only). The last variant of a product cannot be deleted.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param variantId path int true "Variant ID"
+// @Success 200 {object} domain.SuccessResponse "Variant deleted successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid ID / last variant of the product"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "variant not found"
+// @Failure 500 {object} domain.ErrorResponse "could not delete variant"
+// @Router /products/{id}/variants/{variantId} [delete]
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	productID, variantID, ok := variantParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.DeleteVariant(adminContext(c), productID, variantID); err != nil {
+		h.variantError(c, err, "could not delete variant")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "Variant deleted successfully"})
+}
+
+// variantParams parses the product and variant IDs of a variant route, answering 400 when either is invalid
+func variantParams(c *gin.Context) (uint, uint, bool) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return 0, 0, false
+	}
+	variantID, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid variant ID"})
+		return 0, 0, false
+	}
+	return uint(productID), uint(variantID), true
+}
+
+// variantError writes the response of a failed variant change
+func (h *ProductHandler) variantError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidVariant), errors.Is(err, domain.ErrInvalidWarehouseStock):
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+	case errors.Is(err, domain.ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "variant not found"})
+	case strings.Contains(err.Error(), "duplicate key value"):
+		c.JSON(http.StatusConflict, domain.ErrorResponse{Error: "SKU already exists"})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: fallback})
+	}
+}
+
+// UploadImage godoc
+// @Summary Upload a product image
+// @Description Upload a JPEG, PNG or GIF of up to 5 MB as multipart form field "image" (Admin only). The image is added after the existing images of the product, with a thumbnail of at most 320x320 pixels.
+// @Tags Products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param image formData file true "Image file"
+// @Success 201 {object} domain.ImageSuccessResponse "Image uploaded successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid product ID / missing image / invalid product image"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 413 {object} domain.ErrorResponse "image is too large"
+// @Failure 500 {object} domain.ErrorResponse "could not upload image"
+// @Router /products/{id}/images [post]
+func (h *ProductHandler) UploadImage(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, domain.MaxImageSize+1<<20)
+	file, _, err := c.Request.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, domain.ErrorResponse{Error: "image is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "image file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, domain.MaxImageSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "image file could not be read"})
+		return
+	}
+
+	image, err := h.productService.AddProductImage(c.Request.Context(), uint(productID), data)
+	if err != nil {
+		h.imageError(c, err, "could not upload image")
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.ImageSuccessResponse{Message: "Image uploaded successfully", Image: *image})
+}
+
+// ReorderImages godoc
+// @Summary Reorder product images
+// @Description Set the display order of the images of a product (Admin only). Every image of the product must be listed once, the first one becomes the main image.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param order body domain.ReorderImagesRequest true "Image IDs in display order"
+// @Success 200 {object} domain.ImagesResponse "Images in their new order"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body / image order must list every image of the product once"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 500 {object} domain.ErrorResponse "could not reorder images"
+// @Router /products/{id}/images [put]
+func (h *ProductHandler) ReorderImages(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	var req domain.ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	images, err := h.productService.ReorderProductImages(c.Request.Context(), uint(productID), req.ImageIDs)
+	if err != nil {
+		h.imageError(c, err, "could not reorder images")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.ImagesResponse{Images: images})
+}
+
+// DeleteImage godoc
+// @Summary Delete a product image
+// @Description Remove an image from a product and delete its files (Admin only)
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} domain.SuccessResponse "Image deleted successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid ID"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "image not found"
+// @Failure 500 {object} domain.ErrorResponse "could not delete image"
+// @Router /products/{id}/images/{imageId} [delete]
+func (h *ProductHandler) DeleteImage(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+	imageID, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid image ID"})
+		return
+	}
+
+	if err := h.productService.DeleteProductImage(c.Request.Context(), uint(productID), uint(imageID)); err != nil {
+		h.imageError(c, err, "could not delete image")
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "Image deleted successfully"})
+}
+
+// imageError writes the response of a failed image change
+func (h *ProductHandler) imageError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidImage), errors.Is(err, domain.ErrInvalidImageOrder):
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+	case errors.Is(err, domain.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "image not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: fallback})
+	}
+}
+
+// GetReservations godoc
+// @Summary List product stock reservations
+// @Description Show the stock held for orders of a product along with its stock on hand, reserved and available (Admin only). Active reservations hold stock until their order is paid, cancelled or the reservation expires.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param status query string false "Reservation status" Enums(ACTIVE, COMMITTED, RELEASED, EXPIRED, RETURNED)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} domain.ProductReservations "Reservations of the product, newest first"
+// @Failure 400 {object} domain.ErrorResponse "Invalid product ID / invalid reservation status"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 500 {object} domain.ErrorResponse "could not get reservations"
+// @Router /products/{id}/reservations [get]
+func (h *ProductHandler) GetReservations(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	filter := &domain.ReservationListFilter{ProductID: uint(productID), Status: c.Query("status")}
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	reservations, err := h.productService.GetProductReservations(c.Request.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidReservationStatus):
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not get reservations"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+// GetStockHistory godoc
+// @Summary Get product stock history
+// @Description Reconstruct the stock history of a product from the stock ledger (Admin only). Every change to the stock on hand or reserved is a movement, listed newest first with the stock on hand and reserved after it.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Param variant_id query int false "Only the movements of this variant"
+// @Param reason query string false "Movement reason" Enums(OPENING, ADJUST, RESERVE, RELEASE, EXPIRE, COMMIT, RETURN)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} domain.StockHistory "Stock movements of the product, newest first"
+// @Failure 400 {object} domain.ErrorResponse "Invalid product ID / invalid variant ID / invalid stock movement reason"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 500 {object} domain.ErrorResponse "could not get stock history"
+// @Router /products/{id}/stock-movements [get]
+func (h *ProductHandler) GetStockHistory(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	filter := &domain.MovementListFilter{ProductID: uint(productID), Reason: c.Query("reason")}
+	if variantIDStr := c.Query("variant_id"); variantIDStr != "" {
+		variantID, err := strconv.ParseUint(variantIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid variant ID"})
+			return
+		}
+		id := uint(variantID)
+		filter.VariantID = &id
+	}
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := h.productService.GetStockHistory(c.Request.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidMovementReason):
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not get stock history"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// adminContext returns the context of a request, recording the stock movements it makes as made by the signed in admin
+func adminContext(c *gin.Context) context.Context {
+	return domain.WithMovementSource(c.Request.Context(), domain.MovementSource{Actor: fmt.Sprintf("admin:%d", c.GetUint("userID"))})
+}
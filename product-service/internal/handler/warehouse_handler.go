@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"product-service/internal/domain"
+	"product-service/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WarehouseHandler struct {
+	productService *service.ProductService
+}
+
+func NewWarehouseHandler(ps *service.ProductService) *WarehouseHandler {
+	return &WarehouseHandler{productService: ps}
+}
+
+// Create godoc
+// @Summary Create a warehouse
+// @Description Create a warehouse stock is kept and shipped from (Admin only). Warehouses with a lower priority are reserved from first, the first one is the default warehouse.
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param warehouse body domain.WarehouseRequest true "Warehouse data"
+// @Success 201 {object} domain.WarehouseSuccessResponse "Warehouse created successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid request body"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 409 {object} domain.ErrorResponse "warehouse already exists"
+// @Failure 500 {object} domain.ErrorResponse "could not create warehouse"
+// @Router /warehouses [post]
+func (h *WarehouseHandler) Create(c *gin.Context) {
+	var req domain.WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	warehouse, err := h.productService.CreateWarehouse(c.Request.Context(), &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value") {
+			c.JSON(http.StatusConflict, domain.ErrorResponse{Error: "warehouse already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not create warehouse"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.WarehouseSuccessResponse{Message: "Warehouse created successfully", Warehouse: *warehouse})
+}
+
+// List godoc
+// @Summary List warehouses
+// @Description List the warehouses by priority, the order they are reserved from (Admin only)
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.WarehousesResponse "Warehouses by priority"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 500 {object} domain.ErrorResponse "could not list warehouses"
+// @Router /warehouses [get]
+func (h *WarehouseHandler) List(c *gin.Context) {
+	warehouses, err := h.productService.ListWarehouses(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not list warehouses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.WarehousesResponse{Warehouses: warehouses})
+}
+
+// Update godoc
+// @Summary Update a warehouse
+// @Description Update the name, postal code or priority of a warehouse (Admin only)
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Param warehouse body domain.UpdateWarehouseRequest true "Warehouse update data"
+// @Success 200 {object} domain.WarehouseSuccessResponse "Warehouse updated successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid warehouse ID / invalid request body"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "warehouse not found"
+// @Failure 500 {object} domain.ErrorResponse "could not update warehouse"
+// @Router /warehouses/{id} [put]
+func (h *WarehouseHandler) Update(c *gin.Context) {
+	warehouseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid warehouse ID"})
+		return
+	}
+
+	var req domain.UpdateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	warehouse, err := h.productService.UpdateWarehouse(c.Request.Context(), uint(warehouseID), &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrWarehouseNotFound) {
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "warehouse not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not update warehouse"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.WarehouseSuccessResponse{Message: "Warehouse updated successfully", Warehouse: *warehouse})
+}
+
+// SetStock godoc
+// @Summary Set the stock kept in a warehouse
+// @Description Set the stock on hand of a product without variants, or of one variant, in a warehouse (Admin only). The stock of the product is that of all warehouses. Stock held by active reservations in the warehouse cannot be taken away.
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Param stock body domain.WarehouseStockRequest true "Stock data"
+// @Success 200 {object} domain.WarehouseStockSuccessResponse "Warehouse stock updated successfully"
+// @Failure 400 {object} domain.ErrorResponse "Invalid warehouse ID / invalid request body / invalid product variant / invalid warehouse stock"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "warehouse not found / product not found / variant not found"
+// @Failure 500 {object} domain.ErrorResponse "could not update warehouse stock"
+// @Router /warehouses/{id}/stock [put]
+func (h *WarehouseHandler) SetStock(c *gin.Context) {
+	warehouseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid warehouse ID"})
+		return
+	}
+
+	var req domain.WarehouseStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	stock, err := h.productService.SetWarehouseStock(adminContext(c), uint(warehouseID), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidVariant), errors.Is(err, domain.ErrInvalidWarehouseStock):
+			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, domain.ErrWarehouseNotFound):
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "warehouse not found"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+		case errors.Is(err, domain.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "variant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not update warehouse stock"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.WarehouseStockSuccessResponse{Message: "Warehouse stock updated successfully", Stock: *stock})
+}
+
+// GetProductStock godoc
+// @Summary Get product stock per warehouse
+// @Description Get the stock on hand and reserved of a product, or of each of its variants, in every warehouse keeping it (Admin only)
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Product ID"
+// @Success 200 {object} domain.ProductWarehouseStocks "Stock of the product per warehouse"
+// @Failure 400 {object} domain.ErrorResponse "Invalid product ID"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Access denied: Admins only"
+// @Failure 404 {object} domain.ErrorResponse "product not found"
+// @Failure 500 {object} domain.ErrorResponse "could not get warehouse stock"
+// @Router /products/{id}/warehouse-stock [get]
+func (h *WarehouseHandler) GetProductStock(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	stocks, err := h.productService.GetProductWarehouseStocks(c.Request.Context(), uint(productID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: "could not get warehouse stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stocks)
+}
@@ -12,6 +12,10 @@ func InitProductConsumerGroup(ctx context.Context, client *redis.Client) error
     streams := map[string]string{
         "stream:orders:created": "product-group",
         "stream:payment:failed": "product-group",
+        "stream:orders:cancelled": "product-group",
+        "stream:orders:expired": "product-group",
+        "stream:orders:items_cancelled": "product-group",
+        "stream:orders:paid": "product-group",
     }
 
     for stream, group := range streams {
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"product-service/internal/domain"
 
 	"github.com/redis/go-redis/v9"
@@ -30,6 +31,20 @@ func (r *RedisRepository) PublishStockReservedEvent(ctx context.Context, event *
 		"order_id":       event.OrderID,
 		"correlation_id": correlationID,
 	}
+	if len(event.UnavailableItems) > 0 {
+		unavailableJSON, err := json.Marshal(event.UnavailableItems)
+		if err != nil {
+			return err
+		}
+		msg["unavailable_items"] = string(unavailableJSON)
+	}
+	if len(event.Allocations) > 0 {
+		allocationsJSON, err := json.Marshal(event.Allocations)
+		if err != nil {
+			return err
+		}
+		msg["allocations"] = string(allocationsJSON)
+	}
 
 	err := r.redisClient.XAdd(
 		ctx,
@@ -54,6 +69,13 @@ func (r *RedisRepository) PublishStockInsufficientEvent(ctx context.Context, eve
 		"order_id":       event.OrderID,
 		"correlation_id": correlationID,
 	}
+	if len(event.UnavailableItems) > 0 {
+		unavailableJSON, err := json.Marshal(event.UnavailableItems)
+		if err != nil {
+			return err
+		}
+		msg["unavailable_items"] = string(unavailableJSON)
+	}
 
 	err := r.redisClient.XAdd(
 		ctx,
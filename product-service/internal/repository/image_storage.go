@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageStorage keeps the files of product images and tells the public URL they are served under.
+// Keys are slash separated paths such as products/12/3f9a.jpg, a file saved under a key never moves.
+type ImageStorage interface {
+	Save(ctx context.Context, key, contentType string, data []byte) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalImageStorage stores images in a directory on disk, served by this service under baseURL
+type LocalImageStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalImageStorage(dir, baseURL string) *LocalImageStorage {
+	return &LocalImageStorage{dir: dir, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Save writes the file under key and returns its public URL. The file is written aside and renamed into
+// place, so it is never served half written.
+func (s *LocalImageStorage) Save(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete removes the file under key. A file that is already gone is not an error.
+func (s *LocalImageStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete image file: %w", err)
+	}
+	return nil
+}
+
+// path maps a key to a file inside the storage directory, rejecting keys that would escape it
+func (s *LocalImageStorage) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid image key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
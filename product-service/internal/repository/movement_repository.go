@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"product-service/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetupLedger records the opening stock of every product and variant in a warehouse the stock ledger has no
+// movement of yet, so the ledger adds up to the stock even for changes made before it existed
+func (r *PostgresRepository) SetupLedger() error {
+	return r.db.Exec(`INSERT INTO stock_movements (product_id, variant_id, warehouse_id, delta, reserved_delta, reason, actor, created_at)
+		SELECT warehouse_stocks.product_id, warehouse_stocks.variant_id, warehouse_stocks.warehouse_id,
+			warehouse_stocks.stock, warehouse_stocks.reserved, ?, 'system', NOW()
+		FROM warehouse_stocks
+		WHERE NOT EXISTS (SELECT 1 FROM stock_movements WHERE stock_movements.product_id = warehouse_stocks.product_id
+			AND stock_movements.variant_id = warehouse_stocks.variant_id AND stock_movements.warehouse_id = warehouse_stocks.warehouse_id)`,
+		domain.MovementOpening).Error
+}
+
+// recordMovement appends a movement to the stock ledger. It reports false and records nothing when the ledger
+// already holds the movement for the order or source of this one.
+func recordMovement(tx *gorm.DB, movement *domain.StockMovement) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(movement)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// recordMovements appends movements to the stock ledger and returns the stock changes of those it did not
+// hold yet, summed per product or variant and warehouse
+func recordMovements(tx *gorm.DB, movements []domain.StockMovement) (map[domain.WarehouseStockKey]stockChange, error) {
+	changes := make(map[domain.WarehouseStockKey]stockChange, len(movements))
+	for i := range movements {
+		recorded, err := recordMovement(tx, &movements[i])
+		if err != nil {
+			return nil, err
+		}
+		if !recorded {
+			continue
+		}
+		key := domain.StockKey{ProductID: movements[i].ProductID, VariantID: movements[i].VariantID}.In(movements[i].WarehouseID)
+		change := changes[key]
+		change.stock += movements[i].Delta
+		change.reserved += movements[i].ReservedDelta
+		changes[key] = change
+	}
+	return changes, nil
+}
+
+// orderMovement returns a movement of the stock of a line of an order in a warehouse
+func orderMovement(source domain.MovementSource, orderID uint, key domain.WarehouseStockKey, reason string, delta, reservedDelta int) domain.StockMovement {
+	movement := source.NewMovement(key, reason, delta, reservedDelta)
+	movement.OrderID = &orderID
+	return movement
+}
+
+// ListMovements returns a page of the stock movements of a product, newest first, and how many there are in
+// total. Each movement carries the stock on hand and reserved after it, counted over the product, or the
+// variant when the filter selects one, whatever the reason filter leaves out.
+func (r *PostgresRepository) ListMovements(filter *domain.MovementListFilter) ([]domain.StockMovement, int64, error) {
+	ledger := r.db.Model(&domain.StockMovement{}).
+		Select("stock_movements.*, SUM(delta) OVER (ORDER BY id) AS balance, SUM(reserved_delta) OVER (ORDER BY id) AS reserved_balance").
+		Where("product_id = ?", filter.ProductID)
+	if filter.VariantID != nil {
+		ledger = ledger.Where("variant_id = ?", *filter.VariantID)
+	}
+
+	query := r.db.Table("(?) AS stock_movements", ledger)
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var movements []domain.StockMovement
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&movements).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return movements, total, nil
+}
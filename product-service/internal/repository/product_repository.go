@@ -4,22 +4,46 @@ import (
 	"errors"
 	"fmt"
 	"product-service/internal/domain"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository interface {
-	SaveProduct(product *domain.CreateProductRequest) error
+	SaveProduct(product *domain.CreateProductRequest, source domain.MovementSource) error
 	CreateCategory(category *domain.Category) error
-	AddStock(productID uint, add int) error
+	AddStock(key domain.StockKey, warehouseID uint, add int, source domain.MovementSource) error
 	Delete(productID uint) error
 	GetByID(productID uint) (*domain.Product, error)
-	ListAll(category, min, max, search, order, sortBy string, page, limit int) ([]domain.Product, int64, error)
+	GetByIDs(productIDs []uint) ([]domain.Product, error)
+	ListAll(filter *domain.ProductListFilter) ([]domain.Product, int64, error)
+	GetFacets(filter *domain.ProductListFilter) (*domain.ProductFacets, error)
 	AssignCategory(productID uint, categoryID []uint) error
 	RemoveCategory(productID uint, categoryID uint) error
 	ListCategories(productID uint) ([]domain.Category, error)
-	UpdateProduct(id uint, req *domain.UpdateProductRequest) (*domain.Product, error)
-	AddStocksInTransaction(updates map[uint]int) error
+	UpdateProduct(id uint, req *domain.UpdateProductRequest, source domain.MovementSource) (*domain.Product, error)
+	ReserveStocks(orderID uint, quantities map[domain.StockKey]int, selection domain.WarehouseSelection, expiresAt time.Time, source domain.MovementSource) ([]domain.StockReservation, []domain.StockKey, error)
+	CommitReservations(orderID uint, source domain.MovementSource) ([]domain.StockReservation, error)
+	ReleaseReservations(orderID uint, lines map[domain.StockKey]int, source domain.MovementSource) error
+	ExpireReservations(now time.Time, limit int, source domain.MovementSource) ([]domain.StockReservation, error)
+	ListReservations(filter *domain.ReservationListFilter) ([]domain.StockReservation, int64, error)
+	ListMovements(filter *domain.MovementListFilter) ([]domain.StockMovement, int64, error)
+	CreateWarehouse(warehouse *domain.Warehouse) error
+	ListWarehouses() ([]domain.Warehouse, error)
+	UpdateWarehouse(id uint, req *domain.UpdateWarehouseRequest) (*domain.Warehouse, error)
+	SetWarehouseStock(warehouseID uint, key domain.StockKey, stock int, source domain.MovementSource) (*domain.WarehouseStock, error)
+	ListWarehouseStocks(productID uint) ([]domain.WarehouseStock, error)
+	AddVariant(productID uint, req *domain.VariantRequest, source domain.MovementSource) (*domain.ProductVariant, error)
+	UpdateVariant(productID, variantID uint, req *domain.UpdateVariantRequest, source domain.MovementSource) (*domain.ProductVariant, error)
+	DeleteVariant(productID, variantID uint, source domain.MovementSource) error
+	AddImage(image *domain.ProductImage) error
+	DeleteImage(productID, imageID uint) (*domain.ProductImage, error)
+	ReorderImages(productID uint, imageIDs []uint) ([]domain.ProductImage, error)
 }
 
 type PostgresRepository struct {
@@ -32,7 +56,7 @@ func NewPostgresRepository(db *gorm.DB) *PostgresRepository {
 
 // CREATE
 
-func (r *PostgresRepository) SaveProduct(req *domain.CreateProductRequest) error {
+func (r *PostgresRepository) SaveProduct(req *domain.CreateProductRequest, source domain.MovementSource) error {
     return r.db.Transaction(func(tx *gorm.DB) error {
         
         if len(req.CategoryIDs) > 0 {
@@ -58,12 +82,34 @@ func (r *PostgresRepository) SaveProduct(req *domain.CreateProductRequest) error
             Stock:       req.Stock,
             Categories:  categories,
         }
+        if len(req.Variants) > 0 {
+            product.Options = domain.NewProductOptions(req.Options)
+            product.Variants = make([]domain.ProductVariant, len(req.Variants))
+            for i, variant := range req.Variants {
+                product.Variants[i] = domain.NewProductVariant(variant)
+            }
+            // The product shows the lowest variant price and the stock of all variants
+            product.Price, product.Stock = product.Variants[0].Price, 0
+            for _, variant := range product.Variants {
+                product.Price = min(product.Price, variant.Price)
+                product.Stock += variant.Stock
+            }
+        }
 
         if err := tx.Omit("Categories.*").Create(&product).Error; err != nil {
             return err
         }
 
-        return nil
+        // The initial stock goes to the default warehouse, of the product or of each variant
+        variants := product.Variants
+        if len(variants) == 0 {
+            variants = []domain.ProductVariant{{ProductID: product.ID, Stock: product.Stock}}
+        }
+        if err := openWarehouseStocks(tx, variants, source); err != nil {
+            return err
+        }
+
+        return refreshSearchDocuments(tx, product.ID)
     })
 }
 
@@ -73,92 +119,327 @@ func (r *PostgresRepository) CreateCategory(category *domain.Category) error {
 
 // READ
 
-// FilterByCategory filters products by category ID
-func FilterByCategory(categoryID string) func(db *gorm.DB) *gorm.DB {
+// FilterByCategories filters products by category IDs. With CategoryMatchAll a product needs every
+// category, otherwise any one of them is enough.
+func FilterByCategories(categoryIDs []uint, match string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		if categoryID == "" {
+		if len(categoryIDs) == 0 {
 			return db
 		}
-		// Join with the many-to-many table to filter
-		return db.Joins("JOIN product_categories ON product_categories.product_id = products.id").
-			Where("product_categories.category_id = ?", categoryID)
+		// Subqueries instead of joins, a product in several of the categories must still come back once
+		if match == domain.CategoryMatchAll {
+			distinct := make(map[uint]bool, len(categoryIDs))
+			for _, id := range categoryIDs {
+				distinct[id] = true
+			}
+			return db.Where(`products.id IN (SELECT product_id FROM product_categories WHERE category_id IN ?
+				GROUP BY product_id HAVING COUNT(DISTINCT category_id) = ?)`, categoryIDs, len(distinct))
+		}
+		return db.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", categoryIDs)
 	}
 }
 
 // FilterByPriceRange filters products between min and max price
-func FilterByPriceRange(min, max string) func(db *gorm.DB) *gorm.DB {
+func FilterByPriceRange(min, max *int64) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		if min != "" {
-			db = db.Where("price >= ?", min)
+		if min != nil {
+			db = db.Where("products.price >= ?", *min)
 		}
-		if max != "" {
-			db = db.Where("price <= ?", max)
+		if max != nil {
+			db = db.Where("products.price <= ?", *max)
 		}
 		return db
 	}
 }
 
-// SearchByName performs a case-insensitive search
-func SearchByName(query string) func(db *gorm.DB) *gorm.DB {
+// FilterByStock keeps only products with stock available to order, or only those without
+func FilterByStock(inStock *bool) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		if query == "" {
+		if inStock == nil {
 			return db
 		}
-		// ILIKE is specific to PostgreSQL for case-insensitive search
-		return db.Where("products.name ILIKE ?", "%"+query+"%")
+		if *inStock {
+			return db.Where("products.stock - products.reserved > 0")
+		}
+		return db.Where("products.stock - products.reserved <= 0")
+	}
+}
+
+// Facets, each counted without its own filter
+const (
+	facetCategories   = "categories"
+	facetPrice        = "price"
+	facetAvailability = "availability"
+)
+
+// filterScopes returns the scopes of every filter set in filter, leaving out the filter of the facet named
+// by except. An empty except keeps them all.
+func filterScopes(filter *domain.ProductListFilter, except string) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{Search(filter.Search)}
+	if except != facetCategories {
+		scopes = append(scopes, FilterByCategories(filter.CategoryIDs, filter.CategoryMatch))
+	}
+	if except != facetPrice {
+		scopes = append(scopes, FilterByPriceRange(filter.MinPrice, filter.MaxPrice))
+	}
+	if except != facetAvailability {
+		scopes = append(scopes, FilterByStock(filter.InStock))
 	}
+	return scopes
+}
+
+// searchDocumentSQL computes the search document of the products row it runs on. Matches in the name
+// rank above matches in category names, which rank above matches in the description.
+const searchDocumentSQL = `setweight(to_tsvector('english', coalesce(products.name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce((
+		SELECT string_agg(categories.name, ' ') FROM categories
+		JOIN product_categories ON product_categories.category_id = categories.id
+		WHERE product_categories.product_id = products.id), '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(products.description, '')), 'C')`
+
+// searchSimilarityThreshold is the pg_trgm word similarity a product name needs to match a misspelt search
+const searchSimilarityThreshold = "0.4"
+
+// searchHighlightOptions wraps matched words in <mark> and keeps description snippets short
+const searchHighlightOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2"
+
+// sortColumns whitelists the sort_by values and maps them to columns, search_rank only exists on searches
+var sortColumns = map[string]string{
+	domain.SortByRelevance: "search_rank",
+	domain.SortByCreatedAt: "products.created_at",
+	"updated_at":           "products.updated_at",
+	"name":                 "products.name",
+	"price":                "products.price",
+	"stock":                "products.stock - products.reserved",
+}
+
+// SetupSearch enables pg_trgm, creates the search indexes and fills in the search document of products
+// saved without one, such as seeded products or products created before search documents existed
+func (r *PostgresRepository) SetupSearch() error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_products_search_document ON products USING gin (search_document)",
+		"CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin (name gin_trgm_ops)",
+		"UPDATE products SET search_document = " + searchDocumentSQL + " WHERE search_document IS NULL",
+	}
+	for _, statement := range statements {
+		if err := r.db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshSearchDocuments recomputes the search document of products after their name, description or
+// categories changed
+func refreshSearchDocuments(tx *gorm.DB, productIDs ...uint) error {
+	return tx.Exec("UPDATE products SET search_document = "+searchDocumentSQL+" WHERE id IN ?", productIDs).Error
+}
+
+// searchQuery turns free text into a prefix tsquery, so "wirel mou" becomes "wirel:* & mou:*".
+// Anything but letters and digits is dropped, which also keeps tsquery syntax out of user input.
+func searchQuery(search string) string {
+	terms := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Search matches products whose search document contains every search term as a prefix, or whose name
+// is close enough to the search text to be a misspelling of it
+func Search(search string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if search == "" {
+			return db
+		}
+		return db.Where("products.search_document @@ to_tsquery('english', ?) OR ? <% products.name", searchQuery(search), search)
+	}
+}
+
+// WithSearchRank selects the relevance of every search result as search_rank along with highlighted
+// snippets of its name and description
+func WithSearchRank(search string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if search == "" {
+			return db
+		}
+		query := searchQuery(search)
+		return db.Select(`products.*,
+			ts_rank_cd(products.search_document, to_tsquery('english', ?)) + word_similarity(?, products.name) AS search_rank,
+			ts_headline('english', products.name, to_tsquery('english', ?), ?) AS highlight_name,
+			ts_headline('english', products.description, to_tsquery('english', ?), ?) AS highlight_description`,
+			query, search, query, searchHighlightOptions, query, searchHighlightOptions)
+	}
+}
+
+// WithVariants loads the options of products, in their order, and their variants
+func WithVariants(db *gorm.DB) *gorm.DB {
+	return db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// WithImages loads the images of products in display order
+func WithImages(db *gorm.DB) *gorm.DB {
+	return db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	})
 }
 
 func OrderBy(sortBy, order string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		column := sortColumns[sortBy]
 		if order == "asc" {
-			return db.Order(sortBy + " ASC")
+			db = db.Order(column + " ASC")
 		} else if order == "desc" {
-			return db.Order(sortBy + " DESC")
+			db = db.Order(column + " DESC")
 		}
-		return db
+		// Keep pages stable when several products share the sort value
+		return db.Order("products.id")
 	}
 }
 
 func (r *PostgresRepository) GetByID(productID uint) (*domain.Product, error) {
 	var product domain.Product
-	result := r.db.First(&product, productID)
+	result := r.db.Scopes(WithVariants, WithImages).First(&product, productID)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &product, nil
 }
 
-func (r *PostgresRepository) ListAll(search, category, min, max, order, sortBy string, page, limit int) ([]domain.Product, int64, error) {
+// GetByIDs loads several products with their categories and variants, soft deleted products and variants included
+func (r *PostgresRepository) GetByIDs(productIDs []uint) ([]domain.Product, error) {
 	var products []domain.Product
-	var total int64
+	if err := r.db.Unscoped().Preload("Categories").Scopes(WithVariants, WithImages).Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// searchTransaction runs fn in a transaction. For searches it first loosens the word similarity threshold
+// for that transaction only, so a word with a typo or two still matches a product name.
+func (r *PostgresRepository) searchTransaction(search string, fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if search != "" {
+			if err := tx.Exec("SET LOCAL pg_trgm.word_similarity_threshold = " + searchSimilarityThreshold).Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
 
-	// Build base query
-	query := r.db.Model(&domain.Product{}).
-		Scopes(
-			FilterByCategory(category),
-			FilterByPriceRange(min, max),
-			SearchByName(search),
-		)
+func (r *PostgresRepository) ListAll(filter *domain.ProductListFilter) ([]domain.Product, int64, error) {
+	var products []domain.Product
+	var total int64
 
-	// Count total records
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if _, ok := sortColumns[filter.SortBy]; !ok || (filter.SortBy == domain.SortByRelevance && filter.Search == "") {
+		return nil, 0, fmt.Errorf("%w: %s", domain.ErrInvalidSortField, filter.SortBy)
 	}
 
-	// Calculate offset
-	offset := (page - 1) * limit
+	err := r.searchTransaction(filter.Search, func(tx *gorm.DB) error {
+		// Build base query
+		query := tx.Model(&domain.Product{}).Scopes(filterScopes(filter, "")...)
+
+		// Count total records
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+
+		// Calculate offset
+		offset := (filter.Page - 1) * filter.Limit
 
-	// Apply pagination, ordering, and load data
-	err := query.Preload("Categories").
-		Scopes(OrderBy(sortBy, order)).
-		Offset(offset).
-		Limit(limit).
-		Find(&products).Error
+		// Apply pagination, ordering, and load data
+		return query.Preload("Categories").
+			Scopes(WithVariants, WithImages, WithSearchRank(filter.Search), OrderBy(filter.SortBy, filter.Order)).
+			Offset(offset).
+			Limit(filter.Limit).
+			Find(&products).Error
+	})
 
 	return products, total, err
 }
 
+// priceBucketSQL numbers the price range of domain.PriceFacetBounds a product falls in, 0 is below the first bound
+func priceBucketSQL() string {
+	bounds := make([]string, len(domain.PriceFacetBounds))
+	for i, bound := range domain.PriceFacetBounds {
+		bounds[i] = strconv.FormatInt(bound, 10)
+	}
+	return "width_bucket(products.price, ARRAY[" + strings.Join(bounds, ",") + "]::bigint[])"
+}
+
+// GetFacets counts the products matching filter per category, price range and availability
+func (r *PostgresRepository) GetFacets(filter *domain.ProductListFilter) (*domain.ProductFacets, error) {
+	facets := &domain.ProductFacets{Categories: []domain.CategoryFacet{}}
+
+	err := r.searchTransaction(filter.Search, func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Product{}).
+			Scopes(filterScopes(filter, facetCategories)...).
+			Joins("JOIN product_categories ON product_categories.product_id = products.id").
+			Joins("JOIN categories ON categories.id = product_categories.category_id").
+			Select("categories.id, categories.name, COUNT(*) AS count").
+			Group("categories.id, categories.name").
+			Order("categories.name").
+			Scan(&facets.Categories).Error
+		if err != nil {
+			return err
+		}
+
+		var buckets []priceBucketCount
+		err = tx.Model(&domain.Product{}).
+			Scopes(filterScopes(filter, facetPrice)...).
+			Select(priceBucketSQL() + " AS bucket, COUNT(*) AS count").
+			Group("bucket").
+			Scan(&buckets).Error
+		if err != nil {
+			return err
+		}
+		facets.PriceRanges = priceRanges(buckets)
+
+		return tx.Model(&domain.Product{}).
+			Scopes(filterScopes(filter, facetAvailability)...).
+			Select("COUNT(*) FILTER (WHERE products.stock - products.reserved > 0) AS in_stock, COUNT(*) FILTER (WHERE products.stock - products.reserved <= 0) AS out_of_stock").
+			Scan(&facets.Availability).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+type priceBucketCount struct {
+	Bucket int
+	Count  int64
+}
+
+// priceRanges lists every price range of domain.PriceFacetBounds with the counts of the buckets found
+func priceRanges(buckets []priceBucketCount) []domain.PriceRangeFacet {
+	bounds := domain.PriceFacetBounds
+	ranges := make([]domain.PriceRangeFacet, len(bounds)+1)
+	for i := range ranges {
+		if i > 0 {
+			ranges[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			max := bounds[i]
+			ranges[i].Max = &max
+		}
+	}
+	for _, bucket := range buckets {
+		if bucket.Bucket >= 0 && bucket.Bucket < len(ranges) {
+			ranges[bucket.Bucket].Count = bucket.Count
+		}
+	}
+	return ranges
+}
+
 func (r *PostgresRepository) ListCategories(productID uint) ([]domain.Category, error) {
 	var categories []domain.Category
 	result := r.db.Joins("JOIN product_categories ON categories.id = product_categories.category_id").
@@ -172,28 +453,161 @@ func (r *PostgresRepository) ListCategories(productID uint) ([]domain.Category,
 
 // UPDATE
 
-func (r *PostgresRepository) AddStocksInTransaction(updates map[uint]int) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		for productID, add := range updates {
-			result := tx.Model(&domain.Product{}).
-				Where("id = ?", productID).
-				Where("stock + ? >= 0", add).
-				UpdateColumn("stock", gorm.Expr("stock + ?", add))
+// hasNoVariantsSQL matches products not sold in variants, whose stock is kept per product rather than per
+// variant
+const hasNoVariantsSQL = "NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL)"
+
+// stockChange is a change to the stock on hand and to the reserved stock of a product or variant
+type stockChange struct {
+	stock    int
+	reserved int
+}
+
+// lowersAvailable reports whether the change takes away available stock, which needs enough of it
+func (c stockChange) lowersAvailable() bool {
+	return c.stock-c.reserved < 0
+}
+
+func (c stockChange) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"stock":    gorm.Expr("stock + ?", c.stock),
+		"reserved": gorm.Expr("reserved + ?", c.reserved),
+	}
+}
 
-			if result.Error != nil {
-				return result.Error
+// sellableStockSQL keeps the warehouse stock of deleted products and variants from changing
+const sellableStockSQL = `EXISTS (SELECT 1 FROM products WHERE products.id = warehouse_stocks.product_id AND products.deleted_at IS NULL)
+	AND (warehouse_stocks.variant_id = 0 OR EXISTS (SELECT 1 FROM product_variants
+		WHERE product_variants.id = warehouse_stocks.variant_id AND product_variants.deleted_at IS NULL))`
+
+// applyStocks applies the stock changes of updates to the warehouses keeping the stock, skipping and returning
+// those whose product or variant is missing, not kept in the warehouse or, when guarded, does not have the
+// available stock a change takes away there. The changes applied are added to the variants and products,
+// whose stock is that of all warehouses. Warehouse rows are locked before variant rows and those before
+// product rows, each in key order, so concurrent calls cannot deadlock.
+func applyStocks(tx *gorm.DB, updates map[domain.WarehouseStockKey]stockChange, guarded bool) ([]domain.WarehouseStockKey, error) {
+	keys := make([]domain.WarehouseStockKey, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+
+	var unavailable []domain.WarehouseStockKey
+	variantDeltas := make(map[domain.StockKey]stockChange)
+	var variantKeys []domain.StockKey
+	productDeltas := make(map[uint]stockChange)
+	var productIDs []uint
+	for _, key := range keys {
+		change := updates[key]
+		query := tx.Model(&domain.WarehouseStock{}).
+			Where("product_id = ? AND variant_id = ? AND warehouse_id = ?", key.ProductID, key.VariantID, key.WarehouseID).
+			Where(sellableStockSQL)
+		if guarded && change.lowersAvailable() {
+			query = query.Where("stock - reserved + ? >= 0", change.stock-change.reserved)
+		}
+		result := query.UpdateColumns(change.columns())
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			unavailable = append(unavailable, key)
+			continue
+		}
+
+		if key.VariantID != 0 {
+			if len(variantKeys) == 0 || variantKeys[len(variantKeys)-1] != key.StockKey {
+				variantKeys = append(variantKeys, key.StockKey)
 			}
+			delta := variantDeltas[key.StockKey]
+			variantDeltas[key.StockKey] = stockChange{stock: delta.stock + change.stock, reserved: delta.reserved + change.reserved}
+		}
+		if len(productIDs) == 0 || productIDs[len(productIDs)-1] != key.ProductID {
+			productIDs = append(productIDs, key.ProductID)
+		}
+		delta := productDeltas[key.ProductID]
+		productDeltas[key.ProductID] = stockChange{stock: delta.stock + change.stock, reserved: delta.reserved + change.reserved}
+	}
 
-			// If no rows were changed, it means the product ID is wrong OR the result would have been negative.
-			if result.RowsAffected == 0 {
-				return errors.New("product not found or resulting stock would be negative for product ID " + fmt.Sprint(productID))
+	for _, key := range variantKeys {
+		if delta := variantDeltas[key]; delta != (stockChange{}) {
+			err := tx.Model(&domain.ProductVariant{}).
+				Where("id = ? AND product_id = ?", key.VariantID, key.ProductID).
+				UpdateColumns(delta.columns()).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, productID := range productIDs {
+		if delta := productDeltas[productID]; delta != (stockChange{}) {
+			err := tx.Model(&domain.Product{}).
+				Where("id = ?", productID).
+				UpdateColumns(delta.columns()).Error
+			if err != nil {
+				return nil, err
 			}
 		}
+	}
+	return unavailable, nil
+}
+
+// defaultWarehouse returns the first warehouse by priority, the one stock set on a product or variant goes to
+func defaultWarehouse(tx *gorm.DB) (*domain.Warehouse, error) {
+	var warehouse domain.Warehouse
+	err := tx.Order("priority, id").First(&warehouse).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// setTotalStock brings the stock on hand of a product or variant over all warehouses to stock by changing
+// its stock in the default warehouse. The stock of the other warehouses is only set per warehouse, so a
+// decrease the default warehouse does not have the available stock for is refused.
+func setTotalStock(tx *gorm.DB, key domain.StockKey, stock int, source domain.MovementSource) error {
+	// Locked so the stock change recorded in the ledger is the one made
+	var stocks []domain.WarehouseStock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND variant_id = ?", key.ProductID, key.VariantID).
+		Order("warehouse_id").
+		Find(&stocks).Error; err != nil {
+		return err
+	}
+	total := 0
+	for _, s := range stocks {
+		total += s.Stock
+	}
+	if stock == total {
 		return nil
-	})
+	}
+
+	warehouse, err := defaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	target := key.In(warehouse.ID)
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.WarehouseStock{ProductID: key.ProductID, VariantID: key.VariantID, WarehouseID: warehouse.ID}).Error; err != nil {
+		return err
+	}
+	changes, err := recordMovements(tx, []domain.StockMovement{source.NewMovement(target, domain.MovementAdjust, stock-total, 0)})
+	if err != nil {
+		return err
+	}
+	unavailable, err := applyStocks(tx, changes, true)
+	if err != nil {
+		return err
+	}
+	if len(unavailable) > 0 {
+		return fmt.Errorf("%w: warehouse %s does not have %d available to take away, set the stock per warehouse", domain.ErrInvalidWarehouseStock, warehouse.Code, total-stock)
+	}
+	return nil
 }
 
-func (r *PostgresRepository) UpdateProduct(id uint, req *domain.UpdateProductRequest) (*domain.Product, error) {
+func (r *PostgresRepository) UpdateProduct(id uint, req *domain.UpdateProductRequest, source domain.MovementSource) (*domain.Product, error) {
     var product domain.Product
 
     err := r.db.Transaction(func(tx *gorm.DB) error {
@@ -202,12 +616,28 @@ func (r *PostgresRepository) UpdateProduct(id uint, req *domain.UpdateProductReq
             return err
         }
 
+        // Price and stock of a product sold in variants follow its variants
+        if req.Price != nil || req.Stock != nil {
+            var variants int64
+            if err := tx.Model(&domain.ProductVariant{}).Where("product_id = ?", id).Count(&variants).Error; err != nil {
+                return err
+            }
+            if variants > 0 {
+                return fmt.Errorf("%w: price and stock of a product with variants are set per variant", domain.ErrInvalidVariant)
+            }
+        }
+
         // 2. Update specific fields (Map logic)
         updates := make(map[string]interface{})
         if req.Name != nil { updates["name"] = *req.Name }
         if req.Description != nil { updates["description"] = *req.Description }
         if req.Price != nil { updates["price"] = *req.Price }
-        if req.Stock != nil { updates["stock"] = *req.Stock }
+
+        if req.Stock != nil {
+            if err := setTotalStock(tx, domain.StockKey{ProductID: id}, *req.Stock, source); err != nil {
+                return err
+            }
+        }
 
         if len(updates) > 0 {
             if err := tx.Model(&product).Updates(updates).Error; err != nil {
@@ -226,29 +656,331 @@ func (r *PostgresRepository) UpdateProduct(id uint, req *domain.UpdateProductReq
             }
         }
 
+        if err := refreshSearchDocuments(tx, id); err != nil {
+            return err
+        }
+
         // 4. IMPORTANT: Re-fetch the product with Categories to get the "Final" version
-        return tx.Preload("Categories").First(&product, id).Error
+        return tx.Preload("Categories").Scopes(WithVariants, WithImages).First(&product, id).Error
     })
 
     return &product, err
 }
 
-func (r *PostgresRepository) AddStock(productID uint, add int) error {
-	result := r.db.Model(&domain.Product{}).
-		Where("id = ?", productID).
-		Where("stock + ? >= 0", add).
-		UpdateColumn("stock", gorm.Expr("stock + ?", add))
+// AddStock changes the stock on hand of a product or variant in a warehouse, the default warehouse when
+// warehouseID is 0. Stock held by active reservations cannot be taken away. A source the ledger already
+// holds an adjustment of the line in the warehouse for changes nothing.
+func (r *PostgresRepository) AddStock(key domain.StockKey, warehouseID uint, add int, source domain.MovementSource) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if warehouseID == 0 {
+			warehouse, err := defaultWarehouse(tx)
+			if err != nil {
+				return err
+			}
+			warehouseID = warehouse.ID
+		}
+		if add > 0 {
+			if err := addWarehouseStock(tx, key.In(warehouseID)); err != nil {
+				return err
+			}
+		}
 
-	if result.Error != nil {
-		return result.Error
+		changes, err := recordMovements(tx, []domain.StockMovement{source.NewMovement(key.In(warehouseID), domain.MovementAdjust, add, 0)})
+		if err != nil || len(changes) == 0 {
+			return err
+		}
+		unavailable, err := applyStocks(tx, changes, true)
+		if err != nil {
+			return err
+		}
+
+		// If the line was skipped, it means the ID is wrong OR the result would have been negative.
+		if len(unavailable) > 0 {
+			return errors.New("product not found or resulting stock would be negative")
+		}
+		return nil
+	})
+}
+
+// addWarehouseStock lets a warehouse keep the stock of a product without variants or of a variant, adding
+// an empty stock row for it when the warehouse has none yet
+func addWarehouseStock(tx *gorm.DB, key domain.WarehouseStockKey) error {
+	var warehouse domain.Warehouse
+	err := tx.Select("id").First(&warehouse, key.WarehouseID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrWarehouseNotFound
+	}
+	if err != nil {
+		return err
 	}
 
-	// If no rows were changed, it means the product ID is wrong OR the result would have been negative.
-	if result.RowsAffected == 0 {
-		return errors.New("product not found or resulting stock would be negative")
+	var product domain.Product
+	if err := tx.Select("id").First(&product, key.ProductID).Error; err != nil {
+		return err
+	}
+	if key.VariantID != 0 {
+		var variant domain.ProductVariant
+		if err := findVariant(tx, key.ProductID, key.VariantID, &variant); err != nil {
+			return err
+		}
+	} else {
+		var variants int64
+		if err := tx.Model(&domain.ProductVariant{}).Where("product_id = ?", key.ProductID).Count(&variants).Error; err != nil {
+			return err
+		}
+		if variants > 0 {
+			return fmt.Errorf("%w: the stock of a product with variants is kept per variant", domain.ErrInvalidVariant)
+		}
 	}
 
-	return nil
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.WarehouseStock{ProductID: key.ProductID, VariantID: key.VariantID, WarehouseID: key.WarehouseID}).Error
+}
+
+// AddVariant adds a variant to a product created with options. Option values the product did not have yet
+// are added to its options.
+func (r *PostgresRepository) AddVariant(productID uint, req *domain.VariantRequest, source domain.MovementSource) (*domain.ProductVariant, error) {
+	variant := domain.NewProductVariant(*req)
+	variant.ProductID = productID
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var product domain.Product
+		if err := tx.Scopes(WithVariants).First(&product, productID).Error; err != nil {
+			return err
+		}
+
+		for i := range product.Options {
+			if product.Options[i].AddValue(req.Options[product.Options[i].Name]) {
+				if err := tx.Save(&product.Options[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := domain.ValidateNewVariant(product.Options, product.Variants, *req); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		if err := openWarehouseStocks(tx, []domain.ProductVariant{variant}, source); err != nil {
+			return err
+		}
+		return refreshVariantTotals(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *PostgresRepository) UpdateVariant(productID, variantID uint, req *domain.UpdateVariantRequest, source domain.MovementSource) (*domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := findVariant(tx, productID, variantID, &variant); err != nil {
+			return err
+		}
+
+		updates := make(map[string]interface{})
+		if req.Barcode != nil {
+			updates["barcode"] = *req.Barcode
+		}
+		if req.Price != nil {
+			updates["price"] = *req.Price
+		}
+		if len(updates) == 0 && req.Stock == nil {
+			return nil
+		}
+
+		if req.Stock != nil {
+			if err := setTotalStock(tx, variant.Key(), *req.Stock, source); err != nil {
+				return err
+			}
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&variant).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := refreshVariantTotals(tx, productID); err != nil {
+			return err
+		}
+		return tx.First(&variant, variantID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// DeleteVariant stops selling a variant. The last variant of a product cannot be deleted, delete the product instead.
+// The stock of the variant leaves the product and the warehouses along with it.
+func (r *PostgresRepository) DeleteVariant(productID, variantID uint, source domain.MovementSource) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var variant domain.ProductVariant
+		if err := findVariant(tx, productID, variantID, &variant); err != nil {
+			return err
+		}
+
+		var variants int64
+		if err := tx.Model(&domain.ProductVariant{}).Where("product_id = ?", productID).Count(&variants).Error; err != nil {
+			return err
+		}
+		if variants <= 1 {
+			return fmt.Errorf("%w: the last variant of a product cannot be deleted", domain.ErrInvalidVariant)
+		}
+
+		var stocks []domain.WarehouseStock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ? AND variant_id = ?", productID, variantID).
+			Order("warehouse_id").
+			Find(&stocks).Error; err != nil {
+			return err
+		}
+		for _, stock := range stocks {
+			if stock.Stock == 0 && stock.Reserved == 0 {
+				continue
+			}
+			movement := source.NewMovement(stock.Key(), domain.MovementAdjust, -stock.Stock, -stock.Reserved)
+			if _, err := recordMovement(tx, &movement); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("product_id = ? AND variant_id = ?", productID, variantID).Delete(&domain.WarehouseStock{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		return refreshVariantTotals(tx, productID)
+	})
+}
+
+// findVariant loads a variant of a product, reporting domain.ErrVariantNotFound for a variant of another product
+func findVariant(tx *gorm.DB, productID, variantID uint, variant *domain.ProductVariant) error {
+	err := tx.Where("product_id = ?", productID).First(variant, variantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrVariantNotFound
+	}
+	return err
+}
+
+// refreshVariantTotals sets the price of a product to its lowest variant price and its stock and reserved
+// stock to those of all its variants
+func refreshVariantTotals(tx *gorm.DB, productID uint) error {
+	return tx.Exec(`UPDATE products SET price = totals.price, stock = totals.stock, reserved = totals.reserved
+		FROM (SELECT MIN(price) AS price, SUM(stock) AS stock, SUM(reserved) AS reserved FROM product_variants
+			WHERE product_id = ? AND deleted_at IS NULL) totals
+		WHERE products.id = ? AND totals.price IS NOT NULL`, productID, productID).Error
+}
+
+// openWarehouseStocks puts the initial stock of new variants in the default warehouse and opens their ledger.
+// A variant without an ID stands for a product without variants.
+func openWarehouseStocks(tx *gorm.DB, variants []domain.ProductVariant, source domain.MovementSource) error {
+	warehouse, err := defaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	stocks := make([]domain.WarehouseStock, len(variants))
+	movements := make([]domain.StockMovement, len(variants))
+	for i, variant := range variants {
+		stocks[i] = domain.WarehouseStock{ProductID: variant.ProductID, VariantID: variant.ID, WarehouseID: warehouse.ID, Stock: variant.Stock}
+		movements[i] = source.NewMovement(stocks[i].Key(), domain.MovementAdjust, variant.Stock, 0)
+	}
+	if err := tx.Create(&stocks).Error; err != nil {
+		return err
+	}
+	_, err = recordMovements(tx, movements)
+	return err
+}
+
+// AddImage appends an image to the images of a product
+func (r *PostgresRepository) AddImage(image *domain.ProductImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the product keeps concurrent uploads from taking the same position
+		if err := lockProduct(tx, image.ProductID); err != nil {
+			return err
+		}
+
+		var last *int
+		if err := tx.Model(&domain.ProductImage{}).Where("product_id = ?", image.ProductID).
+			Select("MAX(position)").Scan(&last).Error; err != nil {
+			return err
+		}
+		image.Position = 0
+		if last != nil {
+			image.Position = *last + 1
+		}
+		return tx.Create(image).Error
+	})
+}
+
+// DeleteImage removes an image of a product and returns it, so its files can be deleted from the image storage
+func (r *PostgresRepository) DeleteImage(productID, imageID uint) (*domain.ProductImage, error) {
+	var image domain.ProductImage
+	err := r.db.Where("product_id = ?", productID).First(&image, imageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Delete(&image).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// ReorderImages puts the images of a product in the order of imageIDs, which must list each of them once
+func (r *PostgresRepository) ReorderImages(productID uint, imageIDs []uint) ([]domain.ProductImage, error) {
+	var images []domain.ProductImage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", productID).Find(&images).Error; err != nil {
+			return err
+		}
+
+		positions := make(map[uint]int, len(imageIDs))
+		for i, id := range imageIDs {
+			if _, ok := positions[id]; ok {
+				return fmt.Errorf("%w: image %d is listed twice", domain.ErrInvalidImageOrder, id)
+			}
+			positions[id] = i
+		}
+		if len(positions) != len(images) {
+			return fmt.Errorf("%w: the product has %d images, %d were listed", domain.ErrInvalidImageOrder, len(images), len(positions))
+		}
+
+		for i := range images {
+			position, ok := positions[images[i].ID]
+			if !ok {
+				return fmt.Errorf("%w: image %d is not listed", domain.ErrInvalidImageOrder, images[i].ID)
+			}
+			if images[i].Position == position {
+				continue
+			}
+			if err := tx.Model(&images[i]).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		sort.Slice(images, func(i, j int) bool { return images[i].Position < images[j].Position })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// lockProduct locks the row of a product until the end of the transaction
+func lockProduct(tx *gorm.DB, productID uint) error {
+	var product domain.Product
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&product, productID).Error
 }
 
 func (r *PostgresRepository) AssignCategory(productID uint, categoryIDs []uint) error {
@@ -257,16 +989,28 @@ func (r *PostgresRepository) AssignCategory(productID uint, categoryIDs []uint)
 		categories[i] = domain.Category{ID: id}
 	}
 
-	return r.db.Model(&domain.Product{ID: productID}).
-		Omit("Categories.*").
-		Association("Categories").
-		Append(&categories)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Product{ID: productID}).
+			Omit("Categories.*").
+			Association("Categories").
+			Append(&categories)
+		if err != nil {
+			return err
+		}
+		return refreshSearchDocuments(tx, productID)
+	})
 }
 
 func (r *PostgresRepository) RemoveCategory(productID uint, categoryID uint) error {
-	return r.db.Model(&domain.Product{ID: productID}).
-		Association("Categories").
-		Delete(&domain.Category{ID: categoryID})
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Product{ID: productID}).
+			Association("Categories").
+			Delete(&domain.Category{ID: categoryID})
+		if err != nil {
+			return err
+		}
+		return refreshSearchDocuments(tx, productID)
+	})
 }
 
 // DELETE
@@ -4,6 +4,7 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -24,9 +25,12 @@ func openProductTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Skipf("skipping integration test, cannot connect to product-db: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.Category{}, &domain.Product{}); err != nil {
+	if err := db.AutoMigrate(&domain.Category{}, &domain.Product{}, &domain.ProductOption{}, &domain.ProductVariant{}, &domain.ProductImage{}, &domain.StockReservation{}, &domain.StockMovement{}, &domain.Warehouse{}, &domain.WarehouseStock{}); err != nil {
 		t.Fatalf("AutoMigrate() error = %v", err)
 	}
+	if err := NewPostgresRepository(db).SetupWarehouses(); err != nil {
+		t.Fatalf("SetupWarehouses() error = %v", err)
+	}
 	return db
 }
 
@@ -46,11 +50,11 @@ func TestProductRepository_CreateAndGet_Integration(t *testing.T) {
 		Stock:       7,
 		CategoryIDs: []uint{category.ID},
 	}
-	if err := repo.SaveProduct(req); err != nil {
+	if err := repo.SaveProduct(req, testSource); err != nil {
 		t.Fatalf("SaveProduct() error = %v", err)
 	}
 
-	products, _, err := repo.ListAll("", "", "", "", "", "", 1, 10)
+	products, _, err := repo.ListAll(&domain.ProductListFilter{SortBy: domain.SortByCreatedAt, Page: 1, Limit: 10})
 	if err != nil {
 		t.Fatalf("ListAll() error = %v", err)
 	}
@@ -58,3 +62,567 @@ func TestProductRepository_CreateAndGet_Integration(t *testing.T) {
 		t.Fatal("expected at least one product in list")
 	}
 }
+
+// testSource records the stock movements of the tests
+var testSource = domain.MovementSource{Actor: "test"}
+
+// priority reserves the lines of the tests from warehouses by priority
+var priority = domain.WarehouseSelection{Strategy: domain.WarehouseStrategyPriority}
+
+// testOrderID returns an order ID no other test run has reserved stock for
+func testOrderID() uint {
+	return uint(time.Now().UnixNano() % 1_000_000_000)
+}
+
+func TestProductRepository_ReserveStocksSkipsShortLines_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	plenty := &domain.Product{Name: fmt.Sprintf("plenty-%d", time.Now().UnixNano()), Price: 100, Stock: 5}
+	short := &domain.Product{Name: fmt.Sprintf("short-%d", time.Now().UnixNano()), Price: 100, Stock: 1}
+	for _, product := range []*domain.Product{plenty, short} {
+		if err := db.Create(product).Error; err != nil {
+			t.Fatalf("create product error = %v", err)
+		}
+	}
+	// Products created directly have their stock put in the default warehouse
+	if err := repo.SetupWarehouses(); err != nil {
+		t.Fatalf("SetupWarehouses() error = %v", err)
+	}
+
+	orderID := testOrderID()
+	quantities := map[domain.StockKey]int{{ProductID: plenty.ID}: 3, {ProductID: short.ID}: 2}
+	for attempt := 0; attempt < 2; attempt++ {
+		// The second attempt is a redelivery, it must not reserve again
+		_, unavailable, err := repo.ReserveStocks(orderID, quantities, priority, time.Now().Add(time.Hour), testSource)
+		if err != nil {
+			t.Fatalf("ReserveStocks() error = %v", err)
+		}
+		if len(unavailable) != 1 || unavailable[0] != (domain.StockKey{ProductID: short.ID}) {
+			t.Fatalf("expected only product %d unavailable, got %v", short.ID, unavailable)
+		}
+	}
+
+	got, err := repo.GetByIDs([]uint{plenty.ID, short.ID})
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	for _, product := range got {
+		if product.ID == plenty.ID && (product.Stock != 5 || product.AvailableStock() != 2) {
+			t.Fatalf("expected 5 on hand and 2 available for reserved product, got %d and %d", product.Stock, product.AvailableStock())
+		}
+		if product.ID == short.ID && (product.Stock != 1 || product.Reserved != 0) {
+			t.Fatalf("expected stock of skipped product untouched, got %d on hand and %d reserved", product.Stock, product.Reserved)
+		}
+	}
+}
+
+func TestProductRepository_ReserveStocksOnlyReservesWhatTheLedgerRecords_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	product := &domain.Product{Name: fmt.Sprintf("redelivered-%d", time.Now().UnixNano()), Price: 100, Stock: 5}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("create product error = %v", err)
+	}
+	if err := repo.SetupWarehouses(); err != nil {
+		t.Fatalf("SetupWarehouses() error = %v", err)
+	}
+
+	orderID := testOrderID()
+	quantities := map[domain.StockKey]int{{ProductID: product.ID}: 2}
+	if _, _, err := repo.ReserveStocks(orderID, quantities, priority, time.Now().Add(time.Hour), testSource); err != nil {
+		t.Fatalf("ReserveStocks() error = %v", err)
+	}
+	// Without its reservations the redelivered order is only stopped by the ledger
+	if err := db.Where("order_id = ?", orderID).Delete(&domain.StockReservation{}).Error; err != nil {
+		t.Fatalf("delete reservations error = %v", err)
+	}
+	reservations, _, err := repo.ReserveStocks(orderID, quantities, priority, time.Now().Add(time.Hour), testSource)
+	if err != nil {
+		t.Fatalf("ReserveStocks() redelivery error = %v", err)
+	}
+	if len(reservations) != 0 {
+		t.Fatalf("expected no reservation made again, got %+v", reservations)
+	}
+
+	got, err := repo.GetByID(product.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Reserved != 2 {
+		t.Fatalf("expected 2 reserved once, got %d", got.Reserved)
+	}
+}
+
+func TestProductRepository_ReservationsCommitAndRelease_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	product := &domain.Product{Name: fmt.Sprintf("reserved-%d", time.Now().UnixNano()), Price: 100, Stock: 5}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("create product error = %v", err)
+	}
+	if err := repo.SetupWarehouses(); err != nil {
+		t.Fatalf("SetupWarehouses() error = %v", err)
+	}
+	key := domain.StockKey{ProductID: product.ID}
+	stockOf := func() (int, int) {
+		t.Helper()
+		got, err := repo.GetByID(product.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		return got.Stock, got.Reserved
+	}
+
+	paid, expired := testOrderID(), testOrderID()+1
+	if _, _, err := repo.ReserveStocks(paid, map[domain.StockKey]int{key: 2}, priority, time.Now().Add(time.Hour), testSource); err != nil {
+		t.Fatalf("ReserveStocks() error = %v", err)
+	}
+	if _, _, err := repo.ReserveStocks(expired, map[domain.StockKey]int{key: 3}, priority, time.Now().Add(-time.Minute), testSource); err != nil {
+		t.Fatalf("ReserveStocks() error = %v", err)
+	}
+	// Everything on hand is reserved, so a third order gets nothing
+	_, unavailable, err := repo.ReserveStocks(testOrderID()+2, map[domain.StockKey]int{key: 1}, priority, time.Now().Add(time.Hour), testSource)
+	if err != nil {
+		t.Fatalf("ReserveStocks() error = %v", err)
+	}
+	if len(unavailable) != 1 {
+		t.Fatalf("expected the third order unavailable, got %v", unavailable)
+	}
+	if stock, reserved := stockOf(); stock != 5 || reserved != 5 {
+		t.Fatalf("expected 5 on hand and 5 reserved, got %d and %d", stock, reserved)
+	}
+
+	if _, err := repo.ExpireReservations(time.Now(), 1000, testSource); err != nil {
+		t.Fatalf("ExpireReservations() error = %v", err)
+	}
+	if stock, reserved := stockOf(); stock != 5 || reserved != 2 {
+		t.Fatalf("expected the expired reservation released, got %d on hand and %d reserved", stock, reserved)
+	}
+
+	committed, err := repo.CommitReservations(paid, testSource)
+	if err != nil {
+		t.Fatalf("CommitReservations() error = %v", err)
+	}
+	if len(committed) != 1 || committed[0].Quantity != 2 {
+		t.Fatalf("expected one reservation of 2 committed, got %+v", committed)
+	}
+	if committed, err = repo.CommitReservations(paid, testSource); err != nil || len(committed) != 0 {
+		t.Fatalf("expected a second commit to change nothing, got %+v, %v", committed, err)
+	}
+	if stock, reserved := stockOf(); stock != 3 || reserved != 0 {
+		t.Fatalf("expected 3 on hand and nothing reserved after the commit, got %d and %d", stock, reserved)
+	}
+
+	// Cancelling the paid order puts its stock back on the shelf, releasing the expired one changes nothing
+	if err := repo.ReleaseReservations(paid, map[domain.StockKey]int{key: 2}, testSource); err != nil {
+		t.Fatalf("ReleaseReservations() error = %v", err)
+	}
+	if err := repo.ReleaseReservations(expired, map[domain.StockKey]int{key: 3}, testSource); err != nil {
+		t.Fatalf("ReleaseReservations() error = %v", err)
+	}
+	if stock, reserved := stockOf(); stock != 5 || reserved != 0 {
+		t.Fatalf("expected 5 on hand and nothing reserved after the release, got %d and %d", stock, reserved)
+	}
+
+	reservations, total, err := repo.ListReservations(&domain.ReservationListFilter{ProductID: product.ID, Status: domain.ReservationReturned, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListReservations() error = %v", err)
+	}
+	if total != 1 || len(reservations) != 1 || reservations[0].OrderID != paid {
+		t.Fatalf("expected the paid order's reservation returned, got %d: %+v", total, reservations)
+	}
+}
+
+func TestProductRepository_LedgerAppliesEachMovementOnce_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	req := &domain.CreateProductRequest{Name: fmt.Sprintf("ledgered-%d", time.Now().UnixNano()), Price: 100, Stock: 5}
+	if err := repo.SaveProduct(req, testSource); err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	var product domain.Product
+	if err := db.Where("name = ?", req.Name).First(&product).Error; err != nil {
+		t.Fatalf("load product error = %v", err)
+	}
+	key := domain.StockKey{ProductID: product.ID}
+
+	// A retried request and a redelivered release of an order placed before reservations move stock once
+	retried := domain.MovementSource{Actor: "grpc:update_stock", SourceID: fmt.Sprintf("req-%d", product.ID)}
+	legacyOrder := testOrderID()
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := repo.AddStock(key, 0, 2, retried); err != nil {
+			t.Fatalf("AddStock() error = %v", err)
+		}
+		if err := repo.ReleaseReservations(legacyOrder, map[domain.StockKey]int{key: 1}, testSource); err != nil {
+			t.Fatalf("ReleaseReservations() error = %v", err)
+		}
+	}
+	if err := repo.AddStock(key, 0, -20, testSource); err == nil {
+		t.Fatal("expected an error taking more stock than is on hand")
+	}
+
+	got, err := repo.GetByID(product.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Stock != 8 {
+		t.Fatalf("expected 8 on hand, got %d", got.Stock)
+	}
+
+	movements, total, err := repo.ListMovements(&domain.MovementListFilter{ProductID: product.ID, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListMovements() error = %v", err)
+	}
+	if total != 3 || len(movements) != 3 {
+		t.Fatalf("expected 3 movements, got %d: %+v", total, movements)
+	}
+	if movements[0].Reason != domain.MovementReturn || movements[0].OrderID == nil || *movements[0].OrderID != legacyOrder || movements[0].Balance != 8 {
+		t.Fatalf("expected the return of the legacy order last with 8 on hand, got %+v", movements[0])
+	}
+	if movements[2].Reason != domain.MovementAdjust || movements[2].Delta != 5 || movements[2].Balance != 5 {
+		t.Fatalf("expected the initial stock first, got %+v", movements[2])
+	}
+}
+
+func TestProductRepository_SetWarehouseStockAppliesRetriedRequestOnce_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	req := &domain.CreateProductRequest{Name: fmt.Sprintf("restocked-%d", time.Now().UnixNano()), Price: 100, Stock: 5}
+	if err := repo.SaveProduct(req, testSource); err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	var product domain.Product
+	if err := db.Where("name = ?", req.Name).First(&product).Error; err != nil {
+		t.Fatalf("load product error = %v", err)
+	}
+	key := domain.StockKey{ProductID: product.ID}
+	mainWarehouse, err := defaultWarehouse(db)
+	if err != nil {
+		t.Fatalf("defaultWarehouse() error = %v", err)
+	}
+
+	// The retry arrives after another change, it must not move the stock again
+	retried := domain.MovementSource{Actor: "admin:1", SourceID: fmt.Sprintf("set-%d", product.ID)}
+	if _, err := repo.SetWarehouseStock(mainWarehouse.ID, key, 9, retried); err != nil {
+		t.Fatalf("SetWarehouseStock() error = %v", err)
+	}
+	if err := repo.AddStock(key, mainWarehouse.ID, 1, testSource); err != nil {
+		t.Fatalf("AddStock() error = %v", err)
+	}
+	stock, err := repo.SetWarehouseStock(mainWarehouse.ID, key, 9, retried)
+	if err != nil {
+		t.Fatalf("SetWarehouseStock() retry error = %v", err)
+	}
+	if stock.Stock != 10 {
+		t.Fatalf("expected the retry to leave 10 on hand, got %d", stock.Stock)
+	}
+
+	movements, _, err := repo.ListMovements(&domain.MovementListFilter{ProductID: product.ID, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListMovements() error = %v", err)
+	}
+	if len(movements) == 0 || movements[0].Balance != stock.Stock {
+		t.Fatalf("expected the ledger to end at the stock on hand, got %+v", movements)
+	}
+}
+
+func TestProductRepository_ReserveStocksPerVariant_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	suffix := time.Now().UnixNano()
+	req := &domain.CreateProductRequest{
+		Name:    fmt.Sprintf("T-shirt %d", suffix),
+		Options: []domain.ProductOptionRequest{{Name: "size", Values: []string{"M", "L"}}},
+		Variants: []domain.VariantRequest{
+			{SKU: fmt.Sprintf("TS-M-%d", suffix), Options: map[string]string{"size": "M"}, Price: 120, Stock: 4},
+			{SKU: fmt.Sprintf("TS-L-%d", suffix), Options: map[string]string{"size": "L"}, Price: 100, Stock: 1},
+		},
+	}
+	if err := repo.SaveProduct(req, testSource); err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	var product domain.Product
+	if err := db.Scopes(WithVariants).Where("name = ?", req.Name).First(&product).Error; err != nil {
+		t.Fatalf("load product error = %v", err)
+	}
+	if product.Price != 100 || product.Stock != 5 || len(product.Variants) != 2 {
+		t.Fatalf("expected lowest price 100, stock 5 and 2 variants, got %d, %d and %d", product.Price, product.Stock, len(product.Variants))
+	}
+	medium, large := product.Variants[0], product.Variants[1]
+
+	_, unavailable, err := repo.ReserveStocks(testOrderID(), map[domain.StockKey]int{
+		{ProductID: product.ID, VariantID: medium.ID}: 3,
+		{ProductID: product.ID, VariantID: large.ID}:  2,
+		{ProductID: product.ID}:                       1,
+	}, priority, time.Now().Add(time.Hour), testSource)
+	if err != nil {
+		t.Fatalf("ReserveStocks() error = %v", err)
+	}
+	want := []domain.StockKey{{ProductID: product.ID}, {ProductID: product.ID, VariantID: large.ID}}
+	if len(unavailable) != 2 || unavailable[0] != want[0] || unavailable[1] != want[1] {
+		t.Fatalf("expected the product itself and the large variant unavailable, got %v", unavailable)
+	}
+
+	got, err := repo.GetByID(product.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Stock != 5 || got.AvailableStock() != 2 || got.Variants[0].AvailableStock() != 1 || got.Variants[1].AvailableStock() != 1 {
+		t.Fatalf("expected only the medium variant reserved, got product stock %d, %d available and variants %+v", got.Stock, got.AvailableStock(), got.Variants)
+	}
+}
+
+func TestProductRepository_ReserveStocksByWarehouseStrategy_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	suffix := time.Now().UnixNano()
+	req := &domain.CreateProductRequest{Name: fmt.Sprintf("warehoused-%d", suffix), Price: 100, Stock: 2}
+	if err := repo.SaveProduct(req, testSource); err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	var product domain.Product
+	if err := db.Where("name = ?", req.Name).First(&product).Error; err != nil {
+		t.Fatalf("load product error = %v", err)
+	}
+	key := domain.StockKey{ProductID: product.ID}
+
+	// The 2 set on the product are in the default warehouse, 3 more in Surabaya and 4 in Jakarta
+	surabaya := &domain.Warehouse{Code: fmt.Sprintf("SBY-%d", suffix), Name: "Surabaya", PostalCode: "60111", Priority: 10}
+	jakarta := &domain.Warehouse{Code: fmt.Sprintf("JKT-%d", suffix), Name: "Jakarta", PostalCode: "10110", Priority: 20}
+	for warehouse, stock := range map[*domain.Warehouse]int{surabaya: 3, jakarta: 4} {
+		if err := repo.CreateWarehouse(warehouse); err != nil {
+			t.Fatalf("CreateWarehouse() error = %v", err)
+		}
+		if _, err := repo.SetWarehouseStock(warehouse.ID, key, stock, testSource); err != nil {
+			t.Fatalf("SetWarehouseStock() error = %v", err)
+		}
+	}
+	mainWarehouse, err := defaultWarehouse(db)
+	if err != nil {
+		t.Fatalf("defaultWarehouse() error = %v", err)
+	}
+
+	reserve := func(selection domain.WarehouseSelection, quantity int) []domain.StockReservation {
+		t.Helper()
+		reservations, unavailable, err := repo.ReserveStocks(testOrderID(), map[domain.StockKey]int{key: quantity}, selection, time.Now().Add(time.Hour), testSource)
+		if err != nil || len(unavailable) != 0 {
+			t.Fatalf("ReserveStocks() = %v, %v", unavailable, err)
+		}
+		return reservations
+	}
+	warehousesOf := func(reservations []domain.StockReservation) map[uint]int {
+		quantities := make(map[uint]int)
+		for _, reservation := range reservations {
+			quantities[reservation.WarehouseID] += reservation.Quantity
+		}
+		return quantities
+	}
+
+	split := warehousesOf(reserve(domain.WarehouseSelection{Strategy: domain.WarehouseStrategySplit}, 4))
+	if len(split) != 2 || split[mainWarehouse.ID] != 2 || split[surabaya.ID] != 2 {
+		t.Fatalf("expected 2 from the default warehouse and 2 from Surabaya, got %v", split)
+	}
+	nearest := warehousesOf(reserve(domain.WarehouseSelection{Strategy: domain.WarehouseStrategyNearest, PostalCode: "10120"}, 1))
+	if len(nearest) != 1 || nearest[jakarta.ID] != 1 {
+		t.Fatalf("expected the line shipped from Jakarta, got %v", nearest)
+	}
+	// Surabaya has 1 left, so the whole line comes from Jakarta
+	byPriority := warehousesOf(reserve(priority, 2))
+	if len(byPriority) != 1 || byPriority[jakarta.ID] != 2 {
+		t.Fatalf("expected the line reserved in Jakarta, got %v", byPriority)
+	}
+
+	got, err := repo.GetByID(product.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Stock != 9 || got.Reserved != 7 {
+		t.Fatalf("expected 9 on hand and 7 reserved, got %d and %d", got.Stock, got.Reserved)
+	}
+	stocks, err := repo.ListWarehouseStocks(product.ID)
+	if err != nil {
+		t.Fatalf("ListWarehouseStocks() error = %v", err)
+	}
+	reserved := map[uint]int{mainWarehouse.ID: 2, surabaya.ID: 2, jakarta.ID: 3}
+	for _, stock := range stocks {
+		if stock.Reserved != reserved[stock.WarehouseID] {
+			t.Fatalf("expected %d reserved in warehouse %d, got %d", reserved[stock.WarehouseID], stock.WarehouseID, stock.Reserved)
+		}
+	}
+}
+
+func TestProductRepository_ImagesKeepTheirOrder_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	product := &domain.Product{Name: fmt.Sprintf("pictured-%d", time.Now().UnixNano()), Price: 100, Stock: 1}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("create product error = %v", err)
+	}
+	images := make([]*domain.ProductImage, 3)
+	for i := range images {
+		images[i] = &domain.ProductImage{
+			ProductID: product.ID, URL: fmt.Sprintf("/img/%d.jpg", i), ThumbnailURL: fmt.Sprintf("/img/%d_thumb.jpg", i),
+			ContentType: "image/jpeg", Width: 10, Height: 10, StorageKey: fmt.Sprintf("%d.jpg", i), ThumbnailKey: fmt.Sprintf("%d_thumb.jpg", i),
+		}
+		if err := repo.AddImage(images[i]); err != nil {
+			t.Fatalf("AddImage() error = %v", err)
+		}
+		if images[i].Position != i {
+			t.Fatalf("expected image %d at position %d, got %d", images[i].ID, i, images[i].Position)
+		}
+	}
+
+	if _, err := repo.ReorderImages(product.ID, []uint{images[2].ID, images[0].ID}); !errors.Is(err, domain.ErrInvalidImageOrder) {
+		t.Fatalf("expected ErrInvalidImageOrder for an incomplete order, got %v", err)
+	}
+	reordered, err := repo.ReorderImages(product.ID, []uint{images[2].ID, images[0].ID, images[1].ID})
+	if err != nil {
+		t.Fatalf("ReorderImages() error = %v", err)
+	}
+	if len(reordered) != 3 || reordered[0].ID != images[2].ID || reordered[2].ID != images[1].ID {
+		t.Fatalf("unexpected order: %#v", reordered)
+	}
+
+	if _, err := repo.DeleteImage(product.ID+1, images[0].ID); !errors.Is(err, domain.ErrImageNotFound) {
+		t.Fatalf("expected ErrImageNotFound for an image of another product, got %v", err)
+	}
+	if _, err := repo.DeleteImage(product.ID, images[0].ID); err != nil {
+		t.Fatalf("DeleteImage() error = %v", err)
+	}
+	got, err := repo.GetByID(product.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(got.Images) != 2 || got.Images[0].ID != images[2].ID || got.Images[1].ID != images[1].ID {
+		t.Fatalf("unexpected images after delete: %#v", got.Images)
+	}
+}
+
+func TestProductRepository_SearchRanksAndToleratesTypos_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+	if err := repo.SetupSearch(); err != nil {
+		t.Fatalf("SetupSearch() error = %v", err)
+	}
+
+	suffix := time.Now().UnixNano()
+	category := &domain.Category{Name: fmt.Sprintf("Peripherals %d", suffix)}
+	if err := repo.CreateCategory(category); err != nil {
+		t.Fatalf("CreateCategory() error = %v", err)
+	}
+	named := &domain.CreateProductRequest{Name: fmt.Sprintf("Trackball %d", suffix), Description: "Thumb operated pointer", Price: 100, Stock: 1, CategoryIDs: []uint{category.ID}}
+	described := &domain.CreateProductRequest{Name: fmt.Sprintf("Desk pad %d", suffix), Description: "Fits any trackball or mouse", Price: 100, Stock: 1, CategoryIDs: []uint{category.ID}}
+	for _, req := range []*domain.CreateProductRequest{named, described} {
+		if err := repo.SaveProduct(req, testSource); err != nil {
+			t.Fatalf("SaveProduct() error = %v", err)
+		}
+	}
+	searchFilter := func(search string) *domain.ProductListFilter {
+		return &domain.ProductListFilter{Search: search, CategoryIDs: []uint{category.ID}, SortBy: domain.SortByRelevance, Order: "desc", Page: 1, Limit: 10}
+	}
+
+	// A prefix matches the name of one product and the description of the other, the name match ranks first
+	products, total, err := repo.ListAll(searchFilter("trackba"))
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if total != 2 || products[0].Name != named.Name {
+		t.Fatalf("expected the name match to rank first of 2, got %d results starting with %q", total, products[0].Name)
+	}
+	if products[0].HighlightName == "" || products[1].HighlightDescription == "" {
+		t.Fatalf("expected highlighted snippets, got %#v", products)
+	}
+
+	// A misspelt name still finds the product
+	products, _, err = repo.ListAll(searchFilter("trakball"))
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if len(products) == 0 || products[0].Name != named.Name {
+		t.Fatalf("expected typo to match %q, got %#v", named.Name, products)
+	}
+
+	// Category names are part of the search document
+	_, total, err = repo.ListAll(searchFilter("peripherals"))
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("expected both products to match their category name, got %d", total)
+	}
+
+	if _, _, err := repo.ListAll(&domain.ProductListFilter{SortBy: "price; DROP TABLE products", Page: 1, Limit: 10}); !errors.Is(err, domain.ErrInvalidSortField) {
+		t.Fatalf("expected ErrInvalidSortField, got %v", err)
+	}
+}
+
+func TestProductRepository_GetFacetsIgnoresOwnFilter_Integration(t *testing.T) {
+	db := openProductTestDB(t)
+	repo := NewPostgresRepository(db)
+	if err := repo.SetupSearch(); err != nil {
+		t.Fatalf("SetupSearch() error = %v", err)
+	}
+
+	suffix := time.Now().UnixNano()
+	var categoryIDs []uint
+	for _, name := range []string{"shoes", "sale"} {
+		category := &domain.Category{Name: fmt.Sprintf("%s-%d", name, suffix)}
+		if err := repo.CreateCategory(category); err != nil {
+			t.Fatalf("CreateCategory() error = %v", err)
+		}
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+	shoes, sale := categoryIDs[0], categoryIDs[1]
+	for i, req := range []*domain.CreateProductRequest{
+		{Name: fmt.Sprintf("runner-%d", suffix), Price: 500000, Stock: 3, CategoryIDs: []uint{shoes, sale}},
+		{Name: fmt.Sprintf("boot-%d", suffix), Price: 3000000, Stock: 0, CategoryIDs: []uint{shoes}},
+		{Name: fmt.Sprintf("sock-%d", suffix), Price: 200000, Stock: 9, CategoryIDs: []uint{sale}},
+	} {
+		if err := repo.SaveProduct(req, testSource); err != nil {
+			t.Fatalf("SaveProduct(%d) error = %v", i, err)
+		}
+	}
+
+	// Products must be in both categories, and in stock
+	inStock := true
+	filter := &domain.ProductListFilter{CategoryIDs: categoryIDs, CategoryMatch: domain.CategoryMatchAll, InStock: &inStock, SortBy: domain.SortByCreatedAt, Page: 1, Limit: 10}
+	products, total, err := repo.ListAll(filter)
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if total != 1 || products[0].Name != fmt.Sprintf("runner-%d", suffix) {
+		t.Fatalf("expected only the product in both categories, got %d", total)
+	}
+
+	// With any of the categories the availability facet ignores in_stock and counts all three products
+	filter.CategoryMatch = domain.CategoryMatchAny
+	facets, err := repo.GetFacets(filter)
+	if err != nil {
+		t.Fatalf("GetFacets() error = %v", err)
+	}
+	if facets.Availability.InStock != 2 || facets.Availability.OutOfStock != 1 {
+		t.Fatalf("expected 2 in stock and 1 out of stock, got %#v", facets.Availability)
+	}
+	counts := map[uint]int64{}
+	for _, category := range facets.Categories {
+		counts[category.ID] = category.Count
+	}
+	if counts[shoes] != 1 || counts[sale] != 2 {
+		t.Fatalf("expected in stock counts of 1 shoe and 2 sale products, got %v", counts)
+	}
+	var priced int64
+	for _, priceRange := range facets.PriceRanges {
+		priced += priceRange.Count
+	}
+	if len(facets.PriceRanges) != len(domain.PriceFacetBounds)+1 || priced != 2 || facets.PriceRanges[0].Count != 2 {
+		t.Fatalf("expected both in stock products in the lowest price range, got %#v", facets.PriceRanges)
+	}
+}
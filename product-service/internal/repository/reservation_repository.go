@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"product-service/internal/domain"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReserveStocks holds stock for the lines of an order until expiresAt, line by line in one transaction.
+// The warehouses of each line are picked by selection among those that have its stock available. Products
+// and variants that are missing or that the warehouses cannot cover are skipped and returned, the other
+// lines stay reserved. The reservations made are returned. An order is reserved once: when it already holds
+// reservations, nothing changes and those reservations are returned along with the lines it holds none for,
+// so a redelivered order reports the same outcome.
+func (r *PostgresRepository) ReserveStocks(orderID uint, quantities map[domain.StockKey]int, selection domain.WarehouseSelection, expiresAt time.Time, source domain.MovementSource) ([]domain.StockReservation, []domain.StockKey, error) {
+	var reservations []domain.StockReservation
+	var unavailable []domain.StockKey
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("order_id = ?", orderID).Order("id").Find(&reservations).Error; err != nil {
+			return err
+		}
+		if len(reservations) > 0 {
+			held := make(map[domain.StockKey]bool, len(reservations))
+			for _, reservation := range reservations {
+				held[reservation.Key()] = true
+			}
+			for key := range quantities {
+				if !held[key] {
+					unavailable = append(unavailable, key)
+				}
+			}
+			sort.Slice(unavailable, func(i, j int) bool { return unavailable[i].Less(unavailable[j]) })
+			return nil
+		}
+
+		var warehouses []domain.Warehouse
+		if err := tx.Find(&warehouses).Error; err != nil {
+			return err
+		}
+		warehouses = domain.RankWarehouses(warehouses, selection)
+
+		keys := make([]domain.StockKey, 0, len(quantities))
+		lines := make([][]interface{}, 0, len(quantities))
+		for key := range quantities {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+		for _, key := range keys {
+			lines = append(lines, []interface{}{key.ProductID, key.VariantID})
+		}
+
+		// Locked in key order, the order applyStocks locks them in, so the stock allocated stays available
+		var stocks []domain.WarehouseStock
+		if len(lines) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("(product_id, variant_id) IN ?", lines).
+				Where(sellableStockSQL).
+				Order("product_id, variant_id, warehouse_id").
+				Find(&stocks).Error; err != nil {
+				return err
+			}
+		}
+		available := make(map[domain.StockKey]map[uint]int, len(keys))
+		for _, stock := range stocks {
+			key := domain.StockKey{ProductID: stock.ProductID, VariantID: stock.VariantID}
+			if available[key] == nil {
+				available[key] = make(map[uint]int)
+			}
+			available[key][stock.WarehouseID] = stock.AvailableStock()
+		}
+
+		movements := make([]domain.StockMovement, 0, len(keys))
+		split := selection.Strategy == domain.WarehouseStrategySplit
+		for _, key := range keys {
+			allocations := domain.AllocateLine(key, warehouses, available[key], quantities[key], split)
+			if allocations == nil {
+				unavailable = append(unavailable, key)
+				continue
+			}
+			for _, allocation := range allocations {
+				stockKey := key.In(allocation.WarehouseID)
+				reservations = append(reservations, domain.StockReservation{
+					OrderID:     orderID,
+					ProductID:   key.ProductID,
+					VariantID:   key.VariantID,
+					WarehouseID: allocation.WarehouseID,
+					Quantity:    allocation.Quantity,
+					Status:      domain.ReservationActive,
+					ExpiresAt:   expiresAt,
+				})
+				movements = append(movements, orderMovement(source, orderID, stockKey, domain.MovementReserve, 0, allocation.Quantity))
+			}
+		}
+		// Only the movements the ledger did not hold yet reserve stock, so a redelivered order never reserves twice
+		changes, err := recordMovements(tx, movements)
+		if err != nil {
+			return err
+		}
+		recorded := reservations[:0]
+		for _, reservation := range reservations {
+			if _, ok := changes[reservation.WarehouseKey()]; ok {
+				recorded = append(recorded, reservation)
+			}
+		}
+		reservations = recorded
+		if len(reservations) == 0 {
+			return nil
+		}
+
+		// The allocations fit the stock the locked rows have available
+		if _, err := applyStocks(tx, changes, false); err != nil {
+			return err
+		}
+		return tx.Create(&reservations).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return reservations, unavailable, nil
+}
+
+// CommitReservations turns the reservations of a paid order into sales, taking their stock off the shelf.
+// Reservations that expired before the payment landed are committed too, the customer has paid for them,
+// so their stock is taken even if it went to other orders in the meantime. The reservations are returned
+// as they were before the commit, committing an order twice returns none.
+func (r *PostgresRepository) CommitReservations(orderID uint, source domain.MovementSource) ([]domain.StockReservation, error) {
+	var reservations []domain.StockReservation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status IN ?", orderID, []string{domain.ReservationActive, domain.ReservationExpired}).
+			Order("id").
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+
+		movements := make([]domain.StockMovement, len(reservations))
+		for i, reservation := range reservations {
+			reserved := 0
+			if reservation.Status == domain.ReservationActive {
+				reserved = -reservation.Quantity
+			}
+			movements[i] = orderMovement(source, orderID, reservation.WarehouseKey(), domain.MovementCommit, -reservation.Quantity, reserved)
+		}
+		return settleReservations(tx, reservations, movements, domain.ReservationCommitted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// ReleaseReservations gives back the stock an order holds for lines: active reservations are released and
+// committed ones are returned to the shelf, in every warehouse the lines were reserved in. Lines the order
+// holds no reservation for are left alone, except for orders placed before reservations existed, whose stock
+// was taken off the shelf right away and is put back in the default warehouse, once per line.
+func (r *PostgresRepository) ReleaseReservations(orderID uint, lines map[domain.StockKey]int, source domain.MovementSource) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var reservations []domain.StockReservation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", orderID).
+			Order("id").
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+
+		if len(reservations) == 0 {
+			warehouse, err := defaultWarehouse(tx)
+			if err != nil {
+				return err
+			}
+			movements := make([]domain.StockMovement, 0, len(lines))
+			for key, quantity := range lines {
+				movements = append(movements, orderMovement(source, orderID, key.In(warehouse.ID), domain.MovementReturn, quantity, 0))
+			}
+			changes, err := recordMovements(tx, movements)
+			if err != nil {
+				return err
+			}
+			_, err = applyStocks(tx, changes, false)
+			return err
+		}
+
+		for _, status := range []string{domain.ReservationActive, domain.ReservationCommitted} {
+			var settled []domain.StockReservation
+			var movements []domain.StockMovement
+			for _, reservation := range reservations {
+				if _, ok := lines[reservation.Key()]; !ok || reservation.Status != status {
+					continue
+				}
+				settled = append(settled, reservation)
+				if status == domain.ReservationActive {
+					movements = append(movements, orderMovement(source, orderID, reservation.WarehouseKey(), domain.MovementRelease, 0, -reservation.Quantity))
+				} else {
+					movements = append(movements, orderMovement(source, orderID, reservation.WarehouseKey(), domain.MovementReturn, reservation.Quantity, 0))
+				}
+			}
+			next := domain.ReservationReleased
+			if status == domain.ReservationCommitted {
+				next = domain.ReservationReturned
+			}
+			if err := settleReservations(tx, settled, movements, next); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExpireReservations releases up to limit active reservations that expired before now and returns them.
+// Reservations locked by a concurrent commit or release are skipped, the next sweep picks them up if they
+// are still active.
+func (r *PostgresRepository) ExpireReservations(now time.Time, limit int, source domain.MovementSource) ([]domain.StockReservation, error) {
+	var reservations []domain.StockReservation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at < ?", domain.ReservationActive, now).
+			Order("expires_at").
+			Limit(limit).
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+
+		movements := make([]domain.StockMovement, len(reservations))
+		for i, reservation := range reservations {
+			movements[i] = orderMovement(source, reservation.OrderID, reservation.WarehouseKey(), domain.MovementExpire, 0, -reservation.Quantity)
+		}
+		return settleReservations(tx, reservations, movements, domain.ReservationExpired)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// settleReservations records the stock movements of reservations, applies them and moves the reservations
+// to status. The movements only give back or take off stock that was already accounted for, so they are
+// not guarded.
+func settleReservations(tx *gorm.DB, reservations []domain.StockReservation, movements []domain.StockMovement, status string) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	changes, err := recordMovements(tx, movements)
+	if err != nil {
+		return err
+	}
+	if _, err := applyStocks(tx, changes, false); err != nil {
+		return err
+	}
+	ids := make([]uint, len(reservations))
+	for i, reservation := range reservations {
+		ids[i] = reservation.ID
+	}
+	return tx.Model(&domain.StockReservation{}).Where("id IN ?", ids).Update("status", status).Error
+}
+
+// ListReservations returns a page of the reservations of a product, newest first, and how many there are in total
+func (r *PostgresRepository) ListReservations(filter *domain.ReservationListFilter) ([]domain.StockReservation, int64, error) {
+	query := r.db.Model(&domain.StockReservation{}).Where("product_id = ?", filter.ProductID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reservations []domain.StockReservation
+	err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&reservations).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reservations, total, nil
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"libs/logger"
+	"net/http"
+	"product-service/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// imageFormats maps the accepted image types to the file extension they are stored with
+var imageFormats = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// processedImage is an upload that passed validation, along with its encoded thumbnail
+type processedImage struct {
+	contentType          string
+	width, height        int
+	thumbnail            []byte
+	thumbnailContentType string
+}
+
+// AddProductImage validates an uploaded image, stores it along with a thumbnail and appends it to the images of a product
+func (s *ProductService) AddProductImage(ctx context.Context, productID uint, data []byte) (*domain.ProductImage, error) {
+	l := logger.ForContext(ctx)
+
+	processed, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomImageName()
+	if err != nil {
+		return nil, fmt.Errorf("failed to name image: %w", err)
+	}
+	productImage := &domain.ProductImage{
+		ProductID:    productID,
+		ContentType:  processed.contentType,
+		Width:        processed.width,
+		Height:       processed.height,
+		StorageKey:   fmt.Sprintf("products/%d/%s%s", productID, name, imageFormats[processed.contentType]),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb%s", productID, name, imageFormats[processed.thumbnailContentType]),
+	}
+
+	if productImage.URL, err = s.imageStorage.Save(ctx, productImage.StorageKey, productImage.ContentType, data); err != nil {
+		l.Error("failed to store image", zap.Uint("productID", productID), zap.Error(err))
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	if productImage.ThumbnailURL, err = s.imageStorage.Save(ctx, productImage.ThumbnailKey, processed.thumbnailContentType, processed.thumbnail); err != nil {
+		l.Error("failed to store thumbnail", zap.Uint("productID", productID), zap.Error(err))
+		s.deleteImageFiles(ctx, productImage.StorageKey)
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	if err := s.productRepo.AddImage(productImage); err != nil {
+		l.Error("failed to add image", zap.Uint("productID", productID), zap.Error(err))
+		s.deleteImageFiles(ctx, productImage.StorageKey, productImage.ThumbnailKey)
+		return nil, fmt.Errorf("failed to add image: %w", err)
+	}
+	l.Info("Image added successfully", zap.Uint("productID", productID), zap.Uint("imageID", productImage.ID), zap.Int("size", len(data)))
+	return productImage, nil
+}
+
+// DeleteProductImage removes an image from a product and deletes its files
+func (s *ProductService) DeleteProductImage(ctx context.Context, productID, imageID uint) error {
+	l := logger.ForContext(ctx)
+	productImage, err := s.productRepo.DeleteImage(productID, imageID)
+	if err != nil {
+		l.Error("failed to delete image", zap.Uint("productID", productID), zap.Uint("imageID", imageID), zap.Error(err))
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	s.deleteImageFiles(ctx, productImage.StorageKey, productImage.ThumbnailKey)
+	l.Info("Image deleted successfully", zap.Uint("productID", productID), zap.Uint("imageID", imageID))
+	return nil
+}
+
+// ReorderProductImages sets the display order of the images of a product
+func (s *ProductService) ReorderProductImages(ctx context.Context, productID uint, imageIDs []uint) ([]domain.ProductImage, error) {
+	l := logger.ForContext(ctx)
+	images, err := s.productRepo.ReorderImages(productID, imageIDs)
+	if err != nil {
+		l.Error("failed to reorder images", zap.Uint("productID", productID), zap.Error(err))
+		return nil, fmt.Errorf("failed to reorder images: %w", err)
+	}
+	l.Info("Images reordered successfully", zap.Uint("productID", productID), zap.Int("imageCount", len(images)))
+	return images, nil
+}
+
+// deleteImageFiles removes stored files on a best effort basis. A file left behind is only wasted space,
+// it is never listed once its image is gone.
+func (s *ProductService) deleteImageFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.imageStorage.Delete(ctx, key); err != nil {
+			logger.ForContext(ctx).Warn("failed to delete image file", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// processImage checks the type, size and dimensions of an upload and renders its thumbnail.
+// The type is sniffed from the content, whatever the file name or declared type says.
+func processImage(data []byte) (*processedImage, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidImage)
+	}
+	if len(data) > domain.MaxImageSize {
+		return nil, fmt.Errorf("%w: the file is larger than %d MB", domain.ErrInvalidImage, domain.MaxImageSize>>20)
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := imageFormats[contentType]; !ok {
+		return nil, fmt.Errorf("%w: %s is not supported, upload a JPEG, PNG or GIF", domain.ErrInvalidImage, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file could not be read", domain.ErrInvalidImage)
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > domain.MaxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is too large", domain.ErrInvalidImage, config.Width, config.Height)
+	}
+
+	img, err := decodeImage(contentType, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file could not be read", domain.ErrInvalidImage)
+	}
+
+	processed := &processedImage{contentType: contentType, width: config.Width, height: config.Height}
+	var thumbnail bytes.Buffer
+	thumb := resizeToFit(img, domain.ThumbnailSize)
+	// Photos keep JPEG, anything that may be transparent becomes a PNG
+	if contentType == "image/jpeg" {
+		processed.thumbnailContentType = "image/jpeg"
+		err = jpeg.Encode(&thumbnail, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		processed.thumbnailContentType = "image/png"
+		err = png.Encode(&thumbnail, thumb)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	processed.thumbnail = thumbnail.Bytes()
+	return processed, nil
+}
+
+func decodeImage(contentType string, data []byte) (image.Image, error) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		return png.Decode(bytes.NewReader(data))
+	default:
+		return gif.Decode(bytes.NewReader(data))
+	}
+}
+
+// resizeToFit scales img down to fit in a size by size square, keeping its aspect ratio. Each pixel of
+// the result averages the block of source pixels it covers. Images that already fit are only copied.
+func resizeToFit(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= size && srcH <= size {
+		return src
+	}
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, srcH*size/srcW)
+	} else {
+		dstW = max(1, srcW*size/srcH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// randomImageName returns a random file name, so every upload gets its own URL that can be cached forever
+func randomImageName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"libs/logger"
+	"product-service/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// GetStockHistory returns a page of the stock ledger of a product, newest first, along with its current stock
+func (s *ProductService) GetStockHistory(ctx context.Context, filter *domain.MovementListFilter) (*domain.StockHistory, error) {
+	l := logger.ForContext(ctx)
+	if filter.Reason != "" && !domain.IsMovementReason(filter.Reason) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidMovementReason, filter.Reason)
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	product, err := s.productRepo.GetByID(filter.ProductID)
+	if err != nil {
+		l.Error("failed to get product", zap.Uint("productID", filter.ProductID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	movements, total, err := s.productRepo.ListMovements(filter)
+	if err != nil {
+		l.Error("failed to list stock movements", zap.Uint("productID", filter.ProductID), zap.Error(err))
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+
+	history := &domain.StockHistory{
+		ProductID: product.ID,
+		OnHand:    product.Stock,
+		Reserved:  product.Reserved,
+		Movements: movements,
+		Total:     total,
+		Page:      filter.Page,
+		Limit:     filter.Limit,
+	}
+	// The stock of a single variant, when the history is about one
+	if filter.VariantID != nil {
+		history.OnHand, history.Reserved = 0, 0
+		for _, variant := range product.Variants {
+			if variant.ID == *filter.VariantID {
+				history.OnHand, history.Reserved = variant.Stock, variant.Reserved
+			}
+		}
+	}
+	return history, nil
+}
@@ -2,32 +2,54 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"libs/logger"
 	"product-service/internal/domain"
 	"product-service/internal/repository"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type ProductService struct {
-	productRepo repository.ProductRepository
-	eventRepo   repository.EventRepository
+	productRepo       repository.ProductRepository
+	eventRepo         repository.EventRepository
+	imageStorage      repository.ImageStorage
+	reservationTTL    time.Duration
+	warehouseStrategy string
 }
 
-func NewProductService(pr repository.ProductRepository, er repository.EventRepository) *ProductService {
-	return &ProductService{productRepo: pr, eventRepo: er}
+// Settings are the business rules the product service is configured with
+type Settings struct {
+	// ReservationTTL is how long the stock of an unpaid order stays reserved
+	ReservationTTL time.Duration
+	// WarehouseStrategy picks the warehouses orders are reserved from, one of the domain.WarehouseStrategy values
+	WarehouseStrategy string
+}
+
+func NewProductService(pr repository.ProductRepository, er repository.EventRepository, is repository.ImageStorage, settings Settings) *ProductService {
+	return &ProductService{
+		productRepo:       pr,
+		eventRepo:         er,
+		imageStorage:      is,
+		reservationTTL:    settings.ReservationTTL,
+		warehouseStrategy: settings.WarehouseStrategy,
+	}
 }
 
 func (s *ProductService) CreateProduct(ctx context.Context, product *domain.CreateProductRequest) error {
 	l := logger.ForContext(ctx)
-	err := s.productRepo.SaveProduct(product)
+	if err := domain.ValidateVariants(product.Options, product.Variants); err != nil {
+		return err
+	}
+	err := s.productRepo.SaveProduct(product, domain.MovementSourceFromContext(ctx))
 	if err != nil {
 		l.Error("failed to create product", zap.Error(err))
 		return fmt.Errorf("failed to create product: %w", err)
 	}
-	l.Info("Product created successfully", zap.String("name", product.Name), zap.Int("categoryCount", len(product.CategoryIDs)))
+	l.Info("Product created successfully", zap.String("name", product.Name), zap.Int("categoryCount", len(product.CategoryIDs)), zap.Int("variantCount", len(product.Variants)))
 	return nil
 }
 
@@ -42,29 +64,41 @@ func (s *ProductService) CreateCategory(ctx context.Context, category *domain.Ca
 	return nil
 }
 
-func (s *ProductService) GetProducts(ctx context.Context, search, categoryID, minPrice, maxPrice, order, sortBy string, page, limit int) (*domain.PaginatedProducts, error) {
+func (s *ProductService) GetProducts(ctx context.Context, filter *domain.ProductListFilter) (*domain.PaginatedProducts, error) {
 	l := logger.ForContext(ctx)
 	// Set default values
-	if page < 1 {
-		page = 1
+	if filter.Page < 1 {
+		filter.Page = 1
 	}
-	if limit < 1 {
-		limit = 10
+	if filter.Limit < 1 {
+		filter.Limit = 10
 	}
 	// Set max limit to prevent abuse
-	if limit > 100 {
-		limit = 100
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	// Searches are ranked by relevance unless asked otherwise, there is nothing to rank without a search term
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.SortBy == "" || (filter.SortBy == domain.SortByRelevance && filter.Search == "") {
+		filter.SortBy = domain.SortByCreatedAt
+		if filter.Search != "" {
+			filter.SortBy = domain.SortByRelevance
+		}
 	}
 
-	products, total, err := s.productRepo.ListAll(search, categoryID, minPrice, maxPrice, order, sortBy, page, limit)
+	products, total, err := s.productRepo.ListAll(filter)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidSortField) {
+			return nil, err
+		}
 		l.Error("failed to list products", zap.Error(err))
 		return nil, fmt.Errorf("failed to list products: %w", err)
 	}
 
 	// Calculate total pages
-	totalPages := int(total) / limit
-	if int(total)%limit != 0 {
+	totalPages := int(total) / filter.Limit
+	if int(total)%filter.Limit != 0 {
 		totalPages++
 	}
 
@@ -75,15 +109,24 @@ func (s *ProductService) GetProducts(ctx context.Context, search, categoryID, mi
 		productsResponse[i] = domain.ToProductResponse(p)
 	}
 
-	l.Info("Products retrieved successfully", zap.Int("count", len(productsResponse)), zap.Int("page", page), zap.Int("limit", limit))
-
-	return &domain.PaginatedProducts{
+	result := &domain.PaginatedProducts{
 		Products:   productsResponse,
 		Total:      total,
-		Page:       page,
-		Limit:      limit,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
 		TotalPages: totalPages,
-	}, nil
+	}
+	if filter.Facets {
+		result.Facets, err = s.productRepo.GetFacets(filter)
+		if err != nil {
+			l.Error("failed to count product facets", zap.Error(err))
+			return nil, fmt.Errorf("failed to count product facets: %w", err)
+		}
+	}
+
+	l.Info("Products retrieved successfully", zap.Int("count", len(productsResponse)), zap.Int("page", filter.Page), zap.Int("limit", filter.Limit))
+
+	return result, nil
 }
 
 func (s *ProductService) GetProductByID(ctx context.Context, productID uint) (*domain.Product, error) {
@@ -97,14 +140,27 @@ func (s *ProductService) GetProductByID(ctx context.Context, productID uint) (*d
 	return product, nil
 }
 
-func (s *ProductService) AddStock(ctx context.Context, productID uint, add int) error {
+func (s *ProductService) GetProductsByIDs(ctx context.Context, productIDs []uint) ([]domain.Product, error) {
 	l := logger.ForContext(ctx)
-	err := s.productRepo.AddStock(productID, add)
+	products, err := s.productRepo.GetByIDs(productIDs)
+	if err != nil {
+		l.Error("failed to get products by ids", zap.Error(err))
+		return nil, fmt.Errorf("failed to get products by ids: %w", err)
+	}
+	l.Info("Products retrieved successfully", zap.Int("requested", len(productIDs)), zap.Int("found", len(products)))
+	return products, nil
+}
+
+// AddStock changes the stock of a product without variants, or of one variant of a product, in a warehouse.
+// A warehouseID of 0 changes the stock of the default warehouse.
+func (s *ProductService) AddStock(ctx context.Context, key domain.StockKey, warehouseID uint, add int) error {
+	l := logger.ForContext(ctx)
+	err := s.productRepo.AddStock(key, warehouseID, add, domain.MovementSourceFromContext(ctx))
 	if err != nil {
 		l.Error("failed to add stock", zap.Error(err))
 		return fmt.Errorf("failed to add stock: %w", err)
 	}
-	l.Info("Product stock added successfully", zap.Uint("productID", productID), zap.Int("added", add))
+	l.Info("Product stock added successfully", zap.Uint("productID", key.ProductID), zap.Uint("variantID", key.VariantID), zap.Uint("warehouseID", warehouseID), zap.Int("added", add))
 	return nil
 }
 
@@ -121,7 +177,7 @@ func (s *ProductService) DeleteProduct(ctx context.Context, productID uint) erro
 
 func (s *ProductService) UpdateProduct(ctx context.Context, id uint, product *domain.UpdateProductRequest) (*domain.Product, error) {
 	l := logger.ForContext(ctx)
-	updatedProduct, err := s.productRepo.UpdateProduct(id, product)
+	updatedProduct, err := s.productRepo.UpdateProduct(id, product, domain.MovementSourceFromContext(ctx))
 	if err != nil {
 		l.Error("failed to update product", zap.Error(err))
 		return nil, fmt.Errorf("failed to update product: %w", err)
@@ -130,53 +186,36 @@ func (s *ProductService) UpdateProduct(ctx context.Context, id uint, product *do
 	return updatedProduct, nil
 }
 
-func (s *ProductService) ReserveStock(ctx context.Context, orderID uint, stockUpdates map[uint]int) error {
+func (s *ProductService) AddVariant(ctx context.Context, productID uint, req *domain.VariantRequest) (*domain.ProductVariant, error) {
 	l := logger.ForContext(ctx)
-	// Deduct stocks in a transaction
-	err := s.productRepo.AddStocksInTransaction(stockUpdates)
+	variant, err := s.productRepo.AddVariant(productID, req, domain.MovementSourceFromContext(ctx))
 	if err != nil {
-		// Check if error is due to insufficient stock
-		if strings.Contains(err.Error(), "resulting stock would be negative") {
-			// Publish stock insufficient event
-			publishErr := s.eventRepo.PublishStockInsufficientEvent(ctx, &domain.StockEvent{
-				OrderID:       orderID,
-				CorrelationID: correlationIDFromContext(ctx),
-			})
-			if publishErr != nil {
-				l.Error("failed to reserve stock and publish stock insufficient event: reserveErr=%w, publishErr=%v", zap.Error(err), zap.Error(publishErr))
-				return fmt.Errorf("failed to reserve stock and publish stock insufficient event: reserveErr=%w, publishErr=%v", err, publishErr)
-			}
-			l.Info("Stock insufficient event published", zap.Uint("orderID", orderID))
-		}
-
-		l.Error("failed to reserve stock for order %d", zap.Error(err))
-		return fmt.Errorf("failed to reserve stock for order %d: %w", orderID, err)
+		l.Error("failed to add variant", zap.Uint("productID", productID), zap.Error(err))
+		return nil, fmt.Errorf("failed to add variant: %w", err)
 	}
+	l.Info("Variant added successfully", zap.Uint("productID", productID), zap.Uint("variantID", variant.ID), zap.String("sku", variant.SKU))
+	return variant, nil
+}
 
-	// Publish event after successful stock deduction
-	err = s.eventRepo.PublishStockReservedEvent(ctx, &domain.StockEvent{
-		OrderID:       orderID,
-		CorrelationID: correlationIDFromContext(ctx),
-	})
+func (s *ProductService) UpdateVariant(ctx context.Context, productID, variantID uint, req *domain.UpdateVariantRequest) (*domain.ProductVariant, error) {
+	l := logger.ForContext(ctx)
+	variant, err := s.productRepo.UpdateVariant(productID, variantID, req, domain.MovementSourceFromContext(ctx))
 	if err != nil {
-		l.Error("failed to publish stock reserved event for order %d", zap.Error(err))
-		return fmt.Errorf("failed to publish stock reserved event for order %d: %w", orderID, err)
+		l.Error("failed to update variant", zap.Uint("productID", productID), zap.Uint("variantID", variantID), zap.Error(err))
+		return nil, fmt.Errorf("failed to update variant: %w", err)
 	}
-	l.Info("Stock reserved successfully", zap.Uint("orderID", orderID))
-
-	return nil
+	l.Info("Variant updated successfully", zap.Uint("productID", productID), zap.Uint("variantID", variantID))
+	return variant, nil
 }
 
-func (s *ProductService) ReleaseStock(ctx context.Context, stockUpdates map[uint]int) error {
+func (s *ProductService) DeleteVariant(ctx context.Context, productID, variantID uint) error {
 	l := logger.ForContext(ctx)
-	// Add stocks back in a transaction
-	err := s.productRepo.AddStocksInTransaction(stockUpdates)
+	err := s.productRepo.DeleteVariant(productID, variantID, domain.MovementSourceFromContext(ctx))
 	if err != nil {
-		l.Error("failed to release stock", zap.Error(err))
-		return fmt.Errorf("failed to release stock: %w", err)
+		l.Error("failed to delete variant", zap.Uint("productID", productID), zap.Uint("variantID", variantID), zap.Error(err))
+		return fmt.Errorf("failed to delete variant: %w", err)
 	}
-	l.Info("Stock released successfully", zap.Int("itemCount", len(stockUpdates)))
-
+	l.Info("Variant deleted successfully", zap.Uint("productID", productID), zap.Uint("variantID", variantID))
 	return nil
 }
 
@@ -1,69 +1,144 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"image"
+	"image/png"
+	"strings"
 	"testing"
+	"time"
 
 	"product-service/internal/domain"
 )
 
 type mockProductRepository struct {
-	listAllArgs struct {
-		search     string
-		categoryID string
-		minPrice   string
-		maxPrice   string
-		order      string
-		sortBy     string
-		page       int
-		limit      int
-	}
+	listAllFilter   *domain.ProductListFilter
+	facetsFilter    *domain.ProductListFilter
+	facets          *domain.ProductFacets
 	listAllProducts []domain.Product
 	listAllTotal    int64
 	listAllErr      error
-	addStocksErr    error
+	unavailable     []domain.StockKey
+	reserved        map[domain.StockKey]int
+	reservedExpiry  time.Time
+	selection       domain.WarehouseSelection
+	held            []domain.StockReservation
+	warehouses      []domain.Warehouse
+	released        map[domain.StockKey]int
+	product         *domain.Product
+	reservations    []domain.StockReservation
+	listedFilter    *domain.ReservationListFilter
+	movements       []domain.StockMovement
+	movementFilter  *domain.MovementListFilter
+	source          domain.MovementSource
+	saved           bool
+	addedImage      *domain.ProductImage
+	addImageErr     error
 }
 
-func (m *mockProductRepository) SaveProduct(product *domain.CreateProductRequest) error { return nil }
-func (m *mockProductRepository) CreateCategory(category *domain.Category) error         { return nil }
-func (m *mockProductRepository) AddStock(productID uint, add int) error                 { return nil }
-func (m *mockProductRepository) Delete(productID uint) error                            { return nil }
-func (m *mockProductRepository) GetByID(productID uint) (*domain.Product, error)        { return nil, nil }
+func (m *mockProductRepository) SaveProduct(product *domain.CreateProductRequest, source domain.MovementSource) error {
+	m.saved = true
+	return nil
+}
+func (m *mockProductRepository) CreateCategory(category *domain.Category) error { return nil }
+func (m *mockProductRepository) AddStock(key domain.StockKey, warehouseID uint, add int, source domain.MovementSource) error {
+	m.source = source
+	return nil
+}
+func (m *mockProductRepository) Delete(productID uint) error { return nil }
+func (m *mockProductRepository) GetByID(productID uint) (*domain.Product, error) {
+	return m.product, nil
+}
+func (m *mockProductRepository) GetByIDs(productIDs []uint) ([]domain.Product, error) {
+	return nil, nil
+}
 func (m *mockProductRepository) AssignCategory(productID uint, categoryID []uint) error { return nil }
 func (m *mockProductRepository) RemoveCategory(productID uint, categoryID uint) error   { return nil }
 func (m *mockProductRepository) ListCategories(productID uint) ([]domain.Category, error) {
 	return nil, nil
 }
-func (m *mockProductRepository) UpdateProduct(id uint, req *domain.UpdateProductRequest) (*domain.Product, error) {
+func (m *mockProductRepository) UpdateProduct(id uint, req *domain.UpdateProductRequest, source domain.MovementSource) (*domain.Product, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) ReserveStocks(orderID uint, quantities map[domain.StockKey]int, selection domain.WarehouseSelection, expiresAt time.Time, source domain.MovementSource) ([]domain.StockReservation, []domain.StockKey, error) {
+	m.reserved, m.selection, m.reservedExpiry, m.source = quantities, selection, expiresAt, source
+	return m.held, m.unavailable, nil
+}
+func (m *mockProductRepository) CommitReservations(orderID uint, source domain.MovementSource) ([]domain.StockReservation, error) {
+	return m.reservations, nil
+}
+func (m *mockProductRepository) ReleaseReservations(orderID uint, lines map[domain.StockKey]int, source domain.MovementSource) error {
+	m.released, m.source = lines, source
+	return nil
+}
+func (m *mockProductRepository) ExpireReservations(now time.Time, limit int, source domain.MovementSource) ([]domain.StockReservation, error) {
+	return m.reservations, nil
+}
+func (m *mockProductRepository) ListReservations(filter *domain.ReservationListFilter) ([]domain.StockReservation, int64, error) {
+	m.listedFilter = filter
+	return m.reservations, int64(len(m.reservations)), nil
+}
+func (m *mockProductRepository) ListMovements(filter *domain.MovementListFilter) ([]domain.StockMovement, int64, error) {
+	m.movementFilter = filter
+	return m.movements, int64(len(m.movements)), nil
+}
+func (m *mockProductRepository) CreateWarehouse(warehouse *domain.Warehouse) error { return nil }
+func (m *mockProductRepository) ListWarehouses() ([]domain.Warehouse, error) {
+	return m.warehouses, nil
+}
+func (m *mockProductRepository) UpdateWarehouse(id uint, req *domain.UpdateWarehouseRequest) (*domain.Warehouse, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) SetWarehouseStock(warehouseID uint, key domain.StockKey, stock int, source domain.MovementSource) (*domain.WarehouseStock, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) ListWarehouseStocks(productID uint) ([]domain.WarehouseStock, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) AddVariant(productID uint, req *domain.VariantRequest, source domain.MovementSource) (*domain.ProductVariant, error) {
 	return nil, nil
 }
-func (m *mockProductRepository) AddStocksInTransaction(updates map[uint]int) error {
-	return m.addStocksErr
-}
-func (m *mockProductRepository) ListAll(search, categoryID, minPrice, maxPrice, order, sortBy string, page, limit int) ([]domain.Product, int64, error) {
-	m.listAllArgs.search = search
-	m.listAllArgs.categoryID = categoryID
-	m.listAllArgs.minPrice = minPrice
-	m.listAllArgs.maxPrice = maxPrice
-	m.listAllArgs.order = order
-	m.listAllArgs.sortBy = sortBy
-	m.listAllArgs.page = page
-	m.listAllArgs.limit = limit
+func (m *mockProductRepository) UpdateVariant(productID, variantID uint, req *domain.UpdateVariantRequest, source domain.MovementSource) (*domain.ProductVariant, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) DeleteVariant(productID, variantID uint, source domain.MovementSource) error {
+	return nil
+}
+func (m *mockProductRepository) AddImage(image *domain.ProductImage) error {
+	m.addedImage = image
+	return m.addImageErr
+}
+func (m *mockProductRepository) DeleteImage(productID, imageID uint) (*domain.ProductImage, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) ReorderImages(productID uint, imageIDs []uint) ([]domain.ProductImage, error) {
+	return nil, nil
+}
+func (m *mockProductRepository) ListAll(filter *domain.ProductListFilter) ([]domain.Product, int64, error) {
+	m.listAllFilter = filter
 	return m.listAllProducts, m.listAllTotal, m.listAllErr
 }
+func (m *mockProductRepository) GetFacets(filter *domain.ProductListFilter) (*domain.ProductFacets, error) {
+	m.facetsFilter = filter
+	return m.facets, nil
+}
 
 type mockProductEventRepository struct {
 	reservedCalled      bool
 	insufficientCalled  bool
 	reservedOrderID     uint
 	insufficientOrderID uint
+	reservedEvent       *domain.StockEvent
+	reservedErr         error
 }
 
 func (m *mockProductEventRepository) PublishStockReservedEvent(ctx context.Context, event *domain.StockEvent) error {
 	m.reservedCalled = true
 	m.reservedOrderID = event.OrderID
-	return nil
+	m.reservedEvent = event
+	return m.reservedErr
 }
 
 func (m *mockProductEventRepository) PublishStockInsufficientEvent(ctx context.Context, event *domain.StockEvent) error {
@@ -72,32 +147,115 @@ func (m *mockProductEventRepository) PublishStockInsufficientEvent(ctx context.C
 	return nil
 }
 
+type mockImageStorage struct {
+	saved   map[string][]byte
+	deleted []string
+}
+
+func (m *mockImageStorage) Save(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	if m.saved == nil {
+		m.saved = make(map[string][]byte)
+	}
+	m.saved[key] = data
+	return "/product-images/" + key, nil
+}
+func (m *mockImageStorage) Delete(ctx context.Context, key string) error {
+	m.deleted = append(m.deleted, key)
+	return nil
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
 func TestGetProductsAppliesDefaultPagination(t *testing.T) {
 	repo := &mockProductRepository{listAllProducts: []domain.Product{}, listAllTotal: 0}
 	eventRepo := &mockProductEventRepository{}
-	svc := NewProductService(repo, eventRepo)
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{})
+
+	_, err := svc.GetProducts(context.Background(), &domain.ProductListFilter{})
+	if err != nil {
+		t.Fatalf("GetProducts() error = %v", err)
+	}
+
+	if repo.listAllFilter.Page != 1 {
+		t.Fatalf("expected page=1, got %d", repo.listAllFilter.Page)
+	}
+	if repo.listAllFilter.Limit != 10 {
+		t.Fatalf("expected limit=10, got %d", repo.listAllFilter.Limit)
+	}
+}
+
+func TestGetProductsDefaultsToRelevanceWhenSearching(t *testing.T) {
+	tests := []struct {
+		search string
+		sortBy string
+		want   string
+	}{
+		{search: "mouse", sortBy: "", want: domain.SortByRelevance},
+		{search: "", sortBy: "", want: domain.SortByCreatedAt},
+		{search: "  ", sortBy: domain.SortByRelevance, want: domain.SortByCreatedAt},
+		{search: "mouse", sortBy: "price", want: "price"},
+	}
+	for _, tt := range tests {
+		repo := &mockProductRepository{}
+		svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+		filter := &domain.ProductListFilter{Search: tt.search, SortBy: tt.sortBy, Order: "desc"}
+		if _, err := svc.GetProducts(context.Background(), filter); err != nil {
+			t.Fatalf("GetProducts(%q, %q) error = %v", tt.search, tt.sortBy, err)
+		}
+		if repo.listAllFilter.SortBy != tt.want {
+			t.Fatalf("GetProducts(%q, %q): expected sort by %s, got %s", tt.search, tt.sortBy, tt.want, repo.listAllFilter.SortBy)
+		}
+	}
+}
+
+func TestGetProductsReturnsInvalidSortField(t *testing.T) {
+	repo := &mockProductRepository{listAllErr: domain.ErrInvalidSortField}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+	if _, err := svc.GetProducts(context.Background(), &domain.ProductListFilter{SortBy: "nope"}); !errors.Is(err, domain.ErrInvalidSortField) {
+		t.Fatalf("expected ErrInvalidSortField, got %v", err)
+	}
+}
+
+func TestGetProductsIncludesFacetsOnlyWhenRequested(t *testing.T) {
+	facets := &domain.ProductFacets{Availability: domain.AvailabilityFacet{InStock: 3, OutOfStock: 1}}
+	repo := &mockProductRepository{facets: facets}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
 
-	_, err := svc.GetProducts(context.Background(), "", "", "", "", "", "", 0, 0)
+	result, err := svc.GetProducts(context.Background(), &domain.ProductListFilter{})
 	if err != nil {
 		t.Fatalf("GetProducts() error = %v", err)
 	}
+	if result.Facets != nil || repo.facetsFilter != nil {
+		t.Fatal("expected no facets unless requested")
+	}
 
-	if repo.listAllArgs.page != 1 {
-		t.Fatalf("expected page=1, got %d", repo.listAllArgs.page)
+	filter := &domain.ProductListFilter{CategoryIDs: []uint{1, 2}, CategoryMatch: domain.CategoryMatchAll, Facets: true}
+	result, err = svc.GetProducts(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("GetProducts() error = %v", err)
 	}
-	if repo.listAllArgs.limit != 10 {
-		t.Fatalf("expected limit=10, got %d", repo.listAllArgs.limit)
+	if result.Facets != facets || repo.facetsFilter != filter {
+		t.Fatalf("expected facets counted for the listing filter, got %#v", result.Facets)
 	}
 }
 
-func TestReserveStockPublishesInsufficientEventWhenStockError(t *testing.T) {
-	repo := &mockProductRepository{addStocksErr: errors.New("resulting stock would be negative")}
+func TestReserveStockPublishesInsufficientEventWhenNoLineCanBeReserved(t *testing.T) {
+	repo := &mockProductRepository{unavailable: []domain.StockKey{{ProductID: 1}, {ProductID: 2}}}
 	eventRepo := &mockProductEventRepository{}
-	svc := NewProductService(repo, eventRepo)
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{})
 
-	err := svc.ReserveStock(context.Background(), 44, map[uint]int{1: -10})
-	if err == nil {
-		t.Fatal("expected reserve stock error")
+	err := svc.ReserveStock(context.Background(), 44, map[domain.StockKey]int{{ProductID: 1}: 10, {ProductID: 2}: 1}, "")
+	if err != nil {
+		t.Fatalf("ReserveStock() error = %v", err)
 	}
 	if !eventRepo.insufficientCalled {
 		t.Fatal("expected insufficient event to be published")
@@ -110,12 +268,68 @@ func TestReserveStockPublishesInsufficientEventWhenStockError(t *testing.T) {
 	}
 }
 
+func TestReserveStockReportsUnavailableLinesInReservedEvent(t *testing.T) {
+	short := domain.StockKey{ProductID: 2, VariantID: 7}
+	repo := &mockProductRepository{unavailable: []domain.StockKey{short}}
+	eventRepo := &mockProductEventRepository{}
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{})
+
+	if err := svc.ReserveStock(context.Background(), 45, map[domain.StockKey]int{{ProductID: 1}: 3, short: 1}, ""); err != nil {
+		t.Fatalf("ReserveStock() error = %v", err)
+	}
+	if eventRepo.insufficientCalled {
+		t.Fatal("did not expect insufficient event when some lines were reserved")
+	}
+	if eventRepo.reservedEvent == nil || len(eventRepo.reservedEvent.UnavailableItems) != 1 || eventRepo.reservedEvent.UnavailableItems[0] != short {
+		t.Fatalf("expected variant 7 of product 2 reported unavailable, got %#v", eventRepo.reservedEvent)
+	}
+}
+
+func TestReserveStockKeepsReservationsForRedeliveryWhenPublishFails(t *testing.T) {
+	repo := &mockProductRepository{unavailable: []domain.StockKey{{ProductID: 2}}}
+	eventRepo := &mockProductEventRepository{reservedErr: errors.New("redis down")}
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{})
+	quantities := map[domain.StockKey]int{{ProductID: 1}: 3, {ProductID: 2}: 1}
+
+	if err := svc.ReserveStock(context.Background(), 46, quantities, ""); err == nil {
+		t.Fatal("expected publish error")
+	}
+	if repo.released != nil {
+		t.Fatalf("expected reservations kept for the redelivered order, got %v released", repo.released)
+	}
+
+	// The redelivered order reserves nothing new and publishes the same outcome
+	eventRepo.reservedErr = nil
+	if err := svc.ReserveStock(context.Background(), 46, quantities, ""); err != nil {
+		t.Fatalf("ReserveStock() error = %v", err)
+	}
+	if eventRepo.reservedEvent == nil || len(eventRepo.reservedEvent.UnavailableItems) != 1 || eventRepo.reservedEvent.UnavailableItems[0] != (domain.StockKey{ProductID: 2}) {
+		t.Fatalf("expected product 2 reported unavailable, got %#v", eventRepo.reservedEvent)
+	}
+}
+
+func TestReserveStockHoldsStockForReservationTTL(t *testing.T) {
+	repo := &mockProductRepository{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{ReservationTTL: time.Hour})
+
+	before := time.Now()
+	if err := svc.ReserveStock(context.Background(), 47, map[domain.StockKey]int{{ProductID: 1}: 2}, ""); err != nil {
+		t.Fatalf("ReserveStock() error = %v", err)
+	}
+	if repo.reserved[domain.StockKey{ProductID: 1}] != 2 {
+		t.Fatalf("expected 2 of product 1 reserved, got %v", repo.reserved)
+	}
+	if repo.reservedExpiry.Before(before.Add(time.Hour)) || repo.reservedExpiry.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("expected reservation to expire in an hour, got %v", repo.reservedExpiry)
+	}
+}
+
 func TestReserveStockPublishesReservedEventOnSuccess(t *testing.T) {
 	repo := &mockProductRepository{}
 	eventRepo := &mockProductEventRepository{}
-	svc := NewProductService(repo, eventRepo)
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{})
 
-	err := svc.ReserveStock(context.Background(), 55, map[uint]int{1: -2})
+	err := svc.ReserveStock(context.Background(), 55, map[domain.StockKey]int{{ProductID: 1}: 2}, "")
 	if err != nil {
 		t.Fatalf("ReserveStock() error = %v", err)
 	}
@@ -126,3 +340,212 @@ func TestReserveStockPublishesReservedEventOnSuccess(t *testing.T) {
 		t.Fatalf("expected reserved event orderID=55, got %d", eventRepo.reservedOrderID)
 	}
 }
+
+func TestReserveStockPublishesWarehouseOfEachAllocation(t *testing.T) {
+	line := domain.StockKey{ProductID: 1, VariantID: 4}
+	repo := &mockProductRepository{
+		held: []domain.StockReservation{
+			{OrderID: 56, ProductID: 1, VariantID: 4, WarehouseID: 2, Quantity: 3},
+			{OrderID: 56, ProductID: 1, VariantID: 4, WarehouseID: 1, Quantity: 2},
+		},
+		warehouses: []domain.Warehouse{{ID: 1, Code: "MAIN"}, {ID: 2, Code: "SBY"}},
+	}
+	eventRepo := &mockProductEventRepository{}
+	svc := NewProductService(repo, eventRepo, &mockImageStorage{}, Settings{WarehouseStrategy: domain.WarehouseStrategySplit})
+
+	if err := svc.ReserveStock(context.Background(), 56, map[domain.StockKey]int{line: 5}, "60111"); err != nil {
+		t.Fatalf("ReserveStock() error = %v", err)
+	}
+	if repo.selection != (domain.WarehouseSelection{Strategy: domain.WarehouseStrategySplit, PostalCode: "60111"}) {
+		t.Fatalf("expected warehouses picked by the split strategy for postal code 60111, got %+v", repo.selection)
+	}
+	want := []domain.StockAllocation{
+		{StockKey: line, WarehouseID: 2, WarehouseCode: "SBY", Quantity: 3},
+		{StockKey: line, WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 2},
+	}
+	if eventRepo.reservedEvent == nil || len(eventRepo.reservedEvent.Allocations) != len(want) {
+		t.Fatalf("expected allocations %+v, got %#v", want, eventRepo.reservedEvent)
+	}
+	for i, allocation := range eventRepo.reservedEvent.Allocations {
+		if allocation != want[i] {
+			t.Fatalf("expected allocation %+v, got %+v", want[i], allocation)
+		}
+	}
+}
+
+func TestCreateProductRejectsInvalidVariants(t *testing.T) {
+	options := []domain.ProductOptionRequest{{Name: "size", Values: []string{"M", "L"}}, {Name: "color", Values: []string{"red"}}}
+	tests := map[string][]domain.VariantRequest{
+		"unknown value":  {{SKU: "TS-XL", Options: map[string]string{"size": "XL", "color": "red"}, Price: 100}},
+		"missing option": {{SKU: "TS-M", Options: map[string]string{"size": "M"}, Price: 100}},
+		"same options": {
+			{SKU: "TS-M-1", Options: map[string]string{"size": "M", "color": "red"}, Price: 100},
+			{SKU: "TS-M-2", Options: map[string]string{"size": "M", "color": "red"}, Price: 100},
+		},
+		"no variants": nil,
+	}
+	for name, variants := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := &mockProductRepository{}
+			svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+			err := svc.CreateProduct(context.Background(), &domain.CreateProductRequest{Name: "T-shirt", Options: options, Variants: variants})
+			if !errors.Is(err, domain.ErrInvalidVariant) {
+				t.Fatalf("expected ErrInvalidVariant, got %v", err)
+			}
+			if repo.saved {
+				t.Fatal("did not expect an invalid product to be saved")
+			}
+		})
+	}
+
+	repo := &mockProductRepository{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+	variants := []domain.VariantRequest{{SKU: "TS-M-RED", Options: map[string]string{"size": "M", "color": "red"}, Price: 100}}
+	if err := svc.CreateProduct(context.Background(), &domain.CreateProductRequest{Name: "T-shirt", Options: options, Variants: variants}); err != nil {
+		t.Fatalf("CreateProduct() error = %v", err)
+	}
+	if !repo.saved {
+		t.Fatal("expected product with valid variants to be saved")
+	}
+}
+
+func TestAddProductImageStoresImageAndThumbnail(t *testing.T) {
+	repo := &mockProductRepository{}
+	storage := &mockImageStorage{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, storage, Settings{})
+
+	img, err := svc.AddProductImage(context.Background(), 7, testPNG(t, 800, 400))
+	if err != nil {
+		t.Fatalf("AddProductImage() error = %v", err)
+	}
+	if repo.addedImage != img || img.ProductID != 7 || img.Width != 800 || img.Height != 400 || img.ContentType != "image/png" {
+		t.Fatalf("unexpected image: %#v", img)
+	}
+	if !strings.HasPrefix(img.StorageKey, "products/7/") || img.URL != "/product-images/"+img.StorageKey || img.ThumbnailURL != "/product-images/"+img.ThumbnailKey {
+		t.Fatalf("unexpected keys or URLs: %#v", img)
+	}
+
+	thumbnail, err := png.DecodeConfig(bytes.NewReader(storage.saved[img.ThumbnailKey]))
+	if err != nil {
+		t.Fatalf("thumbnail is not a PNG: %v", err)
+	}
+	if thumbnail.Width != 320 || thumbnail.Height != 160 {
+		t.Fatalf("expected a 320x160 thumbnail, got %dx%d", thumbnail.Width, thumbnail.Height)
+	}
+}
+
+func TestAddProductImageRejectsInvalidUploads(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":     {},
+		"not image": []byte("just some text"),
+		"truncated": testPNG(t, 10, 10)[:40],
+		"too large": append(testPNG(t, 10, 10), make([]byte, domain.MaxImageSize)...),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			storage := &mockImageStorage{}
+			svc := NewProductService(&mockProductRepository{}, &mockProductEventRepository{}, storage, Settings{})
+
+			_, err := svc.AddProductImage(context.Background(), 7, data)
+			if !errors.Is(err, domain.ErrInvalidImage) {
+				t.Fatalf("expected ErrInvalidImage, got %v", err)
+			}
+			if len(storage.saved) != 0 {
+				t.Fatalf("expected nothing stored, got %d files", len(storage.saved))
+			}
+		})
+	}
+}
+
+func TestAddProductImageRemovesFilesWhenItCannotBeSaved(t *testing.T) {
+	repo := &mockProductRepository{addImageErr: errors.New("record not found")}
+	storage := &mockImageStorage{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, storage, Settings{})
+
+	if _, err := svc.AddProductImage(context.Background(), 7, testPNG(t, 10, 10)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(storage.deleted) != 2 {
+		t.Fatalf("expected the image and its thumbnail deleted, got %v", storage.deleted)
+	}
+}
+
+func TestGetProductReservationsReportsStockTotals(t *testing.T) {
+	repo := &mockProductRepository{
+		product:      &domain.Product{ID: 3, Stock: 10, Reserved: 4},
+		reservations: []domain.StockReservation{{ID: 1, OrderID: 9, ProductID: 3, Quantity: 4, Status: domain.ReservationActive}},
+	}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+	result, err := svc.GetProductReservations(context.Background(), &domain.ReservationListFilter{ProductID: 3, Status: domain.ReservationActive})
+	if err != nil {
+		t.Fatalf("GetProductReservations() error = %v", err)
+	}
+	if result.OnHand != 10 || result.Reserved != 4 || result.Available != 6 {
+		t.Fatalf("expected 10 on hand, 4 reserved and 6 available, got %+v", result)
+	}
+	if result.Total != 1 || repo.listedFilter.Page != 1 || repo.listedFilter.Limit != 10 {
+		t.Fatalf("expected the first page of 10 with 1 reservation, got %+v", result)
+	}
+}
+
+func TestGetProductReservationsRejectsUnknownStatus(t *testing.T) {
+	repo := &mockProductRepository{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+	_, err := svc.GetProductReservations(context.Background(), &domain.ReservationListFilter{ProductID: 3, Status: "PENDING"})
+	if !errors.Is(err, domain.ErrInvalidReservationStatus) {
+		t.Fatalf("expected ErrInvalidReservationStatus, got %v", err)
+	}
+	if repo.listedFilter != nil {
+		t.Fatal("did not expect reservations to be listed")
+	}
+}
+
+func TestReleaseStockRecordsMovementSourceOfContext(t *testing.T) {
+	repo := &mockProductRepository{}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+	source := domain.MovementSource{Actor: "stream:orders:cancelled", SourceID: "1700000000000-0"}
+
+	if err := svc.ReleaseStock(domain.WithMovementSource(context.Background(), source), 48, map[domain.StockKey]int{{ProductID: 1}: 2}); err != nil {
+		t.Fatalf("ReleaseStock() error = %v", err)
+	}
+	if repo.source != source {
+		t.Fatalf("expected movements recorded for %+v, got %+v", source, repo.source)
+	}
+
+	if err := svc.AddStock(context.Background(), domain.StockKey{ProductID: 1}, 0, 5); err != nil {
+		t.Fatalf("AddStock() error = %v", err)
+	}
+	if repo.source.Actor != "system" || repo.source.SourceID != "" {
+		t.Fatalf("expected movements without a source recorded for the system, got %+v", repo.source)
+	}
+}
+
+func TestGetStockHistoryReportsVariantStock(t *testing.T) {
+	variantID := uint(7)
+	repo := &mockProductRepository{
+		product: &domain.Product{ID: 3, Stock: 10, Reserved: 4, Variants: []domain.ProductVariant{
+			{ID: 6, ProductID: 3, Stock: 4},
+			{ID: variantID, ProductID: 3, Stock: 6, Reserved: 4},
+		}},
+		movements: []domain.StockMovement{{ID: 2, ProductID: 3, VariantID: variantID, ReservedDelta: 4, Reason: domain.MovementReserve, Balance: 6, ReservedBalance: 4}},
+	}
+	svc := NewProductService(repo, &mockProductEventRepository{}, &mockImageStorage{}, Settings{})
+
+	history, err := svc.GetStockHistory(context.Background(), &domain.MovementListFilter{ProductID: 3, VariantID: &variantID})
+	if err != nil {
+		t.Fatalf("GetStockHistory() error = %v", err)
+	}
+	if history.OnHand != 6 || history.Reserved != 4 || history.Total != 1 {
+		t.Fatalf("expected the stock of variant 7 with 1 movement, got %+v", history)
+	}
+	if repo.movementFilter.Page != 1 || repo.movementFilter.Limit != 20 {
+		t.Fatalf("expected the first page of 20, got %+v", repo.movementFilter)
+	}
+
+	if _, err := svc.GetStockHistory(context.Background(), &domain.MovementListFilter{ProductID: 3, Reason: "THEFT"}); !errors.Is(err, domain.ErrInvalidMovementReason) {
+		t.Fatalf("expected ErrInvalidMovementReason, got %v", err)
+	}
+}
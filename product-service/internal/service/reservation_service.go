@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"libs/logger"
+	"product-service/internal/domain"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// expiredReservationsBatchSize caps how many expired reservations a single sweep releases
+const expiredReservationsBatchSize = 500
+
+// ReserveStock reserves the stock of every order line that can be fulfilled, per variant for products sold in variants. Lines without enough stock
+// are left out and reported in the stock reserved event, so order-service can ship the rest. Only an
+// order none of whose lines can be reserved gets a stock insufficient event. The stock stays on hand until
+// the order is paid, and goes back to other orders if it is not paid before the reservation expires.
+// The warehouses each line is reserved in are picked by the warehouse strategy, using the postal code the
+// order ships to, and published in the stock reserved event so delivery-service knows where to ship from.
+func (s *ProductService) ReserveStock(ctx context.Context, orderID uint, quantities map[domain.StockKey]int, postalCode string) error {
+	l := logger.ForContext(ctx)
+	selection := domain.WarehouseSelection{Strategy: s.warehouseStrategy, PostalCode: postalCode}
+	// Reserving an order twice changes nothing, so a redelivered order only publishes its event again
+	reservations, unavailable, err := s.productRepo.ReserveStocks(orderID, quantities, selection, time.Now().Add(s.reservationTTL), domain.MovementSourceFromContext(ctx))
+	if err != nil {
+		l.Error("failed to reserve stock", zap.Uint("orderID", orderID), zap.Error(err))
+		return fmt.Errorf("failed to reserve stock for order %d: %w", orderID, err)
+	}
+
+	event := &domain.StockEvent{
+		OrderID:          orderID,
+		CorrelationID:    correlationIDFromContext(ctx),
+		UnavailableItems: unavailable,
+	}
+	if len(reservations) > 0 {
+		if event.Allocations, err = s.stockAllocations(reservations); err != nil {
+			l.Error("failed to get warehouses of reservations", zap.Uint("orderID", orderID), zap.Error(err))
+			return fmt.Errorf("failed to get warehouses of order %d: %w", orderID, err)
+		}
+	}
+	if len(unavailable) == len(quantities) {
+		if err := s.eventRepo.PublishStockInsufficientEvent(ctx, event); err != nil {
+			l.Error("failed to publish stock insufficient event", zap.Uint("orderID", orderID), zap.Error(err))
+			return fmt.Errorf("failed to publish stock insufficient event for order %d: %w", orderID, err)
+		}
+		l.Info("Stock insufficient event published", zap.Uint("orderID", orderID))
+		return nil
+	}
+
+	if err := s.eventRepo.PublishStockReservedEvent(ctx, event); err != nil {
+		l.Error("failed to publish stock reserved event", zap.Uint("orderID", orderID), zap.Error(err))
+		return fmt.Errorf("failed to publish stock reserved event for order %d: %w", orderID, err)
+	}
+	l.Info("Stock reserved successfully", zap.Uint("orderID", orderID), zap.Int("unavailableCount", len(unavailable)), zap.Int("allocationCount", len(event.Allocations)))
+
+	return nil
+}
+
+// stockAllocations tells which warehouse each reservation holds stock in
+func (s *ProductService) stockAllocations(reservations []domain.StockReservation) ([]domain.StockAllocation, error) {
+	warehouses, err := s.productRepo.ListWarehouses()
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[uint]string, len(warehouses))
+	for _, warehouse := range warehouses {
+		codes[warehouse.ID] = warehouse.Code
+	}
+	allocations := make([]domain.StockAllocation, len(reservations))
+	for i, reservation := range reservations {
+		allocations[i] = domain.StockAllocation{
+			StockKey:      reservation.Key(),
+			WarehouseID:   reservation.WarehouseID,
+			WarehouseCode: codes[reservation.WarehouseID],
+			Quantity:      reservation.Quantity,
+		}
+	}
+	return allocations, nil
+}
+
+// CommitStock takes the reserved stock of a paid order off the shelf
+func (s *ProductService) CommitStock(ctx context.Context, orderID uint) error {
+	l := logger.ForContext(ctx)
+	reservations, err := s.productRepo.CommitReservations(orderID, domain.MovementSourceFromContext(ctx))
+	if err != nil {
+		l.Error("failed to commit stock", zap.Uint("orderID", orderID), zap.Error(err))
+		return fmt.Errorf("failed to commit stock for order %d: %w", orderID, err)
+	}
+	for _, reservation := range reservations {
+		if reservation.Status == domain.ReservationExpired {
+			l.Warn("order paid after its stock reservation expired, stock may be oversold",
+				zap.Uint("orderID", orderID),
+				zap.Uint("productID", reservation.ProductID),
+				zap.Uint("variantID", reservation.VariantID),
+				zap.Int("quantity", reservation.Quantity))
+		}
+	}
+	l.Info("Stock committed successfully", zap.Uint("orderID", orderID), zap.Int("reservationCount", len(reservations)))
+	return nil
+}
+
+// ReleaseStock gives back the stock an order holds for lines, whether it is still reserved or already sold.
+// Releasing a line twice gives its stock back once.
+func (s *ProductService) ReleaseStock(ctx context.Context, orderID uint, lines map[domain.StockKey]int) error {
+	l := logger.ForContext(ctx)
+	err := s.productRepo.ReleaseReservations(orderID, lines, domain.MovementSourceFromContext(ctx))
+	if err != nil {
+		l.Error("failed to release stock", zap.Uint("orderID", orderID), zap.Error(err))
+		return fmt.Errorf("failed to release stock for order %d: %w", orderID, err)
+	}
+	l.Info("Stock released successfully", zap.Uint("orderID", orderID), zap.Int("itemCount", len(lines)))
+
+	return nil
+}
+
+// ExpireReservations releases the stock of reservations whose order was not paid in time and returns how many were released
+func (s *ProductService) ExpireReservations(ctx context.Context) (int, error) {
+	l := logger.ForContext(ctx)
+	reservations, err := s.productRepo.ExpireReservations(time.Now(), expiredReservationsBatchSize, domain.MovementSourceFromContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+	for _, reservation := range reservations {
+		l.Info("Stock reservation expired",
+			zap.Uint("orderID", reservation.OrderID),
+			zap.Uint("productID", reservation.ProductID),
+			zap.Uint("variantID", reservation.VariantID),
+			zap.Int("quantity", reservation.Quantity))
+	}
+	return len(reservations), nil
+}
+
+// GetProductReservations returns a page of the reservations of a product along with its stock totals
+func (s *ProductService) GetProductReservations(ctx context.Context, filter *domain.ReservationListFilter) (*domain.ProductReservations, error) {
+	l := logger.ForContext(ctx)
+	if filter.Status != "" && !domain.IsReservationStatus(filter.Status) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidReservationStatus, filter.Status)
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	product, err := s.productRepo.GetByID(filter.ProductID)
+	if err != nil {
+		l.Error("failed to get product", zap.Uint("productID", filter.ProductID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	reservations, total, err := s.productRepo.ListReservations(filter)
+	if err != nil {
+		l.Error("failed to list reservations", zap.Uint("productID", filter.ProductID), zap.Error(err))
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+
+	return &domain.ProductReservations{
+		ProductID:    product.ID,
+		OnHand:       product.Stock,
+		Reserved:     product.Reserved,
+		Available:    product.AvailableStock(),
+		Reservations: reservations,
+		Total:        total,
+		Page:         filter.Page,
+		Limit:        filter.Limit,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"libs/logger"
+	"product-service/internal/domain"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+func (s *ProductService) CreateWarehouse(ctx context.Context, req *domain.WarehouseRequest) (*domain.Warehouse, error) {
+	l := logger.ForContext(ctx)
+	warehouse := &domain.Warehouse{
+		Code:       strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:       strings.TrimSpace(req.Name),
+		PostalCode: strings.TrimSpace(req.PostalCode),
+		Priority:   req.Priority,
+	}
+	if err := s.productRepo.CreateWarehouse(warehouse); err != nil {
+		l.Error("failed to create warehouse", zap.String("code", warehouse.Code), zap.Error(err))
+		return nil, fmt.Errorf("failed to create warehouse: %w", err)
+	}
+	l.Info("Warehouse created successfully", zap.Uint("warehouseID", warehouse.ID), zap.String("code", warehouse.Code))
+	return warehouse, nil
+}
+
+// ListWarehouses returns the warehouses in the order they are reserved from by priority
+func (s *ProductService) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	l := logger.ForContext(ctx)
+	warehouses, err := s.productRepo.ListWarehouses()
+	if err != nil {
+		l.Error("failed to list warehouses", zap.Error(err))
+		return nil, fmt.Errorf("failed to list warehouses: %w", err)
+	}
+	return warehouses, nil
+}
+
+func (s *ProductService) UpdateWarehouse(ctx context.Context, id uint, req *domain.UpdateWarehouseRequest) (*domain.Warehouse, error) {
+	l := logger.ForContext(ctx)
+	if req.PostalCode != nil {
+		postalCode := strings.TrimSpace(*req.PostalCode)
+		req.PostalCode = &postalCode
+	}
+	warehouse, err := s.productRepo.UpdateWarehouse(id, req)
+	if err != nil {
+		l.Error("failed to update warehouse", zap.Uint("warehouseID", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to update warehouse: %w", err)
+	}
+	l.Info("Warehouse updated successfully", zap.Uint("warehouseID", id))
+	return warehouse, nil
+}
+
+// SetWarehouseStock sets the stock of a product without variants, or of one variant, kept in a warehouse
+func (s *ProductService) SetWarehouseStock(ctx context.Context, warehouseID uint, req *domain.WarehouseStockRequest) (*domain.WarehouseStock, error) {
+	l := logger.ForContext(ctx)
+	key := domain.StockKey{ProductID: req.ProductID, VariantID: req.VariantID}
+	stock, err := s.productRepo.SetWarehouseStock(warehouseID, key, *req.Stock, domain.MovementSourceFromContext(ctx))
+	if err != nil {
+		l.Error("failed to set warehouse stock", zap.Uint("warehouseID", warehouseID), zap.Uint("productID", key.ProductID), zap.Uint("variantID", key.VariantID), zap.Error(err))
+		return nil, fmt.Errorf("failed to set warehouse stock: %w", err)
+	}
+	l.Info("Warehouse stock set successfully", zap.Uint("warehouseID", warehouseID), zap.Uint("productID", key.ProductID), zap.Uint("variantID", key.VariantID), zap.Int("stock", stock.Stock))
+	return stock, nil
+}
+
+// GetProductWarehouseStocks returns the stock of a product, or of each of its variants, per warehouse
+func (s *ProductService) GetProductWarehouseStocks(ctx context.Context, productID uint) (*domain.ProductWarehouseStocks, error) {
+	l := logger.ForContext(ctx)
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		l.Error("failed to get product", zap.Uint("productID", productID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	stocks, err := s.productRepo.ListWarehouseStocks(productID)
+	if err != nil {
+		l.Error("failed to list warehouse stocks", zap.Uint("productID", productID), zap.Error(err))
+		return nil, fmt.Errorf("failed to list warehouse stocks: %w", err)
+	}
+	return &domain.ProductWarehouseStocks{ProductID: productID, Stocks: stocks}, nil
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"libs/logger"
+	"product-service/internal/domain"
+	"product-service/internal/infrastructure"
+	"product-service/internal/service"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type OrderCancelledWorker struct {
+	s *service.ProductService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewOrderCancelledWorker(brokerRedis *redis.Client, service *service.ProductService) *OrderCancelledWorker {
+	return &OrderCancelledWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:orders:cancelled", "stream:orders:cancelled:dlq", "product-group", "order-cancelled-worker"),
+	}
+}
+
+func (d *OrderCancelledWorker) ListenForOrderCancellations(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderID, err := orderIDFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order cancelled message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		// Orders cancelled before the reservation landed hold no stock yet
+		if reserved, _ := msg.Values["stock_reserved"].(string); reserved != "true" {
+			logger.Log.Info("order cancelled before stock reservation, nothing to release",
+				zap.Uint("orderID", orderID))
+			return nil
+		}
+
+		lines, err := orderStockFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order cancelled message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "stream:orders:cancelled", SourceID: msg.ID})
+		return d.s.ReleaseStock(ctx, orderID, lines)
+	})
+}
+
+// orderIDFromMessage reads the ID of the order an event is about
+func orderIDFromMessage(msg redis.XMessage) (uint, error) {
+	orderIDStr, ok := msg.Values["order_id"].(string)
+	if !ok || orderIDStr == "" {
+		return 0, errors.New("missing order_id")
+	}
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid order_id %q", orderIDStr)
+	}
+	return uint(orderID), nil
+}
+
+// orderStockFromMessage sums the item quantities of an order event per product and variant
+func orderStockFromMessage(msg redis.XMessage) (map[domain.StockKey]int, error) {
+	itemsStr, ok := msg.Values["items"].(string)
+	if !ok || itemsStr == "" {
+		return nil, errors.New("missing items")
+	}
+
+	var items []struct {
+		ProductID uint `json:"product_id"`
+		VariantID uint `json:"variant_id"`
+		Quantity  int  `json:"quantity"`
+	}
+	if err := json.Unmarshal([]byte(itemsStr), &items); err != nil {
+		return nil, fmt.Errorf("malformed items: %w", err)
+	}
+
+	stockUpdates := make(map[domain.StockKey]int)
+	for _, item := range items {
+		stockUpdates[domain.StockKey{ProductID: item.ProductID, VariantID: item.VariantID}] += item.Quantity
+	}
+	return stockUpdates, nil
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"product-service/internal/domain"
+	"product-service/internal/infrastructure"
+	"product-service/internal/service"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type OrderExpiredWorker struct {
+	s *service.ProductService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewOrderExpiredWorker(brokerRedis *redis.Client, service *service.ProductService) *OrderExpiredWorker {
+	return &OrderExpiredWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:orders:expired", "stream:orders:expired:dlq", "product-group", "order-expired-worker"),
+	}
+}
+
+// ListenForOrderExpirations releases the stock of orders that were not paid in time.
+// Only orders awaiting payment expire, so their stock is always reserved.
+func (d *OrderExpiredWorker) ListenForOrderExpirations(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderID, err := orderIDFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order expired message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		lines, err := orderStockFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order expired message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "stream:orders:expired", SourceID: msg.ID})
+		return d.s.ReleaseStock(ctx, orderID, lines)
+	})
+}
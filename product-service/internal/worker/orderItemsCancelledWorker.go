@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"product-service/internal/domain"
+	"product-service/internal/infrastructure"
+	"product-service/internal/service"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type OrderItemsCancelledWorker struct {
+	s *service.ProductService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewOrderItemsCancelledWorker(brokerRedis *redis.Client, service *service.ProductService) *OrderItemsCancelledWorker {
+	return &OrderItemsCancelledWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:orders:items_cancelled", "stream:orders:items_cancelled:dlq", "product-group", "order-items-cancelled-worker"),
+	}
+}
+
+// ListenForOrderItemCancellations releases the stock of lines cancelled from an order that goes on without them.
+// Only reserved lines are cancelled this way, so their stock is always held.
+func (d *OrderItemsCancelledWorker) ListenForOrderItemCancellations(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderID, err := orderIDFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order items cancelled message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		lines, err := orderStockFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order items cancelled message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "stream:orders:items_cancelled", SourceID: msg.ID})
+		return d.s.ReleaseStock(ctx, orderID, lines)
+	})
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"product-service/internal/domain"
+	"product-service/internal/infrastructure"
+	"product-service/internal/service"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type OrderPaidWorker struct {
+	s *service.ProductService
+	w *infrastructure.EventConsumerWorker
+}
+
+func NewOrderPaidWorker(brokerRedis *redis.Client, service *service.ProductService) *OrderPaidWorker {
+	return &OrderPaidWorker{
+		s: service,
+		w: infrastructure.NewEventConsumerWorker(brokerRedis, "stream:orders:paid", "stream:orders:paid:dlq", "product-group", "order-paid-worker"),
+	}
+}
+
+// ListenForOrderPayments commits the stock reservations of paid orders, their stock leaves the shelf for good.
+func (d *OrderPaidWorker) ListenForOrderPayments(ctx context.Context) {
+	d.w.ListenForEvents(ctx, func(ctx context.Context, msg redis.XMessage) error {
+		orderID, err := orderIDFromMessage(msg)
+		if err != nil {
+			logger.Log.Warn("dropping invalid order paid message",
+				zap.String("msgID", msg.ID),
+				zap.Any("raw_values", msg.Values),
+				zap.Error(err))
+			return nil
+		}
+
+		ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "stream:orders:paid", SourceID: msg.ID})
+		return d.s.CommitStock(ctx, orderID)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"libs/logger"
+	"product-service/internal/domain"
 	"product-service/internal/infrastructure"
 	"product-service/internal/service"
 	"time"
@@ -139,6 +140,7 @@ func (w *OrderWorker) ListenForOrders(ctx context.Context) {
 					// Define the structure for order items
 					type OrderItem struct {
 						ProductID uint `json:"product_id"`
+						VariantID uint `json:"variant_id"`
 						Quantity  int  `json:"quantity"`
 					}
 
@@ -154,13 +156,16 @@ func (w *OrderWorker) ListenForOrders(ctx context.Context) {
 						continue
 					}
 
-					stockUpdates := make(map[uint]int)
+					quantities := make(map[domain.StockKey]int)
 					for _, item := range items {
-						// For stock deduction, we use negative quantity
-						stockUpdates[item.ProductID] -= item.Quantity
+						quantities[domain.StockKey{ProductID: item.ProductID, VariantID: item.VariantID}] += item.Quantity
 					}
 
-					err = w.service.ReserveStock(msgCtx, orderID, stockUpdates)
+					// Orders published before the postal code was sent are reserved by warehouse priority
+					postalCode, _ := msg.Values["shipping_postal_code"].(string)
+
+					msgCtx = domain.WithMovementSource(msgCtx, domain.MovementSource{Actor: STREAM_NAME, SourceID: msg.ID})
+					err = w.service.ReserveStock(msgCtx, orderID, quantities, postalCode)
 					if err != nil {
 						logger.Log.Error("failed to process order message", zap.String("msgID", msg.ID), zap.Error(err))
 						continue // Do not ack the message, so it can be retried
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"libs/logger"
+	"product-service/internal/domain"
 	"product-service/internal/infrastructure"
 	"product-service/internal/service"
 	"time"
@@ -139,6 +140,7 @@ func (w *PaymentFailedWorker) ListenForPaymentFailures(ctx context.Context) {
 					// Define the structure for order items
 					type OrderItem struct {
 						ProductID uint `json:"product_id"`
+						VariantID uint `json:"variant_id"`
 						Quantity  int  `json:"quantity"`
 					}
 
@@ -154,12 +156,13 @@ func (w *PaymentFailedWorker) ListenForPaymentFailures(ctx context.Context) {
 						continue
 					}
 
-					stockUpdates := make(map[uint]int)
+					lines := make(map[domain.StockKey]int)
 					for _, item := range items {
-						stockUpdates[item.ProductID] = item.Quantity
+						lines[domain.StockKey{ProductID: item.ProductID, VariantID: item.VariantID}] = item.Quantity
 					}
 
-					err = w.service.ReleaseStock(msgCtx, stockUpdates)
+					msgCtx = domain.WithMovementSource(msgCtx, domain.MovementSource{Actor: STREAM_NAME, SourceID: msg.ID})
+					err = w.service.ReleaseStock(msgCtx, orderID, lines)
 					if err != nil {
 						logger.Log.Error("failed to process payment failed message", zap.String("msgID", msg.ID), zap.Error(err))
 						continue // Do not ack the message, so it can be retried
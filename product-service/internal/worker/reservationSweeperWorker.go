@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"libs/logger"
+	"product-service/internal/domain"
+	"product-service/internal/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ReservationSweeperWorker struct {
+	service *service.ProductService
+}
+
+func NewReservationSweeperWorker(service *service.ProductService) *ReservationSweeperWorker {
+	return &ReservationSweeperWorker{service: service}
+}
+
+// StartReservationSweeper releases the stock of reservations that expired unpaid every minute
+func (w *ReservationSweeperWorker) StartReservationSweeper(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	logger.Log.Info("Starting stock reservation sweeper", zap.Duration("interval", 1*time.Minute))
+	ctx = domain.WithMovementSource(ctx, domain.MovementSource{Actor: "sweeper:reservation_expiry"})
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Stopping stock reservation sweeper")
+			return
+		case <-ticker.C:
+			expired, err := w.service.ExpireReservations(ctx)
+			if err != nil {
+				logger.Log.Error("stock reservation sweep failed", zap.Error(err))
+				continue
+			}
+			if expired > 0 {
+				logger.Log.Info("Stock reservation sweep completed", zap.Int("expiredCount", expired))
+			}
+		}
+	}
+}
@@ -66,10 +66,23 @@ func main() {
 	}
 
 	// Auto-migrate (creates the table if it doesn't exist)
-	db.AutoMigrate(&domain.User{})
+	db.AutoMigrate(&domain.User{}, &domain.EmailVerification{})
+
+	// Verification codes prove a guest email belongs to the caller, so they are only ever emailed
+	var sender repository.VerificationSender
+	switch {
+	case cfg.SMTPHost != "":
+		sender = repository.NewSMTPVerificationSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case cfg.MailLogOnly:
+		logger.Log.Warn("MAIL_LOG_ONLY is set, verification codes are not delivered")
+		sender = repository.NewLogVerificationSender()
+	default:
+		logger.Log.Error("SMTP_HOST environment variable is required, set MAIL_LOG_ONLY=true to run without mail in development")
+		os.Exit(1)
+	}
 
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewUserService(repo)
+	svc := service.NewUserService(repo, sender)
 	hdl := handler.NewUserHandler(svc)
 
 	// Seed admin user
@@ -105,6 +118,7 @@ func main() {
 
 		api.POST("/register", hdl.Register)
 		api.POST("/login", authMiddleware.LoginHandler)
+		api.POST("/auth/guest", hdl.Guest(authMiddleware))
 
 	}
 
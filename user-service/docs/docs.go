@@ -88,6 +88,76 @@ const docTemplate = `{
                 }
             }
         },
+        "/auth/guest": {
+            "post": {
+                "description": "Issue a guest token tied to an email so a customer without an account can use the cart and place orders. Once the guest exists, a token for it is only issued with the verification code sent to the email: a call without verification_code sends one and answers 202. Registering later with that email keeps the guest's cart and orders on the new account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Start a guest checkout",
+                "parameters": [
+                    {
+                        "description": "Guest email",
+                        "name": "guest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GuestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Guest token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.LoginResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "A verification code was sent to the email",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body: validation error details",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired verification code",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "An account with this email already exists, log in instead",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many verification requests for this email, try again later",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error while starting guest checkout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/auth/logout": {
             "post": {
                 "security": [
@@ -222,7 +292,7 @@ const docTemplate = `{
         },
         "/register": {
             "post": {
-                "description": "Create a new user account in the system",
+                "description": "Create a new user account in the system. Registering with the email of a guest takes over the guest's cart and orders: the first call sends a verification code to the email and answers 202, the registration is repeated with the code as verification_code.",
                 "consumes": [
                     "application/json"
                 ],
@@ -251,18 +321,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_handler.SuccessResponse"
                         }
                     },
+                    "202": {
+                        "description": "A verification code was sent to the email",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SuccessResponse"
+                        }
+                    },
                     "400": {
                         "description": "Invalid request body: validation error details",
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Invalid or expired verification code",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "409": {
                         "description": "Email or username already exists",
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many verification requests for this email, try again later",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error while creating user",
                         "schema": {
@@ -285,6 +373,23 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_handler.GuestRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "verification_code": {
+                    "description": "VerificationCode is needed when a guest checked out with the email before",
+                    "type": "string",
+                    "example": "042913"
+                }
+            }
+        },
         "internal_handler.LoginRequest": {
             "type": "object",
             "required": [
@@ -344,6 +449,11 @@ const docTemplate = `{
                     "type": "string",
                     "minLength": 3,
                     "example": "johndoe"
+                },
+                "verification_code": {
+                    "description": "VerificationCode is needed when a guest checked out with the email before",
+                    "type": "string",
+                    "example": "042913"
                 }
             }
         },
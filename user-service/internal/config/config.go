@@ -15,6 +15,15 @@ type Config struct {
 	ServerPort  string
 	Environment string
 	ConsulAddr  string
+
+	// Verification codes are emailed through this SMTP server
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	// MailLogOnly runs without a mail server in development, codes are then not delivered at all
+	MailLogOnly bool
 }
 
 func LoadConfig() *Config {
@@ -28,6 +37,13 @@ func LoadConfig() *Config {
 		GRPCPort:    getEnv("GRPC_PORT", "50051"),
 		Environment: getEnv("ENVIRONMENT", "development"),
 		ConsulAddr:  getEnv("CONSUL_ADDR", "consul:8500"),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailLogOnly:  getEnv("MAIL_LOG_ONLY", "false") == "true",
 	}
 }
 
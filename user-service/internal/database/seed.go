@@ -27,7 +27,7 @@ func SeedAdmin(db *gorm.DB, svc *service.UserService) {
 		Role:     "admin",
 	}
 
-	if err := svc.RegisterUser(context.Background(), adminUser); err != nil {
+	if err := svc.RegisterUser(context.Background(), adminUser, ""); err != nil {
 		log.Println("Failed to seed admin user:", err)
 		return
 	}
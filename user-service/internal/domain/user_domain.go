@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 )
 
@@ -13,3 +14,43 @@ type User struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 	Role      string    `gorm:"type:varchar(20);not null;default:'user'" json:"role" binding:"omitempty,oneof=admin user"`
 }
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+	// RoleGuest accounts are created by guest checkout. They have no password and become a
+	// regular user when someone registers with their email.
+	RoleGuest = "guest"
+)
+
+// NormalizeEmail is the form emails are stored and looked up in, so letter case never tells two accounts apart
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailVerification is a one-time code sent to an email to prove it belongs to whoever asks for the
+// guest account tied to it. Only the hash of the code is kept. Wrong codes and sent codes are counted
+// per email from CreatedAt on, a new code replaces the old one but not the counts.
+type EmailVerification struct {
+	Email     string    `gorm:"type:varchar(255);primaryKey"`
+	CodeHash  string    `gorm:"type:varchar(64);not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	Sends     int       `gorm:"not null;default:1"`
+	ExpiresAt time.Time `gorm:"not null"`
+	SentAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+const (
+	// VerificationCodeTTL is how long a verification code can be used
+	VerificationCodeTTL = 15 * time.Minute
+	// VerificationWindow is how long wrong codes and sent codes of an email are counted before starting over
+	VerificationWindow = time.Hour
+	// VerificationMaxAttempts is how many wrong codes an email accepts per window, after that it is locked
+	// until the window ends, whatever new codes are asked for
+	VerificationMaxAttempts = 5
+	// VerificationMaxSends is how many codes are sent to an email per window
+	VerificationMaxSends = 5
+	// VerificationResendInterval is how long to wait before another code is sent to the same email
+	VerificationResendInterval = time.Minute
+)
@@ -50,7 +50,7 @@ func NewAuthMiddleware(repo repository.UserRepository, secretKey string) (*jwt.G
 			}
 
 			// Find user in DB via repository
-			user, err := repo.FindByEmail(loginVals.Email)
+			user, err := repo.FindByEmail(domain.NormalizeEmail(loginVals.Email))
 			if err != nil {
 				return nil, jwt.ErrFailedAuthentication
 			}
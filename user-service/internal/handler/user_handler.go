@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"libs/logger"
 	"log"
 	"net/http"
@@ -26,6 +27,15 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 	Username string `json:"username" binding:"required,min=3" example:"johndoe"`
 	Password string `json:"password" binding:"required,min=6" example:"password123"`
+	// VerificationCode is needed when a guest checked out with the email before
+	VerificationCode string `json:"verification_code,omitempty" example:"042913"`
+}
+
+// GuestRequest represents the guest checkout payload
+type GuestRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+	// VerificationCode is needed when a guest checked out with the email before
+	VerificationCode string `json:"verification_code,omitempty" example:"042913"`
 }
 
 // SuccessResponse represents a success message
@@ -35,27 +45,37 @@ type SuccessResponse struct {
 
 // Register godoc
 // @Summary Register a new user
-// @Description Create a new user account in the system
+// @Description Create a new user account in the system. Registering with the email of a guest takes over the guest's cart and orders: the first call sends a verification code to the email and answers 202, the registration is repeated with the code as verification_code.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param user body RegisterRequest true "User Registration Data"
 // @Success 201 {object} SuccessResponse "User registered successfully"
+// @Success 202 {object} SuccessResponse "A verification code was sent to the email"
 // @Failure 400 {object} ErrorResponse "Invalid request body: validation error details"
+// @Failure 401 {object} ErrorResponse "Invalid or expired verification code"
 // @Failure 409 {object} ErrorResponse "Email or username already exists"
+// @Failure 429 {object} ErrorResponse "Too many verification requests for this email, try again later"
 // @Failure 500 {object} ErrorResponse "Internal server error while creating user"
 // @Router /register [post]
 func (h *UserHandler) Register(c *gin.Context) {
-	var user domain.User
+	var req struct {
+		domain.User
+		VerificationCode string `json:"verification_code"`
+	}
 
 	// Bind JSON to struct
-	if err := c.ShouldBindJSON(&user); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: "Invalid request body: " + err.Error()})
 		return
 	}
+	user := req.User
 
 	// Call the service layer
-	if err := h.userService.RegisterUser(c.Request.Context(), &user); err != nil {
+	if err := h.userService.RegisterUser(c.Request.Context(), &user, req.VerificationCode); err != nil {
+		if verificationError(c, err) {
+			return
+		}
 		// Check PostgreSQL unique constraint violation return 409
 		if strings.Contains(err.Error(), "duplicate key value") {
 			c.JSON(http.StatusConflict, ErrorResponse{Code: http.StatusConflict, Message: "Email or username already exists"})
@@ -71,6 +91,73 @@ func (h *UserHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, SuccessResponse{Message: "User registered successfully"})
 }
 
+// Guest godoc
+// @Summary Start a guest checkout
+// @Description Issue a guest token tied to an email so a customer without an account can use the cart and place orders. Once the guest exists, a token for it is only issued with the verification code sent to the email: a call without verification_code sends one and answers 202. Registering later with that email keeps the guest's cart and orders on the new account.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param guest body GuestRequest true "Guest email"
+// @Success 200 {object} LoginResponse "Guest token"
+// @Success 202 {object} SuccessResponse "A verification code was sent to the email"
+// @Failure 400 {object} ErrorResponse "Invalid request body: validation error details"
+// @Failure 401 {object} ErrorResponse "Invalid or expired verification code"
+// @Failure 409 {object} ErrorResponse "An account with this email already exists, log in instead"
+// @Failure 429 {object} ErrorResponse "Too many verification requests for this email, try again later"
+// @Failure 500 {object} ErrorResponse "Internal server error while starting guest checkout"
+// @Router /auth/guest [post]
+func (h *UserHandler) Guest(authMiddleware *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GuestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: "Invalid request body: " + err.Error()})
+			return
+		}
+
+		guest, err := h.userService.StartGuestSession(c.Request.Context(), req.Email, req.VerificationCode)
+		if err != nil {
+			if verificationError(c, err) {
+				return
+			}
+			if errors.Is(err, service.ErrAccountExists) {
+				c.JSON(http.StatusConflict, ErrorResponse{Code: http.StatusConflict, Message: "An account with this email already exists, log in instead"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: http.StatusInternalServerError, Message: "Internal server error while starting guest checkout"})
+			return
+		}
+
+		// Guest tokens carry role "guest", which order service only accepts for checkout
+		token, err := authMiddleware.TokenGenerator(c.Request.Context(), guest)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Code: http.StatusInternalServerError, Message: "Internal server error while starting guest checkout"})
+			return
+		}
+
+		c.JSON(http.StatusOK, LoginResponse{
+			AccessToken:  token.AccessToken,
+			ExpiresIn:    int(token.ExpiresIn()),
+			RefreshToken: token.RefreshToken,
+			TokenType:    token.TokenType,
+		})
+	}
+}
+
+// verificationError answers the errors of email verification, reporting whether err was one
+func verificationError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrVerificationRequired):
+		c.JSON(http.StatusAccepted, SuccessResponse{Message: "A verification code was sent to the email, repeat the request with it as verification_code"})
+	case errors.Is(err, service.ErrInvalidVerificationCode):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid or expired verification code"})
+	case errors.Is(err, service.ErrVerificationRateLimited):
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Code: http.StatusTooManyRequests, Message: "Too many verification requests for this email, try again later"})
+	default:
+		return false
+	}
+	return true
+}
+
 // Profile godoc
 // @Summary Get user profile
 // @Description Get current user profile information
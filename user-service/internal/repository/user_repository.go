@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"time"
 	"user-service/internal/domain"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
@@ -11,6 +13,10 @@ type UserRepository interface {
 	FindByEmail(email string) (*domain.User, error)
 	FindByID(userID uint) (*domain.User, error)
 	UpdatePassword(userID uint, newPassword string) error
+	UpgradeGuest(user *domain.User) error
+	SaveVerification(verification *domain.EmailVerification) (bool, error)
+	ConsumeVerification(email, codeHash string, now time.Time) (bool, error)
+	AddVerificationAttempt(email string) error
 }
 
 type PostgresRepository struct {
@@ -31,9 +37,10 @@ func (r *PostgresRepository) Save(user *domain.User) error {
 	return r.db.Create(user).Error
 }
 
+// FindByEmail ignores letter case, accounts registered before emails were normalized keep their own
 func (r *PostgresRepository) FindByEmail(email string) (*domain.User, error) {
 	var user domain.User
-	result := r.db.Where("email = ?", email).First(&user)
+	result := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -55,6 +62,66 @@ func (r *PostgresRepository) UpdatePassword(userID uint, newPassword string) err
 	}
 }
 
+// UpgradeGuest turns the guest account user.ID into a regular account with the username, password and role of user
+func (r *PostgresRepository) UpgradeGuest(user *domain.User) error {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND role = ?", user.ID, domain.RoleGuest).
+		Updates(map[string]interface{}{"username": user.Username, "password": user.Password, "role": user.Role})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SaveVerification stores the verification code of an email, replacing the one sent before, and reports
+// whether it did. The wrong codes and sent codes of the current window are kept, so asking for a new code
+// neither resets the attempts nor sends one while the email is locked, over its sends or asked too recently.
+func (r *PostgresRepository) SaveVerification(verification *domain.EmailVerification) (bool, error) {
+	windowStart := verification.SentAt.Add(-domain.VerificationWindow)
+	resendAfter := verification.SentAt.Add(-domain.VerificationResendInterval)
+	expired := gorm.Expr("email_verifications.created_at <= ?", windowStart)
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "email"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"code_hash":  gorm.Expr("excluded.code_hash"),
+			"expires_at": gorm.Expr("excluded.expires_at"),
+			"sent_at":    gorm.Expr("excluded.sent_at"),
+			"attempts":   gorm.Expr("CASE WHEN ? THEN 0 ELSE email_verifications.attempts END", expired),
+			"sends":      gorm.Expr("CASE WHEN ? THEN 1 ELSE email_verifications.sends + 1 END", expired),
+			"created_at": gorm.Expr("CASE WHEN ? THEN excluded.created_at ELSE email_verifications.created_at END", expired),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("email_verifications.sent_at <= ?", resendAfter),
+			gorm.Expr("(? OR (email_verifications.attempts < ? AND email_verifications.sends < ?))",
+				expired, domain.VerificationMaxAttempts, domain.VerificationMaxSends),
+		}},
+	}).Create(verification)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ConsumeVerification deletes the verification code of an email when codeHash matches it and it can still be
+// used, reporting whether it did. Deleting it makes the code usable once, even by concurrent requests.
+func (r *PostgresRepository) ConsumeVerification(email, codeHash string, now time.Time) (bool, error) {
+	result := r.db.Where("email = ? AND code_hash = ? AND expires_at > ? AND attempts < ?", email, codeHash, now, domain.VerificationMaxAttempts).
+		Delete(&domain.EmailVerification{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *PostgresRepository) AddVerificationAttempt(email string) error {
+	return r.db.Model(&domain.EmailVerification{}).Where("email = ?", email).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
 // MOCK REPOSITORY IMPLEMENTATION
 // func NewMockRepository() UserRepository {
 // 	return &MockRepository{users: []domain.User{}}
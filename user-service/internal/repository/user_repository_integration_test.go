@@ -25,7 +25,7 @@ func openUserTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Skipf("skipping integration test, cannot connect to user-db: %v", err)
 	}
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
+	if err := db.AutoMigrate(&domain.User{}, &domain.EmailVerification{}); err != nil {
 		t.Fatalf("AutoMigrate() error = %v", err)
 	}
 	return db
@@ -56,3 +56,70 @@ func TestUserRepository_SaveAndFindByEmail_Integration(t *testing.T) {
 		t.Fatalf("expected email %s, got %s", email, got.Email)
 	}
 }
+
+func TestUserRepository_UpgradeGuest_Integration(t *testing.T) {
+	db := openUserTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	email := fmt.Sprintf("guest-%d@example.com", time.Now().UnixNano())
+	guest := &domain.User{Username: "guest-" + email, Email: email, Role: domain.RoleGuest}
+	if err := repo.Save(guest); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	upgraded := &domain.User{ID: guest.ID, Username: email, Password: "hashed", Role: domain.RoleUser}
+	if err := repo.UpgradeGuest(upgraded); err != nil {
+		t.Fatalf("UpgradeGuest() error = %v", err)
+	}
+	got, err := repo.FindByEmail(email)
+	if err != nil {
+		t.Fatalf("FindByEmail() error = %v", err)
+	}
+	if got.ID != guest.ID || got.Role != domain.RoleUser || got.Password != "hashed" {
+		t.Fatalf("expected guest %d upgraded in place, got %#v", guest.ID, got)
+	}
+
+	if err := repo.UpgradeGuest(upgraded); err == nil {
+		t.Fatal("expected upgrading a registered account to fail")
+	}
+}
+
+func TestUserRepository_SaveVerificationKeepsAttemptsAcrossCodes_Integration(t *testing.T) {
+	db := openUserTestDB(t)
+	repo := NewPostgresRepository(db)
+
+	email := fmt.Sprintf("verify-%d@example.com", time.Now().UnixNano())
+	sentAt := time.Now()
+	code := func(hash string, at time.Time) *domain.EmailVerification {
+		return &domain.EmailVerification{Email: email, CodeHash: hash, Sends: 1, ExpiresAt: at.Add(domain.VerificationCodeTTL), SentAt: at, CreatedAt: at}
+	}
+
+	if saved, err := repo.SaveVerification(code("first", sentAt)); err != nil || !saved {
+		t.Fatalf("SaveVerification() = %v, %v", saved, err)
+	}
+	if saved, err := repo.SaveVerification(code("too-soon", sentAt.Add(time.Second))); err != nil || saved {
+		t.Fatalf("expected a code asked for too soon refused, got %v, %v", saved, err)
+	}
+
+	for i := 0; i < domain.VerificationMaxAttempts; i++ {
+		if err := repo.AddVerificationAttempt(email); err != nil {
+			t.Fatalf("AddVerificationAttempt() error = %v", err)
+		}
+	}
+	if saved, err := repo.SaveVerification(code("locked", sentAt.Add(2*domain.VerificationResendInterval))); err != nil || saved {
+		t.Fatalf("expected no new code for a locked email, got %v, %v", saved, err)
+	}
+
+	// Once the window ends the email starts over
+	later := sentAt.Add(domain.VerificationWindow + time.Minute)
+	if saved, err := repo.SaveVerification(code("next", later)); err != nil || !saved {
+		t.Fatalf("SaveVerification() after the window = %v, %v", saved, err)
+	}
+	var got domain.EmailVerification
+	if err := db.Where("email = ?", email).First(&got).Error; err != nil {
+		t.Fatalf("load verification error = %v", err)
+	}
+	if got.CodeHash != "next" || got.Attempts != 0 || got.Sends != 1 {
+		t.Fatalf("expected a fresh window, got %#v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"libs/logger"
+	"net"
+	"net/smtp"
+	"strings"
+	"user-service/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// VerificationSender delivers email verification codes to their owner
+type VerificationSender interface {
+	SendVerificationCode(ctx context.Context, email, code string) error
+}
+
+// SMTPVerificationSender emails verification codes through an SMTP server
+type SMTPVerificationSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPVerificationSender sends from the address from through host:port, logging in when username is set
+func NewSMTPVerificationSender(host, port, username, password, from string) *SMTPVerificationSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPVerificationSender{addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+func (s *SMTPVerificationSender) SendVerificationCode(ctx context.Context, email, code string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", email)
+	msg.WriteString("Subject: Your verification code\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "Your verification code is %s. It expires in %d minutes.\r\n", code, int(domain.VerificationCodeTTL.Minutes()))
+	msg.WriteString("If you did not ask for it, you can ignore this email.\r\n")
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{email}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to email verification code: %w", err)
+	}
+	return nil
+}
+
+// LogVerificationSender sends nothing and only logs that a code was issued, for development without a mail
+// server. The code itself is never logged, anyone reading the logs could claim the guest account with it.
+type LogVerificationSender struct{}
+
+func NewLogVerificationSender() *LogVerificationSender {
+	return &LogVerificationSender{}
+}
+
+func (s *LogVerificationSender) SendVerificationCode(ctx context.Context, email, code string) error {
+	logger.ForContext(ctx).Warn("Email verification code not sent, mail is disabled", zap.String("email", email))
+	return nil
+}
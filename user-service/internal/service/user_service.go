@@ -2,27 +2,48 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"libs/logger"
+	"math/big"
 	"time"
 	"user-service/internal/domain"
 	"user-service/internal/repository"
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAccountExists is returned when a guest checkout uses the email of a registered account
+	ErrAccountExists = errors.New("an account with this email already exists")
+	// ErrVerificationRequired is returned when the email of a guest account has to be verified, a code was sent to it
+	ErrVerificationRequired = errors.New("email verification required")
+	// ErrInvalidVerificationCode is returned for a wrong, expired or used verification code
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+	// ErrVerificationRateLimited is returned when no code is sent to an email because it asked too often
+	// or guessed wrong too often, until the verification window ends
+	ErrVerificationRateLimited = errors.New("too many verification requests")
 )
 
 type UserService struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	sender repository.VerificationSender
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repository.UserRepository, sender repository.VerificationSender) *UserService {
+	return &UserService{repo: repo, sender: sender}
 }
 
-func (s *UserService) RegisterUser(ctx context.Context, user *domain.User) error {
+// RegisterUser creates the account of user. Registering with the email of a guest account takes the guest's
+// cart and orders over, which needs verificationCode to prove the email belongs to the new user.
+func (s *UserService) RegisterUser(ctx context.Context, user *domain.User, verificationCode string) error {
 	l := logger.ForContext(ctx)
+	user.Email = domain.NormalizeEmail(user.Email)
 	// Hash the password before saving
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -31,6 +52,28 @@ func (s *UserService) RegisterUser(ctx context.Context, user *domain.User) error
 	}
 	user.Password = string(hashedPassword)
 	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	// Registering with the email of a guest account keeps the account, so the guest's cart and orders stay attached
+	existing, err := s.repo.FindByEmail(user.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		l.Error("failed to look up user by email", zap.Error(err))
+		return fmt.Errorf("failed to look up user by email: %w", err)
+	}
+	if existing != nil && existing.Role == domain.RoleGuest {
+		if err := s.verifyEmail(ctx, user.Email, verificationCode); err != nil {
+			return err
+		}
+		user.ID = existing.ID
+		if err := s.repo.UpgradeGuest(user); err != nil {
+			l.Error("failed to upgrade guest account", zap.Error(err))
+			return fmt.Errorf("failed to upgrade guest account: %w", err)
+		}
+		l.Info("Guest account registered", zap.Uint("userID", user.ID), zap.String("email", user.Email))
+		return nil
+	}
 
 	err = s.repo.Save(user)
 	if err != nil {
@@ -71,3 +114,108 @@ func (s *UserService) ChangePassword(ctx context.Context, userID uint, oldPasswo
 	l.Info("User password changed successfully", zap.Uint("userID", userID))
 	return nil
 }
+
+// StartGuestSession returns the guest account for email, creating it on first use. An existing guest
+// holds orders and addresses, so it is only returned with the verification code sent to its email. Emails
+// of registered accounts are refused, those customers have to log in.
+func (s *UserService) StartGuestSession(ctx context.Context, email, verificationCode string) (*domain.User, error) {
+	l := logger.ForContext(ctx)
+	email = domain.NormalizeEmail(email)
+	existing, err := s.repo.FindByEmail(email)
+	if err == nil {
+		if existing.Role != domain.RoleGuest {
+			return nil, ErrAccountExists
+		}
+		if err := s.verifyEmail(ctx, email, verificationCode); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		l.Error("failed to look up user by email", zap.Error(err))
+		return nil, fmt.Errorf("failed to look up user by email: %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate guest username: %w", err)
+	}
+	guest := &domain.User{
+		Email:     email,
+		Username:  "guest-" + hex.EncodeToString(suffix),
+		Role:      domain.RoleGuest,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Save(guest); err != nil {
+		l.Error("failed to save guest", zap.Error(err))
+		return nil, fmt.Errorf("failed to save guest: %w", err)
+	}
+	l.Info("Guest account created", zap.Uint("userID", guest.ID), zap.String("email", email))
+	return guest, nil
+}
+
+// verifyEmail checks code against the verification code sent to email. Without a code a new one is sent and
+// ErrVerificationRequired returned.
+func (s *UserService) verifyEmail(ctx context.Context, email, code string) error {
+	l := logger.ForContext(ctx)
+	if code == "" {
+		if err := s.sendVerificationCode(ctx, email); err != nil {
+			return err
+		}
+		return ErrVerificationRequired
+	}
+
+	consumed, err := s.repo.ConsumeVerification(email, hashVerificationCode(code), time.Now())
+	if err != nil {
+		l.Error("failed to verify email", zap.Error(err))
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if !consumed {
+		if err := s.repo.AddVerificationAttempt(email); err != nil {
+			l.Error("failed to count verification attempt", zap.Error(err))
+		}
+		l.Warn("Invalid email verification code", zap.String("email", email))
+		return ErrInvalidVerificationCode
+	}
+	l.Info("Email verified", zap.String("email", email))
+	return nil
+}
+
+func (s *UserService) sendVerificationCode(ctx context.Context, email string) error {
+	l := logger.ForContext(ctx)
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	now := time.Now()
+	verification := &domain.EmailVerification{
+		Email:     email,
+		CodeHash:  hashVerificationCode(code),
+		Sends:     1,
+		ExpiresAt: now.Add(domain.VerificationCodeTTL),
+		SentAt:    now,
+		CreatedAt: now,
+	}
+	saved, err := s.repo.SaveVerification(verification)
+	if err != nil {
+		l.Error("failed to save verification code", zap.Error(err))
+		return fmt.Errorf("failed to save verification code: %w", err)
+	}
+	if !saved {
+		l.Warn("Verification code not sent, too many requests", zap.String("email", email))
+		return ErrVerificationRateLimited
+	}
+	if err := s.sender.SendVerificationCode(ctx, email, code); err != nil {
+		l.Error("failed to send verification code", zap.Error(err))
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+	l.Info("Verification code sent", zap.String("email", email))
+	return nil
+}
+
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
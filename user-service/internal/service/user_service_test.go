@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 	"user-service/internal/domain"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type mockUserRepository struct {
@@ -17,6 +19,10 @@ type mockUserRepository struct {
 	updatePasswordID  uint
 	updatedPassword   string
 	updatePasswordErr error
+	findByEmailUser   *domain.User
+	upgradedUser      *domain.User
+	verification      *domain.EmailVerification
+	attempts          int
 }
 
 func (m *mockUserRepository) Save(user *domain.User) error {
@@ -25,7 +31,10 @@ func (m *mockUserRepository) Save(user *domain.User) error {
 }
 
 func (m *mockUserRepository) FindByEmail(email string) (*domain.User, error) {
-	return nil, errors.New("not implemented")
+	if m.findByEmailUser == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m.findByEmailUser, nil
 }
 
 func (m *mockUserRepository) FindByID(userID uint) (*domain.User, error) {
@@ -38,12 +47,51 @@ func (m *mockUserRepository) UpdatePassword(userID uint, newPassword string) err
 	return m.updatePasswordErr
 }
 
+func (m *mockUserRepository) UpgradeGuest(user *domain.User) error {
+	m.upgradedUser = user
+	return nil
+}
+
+// SaveVerification keeps the wrong attempts across codes and refuses new codes once they are used up
+func (m *mockUserRepository) SaveVerification(verification *domain.EmailVerification) (bool, error) {
+	if m.attempts >= domain.VerificationMaxAttempts {
+		return false, nil
+	}
+	m.verification = verification
+	return true, nil
+}
+
+func (m *mockUserRepository) ConsumeVerification(email, codeHash string, now time.Time) (bool, error) {
+	v := m.verification
+	if v == nil || v.Email != email || v.CodeHash != codeHash || !now.Before(v.ExpiresAt) || m.attempts >= domain.VerificationMaxAttempts {
+		return false, nil
+	}
+	m.verification = nil
+	return true, nil
+}
+
+func (m *mockUserRepository) AddVerificationAttempt(email string) error {
+	m.attempts++
+	return nil
+}
+
+// mockVerificationSender keeps the last code sent
+type mockVerificationSender struct {
+	email string
+	code  string
+}
+
+func (m *mockVerificationSender) SendVerificationCode(ctx context.Context, email, code string) error {
+	m.email, m.code = email, code
+	return nil
+}
+
 func TestRegisterUserHashesPasswordBeforeSaving(t *testing.T) {
 	repo := &mockUserRepository{}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mockVerificationSender{})
 
 	user := &domain.User{Email: "john@example.com", Password: "plain-password"}
-	if err := svc.RegisterUser(context.Background(), user); err != nil {
+	if err := svc.RegisterUser(context.Background(), user, ""); err != nil {
 		t.Fatalf("RegisterUser() error = %v", err)
 	}
 
@@ -65,7 +113,7 @@ func TestChangePasswordRejectsInvalidOldPassword(t *testing.T) {
 	}
 
 	repo := &mockUserRepository{findByIDUser: &domain.User{ID: 10, Password: string(hash)}}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mockVerificationSender{})
 
 	err = svc.ChangePassword(context.Background(), 10, "wrong-old", "new-password")
 	if err == nil {
@@ -83,7 +131,7 @@ func TestChangePasswordUpdatesPasswordWhenOldPasswordMatches(t *testing.T) {
 	}
 
 	repo := &mockUserRepository{findByIDUser: &domain.User{ID: 33, Password: string(hash)}}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mockVerificationSender{})
 
 	err = svc.ChangePassword(context.Background(), 33, "correct-old", "new-password")
 	if err != nil {
@@ -100,3 +148,117 @@ func TestChangePasswordUpdatesPasswordWhenOldPasswordMatches(t *testing.T) {
 		t.Fatalf("updated password is not valid bcrypt hash: %v", err)
 	}
 }
+
+func TestRegisterUserUpgradesGuestAccountOnceEmailIsVerified(t *testing.T) {
+	repo := &mockUserRepository{findByEmailUser: &domain.User{ID: 21, Email: "john@example.com", Username: "guest-1a2b", Role: domain.RoleGuest}}
+	sender := &mockVerificationSender{}
+	svc := NewUserService(repo, sender)
+
+	user := &domain.User{Email: "john@example.com", Username: "john", Password: "plain-password"}
+	if err := svc.RegisterUser(context.Background(), user, ""); !errors.Is(err, ErrVerificationRequired) {
+		t.Fatalf("expected ErrVerificationRequired, got %v", err)
+	}
+	if repo.upgradedUser != nil || sender.email != "john@example.com" || len(sender.code) != 6 {
+		t.Fatalf("expected a code sent to the guest's email before any upgrade, got %#v and %q", repo.upgradedUser, sender.code)
+	}
+
+	user = &domain.User{Email: "john@example.com", Username: "john", Password: "plain-password"}
+	if err := svc.RegisterUser(context.Background(), user, sender.code); err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	if repo.savedUser != nil {
+		t.Fatal("expected the guest account to be reused instead of saving a new user")
+	}
+	if repo.upgradedUser == nil || repo.upgradedUser.ID != 21 || repo.upgradedUser.Role != domain.RoleUser || repo.upgradedUser.Username != "john" {
+		t.Fatalf("expected guest 21 upgraded to a user, got %#v", repo.upgradedUser)
+	}
+}
+
+func TestRegisterUserUpgradesGuestWithDifferentlyCasedEmail(t *testing.T) {
+	repo := &mockUserRepository{findByEmailUser: &domain.User{ID: 21, Email: "john@example.com", Username: "guest-1a2b", Role: domain.RoleGuest}}
+	sender := &mockVerificationSender{}
+	svc := NewUserService(repo, sender)
+
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", ""); !errors.Is(err, ErrVerificationRequired) {
+		t.Fatalf("expected ErrVerificationRequired, got %v", err)
+	}
+	user := &domain.User{Email: " John@Example.COM", Username: "john", Password: "plain-password"}
+	if err := svc.RegisterUser(context.Background(), user, sender.code); err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	if repo.upgradedUser == nil || repo.upgradedUser.ID != 21 || repo.upgradedUser.Email != "john@example.com" {
+		t.Fatalf("expected guest 21 upgraded under the normalized email, got %#v", repo.upgradedUser)
+	}
+}
+
+func TestStartGuestSessionCreatesGuestOnce(t *testing.T) {
+	repo := &mockUserRepository{}
+	sender := &mockVerificationSender{}
+	svc := NewUserService(repo, sender)
+
+	guest, err := svc.StartGuestSession(context.Background(), "john@example.com", "")
+	if err != nil {
+		t.Fatalf("StartGuestSession() error = %v", err)
+	}
+	if guest.Role != domain.RoleGuest || guest.Password != "" || repo.savedUser != guest {
+		t.Fatalf("expected a saved guest without password, got %#v", guest)
+	}
+
+	// The guest now holds orders, only the owner of the email gets its token again
+	repo.savedUser = nil
+	repo.findByEmailUser = guest
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", ""); !errors.Is(err, ErrVerificationRequired) {
+		t.Fatalf("expected ErrVerificationRequired, got %v", err)
+	}
+	again, err := svc.StartGuestSession(context.Background(), "john@example.com", sender.code)
+	if err != nil {
+		t.Fatalf("StartGuestSession() error = %v", err)
+	}
+	if again != guest || repo.savedUser != nil {
+		t.Fatal("expected the existing guest to be reused")
+	}
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", sender.code); !errors.Is(err, ErrInvalidVerificationCode) {
+		t.Fatalf("expected a used code refused, got %v", err)
+	}
+}
+
+func TestStartGuestSessionRefusesWrongVerificationCode(t *testing.T) {
+	repo := &mockUserRepository{findByEmailUser: &domain.User{ID: 21, Email: "john@example.com", Role: domain.RoleGuest}}
+	sender := &mockVerificationSender{}
+	svc := NewUserService(repo, sender)
+
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", ""); !errors.Is(err, ErrVerificationRequired) {
+		t.Fatalf("expected ErrVerificationRequired, got %v", err)
+	}
+	wrong := "000000"
+	if sender.code == wrong {
+		wrong = "000001"
+	}
+	for attempt := 0; attempt < domain.VerificationMaxAttempts; attempt++ {
+		if _, err := svc.StartGuestSession(context.Background(), "john@example.com", wrong); !errors.Is(err, ErrInvalidVerificationCode) {
+			t.Fatalf("expected ErrInvalidVerificationCode, got %v", err)
+		}
+	}
+	// Too many wrong codes use the right one up
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", sender.code); !errors.Is(err, ErrInvalidVerificationCode) {
+		t.Fatalf("expected the code refused after %d wrong attempts, got %v", domain.VerificationMaxAttempts, err)
+	}
+	// and asking for a new code does not start the guessing over
+	sent := sender.code
+	sender.code = ""
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", ""); !errors.Is(err, ErrVerificationRateLimited) {
+		t.Fatalf("expected ErrVerificationRateLimited, got %v", err)
+	}
+	if sender.code != "" || repo.verification.CodeHash != hashVerificationCode(sent) {
+		t.Fatal("expected no new code sent to a locked email")
+	}
+}
+
+func TestStartGuestSessionRejectsRegisteredEmail(t *testing.T) {
+	repo := &mockUserRepository{findByEmailUser: &domain.User{ID: 5, Email: "john@example.com", Role: domain.RoleUser}}
+	svc := NewUserService(repo, &mockVerificationSender{})
+
+	if _, err := svc.StartGuestSession(context.Background(), "john@example.com", ""); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("expected ErrAccountExists, got %v", err)
+	}
+}